@@ -0,0 +1,241 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/anacrolix/missinggo/perf"
+	"github.com/gin-gonic/gin"
+
+	"github.com/elgatito/elementum/cache"
+	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/library/uid"
+	"github.com/elgatito/elementum/tmdb"
+	"github.com/elgatito/elementum/trakt"
+	"github.com/elgatito/elementum/util/ical"
+	"github.com/elgatito/elementum/util/ip"
+)
+
+const (
+	// calendarDefaultDays is a default number of days, published in calendar feed
+	calendarDefaultDays = 30
+	// calendarMaxDays is a maximum number of days, Trakt returns for a calendar request
+	calendarMaxDays = 33
+	// calendarDefaultRuntime is a duration of events without known runtime
+	calendarDefaultRuntime = 60 * time.Minute
+)
+
+// CalendarICS publishes upcoming episodes of followed and library shows
+// and releases of watchlisted movies as iCalendar feed.
+// Time zone of events can be set with "tz" parameter, local time zone is used by default.
+func CalendarICS(ctx *gin.Context) {
+	defer perf.ScopeTimer()()
+
+	location := time.Local
+	if tz := ctx.Query("tz"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			ctx.String(400, err.Error())
+			return
+		}
+		location = loc
+	}
+
+	days, _ := strconv.Atoi(ctx.DefaultQuery("days", strconv.Itoa(calendarDefaultDays)))
+	if days <= 0 || days > calendarMaxDays {
+		days = calendarDefaultDays
+	}
+
+	now := time.Now().In(location)
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+	end := start.AddDate(0, 0, days)
+
+	c := &calendarFeed{
+		host:   ip.GetContextHTTPHost(ctx),
+		events: map[string]*ical.Event{},
+	}
+
+	if config.Get().TraktAuthorized {
+		c.addTraktShows(start, days)
+		c.addTraktMovies(start, days)
+	}
+	c.addLibraryShows(start, end)
+
+	cal := &ical.Calendar{
+		ProductID: "-//Elementum//Calendar//EN",
+		Name:      "Elementum",
+		Location:  location,
+		Events:    c.sorted(),
+	}
+
+	ctx.Header("Content-Type", "text/calendar; charset=utf-8")
+	ctx.Header("Content-Disposition", `inline; filename="elementum.ics"`)
+	ctx.Status(200)
+	if err := cal.Write(ctx.Writer); err != nil {
+		log.Warningf("Cannot write calendar: %s", err)
+	}
+}
+
+// calendarFeed collects calendar events, first added event wins for the same item
+type calendarFeed struct {
+	mu     sync.Mutex
+	host   string
+	events map[string]*ical.Event
+}
+
+func (c *calendarFeed) add(e *ical.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.events[e.UID]; !ok {
+		c.events[e.UID] = e
+	}
+}
+
+func (c *calendarFeed) sorted() []*ical.Event {
+	ret := make([]*ical.Event, 0, len(c.events))
+	for _, e := range c.events {
+		ret = append(ret, e)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Start.Before(ret[j].Start)
+	})
+	return ret
+}
+
+func (c *calendarFeed) episodeEvent(showID int, showName string, season, episode int, title string) *ical.Event {
+	e := &ical.Event{
+		UID:     fmt.Sprintf("episode-%d-%d-%d@elementum", showID, season, episode),
+		Summary: fmt.Sprintf("%s S%02dE%02d", showName, season, episode),
+		URL:     URLQuery(c.host+fmt.Sprintf("/show/%d/season/%d/episode/%d/play", showID, season, episode), "external", "1"),
+	}
+	if title != "" {
+		e.Summary += " - " + title
+	}
+	return e
+}
+
+// addTraktShows adds episodes from Trakt calendar of watched, collected and watchlisted shows
+func (c *calendarFeed) addTraktShows(start time.Time, days int) {
+	shows, err := trakt.CalendarShowsRange("my/shows", start, days, cache.TraktShowsCalendarMyExpire)
+	if err != nil {
+		log.Warningf("Cannot get Trakt shows calendar: %s", err)
+		return
+	}
+
+	for _, s := range shows {
+		if s == nil || s.Show == nil || s.Show.IDs == nil || s.Show.IDs.TMDB == 0 || s.Episode == nil {
+			continue
+		}
+		aired, err := time.Parse(time.RFC3339, s.FirstAired)
+		if err != nil {
+			continue
+		}
+
+		e := c.episodeEvent(s.Show.IDs.TMDB, s.Show.Title, s.Episode.Season, s.Episode.Number, s.Episode.Title)
+		e.Description = s.Episode.Overview
+		e.Start = aired
+		e.Duration = calendarRuntime(s.Episode.Runtime, s.Show.Runtime)
+		c.add(e)
+	}
+}
+
+// addTraktMovies adds releases of watchlisted and collected movies from Trakt calendar
+func (c *calendarFeed) addTraktMovies(start time.Time, days int) {
+	movies, err := trakt.CalendarMoviesRange("my/movies", start, days, cache.TraktMoviesCalendarMyExpire)
+	if err != nil {
+		log.Warningf("Cannot get Trakt movies calendar: %s", err)
+		return
+	}
+
+	for _, m := range movies {
+		if m == nil || m.Movie == nil || m.Movie.IDs == nil || m.Movie.IDs.TMDB == 0 {
+			continue
+		}
+		released, err := time.Parse(time.DateOnly, m.Released)
+		if err != nil {
+			continue
+		}
+
+		c.add(&ical.Event{
+			UID:         fmt.Sprintf("movie-%d@elementum", m.Movie.IDs.TMDB),
+			Summary:     fmt.Sprintf("%s (%d)", m.Movie.Title, m.Movie.Year),
+			Description: m.Movie.Overview,
+			URL:         URLQuery(c.host+fmt.Sprintf("/movie/%d/play", m.Movie.IDs.TMDB), "external", "1"),
+			Start:       released,
+			AllDay:      true,
+		})
+	}
+}
+
+// addLibraryShows adds upcoming episodes of Kodi library shows, which are not known to Trakt calendar.
+// TMDB has only air dates, so these episodes are published as all-day events.
+func (c *calendarFeed) addLibraryShows(start, end time.Time) {
+	l := uid.Get()
+	mu := l.GetMutex(uid.ShowsMutex)
+	mu.RLock()
+	ids := make([]int, 0, len(l.Shows))
+	for _, s := range l.Shows {
+		if s != nil && s.UIDs != nil && s.UIDs.TMDB != 0 {
+			ids = append(ids, s.UIDs.TMDB)
+		}
+	}
+	mu.RUnlock()
+
+	language := config.Get().Language
+
+	var wg sync.WaitGroup
+	wg.Add(len(ids))
+	for _, id := range ids {
+		go func(id int) {
+			defer wg.Done()
+
+			show := tmdb.GetShow(id, language)
+			if show == nil || show.NextEpisodeToAir == nil {
+				return
+			}
+			// Air dates are compared as strings, since they are in YYYY-MM-DD format
+			from, to := start.Format(time.DateOnly), end.Format(time.DateOnly)
+			if show.NextEpisodeToAir.AirDate == "" || show.NextEpisodeToAir.AirDate >= to {
+				return
+			}
+
+			season := tmdb.GetSeason(id, show.NextEpisodeToAir.SeasonNumber, language, len(show.Seasons), true)
+			if season == nil {
+				return
+			}
+
+			for _, episode := range season.Episodes {
+				if episode == nil {
+					continue
+				}
+				if episode.AirDate < from || episode.AirDate >= to {
+					continue
+				}
+				aired, err := time.Parse(time.DateOnly, episode.AirDate)
+				if err != nil {
+					continue
+				}
+
+				e := c.episodeEvent(id, show.GetName(), episode.SeasonNumber, episode.EpisodeNumber, episode.GetName(show))
+				e.Description = episode.Overview
+				e.Start = aired
+				e.AllDay = true
+				c.add(e)
+			}
+		}(id)
+	}
+	wg.Wait()
+}
+
+func calendarRuntime(runtimes ...int) time.Duration {
+	for _, r := range runtimes {
+		if r > 0 {
+			return time.Duration(r) * time.Minute
+		}
+	}
+	return calendarDefaultRuntime
+}
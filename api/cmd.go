@@ -8,6 +8,7 @@ import (
 	"github.com/elgatito/elementum/config"
 	"github.com/elgatito/elementum/database"
 	"github.com/elgatito/elementum/library"
+	"github.com/elgatito/elementum/library/recommend"
 	"github.com/elgatito/elementum/xbmc"
 )
 
@@ -214,6 +215,7 @@ func ClearDatabaseMovies(ctx *gin.Context) {
 
 	query := db.Select(q.Eq("MediaType", library.MovieType))
 	_ = query.Delete(&database.LibraryItem{})
+	recommend.Clear()
 
 	xbmcHost.Notify("Elementum", "LOCALIZE[30472]", config.AddonIcon())
 
@@ -248,6 +250,7 @@ func ClearDatabaseShows(ctx *gin.Context) {
 
 	query := database.GetStormDB().Select(q.Eq("MediaType", library.ShowType))
 	_ = query.Delete(&database.LibraryItem{})
+	recommend.Clear()
 
 	xbmcHost.Notify("Elementum", "LOCALIZE[30472]", config.AddonIcon())
 
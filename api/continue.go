@@ -0,0 +1,94 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/anacrolix/missinggo/perf"
+	"github.com/gin-gonic/gin"
+
+	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/library/history"
+	"github.com/elgatito/elementum/tmdb"
+	"github.com/elgatito/elementum/xbmc"
+)
+
+// ContinueWatching lists not finished items from local watch history
+func ContinueWatching(ctx *gin.Context) {
+	defer perf.ScopeTimer()()
+
+	language := config.Get().Language
+
+	items := xbmc.ListItems{}
+	for _, h := range history.InProgress() {
+		var item *xbmc.ListItem
+
+		switch h.MediaType {
+		case history.MovieType:
+			movie := tmdb.GetMovie(h.TMDBID, language)
+			if movie == nil {
+				continue
+			}
+
+			item = movie.ToListItem()
+			thisURL := URLForXBMC("/movie/%d/", movie.ID) + "%s/%s"
+			item.Path = contextPlayURL(thisURL, fmt.Sprintf("%s (%d)", item.Info.OriginalTitle, item.Info.Year), false)
+
+		case history.EpisodeType:
+			show := tmdb.GetShow(h.ShowID, language)
+			if show == nil {
+				continue
+			}
+			episode := tmdb.GetEpisode(h.ShowID, h.Season, h.Episode, language)
+			if episode == nil {
+				continue
+			}
+
+			item = episode.ToListItem(show, nil)
+			setEpisodeItemActions(show, item)
+			item.Label = fmt.Sprintf("%s - %dx%02d %s", show.GetName(), h.Season, h.Episode, episode.GetName(show))
+
+		default:
+			item = &xbmc.ListItem{
+				Label: h.Title,
+				Info: &xbmc.ListItemInfo{
+					Mediatype: "video",
+				},
+				Properties: &xbmc.ListItemProperties{},
+			}
+			if h.URI != "" {
+				item.Path = URLQuery(URLForXBMC("/play"), "uri", h.URI, "oindex", strconv.Itoa(h.Index))
+			} else {
+				item.Path = URLQuery(URLForXBMC("/play"), "resume", h.InfoHash, "oindex", strconv.Itoa(h.Index))
+			}
+			item.Properties.ResumeTime = strconv.FormatFloat(h.Position, 'f', 6, 64)
+			item.Properties.TotalTime = strconv.FormatFloat(h.Duration, 'f', 6, 64)
+		}
+
+		item.ContextMenu = append(item.ContextMenu, []string{"LOCALIZE[30406]", fmt.Sprintf("RunPlugin(%s)",
+			URLQuery(URLForXBMC("/continue/remove"), "id", h.ID))})
+		item.IsPlayable = true
+		items = append(items, item)
+	}
+
+	ctx.JSON(200, xbmc.NewView("", filterListItems(items)))
+}
+
+// ContinueWatchingRemove removes item from local watch history
+func ContinueWatchingRemove(ctx *gin.Context) {
+	defer perf.ScopeTimer()()
+
+	xbmcHost, _ := xbmc.GetXBMCHostWithContext(ctx)
+	if xbmcHost == nil {
+		return
+	}
+
+	if id := ctx.Query("id"); id != "" {
+		if err := history.Remove(id); err != nil {
+			log.Debugf("Could not remove watch history item %s: %s", id, err)
+		}
+	}
+
+	xbmcHost.Refresh()
+	ctx.String(200, "")
+}
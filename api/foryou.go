@@ -0,0 +1,81 @@
+package api
+
+import (
+	"strconv"
+
+	"github.com/anacrolix/missinggo/perf"
+	"github.com/gin-gonic/gin"
+
+	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/library/recommend"
+	"github.com/elgatito/elementum/tmdb"
+	"github.com/elgatito/elementum/trakt"
+)
+
+// ForYouMovies shows movies, recommended from local history and library
+func ForYouMovies(ctx *gin.Context) {
+	defer perf.ScopeTimer()()
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	ids := recommend.Movies(config.Get().Language, hiddenRecommendations("movie"))
+	renderMovies(ctx, tmdb.GetMovies(pageOfIDs(ids, page), config.Get().Language), page, len(ids), "", false)
+}
+
+// ForYouShows shows TV shows, recommended from local history and library
+func ForYouShows(ctx *gin.Context) {
+	defer perf.ScopeTimer()()
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	hidden := hiddenRecommendations("show")
+	for id := range hiddenProgressShows() {
+		hidden[id] = true
+	}
+
+	ids := recommend.Shows(config.Get().Language, hidden)
+	renderShows(ctx, tmdb.GetShows(pageOfIDs(ids, page), config.Get().Language), page, len(ids), "", false)
+}
+
+// hiddenRecommendations returns TMDB IDs of items, hidden on Trakt
+func hiddenRecommendations(contentType string) map[int]bool {
+	if !config.Get().TraktAuthorized || !config.Get().TraktSyncHidden {
+		return map[int]bool{}
+	}
+
+	hidden, err := trakt.HiddenRecommendations(contentType, false)
+	if err != nil {
+		log.Warningf("Cannot get hidden %s recommendations: %s", contentType, err)
+	}
+	return hidden
+}
+
+// hiddenProgressShows returns TMDB IDs of shows, hidden from progress on Trakt
+func hiddenProgressShows() map[int]bool {
+	hidden := map[int]bool{}
+	if !config.Get().TraktAuthorized || !config.Get().TraktSyncHidden {
+		return hidden
+	}
+
+	shows, _ := trakt.ListHiddenShows("progress_watched", false)
+	for _, s := range shows {
+		if s != nil && s.Show != nil && s.Show.IDs != nil && s.Show.IDs.TMDB != 0 {
+			hidden[s.Show.IDs.TMDB] = true
+		}
+	}
+	return hidden
+}
+
+func pageOfIDs(ids []int, page int) []int {
+	if page < 1 {
+		page = 1
+	}
+
+	start := (page - 1) * config.Get().ResultsPerPage
+	if start >= len(ids) {
+		return nil
+	}
+	end := start + config.Get().ResultsPerPage
+	if end > len(ids) {
+		end = len(ids)
+	}
+	return ids[start:end]
+}
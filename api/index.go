@@ -34,11 +34,13 @@ func Index(s *bittorrent.Service) gin.HandlerFunc {
 			{Label: "LOCALIZE[30209]", Path: URLForXBMC("/search"), Thumbnail: config.AddonResource("img", "search.png")},
 			{Label: "LOCALIZE[30229]", Path: URLForXBMC("/torrents/"), Thumbnail: config.AddonResource("img", "cloud.png")},
 			{Label: "LOCALIZE[30216]", Path: URLForXBMC("/playtorrent"), Thumbnail: config.AddonResource("img", "magnet.png")},
+			{Label: "LOCALIZE[30728]", Path: URLForXBMC("/continue/"), Thumbnail: config.AddonResource("img", "clock.png")},
 			{Label: "LOCALIZE[30537]", Path: URLForXBMC("/history/"), Thumbnail: config.AddonResource("img", "clock.png")},
 			{Label: "LOCALIZE[30239]", Path: URLForXBMC("/provider/"), Thumbnail: config.AddonResource("img", "shield.png")},
 			{Label: "LOCALIZE[30355]", Path: URLForXBMC("/changelog"), Thumbnail: config.AddonResource("img", "faq8.png")},
 			{Label: "LOCALIZE[30393]", Path: URLForXBMC("/status"), Thumbnail: config.AddonResource("img", "clock.png")},
 			{Label: "LOCALIZE[30527]", Path: URLForXBMC("/donate"), Thumbnail: config.AddonResource("img", "faq8.png")},
+			{Label: "LOCALIZE[30734]", Path: URLForXBMC("/profiles"), Thumbnail: config.AddonResource("img", "settings.png")},
 			{Label: "LOCALIZE[30579]", Path: URLForXBMC("/settings/plugin.video.elementum"), Thumbnail: config.AddonResource("img", "settings.png")},
 		}
 
@@ -311,3 +311,142 @@ func UnduplicateLibrary(ctx *gin.Context) {
 	}
 	ctx.String(200, "")
 }
+
+var checkCategoryNames = map[library.CheckCategory]string{
+	library.CheckOrphans: "Folders without library item",
+	library.CheckDeleted: "Removed items with files",
+	library.CheckMissing: "Library items without files",
+	library.CheckKodi:    "Kodi entries without files",
+}
+
+var checkActionNames = map[library.CheckAction]string{
+	library.CheckActionRecreate: "Recreate strm files",
+	library.CheckActionRemove:   "Remove",
+	library.CheckActionRelink:   "Re-link to library",
+}
+
+type libraryCheckFix struct {
+	Action library.CheckAction `json:"action"`
+	Name   string              `json:"name"`
+	URL    string              `json:"url"`
+}
+
+type libraryCheckCategory struct {
+	Category library.CheckCategory `json:"category"`
+	Name     string                `json:"name"`
+	Count    int                   `json:"count"`
+	Fixes    []libraryCheckFix     `json:"fixes"`
+	Issues   []*library.CheckIssue `json:"issues"`
+}
+
+// LibraryCheck returns a report of inconsistencies between strm files, Kodi library and Elementum database,
+// with actions, available to fix each category
+func LibraryCheck(ctx *gin.Context) {
+	defer perf.ScopeTimer()()
+
+	report, err := library.Check()
+	if err != nil {
+		ctx.String(500, err.Error())
+		return
+	}
+
+	ctx.JSON(200, libraryCheckCategories(report, library.CheckCategories))
+}
+
+// LibraryCheckCategory returns a report of a single library check category
+func LibraryCheckCategory(ctx *gin.Context) {
+	defer perf.ScopeTimer()()
+
+	category := library.CheckCategory(ctx.Params.ByName("category"))
+	if _, ok := library.CheckActions[category]; !ok {
+		ctx.String(404, "Unknown category")
+		return
+	}
+
+	report, err := library.Check()
+	if err != nil {
+		ctx.String(500, err.Error())
+		return
+	}
+
+	ctx.JSON(200, libraryCheckCategories(report, []library.CheckCategory{category})[0])
+}
+
+func libraryCheckCategories(report *library.CheckReport, categories []library.CheckCategory) []libraryCheckCategory {
+	ret := make([]libraryCheckCategory, 0, len(categories))
+	for _, category := range categories {
+		issues := report.Issues[category]
+		if issues == nil {
+			issues = []*library.CheckIssue{}
+		}
+
+		fixes := []libraryCheckFix{}
+		if len(issues) > 0 {
+			for _, action := range library.CheckActions[category] {
+				fixes = append(fixes, libraryCheckFix{
+					Action: action,
+					Name:   checkActionNames[action],
+					URL:    fmt.Sprintf("/library/check/%s/fix/%s", category, action),
+				})
+			}
+		}
+
+		ret = append(ret, libraryCheckCategory{
+			Category: category,
+			Name:     checkCategoryNames[category],
+			Count:    len(issues),
+			Fixes:    fixes,
+			Issues:   issues,
+		})
+	}
+	return ret
+}
+
+// LibraryCheckFix applies an action to all issues of a library check category,
+// confirmation dialog is skipped with confirm=true, which is required without Kodi to ask
+func LibraryCheckFix(ctx *gin.Context) {
+	defer perf.ScopeTimer()()
+
+	category := library.CheckCategory(ctx.Params.ByName("category"))
+	if _, ok := library.CheckActions[category]; !ok {
+		ctx.String(404, "Unknown category")
+		return
+	}
+	action := library.CheckAction(ctx.Params.ByName("action"))
+
+	xbmcHost, _ := xbmc.GetXBMCHostWithContext(ctx)
+	if ctx.Query("confirm") != "true" {
+		if xbmcHost == nil {
+			ctx.String(400, "No Kodi instance found to confirm, pass confirm=true")
+			return
+		}
+		if !xbmcHost.DialogConfirmNonTimed("Elementum", fmt.Sprintf("%s: %s?", checkCategoryNames[category], checkActionNames[action])) {
+			ctx.String(200, "")
+			return
+		}
+	}
+
+	fixed, total, err := library.FixCheckIssues(category, action)
+	if err != nil {
+		log.Warningf("Could not fix library issues: %s", err)
+		if xbmcHost != nil {
+			xbmcHost.Notify("Elementum", err.Error(), config.AddonIcon())
+		}
+
+		switch err {
+		case library.ErrUnknownCheckAction:
+			ctx.String(400, err.Error())
+		case library.ErrLibraryReadOnly:
+			ctx.String(403, err.Error())
+		default:
+			ctx.String(500, err.Error())
+		}
+		return
+	}
+
+	if xbmcHost != nil {
+		xbmcHost.Notify("Elementum", fmt.Sprintf("%s: %d/%d", checkActionNames[action], fixed, total), config.AddonIcon())
+		library.ClearPageCache(xbmcHost)
+	}
+	ctx.JSON(200, gin.H{"fixed": fixed, "total": total})
+}
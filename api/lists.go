@@ -0,0 +1,244 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/anacrolix/missinggo/perf"
+	"github.com/gin-gonic/gin"
+
+	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/library"
+	"github.com/elgatito/elementum/trakt"
+	"github.com/elgatito/elementum/xbmc"
+)
+
+// traktListActions returns context menu actions to manage Trakt list
+func traktListActions(list *trakt.List) [][]string {
+	if !config.Get().TraktAuthorized {
+		return nil
+	}
+
+	if !list.IsOur() {
+		return [][]string{
+			{"LOCALIZE[30747]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/trakt/lists/%s/%d/copy", list.Username(), list.ID()))},
+		}
+	}
+
+	return [][]string{
+		{"LOCALIZE[30743]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/trakt/lists/%s/%d/rename", list.Username(), list.ID()))},
+		{"LOCALIZE[30746]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/trakt/lists/%s/%d/reorder", list.Username(), list.ID()))},
+		{"LOCALIZE[30744]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/trakt/lists/%s/%d/delete", list.Username(), list.ID()))},
+	}
+}
+
+// traktListCreateItem returns list item, which creates new Trakt list
+func traktListCreateItem() *xbmc.ListItem {
+	return &xbmc.ListItem{
+		Label:     "LOCALIZE[30740]",
+		Path:      URLForXBMC("/trakt/lists/create"),
+		Thumbnail: config.AddonResource("img", "trakt.png"),
+	}
+}
+
+// CreateTraktList creates personal list, name and privacy are asked if not passed
+func CreateTraktList(ctx *gin.Context) {
+	defer perf.ScopeTimer()()
+
+	xbmcHost, _ := xbmc.GetXBMCHostWithContext(ctx)
+	if xbmcHost == nil {
+		return
+	}
+
+	name := ctx.Query("name")
+	privacy := ctx.Query("privacy")
+	if name == "" {
+		if name = xbmcHost.Keyboard("", "LOCALIZE[30741]"); name == "" {
+			ctx.String(200, "")
+			return
+		}
+
+		choice := xbmcHost.ListDialog("LOCALIZE[30742]", "LOCALIZE[30751]", "LOCALIZE[30752]", "LOCALIZE[30753]")
+		if choice < 0 {
+			ctx.String(200, "")
+			return
+		}
+		privacy = trakt.ListPrivacies[choice]
+	}
+
+	if _, err := trakt.CreateList(name, ctx.Query("description"), privacy); err != nil {
+		xbmcHost.Notify("Elementum", err.Error(), config.AddonIcon())
+		ctx.String(400, err.Error())
+		return
+	}
+
+	onTraktListChanged(ctx, xbmcHost)
+	ctx.String(200, "")
+}
+
+// RenameTraktList renames personal list, new name is asked if not passed
+func RenameTraktList(ctx *gin.Context) {
+	defer perf.ScopeTimer()()
+
+	xbmcHost, _ := xbmc.GetXBMCHostWithContext(ctx)
+	if xbmcHost == nil {
+		return
+	}
+
+	listID, _ := strconv.Atoi(ctx.Params.ByName("listId"))
+	name := ctx.Query("name")
+	if name == "" {
+		current := ""
+		if list, err := trakt.GetList(ctx.Params.ByName("user"), ctx.Params.ByName("listId")); err == nil && list != nil {
+			current = list.Name
+		}
+		if name = xbmcHost.Keyboard(current, "LOCALIZE[30743]"); name == "" || name == current {
+			ctx.String(200, "")
+			return
+		}
+	}
+
+	if _, err := trakt.UpdateList(listID, name, ctx.Query("privacy")); err != nil {
+		xbmcHost.Notify("Elementum", err.Error(), config.AddonIcon())
+		ctx.String(400, err.Error())
+		return
+	}
+
+	onTraktListChanged(ctx, xbmcHost)
+	ctx.String(200, "")
+}
+
+// DeleteTraktList deletes personal list after confirmation
+func DeleteTraktList(ctx *gin.Context) {
+	defer perf.ScopeTimer()()
+
+	xbmcHost, _ := xbmc.GetXBMCHostWithContext(ctx)
+	if xbmcHost == nil {
+		return
+	}
+
+	listID, _ := strconv.Atoi(ctx.Params.ByName("listId"))
+	if ctx.Query("confirm") != "true" {
+		name := ctx.Params.ByName("listId")
+		if list, err := trakt.GetList(ctx.Params.ByName("user"), ctx.Params.ByName("listId")); err == nil && list != nil {
+			name = list.Name
+		}
+		if !xbmcHost.DialogConfirm("Elementum", fmt.Sprintf("LOCALIZE[30745];;%s", name)) {
+			ctx.String(200, "")
+			return
+		}
+	}
+
+	if err := trakt.DeleteList(listID); err != nil {
+		xbmcHost.Notify("Elementum", err.Error(), config.AddonIcon())
+		ctx.String(400, err.Error())
+		return
+	}
+
+	onTraktListChanged(ctx, xbmcHost)
+	ctx.String(200, "")
+}
+
+// ReorderTraktList sets order of list items from comma-separated list item IDs,
+// or moves single item, selected in dialogs, if IDs are not passed
+func ReorderTraktList(ctx *gin.Context) {
+	defer perf.ScopeTimer()()
+
+	xbmcHost, _ := xbmc.GetXBMCHostWithContext(ctx)
+	if xbmcHost == nil {
+		return
+	}
+
+	listID, _ := strconv.Atoi(ctx.Params.ByName("listId"))
+
+	ids := []int{}
+	if param := ctx.Query("ids"); param != "" {
+		for _, s := range strings.Split(param, ",") {
+			if id, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+				ids = append(ids, id)
+			}
+		}
+	} else {
+		items, err := trakt.ListItems(ctx.Params.ByName("user"), ctx.Params.ByName("listId"))
+		if err != nil || len(items) < 2 {
+			ctx.String(200, "")
+			return
+		}
+
+		labels := make([]string, 0, len(items))
+		for _, i := range items {
+			labels = append(labels, traktListItemLabel(i))
+		}
+
+		from := xbmcHost.ListDialog("LOCALIZE[30748]", labels...)
+		if from < 0 {
+			ctx.String(200, "")
+			return
+		}
+		to := xbmcHost.ListDialog("LOCALIZE[30749]", append(labels, "LOCALIZE[30754]")...)
+		if to < 0 || to == from || to == from+1 {
+			ctx.String(200, "")
+			return
+		}
+
+		for index, i := range items {
+			if index == to {
+				ids = append(ids, items[from].ID)
+			}
+			if index != from {
+				ids = append(ids, i.ID)
+			}
+		}
+		if to == len(items) {
+			ids = append(ids, items[from].ID)
+		}
+	}
+
+	if err := trakt.ReorderList(listID, ids); err != nil {
+		xbmcHost.Notify("Elementum", err.Error(), config.AddonIcon())
+		ctx.String(400, err.Error())
+		return
+	}
+
+	onTraktListChanged(ctx, xbmcHost)
+	ctx.String(200, "")
+}
+
+// CopyTraktList copies liked list into a new personal list
+func CopyTraktList(ctx *gin.Context) {
+	defer perf.ScopeTimer()()
+
+	xbmcHost, _ := xbmc.GetXBMCHostWithContext(ctx)
+	if xbmcHost == nil {
+		return
+	}
+
+	list, err := trakt.CopyList(ctx.Params.ByName("user"), ctx.Params.ByName("listId"), ctx.Query("name"))
+	if err != nil {
+		xbmcHost.Notify("Elementum", err.Error(), config.AddonIcon())
+		ctx.String(400, err.Error())
+		return
+	}
+
+	onTraktListChanged(ctx, xbmcHost)
+	ctx.JSON(200, list)
+}
+
+// onTraktListChanged notifies about list change and refreshes current view
+func onTraktListChanged(ctx *gin.Context, xbmcHost *xbmc.XBMCHost) {
+	xbmcHost.Notify("Elementum", "LOCALIZE[30750]", config.AddonIcon())
+	if ctx != nil {
+		ctx.Abort()
+	}
+	library.ClearPageCache(xbmcHost)
+}
+
+func traktListItemLabel(i *trakt.ListItem) string {
+	if i.Movie != nil {
+		return fmt.Sprintf("%s (%d)", i.Movie.Title, i.Movie.Year)
+	} else if i.Show != nil {
+		return fmt.Sprintf("%s (%d)", i.Show.Title, i.Show.Year)
+	}
+	return strconv.Itoa(i.ID)
+}
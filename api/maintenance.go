@@ -82,7 +82,9 @@ func Notification(s *bittorrent.Service) gin.HandlerFunc {
 			go func() {
 				// TODO: Do we need to clear deadlines? It can be just few pieces in the waitlist.
 				// p.GetTorrent().ClearDeadlines()
-				p.GetTorrent().PrioritizePieces()
+				if t := p.GetTorrent(); t != nil {
+					t.PrioritizePieces()
+				}
 			}()
 
 		case "Player.OnPause":
@@ -1,7 +1,9 @@
 package api
 
 import (
+	"github.com/elgatito/elementum/config"
 	"github.com/elgatito/elementum/database"
+	"github.com/elgatito/elementum/profile"
 	"github.com/elgatito/elementum/xbmc"
 	"github.com/gin-gonic/gin"
 )
@@ -32,12 +34,19 @@ type MenuItem struct {
 
 // Load ...
 func (m *Menu) Load() {
-	database.GetCache().GetObject(database.CommonBucket, m.Name, m)
+	m.AddItems = nil
+	m.RemoveItems = nil
+	database.GetCache().GetObject(database.CommonBucket, m.key(), m)
 }
 
 // Save ...
 func (m *Menu) Save() {
-	database.GetCache().SetObject(database.CommonBucket, m.Name, m)
+	database.GetCache().SetObject(database.CommonBucket, m.key(), m)
+}
+
+// key returns storage key of the menu for active profile
+func (m *Menu) key() string {
+	return profile.CacheKey(config.Get().Profile, m.Name)
 }
 
 // Add ...
@@ -16,6 +16,7 @@ import (
 	"github.com/elgatito/elementum/config"
 	"github.com/elgatito/elementum/database"
 	"github.com/elgatito/elementum/library"
+	"github.com/elgatito/elementum/library/history"
 	"github.com/elgatito/elementum/library/uid"
 	"github.com/elgatito/elementum/providers"
 	"github.com/elgatito/elementum/tmdb"
@@ -68,6 +69,7 @@ func MoviesIndex(ctx *gin.Context) {
 		{Label: "Trakt > LOCALIZE[30257]", Path: URLForXBMC("/movies/trakt/collection"), Thumbnail: config.AddonResource("img", "trakt.png"), ContextMenu: [][]string{{"LOCALIZE[30252]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/library/movie/list/add/collection"))}}, TraktAuth: true},
 		{Label: "Trakt > LOCALIZE[30290]", Path: URLForXBMC("/movies/trakt/calendars/"), Thumbnail: config.AddonResource("img", "most_anticipated.png"), TraktAuth: true},
 		{Label: "Trakt > LOCALIZE[30423]", Path: URLForXBMC("/movies/trakt/recommendations"), Thumbnail: config.AddonResource("img", "movies.png"), TraktAuth: true},
+		{Label: "LOCALIZE[30755]", Path: URLForXBMC("/movies/foryou"), Thumbnail: config.AddonResource("img", "movies.png")},
 		{Label: "Trakt > LOCALIZE[30422]", Path: URLForXBMC("/movies/trakt/toplists"), Thumbnail: config.AddonResource("img", "most_collected.png")},
 		{Label: "Trakt > LOCALIZE[30246]", Path: URLForXBMC("/movies/trakt/trending"), Thumbnail: config.AddonResource("img", "trending.png")},
 		{Label: "Trakt > LOCALIZE[30210]", Path: URLForXBMC("/movies/trakt/popular"), Thumbnail: config.AddonResource("img", "popular.png")},
@@ -327,8 +329,12 @@ func MoviesTraktLists(ctx *gin.Context) {
 				menuItem,
 			},
 		}
+		item.ContextMenu = append(item.ContextMenu, traktListActions(list)...)
 		items = append(items, item)
 	}
+	if config.Get().TraktAuthorized {
+		items = append(items, traktListCreateItem())
+	}
 	ctx.JSON(200, xbmc.NewView("menus_movies", filterListItems(items)))
 }
 
@@ -398,6 +404,9 @@ func renderMovies(ctx *gin.Context, movies tmdb.Movies, page int, total int, que
 			if uid.IsDuplicateMovie(tmdbID) || uid.IsAddedToLibrary(tmdbID, library.MovieType) || library.IsInLibrary(movie.ID, library.MovieType) {
 				libraryActions = append(libraryActions, []string{"LOCALIZE[30283]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/library/movie/add/%d?force=true", movie.ID))})
 				libraryActions = append(libraryActions, []string{"LOCALIZE[30253]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/library/movie/remove/%d", movie.ID))})
+				if library.IsInLibrary(movie.ID, library.MovieType) {
+					libraryActions = append(libraryActions, []string{"LOCALIZE[30725]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/movie/%d/quality", movie.ID))})
+				}
 			} else {
 				libraryActions = append(libraryActions, []string{"LOCALIZE[30252]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/library/movie/add/%d", movie.ID))})
 			}
@@ -425,6 +434,7 @@ func renderMovies(ctx *gin.Context, movies tmdb.Movies, page int, total int, que
 				collectionAction,
 				{"LOCALIZE[30034]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/setviewmode/movies"))},
 			}
+			item.ContextMenu = append(item.ContextMenu, movieTraktActions(item, movie.ID)...)
 			item.ContextMenu = append(libraryActions, item.ContextMenu...)
 
 			if config.Get().Platform.Kodi < 17 {
@@ -473,6 +483,10 @@ func setMovieItemProgress(path string, movieID int) {
 				xbmcHost.SetFileProgress(path, int(lm.Resume.Position), int(lm.Resume.Total))
 			}
 		}
+	} else if r := history.GetResume(history.MovieID(movieID)); r != nil {
+		if xbmcHost, _ := xbmc.GetLocalXBMCHost(); xbmcHost != nil {
+			xbmcHost.SetFileProgress(path, int(r.Position), int(r.Total))
+		}
 	}
 }
 
@@ -562,6 +576,42 @@ func SearchMovies(ctx *gin.Context) {
 	renderMovies(ctx, movies, page, total, query, false)
 }
 
+// MovieQualityCutoff shows a dialog to select resolution, up to which downloaded movie is upgraded
+func MovieQualityCutoff(ctx *gin.Context) {
+	xbmcHost, _ := xbmc.GetXBMCHostWithContext(ctx)
+	if xbmcHost == nil {
+		return
+	}
+
+	tmdbID, _ := strconv.Atoi(ctx.Params.ByName("tmdbId"))
+	current := library.GetMovieQualityCutoff(tmdbID)
+
+	resolutions := []int{0, bittorrent.Resolution720p, bittorrent.Resolution1080p, bittorrent.Resolution2K, bittorrent.Resolution4k}
+	items := []string{xbmcHost.GetLocalizedString(30726)}
+	preselect := 0
+	for i, r := range resolutions[1:] {
+		items = append(items, bittorrent.Resolutions[r])
+		if r == current {
+			preselect = i + 1
+		}
+	}
+
+	choice := xbmcHost.ListDialogWithOptions(0, preselect, "LOCALIZE[30725]", items...)
+	if choice < 0 || choice >= len(items) || choice == preselect {
+		ctx.String(200, "")
+		return
+	}
+
+	if err := library.SetMovieQualityCutoff(tmdbID, resolutions[choice]); err != nil {
+		log.Warningf("Could not set quality cutoff for movie %d: %s", tmdbID, err)
+		ctx.String(200, err.Error())
+		return
+	}
+
+	xbmcHost.Notify("Elementum", fmt.Sprintf("LOCALIZE[30727];;%s", items[choice]), config.AddonIcon())
+	ctx.String(200, "")
+}
+
 func movieLinks(xbmcHost *xbmc.XBMCHost, callbackHost string, tmdbID string) []*bittorrent.TorrentFile {
 	log.Info("Searching links for:", tmdbID)
 
@@ -0,0 +1,282 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anacrolix/missinggo/perf"
+	"github.com/gin-gonic/gin"
+
+	"github.com/elgatito/elementum/bittorrent"
+	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/library/history"
+	"github.com/elgatito/elementum/library/playcount"
+	"github.com/elgatito/elementum/library/uid"
+	"github.com/elgatito/elementum/tmdb"
+	"github.com/elgatito/elementum/trakt"
+	"github.com/elgatito/elementum/util"
+	"github.com/elgatito/elementum/xbmc"
+)
+
+// maxNextUpSkips limits number of watched episodes, skipped while looking for next episode
+const maxNextUpSkips = 100
+
+// Sources of "Next up" episode, from the fastest to the slowest
+const (
+	nextUpDownloaded = iota
+	nextUpLibrary
+	nextUpSearch
+)
+
+// nextUpShow is an in-progress show with the first episode, that can be next
+type nextUpShow struct {
+	showID       int
+	season       int
+	episode      int
+	lastActivity time.Time
+}
+
+// NextUpShows shows next unwatched aired episode of each in-progress show,
+// collected from Trakt progress, local history and Kodi library
+func NextUpShows(s *bittorrent.Service) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		defer perf.ScopeTimer()()
+
+		shows := collectNextUpShows()
+		hidden := hiddenProgressShows()
+
+		language := config.Get().Language
+		items := make(xbmc.ListItems, len(shows))
+
+		var wg sync.WaitGroup
+		for i, n := range shows {
+			if hidden[n.showID] {
+				continue
+			}
+
+			wg.Add(1)
+			go func(i int, n *nextUpShow) {
+				defer wg.Done()
+				items[i] = nextUpItem(s, n, language)
+			}(i, n)
+		}
+		wg.Wait()
+
+		for i := len(items) - 1; i >= 0; i-- {
+			if items[i] == nil {
+				items = append(items[:i], items[i+1:]...)
+			}
+		}
+
+		ctx.JSON(200, xbmc.NewView("episodes", items))
+	}
+}
+
+// collectNextUpShows merges in-progress shows from all watch sources, last active first
+func collectNextUpShows() []*nextUpShow {
+	shows := map[int]*nextUpShow{}
+	add := func(showID, season, episode int, dt time.Time) {
+		if showID == 0 {
+			return
+		}
+
+		n, ok := shows[showID]
+		if !ok {
+			shows[showID] = &nextUpShow{showID: showID, season: season, episode: episode, lastActivity: dt}
+			return
+		}
+		if season > n.season || (season == n.season && episode > n.episode) {
+			n.season = season
+			n.episode = episode
+		}
+		if dt.After(n.lastActivity) {
+			n.lastActivity = dt
+		}
+	}
+
+	if config.Get().TraktAuthorized {
+		watchedAt := map[int]time.Time{}
+		if watched, err := trakt.WatchedShows(false); err == nil {
+			for _, w := range watched {
+				if w != nil && w.Show != nil && w.Show.IDs != nil {
+					watchedAt[w.Show.IDs.TMDB] = w.LastWatchedAt
+				}
+			}
+		}
+
+		if progress, err := trakt.WatchedShowsProgress(); err == nil {
+			for _, p := range progress {
+				if p != nil && p.Show != nil && p.Show.IDs != nil && p.Episode != nil {
+					add(p.Show.IDs.TMDB, p.Episode.Season, p.Episode.Number, watchedAt[p.Show.IDs.TMDB])
+				}
+			}
+		}
+	}
+
+	// Watched episode points to the following episode, not finished episode points to itself
+	for _, h := range history.ByMediaType(history.EpisodeType) {
+		if h.Watched {
+			add(h.ShowID, h.Season, h.Episode+1, h.UpdatedAt)
+		} else if h.Position > 0 {
+			add(h.ShowID, h.Season, h.Episode, h.UpdatedAt)
+		}
+	}
+
+	l := uid.Get()
+	mu := l.GetMutex(uid.ShowsMutex)
+	mu.RLock()
+	for _, s := range l.Shows {
+		if s == nil || s.UIDs == nil || s.IsWatched() {
+			continue
+		}
+
+		for _, e := range s.Episodes {
+			if e == nil || e.UIDs == nil {
+				continue
+			}
+			if e.UIDs.Playcount > 0 {
+				add(s.UIDs.TMDB, e.Season, e.Episode+1, e.LastPlayed)
+			} else if e.Resume != nil && e.Resume.Position > 0 {
+				add(s.UIDs.TMDB, e.Season, e.Episode, e.LastPlayed)
+			}
+		}
+	}
+	mu.RUnlock()
+
+	ret := make([]*nextUpShow, 0, len(shows))
+	for _, n := range shows {
+		ret = append(ret, n)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].lastActivity.After(ret[j].lastActivity)
+	})
+	return ret
+}
+
+// nextUpEpisode returns first unwatched episode, starting from given episode and skipping specials
+func nextUpEpisode(show *tmdb.Show, season, episode int) (int, int, bool) {
+	if season < 1 {
+		season, episode = 1, 1
+	}
+	if episode < 1 {
+		episode = 1
+	}
+
+	for i := 0; i < maxNextUpSkips; i++ {
+		if episode > seasonEpisodesCount(show, season) {
+			next := 0
+			for _, s := range show.Seasons {
+				if s != nil && s.Season > season && s.EpisodeCount > 0 && (next == 0 || s.Season < next) {
+					next = s.Season
+				}
+			}
+			if next == 0 {
+				return 0, 0, false
+			}
+			season, episode = next, 1
+		}
+
+		if !playcount.GetWatchedEpisodeByTMDB(show.ID, season, episode) {
+			return season, episode, true
+		}
+		episode++
+	}
+
+	return 0, 0, false
+}
+
+func seasonEpisodesCount(show *tmdb.Show, season int) int {
+	for _, s := range show.Seasons {
+		if s != nil && s.Season == season {
+			return s.EpisodeCount
+		}
+	}
+	return 0
+}
+
+// nextUpItem returns list item for next episode of the show, or nil if there is no aired episode to watch
+func nextUpItem(s *bittorrent.Service, n *nextUpShow, language string) *xbmc.ListItem {
+	show := tmdb.GetShow(n.showID, language)
+	if show == nil {
+		return nil
+	}
+
+	seasonNumber, episodeNumber, ok := nextUpEpisode(show, n.season, n.episode)
+	if !ok {
+		return nil
+	}
+
+	episode := tmdb.GetEpisode(n.showID, seasonNumber, episodeNumber, language)
+	season := tmdb.GetSeason(n.showID, seasonNumber, language, len(show.Seasons), false)
+	if episode == nil || season == nil {
+		return nil
+	}
+
+	if _, isAired := util.AirDateWithAiredCheck(episode.AirDate, time.DateOnly, config.Get().ShowEpisodesOnReleaseDay); !isAired {
+		return nil
+	}
+
+	item := episode.ToListItem(show, season)
+	if item == nil {
+		return nil
+	}
+
+	source, file := nextUpSource(s, n.showID, seasonNumber, episodeNumber)
+
+	label := fmt.Sprintf(`[B]%s[/B] - %dx%02d %s`, show.GetName(), seasonNumber, episodeNumber, episode.GetName(show))
+	switch source {
+	case nextUpDownloaded:
+		label += " [I](LOCALIZE[30757])[/I]"
+	case nextUpLibrary:
+		label += " [I](LOCALIZE[30758])[/I]"
+	}
+	item.Label = label
+	item.Info.Title = label
+
+	thisURL := URLForXBMC("/show/%d/season/%d/episode/%d/", n.showID, seasonNumber, episodeNumber) + "%s/%s"
+	contextTitle := fmt.Sprintf("%s S%02dE%02d", show.GetName(), seasonNumber, episodeNumber)
+	contextLabel := linksLabel
+	if !config.Get().ChooseStreamAutoShow {
+		contextLabel = playLabel
+	}
+
+	if source == nextUpLibrary {
+		// Local file is played by Kodi directly
+		item.Path = file
+	} else {
+		// Active torrent is picked silently by the play route, before searching for new one
+		item.Path = URLQuery(contextPlayURL(thisURL, contextTitle, false), "silent", "true")
+		setEpisodeItemProgress(item.Path, n.showID, seasonNumber, episodeNumber)
+	}
+
+	item.ContextMenu = [][]string{
+		{contextLabel, fmt.Sprintf("PlayMedia(%s)", contextPlayOppositeURL(thisURL, contextTitle, false))},
+		{"LOCALIZE[30667]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/show/%d/season/%d/episode/%d/watched", n.showID, seasonNumber, episodeNumber))},
+		{fmt.Sprintf("LOCALIZE[30709];;%s", show.GetName()), fmt.Sprintf("Container.Update(%s)", URLForXBMC("/show/%d/seasons", n.showID))},
+		{"LOCALIZE[30037]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/setviewmode/episodes"))},
+	}
+	item.ContextMenu = append(item.ContextMenu, episodeTraktActions(item, n.showID, seasonNumber, episodeNumber)...)
+	item.IsPlayable = true
+
+	return item
+}
+
+// nextUpSource returns the fastest source of the episode:
+// active torrent, local file in Kodi library or search in providers
+func nextUpSource(s *bittorrent.Service, showID, season, episode int) (int, string) {
+	if s.HasTorrentByEpisode(showID, season, episode) != nil {
+		return nextUpDownloaded, ""
+	}
+
+	if ls, err := uid.GetShowByTMDB(showID); ls != nil && err == nil {
+		// Library items, added by Elementum, are resolved with the same search
+		if le := ls.GetEpisode(season, episode); le != nil && le.File != "" && !strings.HasSuffix(strings.ToLower(le.File), ".strm") {
+			return nextUpLibrary, le.File
+		}
+	}
+
+	return nextUpSearch, ""
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/elgatito/elementum/bittorrent"
 	"github.com/elgatito/elementum/database"
 	"github.com/elgatito/elementum/util/ip"
+	"github.com/elgatito/elementum/util/source"
 	"github.com/elgatito/elementum/xbmc"
 )
 
@@ -33,6 +34,8 @@ func Play(s *bittorrent.Service) gin.HandlerFunc {
 		// 		Order is just like in the torrent file, without changes.
 		// NIndex is the file next to download
 		// NOIndex is the original torrent file next to download
+		// Source forces "http" or "hls" playback of the URI without torrent,
+		// 	otherwise links to video files and HLS manifests are detected by extension.
 		index := ctx.Query("index")
 		oindex := ctx.Query("oindex")
 		nindex := ctx.Query("nindex")
@@ -52,6 +55,7 @@ func Play(s *bittorrent.Service) gin.HandlerFunc {
 		minSize := ctx.DefaultQuery("min_size", "-1")
 		sizeAtStart := ctx.DefaultQuery("size_at_start", "false")
 		position := ctx.Query("position")
+		sourceKind := ctx.Query("source")
 
 		if uri == "" && resume == "" {
 			return
@@ -95,6 +99,9 @@ func Play(s *bittorrent.Service) gin.HandlerFunc {
 			Query:             query,
 			Background:        background == "true",
 		}
+		if uri != "" {
+			params.Source = source.New(uri, sourceKind)
+		}
 
 		xbmcHost, _ := xbmc.GetXBMCHostWithContext(ctx)
 		if xbmcHost == nil {
@@ -104,7 +111,7 @@ func Play(s *bittorrent.Service) gin.HandlerFunc {
 		player := bittorrent.NewPlayer(s, params, xbmcHost)
 		log.Infof("Playing item: %s", litter.Sdump(params))
 
-		if uri != "" {
+		if uri != "" && params.Source == nil {
 			if t := s.GetTorrentByURI(uri); t != nil {
 				player.Params().ResumeHash = t.InfoHash()
 				player.SetTorrent(t)
@@ -118,7 +125,7 @@ func Play(s *bittorrent.Service) gin.HandlerFunc {
 			}
 		}
 
-		if resume != "" && player.GetTorrent() == nil {
+		if resume != "" && params.Source == nil && player.GetTorrent() == nil {
 			if t := s.GetTorrentByHash(resume); t != nil {
 				player.SetTorrent(t)
 			} else {
@@ -132,6 +139,12 @@ func Play(s *bittorrent.Service) gin.HandlerFunc {
 			return
 		}
 
+		// Kodi reads sources directly, HLS manifests are passed through as is
+		if params.Source != nil {
+			ctx.Redirect(302, player.PlayURL())
+			return
+		}
+
 		rURL, _ := url.Parse(fmt.Sprintf("%s/files/%s", ip.GetContextHTTPHost(ctx), player.PlayURL()))
 		ctx.Redirect(302, rURL.String())
 	}
@@ -182,7 +195,7 @@ func PlayURI(s *bittorrent.Service) gin.HandlerFunc {
 		}
 
 		if uri != "" {
-			xbmcHost.PlayURL(URLQuery(URLForXBMC("/play"), "uri", uri, "index", index))
+			xbmcHost.PlayURL(URLQuery(URLForXBMC("/play"), "uri", uri, "index", index, "source", ctx.Query("source")))
 		} else {
 			var (
 				tmdb        string
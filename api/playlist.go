@@ -0,0 +1,243 @@
+package api
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anacrolix/missinggo/perf"
+	"github.com/gin-gonic/gin"
+
+	"github.com/elgatito/elementum/bittorrent"
+	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/mapping"
+	"github.com/elgatito/elementum/tmdb"
+	"github.com/elgatito/elementum/util"
+	"github.com/elgatito/elementum/util/ip"
+	"github.com/elgatito/elementum/util/playlist"
+)
+
+// TorrentPlaylist returns playlist with playable files of the torrent.
+// Files of show torrents are ordered by matched episode numbers, other files by their paths.
+func TorrentPlaylist(s *bittorrent.Service, format string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		defer perf.ScopeTimer()()
+
+		host := ip.GetContextHTTPHost(ctx)
+
+		torrentID := ctx.Params.ByName("torrentId")
+		t, err := GetTorrentFromParam(s, torrentID)
+		if err != nil {
+			ctx.String(404, err.Error())
+			return
+		} else if !t.HasMetadata() {
+			ctx.String(404, "Torrent metadata is not available yet")
+			return
+		}
+
+		p := &playlist.Playlist{Title: t.Name()}
+		matched := map[int]bool{}
+
+		if t.DBItem != nil && t.DBItem.ShowID != 0 {
+			if show := tmdb.GetShow(t.DBItem.ShowID, config.Get().Language); show != nil {
+				b := newPlaylistBuilder(host, show)
+				for _, season := range show.Seasons {
+					if season == nil || season.EpisodeCount == 0 {
+						continue
+					}
+
+					for _, ef := range b.episodeFiles(t, season.Season) {
+						if !matched[ef.file.Index] {
+							matched[ef.file.Index] = true
+							p.Entries = append(p.Entries, b.episodeEntry(ef))
+						}
+					}
+				}
+			}
+		}
+
+		files := t.GetFiles()
+		choices, biggestFile, err := t.GetCandidateFiles(nil)
+		if err != nil {
+			ctx.String(404, err.Error())
+			return
+		} else if len(choices) == 0 && len(files) > 0 {
+			choices = []*bittorrent.CandidateFile{{Index: biggestFile, Path: files[biggestFile].Path}}
+		}
+
+		rest := []*bittorrent.File{}
+		for _, c := range choices {
+			if !matched[c.Index] {
+				rest = append(rest, files[c.Index])
+			}
+		}
+		sort.Slice(rest, func(i, j int) bool {
+			return strings.ToLower(rest[i].Path) < strings.ToLower(rest[j].Path)
+		})
+
+		b := newPlaylistBuilder(host, nil)
+		for _, f := range rest {
+			p.Entries = append(p.Entries, b.fileEntry(f, filepath.Base(f.Path)))
+		}
+
+		writePlaylist(ctx, p, format)
+	}
+}
+
+// ShowSeasonPlaylist returns playlist with episodes of the season, found in active torrents of the show
+func ShowSeasonPlaylist(s *bittorrent.Service, format string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		defer perf.ScopeTimer()()
+
+		host := ip.GetContextHTTPHost(ctx)
+
+		showID, _ := strconv.Atoi(ctx.Params.ByName("showId"))
+		seasonNumber, _ := strconv.Atoi(ctx.Params.ByName("season"))
+
+		show := tmdb.GetShow(showID, config.Get().Language)
+		if show == nil {
+			ctx.String(404, "Unable to find show")
+			return
+		}
+
+		b := newPlaylistBuilder(host, show)
+		episodes := map[int]*episodeFile{}
+		for _, t := range s.GetTorrents() {
+			if t.DBItem == nil || t.DBItem.ShowID != showID || !t.HasMetadata() {
+				continue
+			}
+
+			for _, ef := range b.episodeFiles(t, seasonNumber) {
+				if _, ok := episodes[ef.episode.EpisodeNumber]; !ok {
+					episodes[ef.episode.EpisodeNumber] = ef
+				}
+			}
+		}
+
+		numbers := make([]int, 0, len(episodes))
+		for n := range episodes {
+			numbers = append(numbers, n)
+		}
+		sort.Ints(numbers)
+
+		p := &playlist.Playlist{Title: fmt.Sprintf("%s - Season %d", show.GetName(), seasonNumber)}
+		for _, n := range numbers {
+			p.Entries = append(p.Entries, b.episodeEntry(episodes[n]))
+		}
+
+		writePlaylist(ctx, p, format)
+	}
+}
+
+// playlistBuilder creates playlist entries with "/files/" URLs, reachable from other machines
+type playlistBuilder struct {
+	host    string
+	show    *tmdb.Show
+	mapping *mapping.Show
+}
+
+// episodeFile is a torrent file, matched to the episode
+type episodeFile struct {
+	file    *bittorrent.File
+	season  *tmdb.Season
+	episode *tmdb.Episode
+}
+
+// newPlaylistBuilder returns builder with host, reachable by the client of the request,
+// so playlists, requested from other machines, do not point to loopback address
+func newPlaylistBuilder(host string, show *tmdb.Show) *playlistBuilder {
+	b := &playlistBuilder{
+		host: host,
+		show: show,
+	}
+	if show != nil {
+		b.mapping = mapping.Get(show)
+	}
+	return b
+}
+
+// episodeFiles returns files of the torrent, matched to episodes of the season and ordered by episode numbers.
+// Torrent of a single episode, which files are not named by episode numbers, gives its biggest file.
+func (b *playlistBuilder) episodeFiles(t *bittorrent.Torrent, seasonNumber int) []*episodeFile {
+	choices, biggestFile, err := t.GetCandidateFiles(nil)
+	if err != nil {
+		return nil
+	}
+
+	season := tmdb.GetSeason(b.show.ID, seasonNumber, config.Get().Language, len(b.show.Seasons), true)
+	if season == nil {
+		return nil
+	}
+
+	activeSeason := 0
+	if t.DBItem != nil {
+		activeSeason = t.DBItem.Season
+	}
+
+	files := t.GetFiles()
+	ret := []*episodeFile{}
+	for _, episode := range season.Episodes {
+		if episode == nil {
+			continue
+		}
+
+		if len(choices) > 0 {
+			index, found := bittorrent.MatchEpisodeFilename(seasonNumber, episode.EpisodeNumber, b.show.CountRealSeasons() == 1, activeSeason, b.show, b.mapping, choices)
+			if index >= 0 && found == 1 {
+				ret = append(ret, &episodeFile{file: files[choices[index].Index], season: season, episode: episode})
+				continue
+			}
+		}
+
+		if t.DBItem != nil && t.DBItem.Season == seasonNumber && t.DBItem.Episode == episode.EpisodeNumber && biggestFile < len(files) {
+			ret = append(ret, &episodeFile{file: files[biggestFile], season: season, episode: episode})
+		}
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].episode.EpisodeNumber < ret[j].episode.EpisodeNumber
+	})
+	return ret
+}
+
+func (b *playlistBuilder) fileEntry(f *bittorrent.File, title string) *playlist.Entry {
+	return &playlist.Entry{
+		Title: title,
+		URL:   b.host + "/files/" + util.EncodeFileURL(f.Path),
+	}
+}
+
+func (b *playlistBuilder) episodeEntry(ef *episodeFile) *playlist.Entry {
+	e := b.fileEntry(ef.file, fmt.Sprintf("%s S%02dE%02d", b.show.GetName(), ef.season.Season, ef.episode.EpisodeNumber))
+	if name := ef.episode.GetName(b.show); name != "" {
+		e.Title += " - " + name
+	}
+
+	runtime := ef.episode.Runtime
+	if runtime == 0 && len(b.show.EpisodeRunTime) > 0 {
+		runtime = b.show.EpisodeRunTime[len(b.show.EpisodeRunTime)-1]
+	}
+	e.Duration = time.Duration(runtime) * time.Minute
+
+	imageQualities := tmdb.GetImageQualities()
+	if ef.episode.StillPath != "" {
+		e.Artwork = tmdb.ImageURL(ef.episode.StillPath, imageQualities.Thumbnail)
+	} else if ef.season.PosterPath != "" {
+		e.Artwork = tmdb.ImageURL(ef.season.PosterPath, imageQualities.Poster)
+	} else {
+		e.Artwork = tmdb.ImageURL(b.show.PosterPath, imageQualities.Poster)
+	}
+	return e
+}
+
+func writePlaylist(ctx *gin.Context, p *playlist.Playlist, format string) {
+	ctx.Header("Content-Type", playlist.ContentType(format))
+	ctx.Header("Content-Disposition", fmt.Sprintf(`inline; filename="playlist.%s"`, format))
+	ctx.Status(200)
+	if err := p.Write(ctx.Writer, format); err != nil {
+		log.Warningf("Could not write playlist: %s", err)
+	}
+}
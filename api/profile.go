@@ -0,0 +1,147 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/anacrolix/missinggo/perf"
+	"github.com/gin-gonic/gin"
+
+	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/profile"
+	"github.com/elgatito/elementum/xbmc"
+)
+
+// Profiles shows dialog to switch, create or remove profiles
+func Profiles(ctx *gin.Context) {
+	defer perf.ScopeTimer()()
+
+	xbmcHost, _ := xbmc.GetXBMCHostWithContext(ctx)
+	if xbmcHost == nil {
+		return
+	}
+
+	names := profile.List()
+	current := profile.Current()
+
+	choices := make([]string, 0, len(names)+2)
+	for _, name := range names {
+		label := profileLabel(name)
+		if name == current {
+			label = "[B]" + label + "[/B]"
+		}
+		choices = append(choices, label)
+	}
+	choices = append(choices, "LOCALIZE[30735]", "LOCALIZE[30737]")
+
+	choice := xbmcHost.ListDialog("LOCALIZE[30734]", choices...)
+	switch {
+	case choice < 0:
+		break
+
+	case choice < len(names):
+		switchProfile(xbmcHost, names[choice])
+
+	case choice == len(names):
+		name := xbmcHost.Keyboard("", "LOCALIZE[30739]")
+		if name == "" {
+			break
+		}
+		if err := profile.Create(name); err != nil {
+			xbmcHost.Notify("Elementum", err.Error(), config.AddonIcon())
+			break
+		}
+		switchProfile(xbmcHost, name)
+
+	default:
+		removable := []string{}
+		for _, name := range names {
+			if name != "" && name != current {
+				removable = append(removable, name)
+			}
+		}
+		if len(removable) == 0 {
+			break
+		}
+
+		if c := xbmcHost.ListDialog("LOCALIZE[30737]", removable...); c >= 0 {
+			removeProfile(xbmcHost, removable[c])
+		}
+	}
+
+	ctx.String(200, "")
+}
+
+// ProfileList returns active profile and names of all profiles
+func ProfileList(ctx *gin.Context) {
+	ctx.JSON(200, gin.H{
+		"current":  profile.Current(),
+		"profiles": profile.List(),
+	})
+}
+
+// ProfileSwitch switches to the profile, empty name selects default profile
+func ProfileSwitch(ctx *gin.Context) {
+	xbmcHost, _ := xbmc.GetXBMCHostWithContext(ctx)
+	if xbmcHost == nil {
+		return
+	}
+
+	if err := switchProfile(xbmcHost, ctx.Query("name")); err != nil {
+		ctx.String(404, err.Error())
+		return
+	}
+	ctx.String(200, "")
+}
+
+// ProfileCreate creates new profile
+func ProfileCreate(ctx *gin.Context) {
+	if err := profile.Create(ctx.Query("name")); err != nil {
+		ctx.String(400, err.Error())
+		return
+	}
+	ctx.String(200, "")
+}
+
+// ProfileRemove removes profile with all its data
+func ProfileRemove(ctx *gin.Context) {
+	xbmcHost, _ := xbmc.GetXBMCHostWithContext(ctx)
+	if xbmcHost == nil {
+		return
+	}
+
+	if err := removeProfile(xbmcHost, ctx.Query("name")); err != nil {
+		ctx.String(400, err.Error())
+		return
+	}
+	ctx.String(200, "")
+}
+
+func switchProfile(xbmcHost *xbmc.XBMCHost, name string) error {
+	if err := profile.Switch(xbmcHost, name); err != nil {
+		log.Warningf("Cannot switch to profile '%s': %s", name, err)
+		xbmcHost.Notify("Elementum", err.Error(), config.AddonIcon())
+		return err
+	}
+
+	MovieMenu.Load()
+	TVMenu.Load()
+
+	xbmcHost.Notify("Elementum", fmt.Sprintf("LOCALIZE[30738];;%s", profileLabel(name)), config.AddonIcon())
+	return nil
+}
+
+func removeProfile(xbmcHost *xbmc.XBMCHost, name string) error {
+	if err := profile.Remove(name); err != nil {
+		xbmcHost.Notify("Elementum", err.Error(), config.AddonIcon())
+		return err
+	}
+	return nil
+}
+
+// profileLabel returns display name of the profile
+func profileLabel(name string) string {
+	if name == "" {
+		return "LOCALIZE[30736]"
+	}
+	return name
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/anacrolix/missinggo/perf"
+	"github.com/gin-gonic/gin"
+
+	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/library"
+	"github.com/elgatito/elementum/trakt"
+	"github.com/elgatito/elementum/xbmc"
+)
+
+// movieTraktActions sets user rating of the movie item and returns rating and favorites actions
+func movieTraktActions(item *xbmc.ListItem, tmdbID int) [][]string {
+	if !config.Get().TraktAuthorized {
+		return nil
+	}
+
+	item.Info.UserRating = trakt.GetMovieRating(tmdbID)
+
+	favoritesAction := []string{"LOCALIZE[30731]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/movie/%d/favorites/add", tmdbID))}
+	if trakt.IsFavoriteMovie(tmdbID) {
+		favoritesAction = []string{"LOCALIZE[30732]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/movie/%d/favorites/remove", tmdbID))}
+	}
+
+	return [][]string{
+		{"LOCALIZE[30729]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/movie/%d/rate", tmdbID))},
+		favoritesAction,
+	}
+}
+
+// showTraktActions sets user rating of the show item and returns rating and favorites actions
+func showTraktActions(item *xbmc.ListItem, showID int) [][]string {
+	if !config.Get().TraktAuthorized {
+		return nil
+	}
+
+	item.Info.UserRating = trakt.GetShowRating(showID)
+
+	favoritesAction := []string{"LOCALIZE[30731]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/show/%d/favorites/add", showID))}
+	if trakt.IsFavoriteShow(showID) {
+		favoritesAction = []string{"LOCALIZE[30732]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/show/%d/favorites/remove", showID))}
+	}
+
+	return [][]string{
+		{"LOCALIZE[30729]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/show/%d/rate", showID))},
+		favoritesAction,
+	}
+}
+
+// episodeTraktActions sets user rating of the episode item and returns rating action
+func episodeTraktActions(item *xbmc.ListItem, showID, season, episode int) [][]string {
+	if !config.Get().TraktAuthorized {
+		return nil
+	}
+
+	item.Info.UserRating = trakt.GetEpisodeRating(showID, season, episode)
+
+	return [][]string{
+		{"LOCALIZE[30729]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/show/%d/season/%d/episode/%d/rate", showID, season, episode))},
+	}
+}
+
+// RateMovie ...
+func RateMovie(ctx *gin.Context) {
+	rateItem(ctx, "movies")
+}
+
+// RateShow ...
+func RateShow(ctx *gin.Context) {
+	rateItem(ctx, "shows")
+}
+
+// RateEpisode ...
+func RateEpisode(ctx *gin.Context) {
+	rateItem(ctx, "episodes")
+}
+
+func rateItem(ctx *gin.Context, itemType string) {
+	defer perf.ScopeTimer()()
+
+	xbmcHost, _ := xbmc.GetXBMCHostWithContext(ctx)
+	if xbmcHost == nil {
+		return
+	}
+
+	var tmdbID, season, episode int
+	if itemType == "movies" {
+		tmdbID, _ = strconv.Atoi(ctx.Params.ByName("tmdbId"))
+	} else {
+		tmdbID, _ = strconv.Atoi(ctx.Params.ByName("showId"))
+		season, _ = strconv.Atoi(ctx.Params.ByName("season"))
+		episode, _ = strconv.Atoi(ctx.Params.ByName("episode"))
+	}
+
+	if trakt.RateDialog(xbmcHost, itemType, tmdbID, season, episode) >= 0 {
+		library.ClearPageCache(xbmcHost)
+	}
+	ctx.String(200, "")
+}
+
+// AddMovieToFavorites ...
+func AddMovieToFavorites(ctx *gin.Context) {
+	setFavorite(ctx, "movies", ctx.Params.ByName("tmdbId"), true)
+}
+
+// RemoveMovieFromFavorites ...
+func RemoveMovieFromFavorites(ctx *gin.Context) {
+	setFavorite(ctx, "movies", ctx.Params.ByName("tmdbId"), false)
+}
+
+// AddShowToFavorites ...
+func AddShowToFavorites(ctx *gin.Context) {
+	setFavorite(ctx, "shows", ctx.Params.ByName("showId"), true)
+}
+
+// RemoveShowFromFavorites ...
+func RemoveShowFromFavorites(ctx *gin.Context) {
+	setFavorite(ctx, "shows", ctx.Params.ByName("showId"), false)
+}
+
+func setFavorite(ctx *gin.Context, itemType string, tmdbID string, add bool) {
+	defer perf.ScopeTimer()()
+
+	xbmcHost, _ := xbmc.GetXBMCHostWithContext(ctx)
+	if xbmcHost == nil {
+		return
+	}
+
+	var err error
+	if add {
+		_, err = trakt.AddToFavorites(itemType, tmdbID)
+	} else {
+		_, err = trakt.RemoveFromFavorites(itemType, tmdbID)
+	}
+	if err != nil {
+		xbmcHost.Notify("Elementum", err.Error(), config.AddonIcon())
+		if err != trakt.ErrQueued {
+			return
+		}
+	} else if add {
+		xbmcHost.Notify("Elementum", "LOCALIZE[30731]", config.AddonIcon())
+	} else {
+		xbmcHost.Notify("Elementum", "LOCALIZE[30732]", config.AddonIcon())
+	}
+
+	if ctx != nil {
+		ctx.Abort()
+	}
+	library.ClearPageCache(xbmcHost)
+}
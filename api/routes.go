@@ -13,6 +13,7 @@ import (
 	"github.com/elgatito/elementum/config"
 	"github.com/elgatito/elementum/providers"
 	"github.com/elgatito/elementum/util"
+	"github.com/elgatito/elementum/util/playlist"
 
 	"github.com/dustin/go-humanize"
 	"github.com/gin-gonic/gin"
@@ -98,6 +99,7 @@ func Routes(s *bittorrent.Service, shutdown func(code int), fileLogger io.Writer
 	r.GET("/playtorrent", PlayTorrent)
 	r.GET("/infolabels", InfoLabelsStored(s))
 	r.GET("/changelog", Changelog)
+	r.GET("/calendar.ics", CalendarICS)
 	r.GET("/donate", Donate)
 	r.GET("/settings/:addon", Settings)
 	r.GET("/status", Status(s))
@@ -121,6 +123,13 @@ func Routes(s *bittorrent.Service, shutdown func(code int), fileLogger io.Writer
 		history.GET("/clear", HistoryClear)
 	}
 
+	continueWatching := r.Group("/continue")
+	{
+		continueWatching.GET("", ContinueWatching)
+		continueWatching.GET("/", ContinueWatching)
+		continueWatching.GET("/remove", ContinueWatchingRemove)
+	}
+
 	search := r.Group("/search")
 	{
 		search.GET("", Search(s))
@@ -158,11 +167,20 @@ func Routes(s *bittorrent.Service, shutdown func(code int), fileLogger io.Writer
 		torrents.GET("/selectfile/:torrentId", SelectFileTorrent(s, true))
 		torrents.GET("/downloadfile/:torrentId", SelectFileTorrent(s, false))
 		torrents.GET("/assign/:torrentId/:tmdbId", AssignTorrent(s))
+		torrents.GET("/:torrentId/playlist.m3u", TorrentPlaylist(s, playlist.FormatM3U))
+		torrents.GET("/:torrentId/playlist.xspf", TorrentPlaylist(s, playlist.FormatXSPF))
 
 		// Web UI json
 		torrents.GET("/list", ListTorrentsWeb(s))
 	}
 
+	shares := r.Group("/shares")
+	{
+		shares.GET("/", ListShares)
+		shares.GET("/add", AddShare(s))
+		shares.GET("/revoke/:shareId", RevokeShare)
+	}
+
 	movies := r.Group("/movies")
 	{
 		movies.GET("/", MoviesIndex)
@@ -176,6 +194,7 @@ func Routes(s *bittorrent.Service, shutdown func(code int), fileLogger io.Writer
 		movies.GET("/recent/language/:language", RecentMovies)
 		movies.GET("/recent/country/:country", RecentMovies)
 		movies.GET("/top", TopRatedMovies)
+		movies.GET("/foryou", ForYouMovies)
 		movies.GET("/imdb250", IMDBTop250)
 		movies.GET("/mostvoted", MoviesMostVoted)
 		movies.GET("/genres", MovieGenres)
@@ -232,10 +251,14 @@ func Routes(s *bittorrent.Service, shutdown func(code int), fileLogger io.Writer
 		movie.GET("/:tmdbId/watchlist/remove", RemoveMovieFromWatchlist)
 		movie.GET("/:tmdbId/collection/add", AddMovieToCollection)
 		movie.GET("/:tmdbId/collection/remove", RemoveMovieFromCollection)
+		movie.GET("/:tmdbId/favorites/add", AddMovieToFavorites)
+		movie.GET("/:tmdbId/favorites/remove", RemoveMovieFromFavorites)
+		movie.GET("/:tmdbId/rate", RateMovie)
 		movie.GET("/:tmdbId/watched", ToggleWatched("movie", true))
 		movie.GET("/:tmdbId/watched/*ident", ToggleWatched("movie", true))
 		movie.GET("/:tmdbId/unwatched", ToggleWatched("movie", false))
 		movie.GET("/:tmdbId/unwatched/*ident", ToggleWatched("movie", false))
+		movie.GET("/:tmdbId/quality", MovieQualityCutoff)
 	}
 
 	shows := r.Group("/shows")
@@ -255,6 +278,8 @@ func Routes(s *bittorrent.Service, shutdown func(code int), fileLogger io.Writer
 		shows.GET("/recent/episodes/language/:language", RecentEpisodes)
 		shows.GET("/recent/episodes/country/:country", RecentEpisodes)
 		shows.GET("/top", TopRatedShows)
+		shows.GET("/foryou", ForYouShows)
+		shows.GET("/nextup", NextUpShows(s))
 		shows.GET("/mostvoted", TVMostVoted)
 		shows.GET("/genres", TVGenres)
 		shows.GET("/languages", TVLanguages)
@@ -301,6 +326,9 @@ func Routes(s *bittorrent.Service, shutdown func(code int), fileLogger io.Writer
 		show.GET("/:showId/unwatched", ToggleWatched("show", false))
 		show.GET("/:showId/unwatched/*ident", ToggleWatched("show", false))
 		show.GET("/:showId/seasons", ShowSeasons)
+		show.GET("/:showId/episode_group", ShowEpisodeGroup)
+		show.GET("/:showId/follow", ShowFollow)
+		show.GET("/:showId/unfollow", ShowUnfollow)
 		show.GET("/:showId/season/:season/download", ShowSeasonRun("download", s))
 		show.GET("/:showId/season/:season/download/*ident", ShowSeasonRun("download", s))
 		show.GET("/:showId/season/:season/links", ShowSeasonRun("links", s))
@@ -316,6 +344,8 @@ func Routes(s *bittorrent.Service, shutdown func(code int), fileLogger io.Writer
 		show.GET("/:showId/season/:season/unwatched", ToggleWatched("season", false))
 		show.GET("/:showId/season/:season/unwatched/*ident", ToggleWatched("season", false))
 		show.GET("/:showId/season/:season/episodes", ShowEpisodes)
+		show.GET("/:showId/season/:season/playlist.m3u", ShowSeasonPlaylist(s, playlist.FormatM3U))
+		show.GET("/:showId/season/:season/playlist.xspf", ShowSeasonPlaylist(s, playlist.FormatXSPF))
 		show.GET("/:showId/season/:season/episode/:episode/infolabels", InfoLabelsEpisode(s))
 		show.GET("/:showId/season/:season/episode/:episode/play", ShowEpisodeRun("play", s))
 		show.GET("/:showId/season/:season/episode/:episode/play/*ident", ShowEpisodeRun("play", s))
@@ -335,6 +365,10 @@ func Routes(s *bittorrent.Service, shutdown func(code int), fileLogger io.Writer
 		show.GET("/:showId/watchlist/remove", RemoveShowFromWatchlist)
 		show.GET("/:showId/collection/add", AddShowToCollection)
 		show.GET("/:showId/collection/remove", RemoveShowFromCollection)
+		show.GET("/:showId/favorites/add", AddShowToFavorites)
+		show.GET("/:showId/favorites/remove", RemoveShowFromFavorites)
+		show.GET("/:showId/rate", RateShow)
+		show.GET("/:showId/season/:season/episode/:episode/rate", RateEpisode)
 	}
 	// TODO: add routes for episode.
 	// episode := r.Group("/episode")
@@ -355,6 +389,9 @@ func Routes(s *bittorrent.Service, shutdown func(code int), fileLogger io.Writer
 
 		library.GET("/update", UpdateLibrary)
 		library.GET("/unduplicate", UnduplicateLibrary)
+		library.GET("/check", LibraryCheck)
+		library.GET("/check/:category", LibraryCheckCategory)
+		library.GET("/check/:category/fix/:action", LibraryCheckFix)
 
 		// DEPRECATED
 		library.GET("/play/movie/:tmdbId", PlayMovie(s))
@@ -406,12 +443,26 @@ func Routes(s *bittorrent.Service, shutdown func(code int), fileLogger io.Writer
 		trakt.GET("/deauthorize", DeauthorizeTrakt)
 		trakt.GET("/select_list/:action/:media", SelectTraktUserList)
 		trakt.GET("/update", UpdateTrakt)
+		trakt.GET("/queue", TraktQueue)
+		trakt.GET("/queue/flush", TraktQueueFlush)
+		trakt.GET("/lists/create", CreateTraktList)
+		trakt.GET("/lists/:user/:listId/rename", RenameTraktList)
+		trakt.GET("/lists/:user/:listId/reorder", ReorderTraktList)
+		trakt.GET("/lists/:user/:listId/delete", DeleteTraktList)
+		trakt.GET("/lists/:user/:listId/copy", CopyTraktList)
+	}
+
+	simkl := r.Group("/simkl")
+	{
+		simkl.GET("/authorize", AuthorizeSimkl)
+		simkl.GET("/deauthorize", DeauthorizeSimkl)
 	}
 
 	r.GET("/setviewmode/:content_type", SetViewMode)
 
 	r.GET("/subtitles", SubtitlesIndex(s))
 	r.GET("/subtitle/:id", SubtitleGet)
+	r.GET("/subtitles/download", SubtitleDownload)
 
 	r.GET("/play", Play(s))
 	r.GET("/play/*ident", Play(s))
@@ -466,6 +517,16 @@ func Routes(s *bittorrent.Service, shutdown func(code int), fileLogger io.Writer
 		}
 	}
 
+	profiles := r.Group("/profiles")
+	{
+		profiles.GET("", Profiles)
+		profiles.GET("/", Profiles)
+		profiles.GET("/list", ProfileList)
+		profiles.GET("/switch", ProfileSwitch)
+		profiles.GET("/create", ProfileCreate)
+		profiles.GET("/remove", ProfileRemove)
+	}
+
 	menu := r.Group("/menu")
 	{
 		menu.GET("/:type/add", MenuAdd)
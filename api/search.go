@@ -6,7 +6,6 @@ import (
 	"strings"
 
 	"github.com/anacrolix/missinggo/perf"
-	"github.com/asdine/storm/q"
 	"github.com/cespare/xxhash"
 	"github.com/gin-gonic/gin"
 	"github.com/op/go-logging"
@@ -193,9 +192,7 @@ func searchHistoryAppend(ctx *gin.Context, historyType string, query string) {
 
 func searchHistoryList(ctx *gin.Context, historyType string) {
 	historyList := []string{}
-	var qs []database.QueryHistory
-	database.GetStormDB().Select(q.Eq("Type", historyType)).OrderBy("Dt").Reverse().Find(&qs)
-	for _, q := range qs {
+	for _, q := range database.GetStorm().GetSearchHistory(historyType) {
 		historyList = append(historyList, q.Query)
 	}
 
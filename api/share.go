@@ -0,0 +1,92 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/anacrolix/missinggo/perf"
+	"github.com/gin-gonic/gin"
+
+	"github.com/elgatito/elementum/bittorrent"
+	"github.com/elgatito/elementum/database"
+	"github.com/elgatito/elementum/share"
+	"github.com/elgatito/elementum/util/ip"
+)
+
+type shareLinkInfo struct {
+	*database.ShareLink
+	URL string
+}
+
+// ListShares returns active share links
+func ListShares(ctx *gin.Context) {
+	// Links are opened by other people, so loopback address of local Kodi is not used
+	host := ip.GetExternalHTTPHost()
+
+	links := share.List()
+	ret := make([]shareLinkInfo, 0, len(links))
+	for _, l := range links {
+		ret = append(ret, shareLinkInfo{ShareLink: l, URL: share.URL(host, l)})
+	}
+	ctx.JSON(200, ret)
+}
+
+// AddShare creates share link for the torrent file ("hash" and optional "index"),
+// movie ("movie") or episode ("show", "season" and "episode").
+// Link is restricted with "ttl" (duration or hours), "ip" and "rate" (KB/s) parameters.
+func AddShare(s *bittorrent.Service) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		defer perf.ScopeTimer()()
+
+		var l *database.ShareLink
+		var err error
+		switch {
+		case ctx.Query("hash") != "":
+			index, _ := strconv.Atoi(ctx.DefaultQuery("index", "-1"))
+			l, err = share.ForTorrent(s, ctx.Query("hash"), index)
+		case ctx.Query("movie") != "":
+			tmdbID, _ := strconv.Atoi(ctx.Query("movie"))
+			l, err = share.ForMovie(s, tmdbID)
+		case ctx.Query("show") != "":
+			showID, _ := strconv.Atoi(ctx.Query("show"))
+			season, _ := strconv.Atoi(ctx.Query("season"))
+			episode, _ := strconv.Atoi(ctx.Query("episode"))
+			l, err = share.ForEpisode(s, showID, season, episode)
+		default:
+			err = errors.New("hash, movie or show parameter is required")
+		}
+		if err != nil {
+			ctx.String(404, err.Error())
+			return
+		}
+
+		opts := share.Options{IP: ctx.Query("ip")}
+		if ttl := ctx.Query("ttl"); ttl != "" {
+			if opts.TTL, err = time.ParseDuration(ttl); err != nil {
+				hours, _ := strconv.Atoi(ttl)
+				opts.TTL = time.Duration(hours) * time.Hour
+			}
+		}
+		if rate, _ := strconv.ParseInt(ctx.Query("rate"), 10, 64); rate > 0 {
+			opts.Rate = rate * 1024
+		}
+
+		if err := share.Create(l, opts); err != nil {
+			ctx.String(500, err.Error())
+			return
+		}
+
+		log.Infof("Created share link %s for %s, expires at %s", l.ID, l.Title, l.ExpiresAt.Format(time.RFC3339))
+		ctx.JSON(200, shareLinkInfo{ShareLink: l, URL: share.URL(ip.GetExternalHTTPHost(), l)})
+	}
+}
+
+// RevokeShare deletes share link
+func RevokeShare(ctx *gin.Context) {
+	if err := share.Revoke(ctx.Params.ByName("shareId")); err != nil {
+		ctx.String(404, err.Error())
+		return
+	}
+	ctx.String(200, "")
+}
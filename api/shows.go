@@ -18,7 +18,9 @@ import (
 	"github.com/elgatito/elementum/config"
 	"github.com/elgatito/elementum/database"
 	"github.com/elgatito/elementum/library"
+	"github.com/elgatito/elementum/library/history"
 	"github.com/elgatito/elementum/library/uid"
+	"github.com/elgatito/elementum/mapping"
 	"github.com/elgatito/elementum/providers"
 	"github.com/elgatito/elementum/tmdb"
 	"github.com/elgatito/elementum/trakt"
@@ -39,6 +41,8 @@ func TVIndex(ctx *gin.Context) {
 		{Label: "Trakt > LOCALIZE[30257]", Path: URLForXBMC("/shows/trakt/collection"), Thumbnail: config.AddonResource("img", "trakt.png"), ContextMenu: [][]string{{"LOCALIZE[30252]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/library/show/list/add/collection"))}}, TraktAuth: true},
 		{Label: "Trakt > LOCALIZE[30290]", Path: URLForXBMC("/shows/trakt/calendars/"), Thumbnail: config.AddonResource("img", "most_anticipated.png"), TraktAuth: true},
 		{Label: "Trakt > LOCALIZE[30423]", Path: URLForXBMC("/shows/trakt/recommendations"), Thumbnail: config.AddonResource("img", "tv.png"), TraktAuth: true},
+		{Label: "LOCALIZE[30756]", Path: URLForXBMC("/shows/nextup"), Thumbnail: config.AddonResource("img", "tv.png")},
+		{Label: "LOCALIZE[30755]", Path: URLForXBMC("/shows/foryou"), Thumbnail: config.AddonResource("img", "tv.png")},
 		{Label: "Trakt > LOCALIZE[30246]", Path: URLForXBMC("/shows/trakt/trending"), Thumbnail: config.AddonResource("img", "trending.png")},
 		{Label: "Trakt > LOCALIZE[30210]", Path: URLForXBMC("/shows/trakt/popular"), Thumbnail: config.AddonResource("img", "popular.png")},
 		{Label: "Trakt > LOCALIZE[30247]", Path: URLForXBMC("/shows/trakt/played"), Thumbnail: config.AddonResource("img", "most_played.png")},
@@ -257,8 +261,12 @@ func TVTraktLists(ctx *gin.Context) {
 				menuItem,
 			},
 		}
+		item.ContextMenu = append(item.ContextMenu, traktListActions(list)...)
 		items = append(items, item)
 	}
+	if config.Get().TraktAuthorized {
+		items = append(items, traktListCreateItem())
+	}
 
 	ctx.JSON(200, xbmc.NewView("menus_tvshows", filterListItems(items)))
 }
@@ -316,6 +324,11 @@ func renderShows(ctx *gin.Context, shows tmdb.Shows, page int, total int, query
 			if uid.IsDuplicateShow(tmdbID) || uid.IsAddedToLibrary(tmdbID, library.ShowType) || library.IsInLibrary(show.ID, library.ShowType) {
 				libraryActions = append(libraryActions, []string{"LOCALIZE[30283]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/library/show/add/%d?force=true", show.ID))})
 				libraryActions = append(libraryActions, []string{"LOCALIZE[30253]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/library/show/remove/%d", show.ID))})
+				if library.IsShowFollowed(show.ID) {
+					libraryActions = append(libraryActions, []string{"LOCALIZE[30719]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/show/%d/unfollow", show.ID))})
+				} else if library.IsInLibrary(show.ID, library.ShowType) {
+					libraryActions = append(libraryActions, []string{"LOCALIZE[30718]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/show/%d/follow", show.ID))})
+				}
 			} else {
 				libraryActions = append(libraryActions, []string{"LOCALIZE[30252]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/library/show/add/%d", show.ID))})
 			}
@@ -340,8 +353,10 @@ func renderShows(ctx *gin.Context, shows tmdb.Shows, page int, total int, query
 				toggleWatchedAction,
 				watchlistAction,
 				collectionAction,
+				{"LOCALIZE[30715]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/show/%d/episode_group", show.ID))},
 				{"LOCALIZE[30035]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/setviewmode/tvshows"))},
 			}
+			item.ContextMenu = append(item.ContextMenu, showTraktActions(item, show.ID)...)
 			item.ContextMenu = append(libraryActions, item.ContextMenu...)
 
 			if config.Get().Platform.Kodi < 17 {
@@ -482,6 +497,11 @@ func ShowSeasons(ctx *gin.Context) {
 		return
 	}
 
+	if group := tmdb.GetEpisodeGroup(mapping.GetEpisodeGroup(show.ID), config.Get().Language); group != nil {
+		ctx.JSON(200, xbmc.NewView("seasons", filterListItems(showGroupSeasons(show, group))))
+		return
+	}
+
 	items := show.Seasons.ToListItems(show)
 	reversedItems := make(xbmc.ListItems, 0)
 	for _, item := range items {
@@ -514,6 +534,7 @@ func ShowSeasons(ctx *gin.Context) {
 
 		item.ContextMenu = [][]string{
 			toggleWatchedAction,
+			{"LOCALIZE[30715]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/show/%d/episode_group", show.ID))},
 			{"LOCALIZE[30036]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/setviewmode/seasons"))},
 		}
 		item.ContextMenu = append(libraryActions, item.ContextMenu...)
@@ -560,6 +581,11 @@ func ShowEpisodes(ctx *gin.Context) {
 		return
 	}
 
+	if group := tmdb.GetEpisodeGroup(mapping.GetEpisodeGroup(show.ID), language); group != nil {
+		ctx.JSON(200, xbmc.NewView("episodes", filterListItems(showGroupEpisodes(show, group, seasonParam))))
+		return
+	}
+
 	seasonsToShow := []int{seasonNumber}
 	if seasonParam == "all" {
 		seasonsToShow = []int{}
@@ -612,43 +638,7 @@ func ShowEpisodes(ctx *gin.Context) {
 					continue
 				}
 
-				thisURL := URLForXBMC("/show/%d/season/%d/episode/%d/",
-					show.ID,
-					seasonNumber,
-					item.Info.Episode,
-				) + "%s/%s"
-				contextLabel := playLabel
-				contextTitle := fmt.Sprintf("%s S%02dE%02d", show.OriginalName, seasonNumber, item.Info.Episode)
-				contextURL := contextPlayOppositeURL(thisURL, contextTitle, false)
-				if config.Get().ChooseStreamAutoShow {
-					contextLabel = linksLabel
-				}
-
-				item.Path = contextPlayURL(thisURL, contextTitle, false)
-				setEpisodeItemProgress(item.Path, show.ID, seasonNumber, item.Info.Episode)
-
-				libraryActions := [][]string{
-					{contextLabel, fmt.Sprintf("PlayMedia(%s)", contextURL)},
-				}
-
-				toggleWatchedAction := []string{"LOCALIZE[30667]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/show/%d/season/%d/episode/%d/watched", show.ID, seasonNumber, item.Info.Episode))}
-				if item.Info.PlayCount > 0 {
-					toggleWatchedAction = []string{"LOCALIZE[30668]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/show/%d/season/%d/episode/%d/unwatched", show.ID, seasonNumber, item.Info.Episode))}
-				}
-
-				item.ContextMenu = [][]string{
-					toggleWatchedAction,
-					{"LOCALIZE[30037]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/setviewmode/episodes"))},
-				}
-				item.ContextMenu = append(libraryActions, item.ContextMenu...)
-
-				if config.Get().Platform.Kodi < 17 {
-					item.ContextMenu = append(item.ContextMenu,
-						[]string{"LOCALIZE[30203]", "Action(Info)"},
-						[]string{"LOCALIZE[30268]", "Action(ToggleWatched)"},
-					)
-				}
-				item.IsPlayable = true
+				setEpisodeItemActions(show, item)
 			}
 
 			episodesCollection[idx] = items
@@ -663,6 +653,212 @@ func ShowEpisodes(ctx *gin.Context) {
 	ctx.JSON(200, xbmc.NewView("episodes", filterListItems(episodes)))
 }
 
+// setEpisodeItemActions sets path and context menu for an episode item, using item's season and episode numbers
+func setEpisodeItemActions(show *tmdb.Show, item *xbmc.ListItem) {
+	seasonNumber := item.Info.Season
+
+	thisURL := URLForXBMC("/show/%d/season/%d/episode/%d/",
+		show.ID,
+		seasonNumber,
+		item.Info.Episode,
+	) + "%s/%s"
+	contextLabel := playLabel
+	contextTitle := fmt.Sprintf("%s S%02dE%02d", show.OriginalName, seasonNumber, item.Info.Episode)
+	contextURL := contextPlayOppositeURL(thisURL, contextTitle, false)
+	if config.Get().ChooseStreamAutoShow {
+		contextLabel = linksLabel
+	}
+
+	item.Path = contextPlayURL(thisURL, contextTitle, false)
+	setEpisodeItemProgress(item.Path, show.ID, seasonNumber, item.Info.Episode)
+
+	libraryActions := [][]string{
+		{contextLabel, fmt.Sprintf("PlayMedia(%s)", contextURL)},
+	}
+
+	toggleWatchedAction := []string{"LOCALIZE[30667]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/show/%d/season/%d/episode/%d/watched", show.ID, seasonNumber, item.Info.Episode))}
+	if item.Info.PlayCount > 0 {
+		toggleWatchedAction = []string{"LOCALIZE[30668]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/show/%d/season/%d/episode/%d/unwatched", show.ID, seasonNumber, item.Info.Episode))}
+	}
+
+	item.ContextMenu = [][]string{
+		toggleWatchedAction,
+		{"LOCALIZE[30037]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/setviewmode/episodes"))},
+	}
+	item.ContextMenu = append(item.ContextMenu, episodeTraktActions(item, show.ID, seasonNumber, item.Info.Episode)...)
+	item.ContextMenu = append(libraryActions, item.ContextMenu...)
+
+	if config.Get().Platform.Kodi < 17 {
+		item.ContextMenu = append(item.ContextMenu,
+			[]string{"LOCALIZE[30203]", "Action(Info)"},
+			[]string{"LOCALIZE[30268]", "Action(ToggleWatched)"},
+		)
+	}
+	item.IsPlayable = true
+}
+
+// showGroupSeasons returns seasons of the episode group, selected for a show
+func showGroupSeasons(show *tmdb.Show, group *tmdb.EpisodeGroup) xbmc.ListItems {
+	items := make(xbmc.ListItems, 0, len(group.Groups))
+	for _, season := range group.Groups {
+		if season == nil || len(season.Episodes) == 0 {
+			continue
+		}
+		if !config.Get().ShowSeasonsSpecials && season.Order <= 0 {
+			continue
+		}
+
+		item := season.ToListItem(show)
+		item.Path = URLForXBMC("/show/%d/season/%d/episodes", show.ID, season.Order)
+		item.ContextMenu = [][]string{
+			{"LOCALIZE[30715]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/show/%d/episode_group", show.ID))},
+			{"LOCALIZE[30036]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/setviewmode/seasons"))},
+		}
+
+		items = append(items, item)
+	}
+
+	if config.Get().ShowSeasonsOrder != 0 {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	if config.Get().ShowSeasonsAll {
+		item := &xbmc.ListItem{
+			Label: "LOCALIZE[30571]",
+			Path:  URLForXBMC("/show/%d/season/all/episodes", show.ID),
+			ContextMenu: [][]string{
+				{"LOCALIZE[30036]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/setviewmode/seasons"))},
+			},
+		}
+
+		items = append(xbmc.ListItems{item}, items...)
+	}
+
+	return items
+}
+
+// showGroupEpisodes returns episodes of the episode group season, numbered in the group order.
+// Links are using TMDB numbers, so that everything else works with aired order.
+func showGroupEpisodes(show *tmdb.Show, group *tmdb.EpisodeGroup, seasonParam string) xbmc.ListItems {
+	seasons := group.Groups
+	if seasonParam != "all" {
+		seasonNumber, _ := strconv.Atoi(seasonParam)
+		seasons = []*tmdb.EpisodeGroupSeason{group.GetSeason(seasonNumber)}
+	}
+
+	m := mapping.Get(show)
+	items := make(xbmc.ListItems, 0)
+	for _, season := range seasons {
+		if season == nil {
+			continue
+		}
+		if seasonParam == "all" && !config.Get().ShowSeasonsSpecials && season.Order <= 0 {
+			continue
+		}
+
+		for _, item := range season.Episodes.ToListItems(show, nil) {
+			if item == nil || item.Info == nil {
+				continue
+			}
+
+			setEpisodeItemActions(show, item)
+
+			if em := m.Find(item.Info.Season, item.Info.Episode); em != nil && em.Group.Episode > 0 {
+				item.Info.Season = em.Group.Season
+				item.Info.Episode = em.Group.Episode
+				if config.Get().AddEpisodeNumbers {
+					item.Label = fmt.Sprintf("%dx%02d %s", em.Group.Season, em.Group.Episode, item.Info.OriginalTitle)
+					item.Info.Title = item.Label
+				}
+			}
+
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// ShowEpisodeGroup shows a dialog to select episode group, that is used for a show instead of aired order
+func ShowEpisodeGroup(ctx *gin.Context) {
+	xbmcHost, _ := xbmc.GetXBMCHostWithContext(ctx)
+	if xbmcHost == nil {
+		return
+	}
+
+	showID, _ := strconv.Atoi(ctx.Params.ByName("showId"))
+	groups := tmdb.GetEpisodeGroups(showID)
+	current := mapping.GetEpisodeGroup(showID)
+
+	items := []string{xbmcHost.GetLocalizedString(30716)}
+	preselect := 0
+	for i, g := range groups {
+		label := g.Name
+		if typeName := g.GetTypeName(); typeName != "" {
+			label = fmt.Sprintf("%s [%s]", g.Name, typeName)
+		}
+		items = append(items, fmt.Sprintf("%s (%d/%d)", label, g.GroupCount, g.EpisodeCount))
+
+		if g.ID == current {
+			preselect = i + 1
+		}
+	}
+
+	choice := xbmcHost.ListDialogWithOptions(0, preselect, "LOCALIZE[30715]", items...)
+	if choice < 0 || choice >= len(items) || choice == preselect {
+		ctx.String(200, "")
+		return
+	}
+
+	groupID := ""
+	if choice > 0 {
+		groupID = groups[choice-1].ID
+	}
+
+	if err := library.SetShowEpisodeGroup(showID, groupID); err != nil {
+		log.Warningf("Could not set episode group for show %d: %s", showID, err)
+		ctx.String(200, err.Error())
+		return
+	}
+
+	xbmcHost.Notify("Elementum", fmt.Sprintf("LOCALIZE[30717];;%s", items[choice]), config.AddonIcon())
+	library.ClearPageCache(xbmcHost)
+	ctx.String(200, "")
+}
+
+// ShowFollow enables automatic download of new episodes for a library show
+func ShowFollow(ctx *gin.Context) {
+	setShowFollow(ctx, true)
+}
+
+// ShowUnfollow disables automatic download of new episodes for a library show
+func ShowUnfollow(ctx *gin.Context) {
+	setShowFollow(ctx, false)
+}
+
+func setShowFollow(ctx *gin.Context, follow bool) {
+	xbmcHost, _ := xbmc.GetXBMCHostWithContext(ctx)
+
+	showID, _ := strconv.Atoi(ctx.Params.ByName("showId"))
+	if err := library.SetShowFollow(showID, follow); err != nil {
+		log.Warningf("Could not change follow for show %d: %s", showID, err)
+		ctx.String(200, err.Error())
+		return
+	}
+
+	if xbmcHost != nil {
+		message := "LOCALIZE[30721]"
+		if !follow {
+			message = "LOCALIZE[30722]"
+		}
+		xbmcHost.Notify("Elementum", message, config.AddonIcon())
+		library.ClearPageCache(xbmcHost)
+	}
+	ctx.String(200, "")
+}
+
 func setEpisodeItemProgress(path string, showID, seasonNumber, episodeNumber int) {
 	if ls, err := uid.GetShowByTMDB(showID); ls != nil && err == nil {
 		if le := ls.GetEpisode(seasonNumber, episodeNumber); le != nil && le.Resume != nil && le.Resume.Position > 0 {
@@ -671,6 +867,13 @@ func setEpisodeItemProgress(path string, showID, seasonNumber, episodeNumber int
 				log.Debugf("SetFileProgress: %s %d %d", path, int(le.Resume.Position), int(le.Resume.Total))
 				xbmcHost.SetFileProgress(path, int(le.Resume.Position), int(le.Resume.Total))
 			}
+			return
+		}
+	}
+
+	if r := history.GetResume(history.EpisodeID(showID, seasonNumber, episodeNumber)); r != nil {
+		if xbmcHost, _ := xbmc.GetLocalXBMCHost(); xbmcHost != nil {
+			xbmcHost.SetFileProgress(path, int(r.Position), int(r.Total))
 		}
 	}
 }
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/simkl"
+	"github.com/elgatito/elementum/xbmc"
+)
+
+// AuthorizeSimkl ...
+func AuthorizeSimkl(ctx *gin.Context) {
+	xbmcHost, _ := xbmc.GetXBMCHostWithContext(ctx)
+	if xbmcHost == nil {
+		return
+	}
+
+	if err := simkl.Authorize(); err != nil {
+		xbmcHost.Notify("Elementum", err.Error(), config.AddonIcon())
+	}
+	ctx.String(200, "")
+}
+
+// DeauthorizeSimkl ...
+func DeauthorizeSimkl(ctx *gin.Context) {
+	xbmcHost, _ := xbmc.GetXBMCHostWithContext(ctx)
+	if xbmcHost == nil {
+		return
+	}
+
+	if err := simkl.Deauthorize(); err != nil {
+		xbmcHost.Notify("Elementum", err.Error(), config.AddonIcon())
+	}
+	ctx.String(200, "")
+}
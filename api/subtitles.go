@@ -2,13 +2,16 @@ package api
 
 import (
 	"net/url"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/elgatito/elementum/bittorrent"
 
 	"github.com/elgatito/elementum/config"
 	"github.com/elgatito/elementum/opensubtitles"
+	"github.com/elgatito/elementum/subtitles"
 	"github.com/elgatito/elementum/util/ip"
 	"github.com/elgatito/elementum/xbmc"
 
@@ -37,41 +40,38 @@ func SubtitlesIndex(s *bittorrent.Service) gin.HandlerFunc {
 			playingFile, _ = url.PathUnescape(playingFile)
 		}
 
+		player := s.GetActivePlayer()
 		showID := 0
-		if s.GetActivePlayer() != nil {
-			showID = s.GetActivePlayer().Params().ShowID
+		if player != nil {
+			showID = player.Params().ShowID
 		}
-		payloads, preferredLanguage := opensubtitles.GetPayloads(xbmcHost, q.Get("searchstring"), strings.Split(q.Get("languages"), ","), q.Get("preferredlanguage"), showID, playingFile)
-		subLog.Infof("Subtitles payload: %#v", payloads)
 
-		results, err := opensubtitles.DoSearch(payloads, preferredLanguage)
-		if err != nil {
-			subLog.Errorf("Error searching subtitles: %s", err)
+		query := subtitles.NewQuery(xbmcHost, q.Get("searchstring"), strings.Split(q.Get("languages"), ","), q.Get("preferredlanguage"), showID, playingFile)
+		if player != nil {
+			player.FillSubtitlesQuery(query)
 		}
+		subLog.Infof("Subtitles query: %#v", query)
 
-		items := make(xbmc.ListItems, 0)
+		results := subtitles.Search(query)
+		items := make(xbmc.ListItems, 0, len(results))
 
 		for _, sub := range results {
-			if len(sub.Attributes.Files) == 0 {
-				continue
-			}
-
-			subFile := sub.Attributes.Files[0]
-
 			item := &xbmc.ListItem{
-				Label:  sub.Attributes.Language,
-				Label2: subFile.FileName,
-				Icon:   strconv.Itoa(int((sub.Attributes.Ratings / 2) + 0.5)),
-				Path: URLQuery(URLForXBMC("/subtitle/%d", subFile.FileID),
-					"file", subFile.FileName,
-					"lang", sub.Attributes.Language,
-					"fmt", sub.Type),
+				Label:  sub.Language,
+				Label2: sub.FileName,
+				Icon:   strconv.Itoa(int((sub.Rating / 2) + 0.5)),
+				Path: URLQuery(URLForXBMC("/subtitles/download"),
+					"provider", sub.Provider,
+					"id", sub.ID,
+					"file", sub.FileName,
+					"lang", sub.Language,
+					"fmt", sub.Format),
 				Properties: &xbmc.ListItemProperties{},
 			}
-			if sub.Attributes.MovieHashMatch {
+			if sub.HashMatch {
 				item.Properties.SubtitlesSync = trueType
 			}
-			if sub.Attributes.HearingImpaired {
+			if sub.HearingImpaired {
 				item.Properties.SubtitlesHearingImpaired = trueType
 			}
 			items = append(items, item)
@@ -81,12 +81,27 @@ func SubtitlesIndex(s *bittorrent.Service) gin.HandlerFunc {
 	}
 }
 
-// SubtitleGet ...
+// SubtitleGet downloads OpenSubtitles subtitle by its file ID
 func SubtitleGet(ctx *gin.Context) {
-	id := ctx.Params.ByName("id")
+	downloadSubtitle(ctx, &subtitles.Subtitle{
+		Provider: opensubtitles.NewProvider().Name(),
+		ID:       ctx.Params.ByName("id"),
+	})
+}
+
+// SubtitleDownload downloads subtitle, found by any of subtitles providers
+func SubtitleDownload(ctx *gin.Context) {
+	downloadSubtitle(ctx, &subtitles.Subtitle{
+		Provider: ctx.Query("provider"),
+		ID:       ctx.Query("id"),
+	})
+}
 
-	log.Debugf("Downloading subtitles: %s", id)
-	outFile, file, _, err := opensubtitles.DoDownload(id)
+func downloadSubtitle(ctx *gin.Context, sub *subtitles.Subtitle) {
+	sub.Language = ctx.Query("lang")
+
+	log.Debugf("Downloading subtitles from %s: %s", sub.Provider, sub.ID)
+	path, err := subtitles.Download(sub, subtitleOptions(ctx))
 	if err != nil {
 		subLog.Error(err)
 		ctx.String(200, err.Error())
@@ -94,6 +109,26 @@ func SubtitleGet(ctx *gin.Context) {
 	}
 
 	ctx.JSON(200, xbmc.NewView("", xbmc.ListItems{
-		{Label: file, Path: outFile.Name()},
+		{Label: filepath.Base(path), Path: path},
 	}))
 }
+
+// subtitleOptions reads processing options from query:
+// format - target format, offset - shift in seconds or as a duration like "-1.5s",
+// fps - frame rate of the video, sub_fps - frame rate subtitles were made for
+func subtitleOptions(ctx *gin.Context) *subtitles.Options {
+	opts := subtitles.DefaultOptions()
+	if format := ctx.Query("format"); format != "" {
+		opts.Format = strings.ToLower(format)
+	}
+	if offset := ctx.Query("offset"); offset != "" {
+		if seconds, err := strconv.ParseFloat(offset, 64); err == nil {
+			opts.Offset = time.Duration(seconds * float64(time.Second))
+		} else if d, err := time.ParseDuration(offset); err == nil {
+			opts.Offset = d
+		}
+	}
+	opts.FPS, _ = strconv.ParseFloat(ctx.Query("fps"), 64)
+	opts.SourceFPS, _ = strconv.ParseFloat(ctx.Query("sub_fps"), 64)
+	return opts
+}
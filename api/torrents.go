@@ -297,6 +297,7 @@ func ListTorrents(s *bittorrent.Service) gin.HandlerFunc {
 				Info: &xbmc.ListItemInfo{
 					Title: torrentName,
 				},
+				StreamInfo: t.ChosenStreamInfo(),
 			}
 
 			item.ContextMenu = [][]string{
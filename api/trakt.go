@@ -15,8 +15,10 @@ import (
 	"github.com/elgatito/elementum/config"
 	"github.com/elgatito/elementum/database"
 	"github.com/elgatito/elementum/library"
+	"github.com/elgatito/elementum/library/history"
 	"github.com/elgatito/elementum/library/uid"
 	"github.com/elgatito/elementum/tmdb"
+	"github.com/elgatito/elementum/tracker"
 	"github.com/elgatito/elementum/trakt"
 	"github.com/elgatito/elementum/util"
 	"github.com/elgatito/elementum/xbmc"
@@ -130,6 +132,21 @@ func DeauthorizeTrakt(ctx *gin.Context) {
 	}
 }
 
+// TraktQueue returns Trakt write requests, that wait to be sent again
+func TraktQueue(ctx *gin.Context) {
+	ctx.JSON(200, trakt.GetQueue())
+}
+
+// TraktQueueFlush sends all queued Trakt write requests immediately
+func TraktQueueFlush(ctx *gin.Context) {
+	sent, failed := trakt.FlushQueue(true)
+	ctx.JSON(200, map[string]int{
+		"sent":   sent,
+		"failed": failed,
+		"queued": len(trakt.GetQueue()),
+	})
+}
+
 //
 // Main lists
 //
@@ -281,12 +298,9 @@ func AddMovieToWatchlist(ctx *gin.Context) {
 		return
 	}
 
-	tmdbID := ctx.Params.ByName("tmdbId")
-	req, err := trakt.AddToWatchlist("movies", tmdbID)
-	if err != nil {
+	tmdbID, _ := strconv.Atoi(ctx.Params.ByName("tmdbId"))
+	if err := tracker.SetWatchlist(&tracker.Item{MediaType: tracker.MovieType, TMDBID: tmdbID}, true); err != nil {
 		xbmcHost.Notify("Elementum", err.Error(), config.AddonIcon())
-	} else if req.ResponseStatusCode != 201 {
-		xbmcHost.Notify("Elementum", fmt.Sprintf("Failed with %d status code", req.ResponseStatusCode), config.AddonIcon())
 	} else {
 		xbmcHost.Notify("Elementum", "Movie added to watchlist", config.AddonIcon())
 		database.GetCache().DeleteWithPrefix(database.CommonBucket, []byte("com.trakt.watchlist.movies"))
@@ -307,9 +321,8 @@ func RemoveMovieFromWatchlist(ctx *gin.Context) {
 		return
 	}
 
-	tmdbID := ctx.Params.ByName("tmdbId")
-	_, err := trakt.RemoveFromWatchlist("movies", tmdbID)
-	if err != nil {
+	tmdbID, _ := strconv.Atoi(ctx.Params.ByName("tmdbId"))
+	if err := tracker.SetWatchlist(&tracker.Item{MediaType: tracker.MovieType, TMDBID: tmdbID}, false); err != nil {
 		xbmcHost.Notify("Elementum", err.Error(), config.AddonIcon())
 	} else {
 		xbmcHost.Notify("Elementum", "Movie removed from watchlist", config.AddonIcon())
@@ -331,12 +344,9 @@ func AddShowToWatchlist(ctx *gin.Context) {
 		return
 	}
 
-	tmdbID := ctx.Params.ByName("showId")
-	req, err := trakt.AddToWatchlist("shows", tmdbID)
-	if err != nil {
+	tmdbID, _ := strconv.Atoi(ctx.Params.ByName("showId"))
+	if err := tracker.SetWatchlist(&tracker.Item{MediaType: tracker.ShowType, TMDBID: tmdbID}, true); err != nil {
 		xbmcHost.Notify("Elementum", err.Error(), config.AddonIcon())
-	} else if req.ResponseStatusCode != 201 {
-		xbmcHost.Notify("Elementum", fmt.Sprintf("Failed %d", req.ResponseStatusCode), config.AddonIcon())
 	} else {
 		xbmcHost.Notify("Elementum", "Show added to watchlist", config.AddonIcon())
 		database.GetCache().DeleteWithPrefix(database.CommonBucket, []byte("com.trakt.watchlist.shows"))
@@ -357,9 +367,8 @@ func RemoveShowFromWatchlist(ctx *gin.Context) {
 		return
 	}
 
-	tmdbID := ctx.Params.ByName("showId")
-	_, err := trakt.RemoveFromWatchlist("shows", tmdbID)
-	if err != nil {
+	tmdbID, _ := strconv.Atoi(ctx.Params.ByName("showId"))
+	if err := tracker.SetWatchlist(&tracker.Item{MediaType: tracker.ShowType, TMDBID: tmdbID}, false); err != nil {
 		xbmcHost.Notify("Elementum", err.Error(), config.AddonIcon())
 	} else {
 		xbmcHost.Notify("Elementum", "Show removed from watchlist", config.AddonIcon())
@@ -381,12 +390,9 @@ func AddMovieToCollection(ctx *gin.Context) {
 		return
 	}
 
-	tmdbID := ctx.Params.ByName("tmdbId")
-	req, err := trakt.AddToCollection("movies", tmdbID)
-	if err != nil {
+	tmdbID, _ := strconv.Atoi(ctx.Params.ByName("tmdbId"))
+	if err := tracker.SetCollection(&tracker.Item{MediaType: tracker.MovieType, TMDBID: tmdbID}, true); err != nil {
 		xbmcHost.Notify("Elementum", err.Error(), config.AddonIcon())
-	} else if req.ResponseStatusCode != 201 {
-		xbmcHost.Notify("Elementum", fmt.Sprintf("Failed with %d status code", req.ResponseStatusCode), config.AddonIcon())
 	} else {
 		xbmcHost.Notify("Elementum", "Movie added to collection", config.AddonIcon())
 		database.GetCache().DeleteWithPrefix(database.CommonBucket, []byte("com.trakt.collection.movies"))
@@ -407,9 +413,8 @@ func RemoveMovieFromCollection(ctx *gin.Context) {
 		return
 	}
 
-	tmdbID := ctx.Params.ByName("tmdbId")
-	_, err := trakt.RemoveFromCollection("movies", tmdbID)
-	if err != nil {
+	tmdbID, _ := strconv.Atoi(ctx.Params.ByName("tmdbId"))
+	if err := tracker.SetCollection(&tracker.Item{MediaType: tracker.MovieType, TMDBID: tmdbID}, false); err != nil {
 		xbmcHost.Notify("Elementum", err.Error(), config.AddonIcon())
 	} else {
 		xbmcHost.Notify("Elementum", "Movie removed from collection", config.AddonIcon())
@@ -431,12 +436,9 @@ func AddShowToCollection(ctx *gin.Context) {
 		return
 	}
 
-	tmdbID := ctx.Params.ByName("showId")
-	req, err := trakt.AddToCollection("shows", tmdbID)
-	if err != nil {
+	tmdbID, _ := strconv.Atoi(ctx.Params.ByName("showId"))
+	if err := tracker.SetCollection(&tracker.Item{MediaType: tracker.ShowType, TMDBID: tmdbID}, true); err != nil {
 		xbmcHost.Notify("Elementum", err.Error(), config.AddonIcon())
-	} else if req.ResponseStatusCode != 201 {
-		xbmcHost.Notify("Elementum", fmt.Sprintf("Failed with %d status code", req.ResponseStatusCode), config.AddonIcon())
 	} else {
 		xbmcHost.Notify("Elementum", "Show added to collection", config.AddonIcon())
 		database.GetCache().DeleteWithPrefix(database.CommonBucket, []byte("com.trakt.collection.shows"))
@@ -457,9 +459,8 @@ func RemoveShowFromCollection(ctx *gin.Context) {
 		return
 	}
 
-	tmdbID := ctx.Params.ByName("showId")
-	_, err := trakt.RemoveFromCollection("shows", tmdbID)
-	if err != nil {
+	tmdbID, _ := strconv.Atoi(ctx.Params.ByName("showId"))
+	if err := tracker.SetCollection(&tracker.Item{MediaType: tracker.ShowType, TMDBID: tmdbID}, false); err != nil {
 		xbmcHost.Notify("Elementum", err.Error(), config.AddonIcon())
 	} else {
 		xbmcHost.Notify("Elementum", "Show removed from collection", config.AddonIcon())
@@ -577,6 +578,7 @@ func renderTraktMovies(ctx *gin.Context, movies []*trakt.Movies, total int, page
 				collectionAction,
 				{"LOCALIZE[30034]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/setviewmode/movies"))},
 			}
+			item.ContextMenu = append(item.ContextMenu, movieTraktActions(item, movieListing.Movie.IDs.TMDB)...)
 			item.ContextMenu = append(libraryActions, item.ContextMenu...)
 
 			if config.Get().Platform.Kodi < 17 {
@@ -913,6 +915,7 @@ func renderTraktShows(ctx *gin.Context, shows []*trakt.Shows, total int, page in
 				collectionAction,
 				{"LOCALIZE[30035]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/setviewmode/tvshows"))},
 			}
+			item.ContextMenu = append(item.ContextMenu, showTraktActions(item, showListing.Show.IDs.TMDB)...)
 			item.ContextMenu = append(libraryActions, item.ContextMenu...)
 
 			if config.Get().Platform.Kodi < 17 {
@@ -1449,6 +1452,7 @@ func renderCalendarMovies(ctx *gin.Context, movies []*trakt.CalendarMovie, total
 				collectionAction,
 				{"LOCALIZE[30034]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/setviewmode/movies"))},
 			}
+			item.ContextMenu = append(item.ContextMenu, movieTraktActions(item, movieListing.Movie.IDs.TMDB)...)
 			item.ContextMenu = append(libraryActions, item.ContextMenu...)
 
 			if config.Get().Platform.Kodi < 17 {
@@ -1654,6 +1658,7 @@ func renderCalendarShows(ctx *gin.Context, shows []*trakt.CalendarShow, total in
 				openShowAction,
 				{"LOCALIZE[30035]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/setviewmode/tvshows"))},
 			}
+			item.ContextMenu = append(item.ContextMenu, showTraktActions(item, showListing.Show.IDs.TMDB)...)
 			item.ContextMenu = append(libraryActions, item.ContextMenu...)
 
 			if config.Get().Platform.Kodi < 17 {
@@ -1817,6 +1822,7 @@ func renderProgressShows(ctx *gin.Context, shows []*trakt.ProgressShow, total in
 				openShowAction,
 				{"LOCALIZE[30037]", fmt.Sprintf("RunPlugin(%s)", URLForXBMC("/setviewmode/episodes"))},
 			}
+			item.ContextMenu = append(item.ContextMenu, episodeTraktActions(item, showListing.Show.IDs.TMDB, seasonNumber, episodeNumber)...)
 			item.ContextMenu = append(libraryActions, item.ContextMenu...)
 
 			if config.Get().Platform.Kodi < 17 {
@@ -1996,9 +2002,13 @@ func ToggleWatched(media string, setWatched bool) gin.HandlerFunc {
 			}
 		}
 
-		if config.Get().TraktToken != "" && watched != nil {
-			log.Debugf("Set Trakt watched to %t for: %#v", setWatched, watched)
-			go trakt.SetWatched(watched)
+		if watched != nil {
+			history.SetWatched(history.NewItem(media, watched.Movie, watched.Show, watched.Season, watched.Episode, "", ""), setWatched)
+		}
+
+		if len(tracker.Get()) > 0 && watched != nil {
+			log.Debugf("Set trackers watched to %t for: %#v", setWatched, watched)
+			go tracker.SetWatched(trakt.TrackerItems([]*trakt.WatchedItem{watched}), setWatched)
 		}
 
 		if !foundInLibrary {
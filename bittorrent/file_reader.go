@@ -0,0 +1,108 @@
+package bittorrent
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/elgatito/elementum/subtitles"
+)
+
+// ErrPieceNotReady is returned by FileReader for pieces, that are not downloaded yet
+var ErrPieceNotReady = errors.New("piece is not downloaded yet")
+
+// FileReader reads downloaded pieces of a torrent file, from disk or memory storage.
+// Unlike TorrentFSEntry it is not registered as torrent reader and never waits for pieces,
+// so it does not change pieces priorities for the playback.
+type FileReader struct {
+	t    *Torrent
+	f    *File
+	file *os.File
+	mf   *MemoryFile
+}
+
+// NewFileReader ...
+func (t *Torrent) NewFileReader(f *File) (*FileReader, error) {
+	fr := &FileReader{t: t, f: f}
+	if t.IsMemoryStorage() {
+		fr.mf = NewMemoryFile(t, nil, t.th.GetMemoryStorage(), f, f.Path)
+		return fr, nil
+	}
+
+	file, err := os.Open(filepath.Join(t.Service.config.DownloadPath, f.Path))
+	if err != nil {
+		return nil, err
+	}
+	fr.file = file
+	return fr, nil
+}
+
+// ReadAt reads file data at offset, if all needed pieces are downloaded
+func (fr *FileReader) ReadAt(b []byte, off int64) (n int, err error) {
+	if off < 0 || off >= fr.f.Size {
+		return 0, io.EOF
+	}
+	if off+int64(len(b)) > fr.f.Size {
+		b = b[:fr.f.Size-off]
+		defer func() {
+			if err == nil {
+				err = io.EOF
+			}
+		}()
+	}
+	if len(b) == 0 || fr.t.pieceLength == 0 {
+		return 0, io.EOF
+	}
+
+	start := fr.f.Offset + off
+	firstPiece := int(start / fr.t.pieceLength)
+	lastPiece := int((start + int64(len(b)) - 1) / fr.t.pieceLength)
+	for piece := firstPiece; piece <= lastPiece; piece++ {
+		if !fr.t.hasPiece(piece) {
+			return 0, ErrPieceNotReady
+		}
+	}
+
+	if fr.file != nil {
+		return fr.file.ReadAt(b, off)
+	}
+
+	for n < len(b) {
+		piece := int((start + int64(n)) / fr.t.pieceLength)
+		pieceOffset := int((start + int64(n)) % fr.t.pieceLength)
+		size := len(b) - n
+		if pieceOffset+size > int(fr.t.pieceLength) {
+			size = int(fr.t.pieceLength) - pieceOffset
+		}
+
+		read, err := fr.mf.ReadPiece(b[n:n+size], piece, pieceOffset)
+		if err != nil && err != io.EOF {
+			return n, err
+		} else if read != size {
+			return n, io.ErrUnexpectedEOF
+		}
+		n += size
+	}
+	return n, nil
+}
+
+// Close ...
+func (fr *FileReader) Close() error {
+	if fr.file != nil {
+		return fr.file.Close()
+	}
+	return nil
+}
+
+// SubtitlesHash computes OpenSubtitles hash of the file from its first and last pieces,
+// which are downloaded with the buffer, so the file does not need to be complete
+func (t *Torrent) SubtitlesHash(f *File) (string, error) {
+	fr, err := t.NewFileReader(f)
+	if err != nil {
+		return "", err
+	}
+	defer fr.Close()
+
+	return subtitles.Hash(fr, f.Size)
+}
@@ -23,16 +23,20 @@ import (
 	"github.com/elgatito/elementum/broadcast"
 	"github.com/elgatito/elementum/config"
 	"github.com/elgatito/elementum/database"
+	"github.com/elgatito/elementum/library/history"
 	"github.com/elgatito/elementum/library/playcount"
 	"github.com/elgatito/elementum/library/uid"
-	"github.com/elgatito/elementum/opensubtitles"
+	"github.com/elgatito/elementum/mapping"
+	"github.com/elgatito/elementum/subtitles"
 	"github.com/elgatito/elementum/tmdb"
+	"github.com/elgatito/elementum/tracker"
 	"github.com/elgatito/elementum/trakt"
-	"github.com/elgatito/elementum/tvdb"
 	"github.com/elgatito/elementum/upnext"
 	"github.com/elgatito/elementum/util"
 	"github.com/elgatito/elementum/util/event"
 	"github.com/elgatito/elementum/util/ip"
+	"github.com/elgatito/elementum/util/probe"
+	"github.com/elgatito/elementum/util/source"
 	"github.com/elgatito/elementum/xbmc"
 )
 
@@ -57,6 +61,15 @@ const (
 	ResumeNo
 )
 
+const (
+	// SkipIntroDisabled ...
+	SkipIntroDisabled = iota
+	// SkipIntroAsk shows a dialog, that seeks to the end of intro, while intro is playing
+	SkipIntroAsk
+	// SkipIntroAuto seeks to the end of intro with a notification
+	SkipIntroAuto
+)
+
 // Player ...
 type Player struct {
 	s                    *Service
@@ -67,10 +80,17 @@ type Player struct {
 	overlayStatus        *xbmc.OverlayStatus
 	next                 NextEpisode
 	scrobble             bool
+	checkin              bool
+	checkedIn            bool
 	overlayStatusEnabled bool
 	chosenFile           *File
 	subtitlesFile        *File
 	subtitlesLoaded      []string
+	subtitlesHash        string
+	subtitlesIncluded    bool
+	mediaInfo            *probe.MediaInfo
+	markers              *probe.Markers
+	introOffered         bool
 	fileSize             int64
 	fileName             string
 	extracted            string
@@ -118,6 +138,7 @@ type PlayerParams struct {
 	MinSize           int64
 	SizeAtStart       bool
 	UpNextSent        bool
+	Source            source.MediaSource
 	UIDs              *uid.UniqueIDs
 	Resume            *uid.Resume
 	StoredResume      *uid.Resume
@@ -151,7 +172,8 @@ func NewPlayer(bts *Service, params PlayerParams, xbmcHost *xbmc.XBMCHost) *Play
 		xbmcHost: xbmcHost,
 
 		overlayStatusEnabled: config.Get().EnableOverlayStatus,
-		scrobble:             config.Get().Scrobble && params.TMDBId > 0 && config.Get().TraktToken != "",
+		scrobble:             config.Get().Scrobble && params.TMDBId > 0 && len(tracker.Get()) > 0,
+		checkin:              config.Get().TraktCheckin && params.TMDBId > 0 && config.Get().TraktToken != "" && (params.ContentType == movieType || params.ContentType == episodeType),
 		hasChosenFile:        false,
 		fileSize:             0,
 		fileName:             "",
@@ -227,6 +249,10 @@ func (btp *Player) resumeTorrent() error {
 
 // PlayURL ...
 func (btp *Player) PlayURL() string {
+	if btp.p.Source != nil {
+		return btp.p.Source.PlayURL()
+	}
+
 	path := ""
 	if btp.chosenFile != nil {
 		path = btp.chosenFile.Path
@@ -240,6 +266,10 @@ func (btp *Player) PlayURL() string {
 
 // Buffer ...
 func (btp *Player) Buffer() error {
+	if btp.p.Source != nil {
+		return btp.bufferSource()
+	}
+
 	if btp.p.ResumeHash != "" {
 		if err := btp.resumeTorrent(); err != nil {
 			log.Errorf("Error resuming torrent: %s", err)
@@ -289,6 +319,18 @@ func (btp *Player) Buffer() error {
 		return errors.New("File not chosen")
 	}
 
+	// Buffer has head and tail of the file, so subtitles hash and container tracks
+	// can be read before file is downloaded
+	if btp.extracted == "" {
+		if hash, err := btp.t.SubtitlesHash(btp.chosenFile); err == nil {
+			btp.subtitlesHash = hash
+		} else {
+			log.Debugf("Cannot compute subtitles hash for %s: %s", btp.chosenFile.Path, err)
+		}
+		btp.mediaInfo = btp.t.ProbeFile(btp.chosenFile)
+		btp.markers = btp.detectMarkers()
+	}
+
 	// If needed select more files for download
 	if config.Get().DownloadFileStrategy != DownloadFilePlaying && !btp.t.IsMemoryStorage() {
 		go btp.t.SelectDownloadFiles(btp)
@@ -517,14 +559,19 @@ func (btp *Player) Close() {
 	btp.closed = true
 	btp.closer.Set()
 
-	// Torrent was not initialized so just close and return
-	if btp.t == nil {
+	// Neither torrent nor source was initialized so just close and return
+	if btp.t == nil && btp.p.Source == nil {
 		return
 	}
 
 	// Cleanup autoloaded subtitles
 	if btp.subtitlesLoaded != nil && len(btp.subtitlesLoaded) > 0 && config.Get().OSDBAutoLoadDelete {
+		// Subtitles from local archive are used in place and should be kept
+		subtitlesDir, _ := subtitles.Dir()
 		for _, f := range btp.subtitlesLoaded {
+			if subtitlesDir == "" || !strings.HasPrefix(f, subtitlesDir) {
+				continue
+			}
 			if _, err := os.Stat(f); err == nil {
 				log.Infof("Deleting saved subtitles file at %s", f)
 				defer os.Remove(f)
@@ -537,6 +584,11 @@ func (btp *Player) Close() {
 		go btp.s.PlayerStop()
 	}()
 
+	if btp.p.Source != nil {
+		btp.p.Source.Close()
+		return
+	}
+
 	if btp.t.HasNextFile && btp.IsWatched() {
 		log.Infof("Leaving torrent '%s' awaiting for next file playback", btp.t.Name())
 		btp.t.startNextTimer()
@@ -728,16 +780,19 @@ func (btp *Player) updateWatchTimes() {
 func (btp *Player) playerLoop() {
 	defer btp.Close()
 
-	log.Info("Buffer loop")
+	// Sources are opened before the loop, there is nothing to buffer
+	if btp.p.Source == nil {
+		log.Info("Buffer loop")
 
-	buffered, bufferDone := btp.bufferEvents.Listen()
-	defer close(bufferDone)
+		buffered, bufferDone := btp.bufferEvents.Listen()
+		defer close(bufferDone)
 
-	go btp.bufferDialog()
+		go btp.bufferDialog()
 
-	if err := <-buffered; err != nil {
-		log.Errorf("Error buffering: %#v", err)
-		return
+		if err := <-buffered; err != nil {
+			log.Errorf("Error buffering: %#v", err)
+			return
+		}
 	}
 
 	log.Info("Waiting for playback...")
@@ -767,17 +822,25 @@ playbackWaitLoop:
 	btp.findNextFile()
 
 	log.Infof("Got playback: %fs / %fs", btp.p.WatchedTime, btp.p.VideoDuration)
+	// Successful check-in adds the item to Trakt history by itself, so Trakt is not scrobbled then
+	if btp.checkin {
+		if err := trakt.Checkin(btp.p.ContentType, btp.p.TMDBId); err != nil {
+			log.Infof("Trakt check-in failed, scrobbling instead: %s", err)
+		} else {
+			btp.checkedIn = true
+		}
+	}
 	if btp.scrobble {
-		trakt.Scrobble("start", btp.p.ContentType, btp.p.TMDBId, btp.p.WatchedTime, btp.p.VideoDuration)
+		btp.scrobbleTrackers("start")
 		btp.p.TraktScrobbled = true
 	}
 
-	btp.t.IsPlaying = true
+	btp.setPlaying(true)
 
 playbackLoop:
 	for {
 		if btp.p.Background || btp.xbmcHost == nil || !btp.xbmcHost.PlayerIsPlaying() {
-			btp.t.IsPlaying = false
+			btp.setPlaying(false)
 			break playbackLoop
 		}
 		<-oneSecond.C
@@ -788,10 +851,15 @@ playbackLoop:
 			go btp.processUpNextPayload()
 		}
 
+		if !btp.introOffered && btp.markers.InIntro(btp.watchedPosition()) {
+			btp.introOffered = true
+			go btp.skipIntro()
+		}
+
 		if btp.p.Seeked {
 			btp.p.Seeked = false
 			if btp.scrobble {
-				go trakt.Scrobble("start", btp.p.ContentType, btp.p.TMDBId, btp.p.WatchedTime, btp.p.VideoDuration)
+				go btp.scrobbleTrackers("start")
 			}
 		} else if btp.xbmcHost == nil || btp.xbmcHost.PlayerIsPaused() {
 			if btp.overlayStatusEnabled && btp.p.Playing {
@@ -807,7 +875,7 @@ playbackLoop:
 			if playing {
 				playing = false
 				if btp.scrobble {
-					go trakt.Scrobble("pause", btp.p.ContentType, btp.p.TMDBId, btp.p.WatchedTime, btp.p.VideoDuration)
+					go btp.scrobbleTrackers("pause")
 				}
 			}
 		} else {
@@ -818,7 +886,7 @@ playbackLoop:
 			if !playing {
 				playing = true
 				if btp.scrobble {
-					go trakt.Scrobble("start", btp.p.ContentType, btp.p.TMDBId, btp.p.WatchedTime, btp.p.VideoDuration)
+					go btp.scrobbleTrackers("start")
 				}
 			}
 		}
@@ -830,17 +898,24 @@ playbackLoop:
 
 	log.Info("Stopped playback")
 	btp.SaveStoredResume()
-	btp.setRateLimiting(false)
+	if btp.t != nil {
+		btp.setRateLimiting(false)
+	}
 	go func() {
 		btp.GetIdent()
 		btp.UpdateWatched()
+		isWatched := btp.IsWatched()
 		if btp.scrobble {
-			if btp.IsWatched() {
-				trakt.Scrobble("stop", btp.p.ContentType, btp.p.TMDBId, btp.p.WatchedTime, btp.p.VideoDuration)
+			if isWatched {
+				btp.scrobbleTrackers("stop")
 			} else {
-				trakt.Scrobble("pause", btp.p.ContentType, btp.p.TMDBId, btp.p.WatchedTime, btp.p.VideoDuration)
+				btp.scrobbleTrackers("pause")
 			}
 		}
+		// Check-in is kept only for watched items, failed check-in may belong to another device and is not cancelled
+		if btp.checkedIn && !isWatched {
+			trakt.CancelCheckin()
+		}
 
 		btp.p.Playing = false
 		btp.p.Paused = false
@@ -849,6 +924,10 @@ playbackLoop:
 		btp.p.WatchedTime = 0
 		btp.p.VideoDuration = 0
 		btp.p.WatchedProgress = 0
+
+		if isWatched {
+			btp.promptRating()
+		}
 	}()
 
 	if btp.overlayStatus != nil {
@@ -856,6 +935,64 @@ playbackLoop:
 	}
 }
 
+// setPlaying marks torrent of the player as playing
+func (btp *Player) setPlaying(playing bool) {
+	if btp.t != nil {
+		btp.t.IsPlaying = playing
+	}
+}
+
+// key identifies the player among active players, by torrent or by media source
+func (btp *Player) key() string {
+	if btp.p.Source != nil {
+		return btp.p.Source.ID()
+	} else if btp.t != nil {
+		return btp.t.InfoHash()
+	}
+	return ""
+}
+
+// promptRating asks user to rate just watched movie or episode on Trakt
+func (btp *Player) promptRating() {
+	if !config.Get().TraktRatePrompt || config.Get().TraktToken == "" || btp.p.Background || btp.xbmcHost == nil {
+		return
+	}
+
+	if btp.p.ContentType == movieType && btp.p.TMDBId != 0 {
+		if trakt.GetMovieRating(btp.p.TMDBId) == 0 {
+			trakt.RateDialog(btp.xbmcHost, "movies", btp.p.TMDBId, 0, 0)
+		}
+	} else if btp.p.ContentType == episodeType && btp.p.ShowID != 0 {
+		if trakt.GetEpisodeRating(btp.p.ShowID, btp.p.Season, btp.p.Episode) == 0 {
+			trakt.RateDialog(btp.xbmcHost, "episodes", btp.p.ShowID, btp.p.Season, btp.p.Episode)
+		}
+	}
+}
+
+// scrobbleTrackers reports current playback state to enabled trackers
+func (btp *Player) scrobbleTrackers(action string) {
+	if btp.p.VideoDuration < 1 || btp.p.ContentType == "search" {
+		return
+	}
+
+	item := &tracker.Item{
+		MediaType: btp.p.ContentType,
+		TMDBID:    btp.p.TMDBId,
+		ShowID:    btp.p.ShowID,
+		Season:    btp.p.Season,
+		Episode:   btp.p.Episode,
+	}
+	tracker.Scrobble(action, item, btp.p.WatchedTime/btp.p.VideoDuration*100, btp.exceptTrackers()...)
+}
+
+// exceptTrackers returns trackers, that are not updated by the player, as Trakt check-in tracks the item itself
+func (btp *Player) exceptTrackers() []string {
+	if btp.checkedIn {
+		return []string{trakt.TrackerName}
+	}
+	return nil
+}
+
 func (btp *Player) isReadyForNextFile() bool {
 	if btp.t.IsMemoryStorage() {
 		ra := btp.t.GetReadaheadSize()
@@ -867,7 +1004,75 @@ func (btp *Player) isReadyForNextFile() bool {
 		return ra > 0 && sum > 0 && ra > sum+btp.next.bufferSize && btp.t.awaitingPieces.IsEmpty() && btp.t.lastProgress > 90
 	}
 
-	return btp.IsWatched()
+	return btp.IsWatched() || btp.markers.InCredits(btp.watchedPosition())
+}
+
+// detectMarkers returns intro and credits of the chosen file, found by container chapters.
+// Runtime from TMDB is used, when container has no duration.
+func (btp *Player) detectMarkers() *probe.Markers {
+	if btp.mediaInfo == nil || len(btp.mediaInfo.Chapters) == 0 {
+		return nil
+	}
+
+	duration := btp.mediaInfo.Duration
+	if duration <= 0 {
+		runtime := 0
+		if btp.p.ShowID != 0 {
+			if episode := tmdb.GetEpisode(btp.p.ShowID, btp.p.Season, btp.p.Episode, config.Get().Language); episode != nil {
+				runtime = episode.Runtime
+			}
+		} else if btp.p.ContentType == movieType && btp.p.TMDBId != 0 {
+			if movie := tmdb.GetMovie(btp.p.TMDBId, config.Get().Language); movie != nil {
+				runtime = movie.Runtime
+			}
+		}
+		duration = time.Duration(runtime) * time.Minute
+	}
+
+	m := probe.DetectMarkers(btp.mediaInfo.Chapters, duration)
+	if m != nil {
+		if m.Intro != nil {
+			log.Infof("Detected intro at %s - %s", m.Intro.Start, m.Intro.End)
+		}
+		if m.Credits != nil {
+			log.Infof("Detected credits at %s", m.Credits.Start)
+		}
+	}
+	return m
+}
+
+// watchedPosition returns current playback position
+func (btp *Player) watchedPosition() time.Duration {
+	return time.Duration(btp.p.WatchedTime * float64(time.Second))
+}
+
+// skipIntro offers to seek to the end of intro, or seeks automatically, depending on settings
+func (btp *Player) skipIntro() {
+	if config.Get().SkipIntro == SkipIntroDisabled || btp.p.Background || btp.xbmcHost == nil || btp.markers == nil || btp.markers.Intro == nil {
+		return
+	}
+
+	end := btp.markers.Intro.End
+	if config.Get().SkipIntro == SkipIntroAuto {
+		btp.xbmcHost.Notify("Elementum", "LOCALIZE[30760]", config.AddonIcon())
+		btp.xbmcHost.PlayerSeek(end.Seconds())
+		return
+	}
+
+	// Dialog is closed, when intro is over, so it acts like a skip button
+	answer := make(chan bool, 1)
+	go func() {
+		answer <- btp.xbmcHost.DialogConfirm("Elementum", "LOCALIZE[30759]")
+	}()
+
+	select {
+	case ok := <-answer:
+		if ok && btp.markers.InIntro(btp.watchedPosition()) {
+			btp.xbmcHost.PlayerSeek(end.Seconds())
+		}
+	case <-time.After(end - btp.watchedPosition()):
+		btp.xbmcHost.CloseAllConfirmDialogs()
+	}
 }
 
 // Params returns Params for external use
@@ -887,36 +1092,38 @@ func (btp *Player) UpdateWatched() {
 
 	// Update Watched state for current file
 	SetWatchedFile(btp.chosenFile.Path, btp.chosenFile.Size, btp.IsWatched())
+	history.Update(btp.historyItem(), btp.p.WatchedTime, btp.p.VideoDuration, btp.IsWatched())
 
 	if btp.IsWatched() {
-		var watched *trakt.WatchedItem
+		var watched *tracker.Item
 
 		// TODO: Make use of Playcount, possibly increment when Watched, use old value if in progress
 		if btp.p.ContentType == movieType {
-			watched = &trakt.WatchedItem{
-				MediaType: btp.p.ContentType,
-				Movie:     btp.p.TMDBId,
-				Watched:   true,
+			watched = &tracker.Item{
+				MediaType: tracker.MovieType,
+				TMDBID:    btp.p.TMDBId,
+				WatchedAt: time.Now(),
 			}
 			if btp.p.KodiID != 0 && btp.xbmcHost != nil {
 				btp.xbmcHost.SetMovieWatched(btp.p.KodiID, 1, 0, 0)
 			}
 		} else if btp.p.ContentType == episodeType {
-			watched = &trakt.WatchedItem{
-				MediaType: btp.p.ContentType,
-				Show:      btp.p.ShowID,
+			watched = &tracker.Item{
+				MediaType: tracker.EpisodeType,
+				TMDBID:    btp.p.TMDBId,
+				ShowID:    btp.p.ShowID,
 				Season:    btp.p.Season,
 				Episode:   btp.p.Episode,
-				Watched:   true,
+				WatchedAt: time.Now(),
 			}
 			if btp.p.KodiID != 0 && btp.xbmcHost != nil {
 				btp.xbmcHost.SetEpisodeWatched(btp.p.KodiID, 1, 0, 0)
 			}
 		}
 
-		if config.Get().TraktToken != "" && watched != nil && !btp.p.TraktScrobbled {
-			log.Debugf("Setting Trakt watched for: %#v", watched)
-			go trakt.SetWatched(watched)
+		if watched != nil && !btp.p.TraktScrobbled {
+			log.Debugf("Setting trackers watched for: %#v", watched)
+			go tracker.SetWatched([]*tracker.Item{watched}, true, btp.exceptTrackers()...)
 		}
 	} else if btp.p.WatchedTime > 180 {
 		if btp.p.Resume != nil {
@@ -955,12 +1162,7 @@ func (btp *Player) smartMatch(choices []*CandidateFile) {
 		return
 	}
 
-	var tvdbShow *tvdb.Show
-	// If show is Anime, we will need Tvdb Show entry for getting absolute numbers for episodes
-	if show.IsAnime() {
-		tvdbID := util.StrInterfaceToInt(show.ExternalIDs.TVDBID)
-		tvdbShow, _ = tvdb.GetShow(tvdbID, config.Get().Language)
-	}
+	m := mapping.Get(show)
 
 	hash := btp.t.InfoHash()
 
@@ -980,7 +1182,7 @@ func (btp *Player) smartMatch(choices []*CandidateFile) {
 				continue
 			}
 
-			index, found := MatchEpisodeFilename(season.Season, episode.EpisodeNumber, show.CountRealSeasons() == 1, btp.p.Season, show, episode, tvdbShow, choices)
+			index, found := MatchEpisodeFilename(season.Season, episode.EpisodeNumber, show.CountRealSeasons() == 1, btp.p.Season, show, m, choices)
 			if index >= 0 && found == 1 {
 				database.GetStorm().AddTorrentLink(strconv.Itoa(episode.ID), hash, b, false)
 			}
@@ -1088,7 +1290,7 @@ func (btp *Player) startNextFile() {
 }
 
 func (btp *Player) findNextFile() {
-	if (btp.p.ShowID == 0 && btp.p.Query == "") || btp.next.done || !config.Get().SmartEpisodeStart {
+	if btp.t == nil || (btp.p.ShowID == 0 && btp.p.Query == "") || btp.next.done || !config.Get().SmartEpisodeStart {
 		return
 	}
 
@@ -1096,7 +1298,12 @@ func (btp *Player) findNextFile() {
 	btp.next.done = true
 
 	if btp.p.ShowID != 0 {
-		// Searching if we have next episode in the torrent
+		// Searching if we have next episode in the torrent, using all known episode orderings
+		if show := tmdb.GetShow(btp.p.ShowID, config.Get().Language); show != nil {
+			if next := mapping.Get(show).Next(btp.p.Season, btp.p.Episode); next != nil {
+				btp.next.f = btp.t.GetMappedEpisodeFile(next, show.IsAnime())
+			}
+		}
 		if btp.next.f == nil {
 			btp.next.f = btp.t.GetNextEpisodeFile(btp.p.Season, btp.p.Episode+1)
 		}
@@ -1150,7 +1357,7 @@ func (btp *Player) findNextFile() {
 
 // InitAudio ...
 func (btp *Player) InitAudio() {
-	if btp.p.DoneAudio || btp.chosenFile == nil {
+	if btp.p.DoneAudio || btp.chosenFile == nil || btp.t == nil {
 		return
 	}
 
@@ -1187,33 +1394,52 @@ func (btp *Player) InitSubtitles() {
 		btp.SetSubtitles()
 	}
 
-	if config.Get().OSDBAutoLoad && (!config.Get().OSDBAutoLoadSkipExists || len(btp.xbmcHost.PlayerGetSubtitles()) == 0) {
+	if config.Get().OSDBAutoLoad && (!config.Get().OSDBAutoLoadSkipExists || !btp.hasSubtitles()) {
 		btp.DownloadSubtitles()
 	}
 
 	btp.p.DoneSubtitles = true
 }
 
+// hasSubtitles returns whether playing file already has subtitles.
+// If container tracks are known, only embedded subtitles in user's languages are counted,
+// otherwise any subtitles, Kodi reports, are counted.
+func (btp *Player) hasSubtitles() bool {
+	if btp.mediaInfo == nil {
+		return len(btp.xbmcHost.PlayerGetSubtitles()) > 0
+	}
+
+	return btp.subtitlesIncluded || btp.mediaInfo.HasSubtitles(subtitlesLanguages(btp.xbmcHost)...)
+}
+
+// subtitlesLanguages returns ISO 639-1 codes of subtitles languages from Kodi and addon settings
+func subtitlesLanguages(xbmcHost *xbmc.XBMCHost) []string {
+	ret := []string{config.Get().OSDBLanguage}
+	if xbmcHost != nil {
+		if lang := xbmcHost.SettingsGetSettingValue("locale.subtitlelanguage"); lang != "" {
+			ret = append(ret, xbmcHost.ConvertLanguage(lang, xbmc.Iso639_1))
+		}
+	}
+	return ret
+}
+
 // DownloadSubtitles ...
 func (btp *Player) DownloadSubtitles() {
-	payloads, preferredLanguage := opensubtitles.GetPayloads(btp.xbmcHost, "", []string{"English"}, btp.xbmcHost.SettingsGetSettingValue("locale.subtitlelanguage"), btp.p.ShowID, btp.xbmcHost.PlayerGetPlayingFile())
-	results, err := opensubtitles.DoSearch(payloads, preferredLanguage)
-	if err != nil || results == nil {
+	q := subtitles.NewQuery(btp.xbmcHost, "", []string{"English"}, btp.xbmcHost.SettingsGetSettingValue("locale.subtitlelanguage"), btp.p.ShowID, btp.xbmcHost.PlayerGetPlayingFile())
+	btp.FillSubtitlesQuery(q)
+
+	results := subtitles.Search(q)
+	if len(results) == 0 {
 		return
 	}
 
 	btp.subtitlesLoaded = []string{}
-	for i, sub := range results {
-		if i+1 > config.Get().OSDBAutoLoadCount {
+	for _, sub := range results {
+		if len(btp.subtitlesLoaded) >= config.Get().OSDBAutoLoadCount {
 			break
 		}
 
-		if len(sub.Attributes.Files) == 0 {
-			continue
-		}
-
-		subFile := sub.Attributes.Files[0]
-		_, _, path, err := opensubtitles.DoDownload(strconv.Itoa(subFile.FileID))
+		path, err := subtitles.Download(sub, nil)
 		if err != nil {
 			continue
 		}
@@ -1229,9 +1455,22 @@ func (btp *Player) DownloadSubtitles() {
 	}
 }
 
+// FillSubtitlesQuery adds hash and name of the playing torrent file to subtitles query
+func (btp *Player) FillSubtitlesQuery(q *subtitles.Query) {
+	if q.Type == "movie" && q.TMDBID == 0 {
+		q.TMDBID = btp.p.TMDBId
+	}
+	if q.Hash == "" {
+		q.Hash = btp.subtitlesHash
+	}
+	if btp.chosenFile != nil && btp.extracted == "" {
+		q.FileName = filepath.Base(btp.chosenFile.Path)
+	}
+}
+
 // SetSubtitles ...
 func (btp *Player) SetSubtitles() {
-	if btp.chosenFile == nil {
+	if btp.chosenFile == nil || btp.t == nil {
 		return
 	}
 
@@ -1254,6 +1493,7 @@ func (btp *Player) SetSubtitles() {
 		if len(collected) > 0 && btp.xbmcHost != nil {
 			log.Debugf("Adding player subtitles: %#v", collected)
 			btp.xbmcHost.PlayerSetSubtitles(collected)
+			btp.subtitlesIncluded = true
 		}
 	}
 }
@@ -1266,6 +1506,28 @@ func (btp *Player) FetchStoredResume() {
 	}
 
 	database.GetCache().GetCachedObject(database.CommonBucket, key, btp.p.StoredResume)
+
+	// Items, played from other torrents, are resumed from local history
+	if btp.p.StoredResume.Position == 0 && btp.hasChosenFile {
+		if r := history.GetResume(btp.historyItem().ID); r != nil {
+			btp.p.StoredResume = r
+		}
+	}
+}
+
+// historyItem returns local history item for current playback
+func (btp *Player) historyItem() *database.WatchHistory {
+	infoHash := ""
+	if btp.t != nil {
+		infoHash = btp.t.InfoHash()
+	}
+
+	item := history.NewItem(btp.p.ContentType, btp.p.TMDBId, btp.p.ShowID, btp.p.Season, btp.p.Episode, infoHash, btp.chosenFile.Path)
+	item.Title = btp.chosenFile.Name
+	item.URI = btp.p.URI
+	item.Size = btp.chosenFile.Size
+	item.Index = btp.chosenFile.Index
+	return item
 }
 
 // SaveStoredResume ...
@@ -1308,6 +1570,10 @@ func (btp *Player) processUpNextPayload() {
 		log.Warningf("Could not prepare UpNext payload: %s", err)
 	}
 
+	if btp.markers != nil && btp.markers.Credits != nil {
+		payload.NotificationTime = int(btp.markers.Credits.Start.Seconds())
+	}
+
 	if btp.xbmcHost != nil {
 		btp.xbmcHost.UpNextNotify(xbmc.Args{payload})
 	}
@@ -1346,6 +1612,11 @@ func (btp *Player) processUpNextShow() (upnext.Payload, error) {
 func (btp *Player) processUpNextQuery() (upnext.Payload, error) {
 	res := upnext.Payload{}
 
+	// Sources are single files without neighbours to play next
+	if btp.t == nil {
+		return res, errNoCandidates
+	}
+
 	currentFile, _, errCurrent := btp.processUpNextFile(btp.p.FileIndex)
 	if errCurrent != nil {
 		log.Warningf("Cannot prepare current UpNext item: %s", errCurrent)
@@ -1550,46 +1821,50 @@ func TrimChoices(choices []*CandidateFile) {
 }
 
 // MatchEpisodeFilename matches season and episode in the filename to get ocurrence
-func MatchEpisodeFilename(s, e int, isSingleSeason bool, activeSeason int, show *tmdb.Show, episode *tmdb.Episode, tvdbShow *tvdb.Show, choices []*CandidateFile) (index, found int) {
-	index = -1
+func MatchEpisodeFilename(s, e int, isSingleSeason bool, activeSeason int, show *tmdb.Show, m *mapping.Show, choices []*CandidateFile) (index, found int) {
+	em := m.Find(s, e)
 
-	re := regexp.MustCompile(fmt.Sprintf(episodeMatchRegex, s, e))
-	for i, choice := range choices {
-		if re.MatchString(choice.Filename) {
-			index = i
-			found++
-		}
+	// Episode group, selected for a show, takes precedence over aired order
+	if em != nil && em.Group.Episode > 0 {
+		index, found = matchChoices(fmt.Sprintf(episodeMatchRegex, em.Group.Season, em.Group.Episode), choices)
+	}
+	if found == 0 {
+		index, found = matchChoices(fmt.Sprintf(episodeMatchRegex, s, e), choices)
 	}
 
-	if isSingleSeason && found == 0 {
-		re := regexp.MustCompile(fmt.Sprintf(singleEpisodeMatchRegex, e))
-		for i, choice := range choices {
-			if re.MatchString(choice.Filename) {
-				index = i
-				found++
+	// Try with alternative orderings, like scene or TVDB numbers
+	if found == 0 && em != nil {
+		for _, n := range em.Alternatives() {
+			if index, found = matchChoices(fmt.Sprintf(episodeMatchRegex, n.Season, n.Episode), choices); found > 0 {
+				break
 			}
 		}
 	}
 
-	if found == 0 && show != nil && episode != nil && show.IsAnime() {
-		if an, _ := show.ShowInfoWithTVDBShow(episode, tvdbShow); an != 0 {
-			re := regexp.MustCompile(fmt.Sprintf(singleEpisodeMatchRegex, an))
-			for i, choice := range choices {
-				if re.MatchString(choice.Filename) {
-					index = i
-					found++
-				}
-			}
-		}
+	if isSingleSeason && found == 0 {
+		index, found = matchChoices(fmt.Sprintf(singleEpisodeMatchRegex, e), choices)
+	}
+
+	if found == 0 && em != nil && em.Absolute > 0 && show != nil && show.IsAnime() {
+		index, found = matchChoices(fmt.Sprintf(singleEpisodeMatchRegex, em.Absolute), choices)
 	}
 
 	if found == 0 && activeSeason == s {
-		re := regexp.MustCompile(fmt.Sprintf(singleEpisodeMatchRegex, e))
-		for i, choice := range choices {
-			if re.MatchString(choice.Filename) {
-				index = i
-				found++
-			}
+		index, found = matchChoices(fmt.Sprintf(singleEpisodeMatchRegex, e), choices)
+	}
+
+	return
+}
+
+// matchChoices returns index of last matched choice and number of matches
+func matchChoices(pattern string, choices []*CandidateFile) (index, found int) {
+	index = -1
+
+	re := regexp.MustCompile(pattern)
+	for i, choice := range choices {
+		if re.MatchString(choice.Filename) {
+			index = i
+			found++
 		}
 	}
 
@@ -0,0 +1,178 @@
+package bittorrent
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/elgatito/elementum/util/probe"
+	"github.com/elgatito/elementum/xbmc"
+)
+
+// probeExtensions are containers, which headers can be probed for tracks
+var probeExtensions = map[string]bool{
+	".mkv":  true,
+	".mk3d": true,
+	".webm": true,
+	".mp4":  true,
+	".m4v":  true,
+	".mov":  true,
+}
+
+const (
+	// probeHeadSize is the size of file start, fetched to probe headers before file choice
+	probeHeadSize = 2 * 1024 * 1024
+	// probeFetchFiles limits number of files, which heads are fetched before file choice
+	probeFetchFiles = 5
+	// probeFetchTimeout limits the wait for head pieces before file choice
+	probeFetchTimeout = 5 * time.Second
+)
+
+// ProbeFile returns tracks of the file, read from container headers through downloaded pieces.
+// Successful results are cached, failed probes are retried when more pieces are downloaded.
+func (t *Torrent) ProbeFile(f *File) *probe.MediaInfo {
+	if f == nil || !probeExtensions[strings.ToLower(filepath.Ext(f.Path))] {
+		return nil
+	}
+	if info, ok := t.mediaInfo.Load(f.Index); ok {
+		return info.(*probe.MediaInfo)
+	}
+
+	fr, err := t.NewFileReader(f)
+	if err != nil {
+		return nil
+	}
+	defer fr.Close()
+
+	info, err := probe.Probe(fr, f.Size)
+	if err != nil {
+		log.Debugf("Cannot probe %s: %s", f.Path, err)
+		return nil
+	}
+
+	log.Infof("Probed %s: %s", f.Path, info)
+	t.mediaInfo.Store(f.Index, info)
+	return info
+}
+
+// StreamInfo returns Kodi stream details of the file, if its headers are already probed
+func (t *Torrent) StreamInfo(f *File) *xbmc.StreamInfo {
+	if f == nil {
+		return nil
+	}
+	i, ok := t.mediaInfo.Load(f.Index)
+	if !ok {
+		return nil
+	}
+	info := i.(*probe.MediaInfo)
+
+	ret := &xbmc.StreamInfo{}
+	if v := info.Video(); v != nil {
+		ret.Video = &xbmc.StreamInfoEntry{
+			Codec:    v.Codec,
+			Width:    v.Width,
+			Height:   v.Height,
+			Duration: int(info.Duration.Seconds()),
+		}
+		if v.Height > 0 {
+			ret.Video.Aspect = float32(v.Width) / float32(v.Height)
+		}
+	}
+	if audio := info.Audio(); len(audio) > 0 {
+		ret.Audio = &xbmc.StreamInfoEntry{
+			Codec:    audio[0].Codec,
+			Language: audio[0].Language,
+			Channels: audio[0].Channels,
+		}
+	}
+	if subs := info.Subtitles(); len(subs) > 0 {
+		ret.Subtitle = &xbmc.StreamInfoEntry{
+			Language: subs[0].Language,
+		}
+	}
+	return ret
+}
+
+// ChosenStreamInfo returns stream details of the first chosen file with probed headers
+func (t *Torrent) ChosenStreamInfo() *xbmc.StreamInfo {
+	for _, f := range t.ChosenFiles {
+		if info := t.StreamInfo(f); info != nil {
+			return info
+		}
+	}
+	return nil
+}
+
+// fetchProbeHeads downloads first pieces of files, that are not probed yet, so their tracks can be shown in file choice.
+// It is bounded by number of files and by time, priorities of pieces, that are not downloaded, are restored after that.
+// Memory storage is skipped, as head pieces would take memory, reserved for playback.
+func (t *Torrent) fetchProbeHeads(files []*File) {
+	if t.th == nil || t.IsMemoryStorage() || t.pieceLength == 0 || t.Closer.IsSet() {
+		return
+	}
+
+	priorities := map[int]int{}
+	count := 0
+	for _, f := range files {
+		if count >= probeFetchFiles {
+			break
+		}
+		if f == nil || f.Size <= 0 || !probeExtensions[strings.ToLower(filepath.Ext(f.Path))] {
+			continue
+		}
+		if _, ok := t.mediaInfo.Load(f.Index); ok {
+			continue
+		}
+		count++
+
+		headSize := f.Size
+		if headSize > probeHeadSize {
+			headSize = probeHeadSize
+		}
+		firstPiece := int(f.Offset / t.pieceLength)
+		lastPiece := int((f.Offset + headSize - 1) / t.pieceLength)
+		for piece := firstPiece; piece <= lastPiece; piece++ {
+			if _, ok := priorities[piece]; ok || t.hasPiece(piece) {
+				continue
+			}
+			priorities[piece] = t.th.PiecePriority(piece).(int)
+			t.th.PiecePriority(piece, 7)
+		}
+	}
+	if len(priorities) == 0 {
+		return
+	}
+
+	log.Debugf("Fetching %d head pieces of %d files to probe tracks", len(priorities), count)
+	defer func() {
+		for piece, priority := range priorities {
+			if !t.hasPiece(piece) {
+				t.th.PiecePriority(piece, priority)
+			}
+		}
+	}()
+
+	timeout := time.After(probeFetchTimeout)
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-timeout:
+			return
+		case <-t.Closer.C():
+			return
+		case <-ticker.C:
+			isDone := true
+			for piece := range priorities {
+				if !t.hasPiece(piece) {
+					isDone = false
+					break
+				}
+			}
+			if isDone {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package bittorrent
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// ReaderPriority defines which reader is served first, when several clients stream the same torrent
+type ReaderPriority int
+
+const (
+	// ReaderBackground is a reader of external clients, like DLNA renderers or shared links
+	ReaderBackground ReaderPriority = iota
+	// ReaderForeground is a reader of Kodi player
+	ReaderForeground
+)
+
+const (
+	// inactiveReaderWeight is a share of readahead, reader keeps after another reader has seeked
+	inactiveReaderWeight = 0.5
+	// memoryReaderMinPieces is a number of pieces, each reader needs in memory storage to stream without thrashing
+	memoryReaderMinPieces = 10
+)
+
+// readerWeights are relative shares of readahead budget for reader priorities
+var readerWeights = map[ReaderPriority]float64{
+	ReaderBackground: 1,
+	ReaderForeground: 3,
+}
+
+func (p ReaderPriority) String() string {
+	if p == ReaderForeground {
+		return "foreground"
+	}
+	return "background"
+}
+
+// ReaderStats is a state of a single reader of the torrent
+type ReaderStats struct {
+	ID        int64
+	Path      string
+	Remote    string
+	Priority  ReaderPriority
+	Position  int64
+	Size      int64
+	Readahead int64
+	Read      int64
+	Active    bool
+	Idle      bool
+	Head      bool
+	Evicted   bool
+	Opened    time.Time
+	LastUsed  time.Time
+}
+
+// ReadersStats returns stats of open readers, ordered by open time
+func (t *Torrent) ReadersStats() []ReaderStats {
+	t.muReaders.Lock()
+	defer t.muReaders.Unlock()
+
+	ret := make([]ReaderStats, 0, len(t.readers))
+	for _, r := range t.readers {
+		pos, _ := r.Pos()
+		ret = append(ret, ReaderStats{
+			ID:        r.id,
+			Path:      r.f.Path,
+			Remote:    r.remote,
+			Priority:  r.priority,
+			Position:  pos,
+			Size:      r.f.Size,
+			Readahead: r.readahead,
+			Read:      atomic.LoadInt64(&r.read),
+			Active:    r.IsActive(),
+			Idle:      r.IsIdle(),
+			Head:      r.IsHead(),
+			Evicted:   r.evicted,
+			Opened:    r.opened,
+			LastUsed:  r.lastUsed,
+		})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Opened.Before(ret[j].Opened)
+	})
+	return ret
+}
+
+// readaheadBudgets returns readahead sizes of readers, should be called with locked readers.
+// HEAD readers get a single piece, other readers share the budget by weights of their priorities.
+// For memory storage, readers that do not fit into memory and idle readers are evicted:
+// they keep a single piece, so their pieces are the first to be dropped from memory.
+func (t *Torrent) readaheadBudgets(budget int64) map[int64]int64 {
+	pieceLength := int64(t.pieceLength)
+	ret := make(map[int64]int64, len(t.readers))
+
+	candidates := make([]*TorrentFSEntry, 0, len(t.readers))
+	for _, r := range t.readers {
+		r.evicted = false
+		if r.IsHead() {
+			ret[r.id] = pieceLength
+			budget -= pieceLength
+		} else {
+			candidates = append(candidates, r)
+		}
+	}
+
+	// Readers in use go first, then foreground readers, then most recently used
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.IsIdle() != b.IsIdle() {
+			return !a.IsIdle()
+		} else if a.priority != b.priority {
+			return a.priority > b.priority
+		}
+		return a.lastUsed.After(b.lastUsed)
+	})
+
+	if t.IsMemoryStorage() && pieceLength > 0 {
+		limit := max(int(budget/(memoryReaderMinPieces*pieceLength)), 1)
+		for i, r := range candidates {
+			if i >= limit || (i > 0 && r.IsIdle()) {
+				r.evicted = true
+				ret[r.id] = pieceLength
+				budget -= pieceLength
+			}
+		}
+	}
+
+	if budget < 0 {
+		budget = 0
+	}
+
+	weights := 0.0
+	for _, r := range candidates {
+		if !r.evicted {
+			weights += r.weight()
+		}
+	}
+	for _, r := range candidates {
+		if r.evicted {
+			continue
+		}
+		if size := int64(float64(budget) * r.weight() / weights); size > pieceLength {
+			ret[r.id] = size
+		} else {
+			ret[r.id] = pieceLength
+		}
+	}
+
+	return ret
+}
+
+// weight returns share of readahead budget for the reader
+func (tf *TorrentFSEntry) weight() float64 {
+	w := readerWeights[tf.priority]
+	if !tf.IsActive() {
+		w *= inactiveReaderWeight
+	}
+	return w
+}
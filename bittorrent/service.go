@@ -743,6 +743,9 @@ func (s *Service) AddTorrent(xbmcHost *xbmc.XBMCHost, options AddOptions) (*Torr
 	} else {
 		if strings.HasPrefix(options.URI, "http") {
 			torrent := NewTorrentFile(options.URI)
+			if torrent.IsMediaSource() {
+				return nil, fmt.Errorf("%s is not a torrent, it can only be played", options.URI)
+			}
 
 			if err = torrent.Resolve(); err != nil {
 				log.Warningf("Could not resolve torrent %s: %s", options.URI, err)
@@ -915,6 +918,12 @@ func (s *Service) RemoveTorrent(xbmcHost *xbmc.XBMCHost, t *Torrent, flags Remov
 		return false
 	}
 
+	if flags.IsReplaced {
+		flags.ForceDrop = true
+		flags.ForceDelete = true
+		flags.ForceConfirmation = false
+	}
+
 	configKeepDownloading := config.Get().KeepDownloading
 	configKeepFilesFinished := config.Get().KeepFilesFinished
 	configKeepFilesPlaying := config.Get().KeepFilesPlaying
@@ -1848,36 +1857,34 @@ func (s *Service) ClientInfo(ctx *gin.Context) {
 
 // AttachPlayer adds Player instance to service
 func (s *Service) AttachPlayer(p *Player) {
-	if p == nil || p.t == nil {
+	if p == nil || p.key() == "" {
 		return
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, ok := s.Players[p.t.InfoHash()]; ok {
+	if _, ok := s.Players[p.key()]; ok {
 		return
 	}
 
-	s.Players[p.t.InfoHash()] = p
+	s.Players[p.key()] = p
 }
 
 // DetachPlayer removes Player instance
 func (s *Service) DetachPlayer(p *Player) {
-	if p == nil || p.t == nil {
+	if p == nil || p.key() == "" {
 		return
 	}
 
-	p.t.PlayerAttached--
+	if p.t != nil {
+		p.t.PlayerAttached--
+	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if p == nil || p.t == nil {
-		return
-	}
-
-	delete(s.Players, p.t.InfoHash())
+	delete(s.Players, p.key())
 }
 
 // GetPlayer searches for player with desired TMDB id
@@ -1886,7 +1893,7 @@ func (s *Service) GetPlayer(kodiID int, tmdbID int) *Player {
 	defer s.mu.Unlock()
 
 	for _, p := range s.Players {
-		if p == nil || p.t == nil {
+		if p == nil || p.key() == "" {
 			continue
 		}
 
@@ -1903,7 +1910,7 @@ func (s *Service) anyPlayerIsPlaying() bool {
 	defer s.mu.Unlock()
 
 	for _, p := range s.Players {
-		if p == nil || p.t == nil {
+		if p == nil || p.key() == "" {
 			continue
 		}
 
@@ -1921,7 +1928,7 @@ func (s *Service) GetActivePlayer() *Player {
 	defer s.mu.Unlock()
 
 	for _, p := range s.Players {
-		if p == nil || p.t == nil {
+		if p == nil || p.key() == "" {
 			continue
 		}
 
@@ -0,0 +1,87 @@
+package bittorrent
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/subtitles"
+	"github.com/elgatito/elementum/util/probe"
+)
+
+// bufferSource prepares media source for playback, instead of torrent buffering.
+// Container headers are read through the source, when it supports random access.
+func (btp *Player) bufferSource() error {
+	src := btp.p.Source
+	if btp.p.Background {
+		return fmt.Errorf("Background download is not supported for %s sources", src.Kind())
+	}
+
+	if err := src.Open(); err != nil {
+		log.Errorf("Error opening %s source: %s", src.Kind(), err)
+		if btp.xbmcHost != nil {
+			btp.xbmcHost.Notify("Elementum", "LOCALIZE[30761]", config.AddonIcon())
+		}
+		return err
+	}
+
+	btp.chosenFile = &File{Index: -1, Name: src.Name(), Path: src.Name(), Size: src.Size()}
+	btp.hasChosenFile = true
+	btp.fileName = src.Name()
+	btp.fileSize = src.Size()
+	btp.overlayStatusEnabled = false
+
+	btp.GetIdent()
+	btp.p.ResumeToken = src.ID()
+	btp.FetchStoredResume()
+
+	if r := src.ReaderAt(); r != nil && btp.fileSize > 0 {
+		if hash, err := hashSource(r, btp.fileSize); err == nil {
+			btp.subtitlesHash = hash
+		} else {
+			log.Debugf("Cannot compute subtitles hash for %s: %s", src.Name(), err)
+		}
+
+		if probeExtensions[strings.ToLower(path.Ext(src.Name()))] {
+			if info, err := probeSource(r, btp.fileSize); err == nil {
+				log.Infof("Probed %s: %s", src.Name(), info)
+				btp.mediaInfo = info
+				btp.markers = btp.detectMarkers()
+			} else {
+				log.Debugf("Cannot probe %s: %s", src.Name(), err)
+			}
+		}
+	}
+
+	go btp.playerLoop()
+	go btp.s.AttachPlayer(btp)
+
+	return nil
+}
+
+// probeSource reads container headers of a remote file, which content is not trusted,
+// so parser failures are returned as errors instead of stopping the daemon
+func probeSource(r io.ReaderAt, size int64) (info *probe.MediaInfo, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			info = nil
+			err = fmt.Errorf("malformed container headers: %v", r)
+		}
+	}()
+
+	return probe.Probe(r, size)
+}
+
+// hashSource computes subtitles hash of a remote file, reader failures are returned as errors
+func hashSource(r io.ReaderAt, size int64) (hash string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			hash = ""
+			err = fmt.Errorf("cannot read source: %v", r)
+		}
+	}()
+
+	return subtitles.Hash(r, size)
+}
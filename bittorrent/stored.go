@@ -0,0 +1,127 @@
+package bittorrent
+
+import (
+	"errors"
+	"time"
+
+	"github.com/anacrolix/sync"
+
+	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/database"
+	"github.com/elgatito/elementum/mapping"
+	"github.com/elgatito/elementum/tmdb"
+)
+
+// activateMu serializes adding of stored torrents, requested by external clients
+var activateMu sync.Mutex
+
+// ActivateTorrent returns active torrent, or adds it from torrents history
+// or torrents, assigned to library items, and waits for its metadata
+func (s *Service) ActivateTorrent(hash string) (*Torrent, error) {
+	activateMu.Lock()
+	defer activateMu.Unlock()
+
+	t := s.GetTorrentByHash(hash)
+	if t == nil {
+		torrent := StoredTorrent(hash)
+		if torrent == nil {
+			return nil, errors.New("torrent is not found")
+		}
+
+		log.Infof("Adding stored torrent %s", torrent.Title)
+		var err error
+		t, err = s.AddTorrent(nil, AddOptions{URI: torrent.URI, DownloadStorage: config.Get().DownloadStorage, FirstTime: true, AddedTime: time.Now()})
+		if err != nil {
+			return nil, err
+		}
+		database.GetStorm().UpdateBTItem(t.InfoHash(), 0, "", []string{}, t.Name(), 0, 0, 0)
+	}
+
+	if !t.HasMetadata() {
+		if err := t.WaitForMetadata(nil, hash); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// StoredTorrent returns torrent from history or torrents, assigned to library items
+func StoredTorrent(hash string) *TorrentFile {
+	var metadata []byte
+	var th database.TorrentHistory
+	var tm database.TorrentAssignMetadata
+	if config.Get().UseTorrentHistory && database.GetStormDB().One("InfoHash", hash, &th) == nil {
+		metadata = th.Metadata
+	} else if database.GetStormDB().One("InfoHash", hash, &tm) == nil {
+		metadata = tm.Metadata
+	}
+	if len(metadata) == 0 {
+		return nil
+	}
+
+	torrent := &TorrentFile{}
+	if metadata[0] == '{' {
+		torrent.UnmarshalJSON(metadata)
+	} else {
+		torrent.LoadFromBytes(metadata)
+	}
+	if torrent.URI == "" {
+		return nil
+	}
+	return torrent
+}
+
+// PlayableFile returns file by index, or, for negative index, the first chosen or the largest candidate file
+func (t *Torrent) PlayableFile(index int) *File {
+	files := t.GetFiles()
+	if index >= 0 {
+		if index < len(files) {
+			return files[index]
+		}
+		return nil
+	}
+
+	if len(t.ChosenFiles) > 0 {
+		return t.ChosenFiles[0]
+	}
+
+	choices, biggestFile, err := t.GetCandidateFiles(nil)
+	if err != nil || len(files) == 0 {
+		return nil
+	}
+
+	var ret *File
+	for _, c := range choices {
+		if f := files[c.Index]; ret == nil || f.Size > ret.Size {
+			ret = f
+		}
+	}
+	if ret == nil {
+		ret = files[biggestFile]
+	}
+	return ret
+}
+
+// EpisodeFile returns candidate file, that matches the episode, or nil, if there is no single match
+func (t *Torrent) EpisodeFile(showID, season, episode int) *File {
+	show := tmdb.GetShow(showID, config.Get().Language)
+	if show == nil {
+		return nil
+	}
+
+	choices, _, err := t.GetCandidateFiles(nil)
+	if err != nil || len(choices) == 0 {
+		return nil
+	}
+
+	activeSeason := season
+	if t.DBItem != nil && t.DBItem.Season > 0 {
+		activeSeason = t.DBItem.Season
+	}
+
+	index, found := MatchEpisodeFilename(season, episode, show.CountRealSeasons() == 1, activeSeason, show, mapping.Get(show), choices)
+	if index < 0 || found != 1 {
+		return nil
+	}
+	return t.GetFiles()[choices[index].Index]
+}
@@ -28,8 +28,9 @@ import (
 
 	"github.com/elgatito/elementum/config"
 	"github.com/elgatito/elementum/database"
+	"github.com/elgatito/elementum/mapping"
+	"github.com/elgatito/elementum/subtitles"
 	"github.com/elgatito/elementum/tmdb"
-	"github.com/elgatito/elementum/tvdb"
 	"github.com/elgatito/elementum/util"
 	"github.com/elgatito/elementum/util/event"
 	"github.com/elgatito/elementum/xbmc"
@@ -107,6 +108,9 @@ type Torrent struct {
 	pieces            Bitfield
 	piecesLastUpdated time.Time
 
+	// mediaInfo keeps probed container headers by file index
+	mediaInfo sync.Map
+
 	bufferTicker     *time.Ticker
 	prioritizeTicker *time.Ticker
 
@@ -378,9 +382,17 @@ func (t *Torrent) Buffer(file *File, isStartup bool) {
 
 	t.startBufferTicker()
 
+	// Head and tail of the file should always be in the buffer, as they are used for subtitles hash
 	startBufferSize := t.Service.GetBufferSize()
+	if startBufferSize < subtitles.HashChunkSize {
+		startBufferSize = subtitles.HashChunkSize
+	}
+	endBufferSize := int64(config.Get().EndBufferSize)
+	if endBufferSize < subtitles.HashChunkSize {
+		endBufferSize = subtitles.HashChunkSize
+	}
 	preBufferStart, preBufferEnd, preBufferOffset, preBufferSize := t.getBufferSize(file.Offset, 0, startBufferSize)
-	postBufferStart, postBufferEnd, postBufferOffset, postBufferSize := t.getBufferSize(file.Offset, file.Size-int64(config.Get().EndBufferSize), int64(config.Get().EndBufferSize))
+	postBufferStart, postBufferEnd, postBufferOffset, postBufferSize := t.getBufferSize(file.Offset, file.Size-endBufferSize, endBufferSize)
 
 	// TODO: Remove this piece of buffer adjustment?
 	// if config.Get().AutoAdjustBufferSize && preBufferEnd-preBufferStart < 10 {
@@ -656,23 +668,31 @@ func (t *Torrent) PrioritizePieces() {
 		log.Debugf("Reader range: %+v, last: %s", pr, r.lastUsed.Format(time.RFC3339))
 
 		for curPiece := pr.Begin; curPiece <= pr.End; curPiece++ {
+			priority := 2
 			if t.awaitingPieces.ContainsInt(curPiece) {
-				readerPieces[curPiece] = 7
+				priority = 7
 			} else {
 				pos := curPiece - pr.Begin
 				switch {
 				case pos <= 0:
-					readerPieces[curPiece] = 6
+					priority = 6
 				case pos <= 2:
-					readerPieces[curPiece] = 5
+					priority = 5
 				case pos <= 5:
-					readerPieces[curPiece] = 4
+					priority = 4
 				case pos <= 9:
-					readerPieces[curPiece] = 3
-				default:
-					readerPieces[curPiece] = 2
+					priority = 3
+				}
+
+				// Background readers should not outrun the player, when they share bandwidth
+				if r.priority == ReaderBackground && priority > 2 {
+					priority--
 				}
 			}
+			// Piece, that is wanted by several readers, keeps the highest priority
+			if priority > readerPieces[curPiece] {
+				readerPieces[curPiece] = priority
+			}
 			priorities[readerPieces[curPiece]] = append(priorities[readerPieces[curPiece]], curPiece)
 
 			readerProgress[curPiece] = 0
@@ -1355,7 +1375,7 @@ func (t *Torrent) CloseReaders() {
 	}
 }
 
-// ResetReaders ...
+// ResetReaders distributes readahead between readers by their priorities and activity
 func (t *Torrent) ResetReaders() {
 	t.muReaders.Lock()
 	defer t.muReaders.Unlock()
@@ -1364,60 +1384,14 @@ func (t *Torrent) ResetReaders() {
 		return
 	}
 
-	perReaderSize := t.GetReadaheadSize()
-	countActive := float64(0)
-	countIdle := float64(0)
-	countHead := float64(0)
-	for _, r := range t.readers {
-		if r.IsHead() {
-			countHead++
-		} else if r.IsActive() {
-			countActive++
-		} else {
-			countIdle++
-		}
-	}
-
-	sizeActive := int64(0)
-	sizeIdle := int64(0)
-	sizeHead := int64(t.pieceLength)
-
-	if countIdle > 1 {
-		countIdle = 2
-	}
-	if countActive > 1 {
-		countActive = 2
-	}
-	if countHead > 1 {
-		countHead = 2
-	}
-
-	if countIdle > 0 {
-		sizeIdle = int64(float64(perReaderSize) * 0.33)
-		if countActive > 0 {
-			sizeActive = perReaderSize - sizeIdle
-		}
-	} else if countActive > 0 {
-		sizeActive = int64(float64(perReaderSize) / countActive)
-	}
-
-	if countActive == 0 && countIdle == 0 && countHead == 0 {
-		return
-	}
-
+	sizes := t.readaheadBudgets(t.GetReadaheadSize())
 	for _, r := range t.readers {
-		size := sizeActive
-		if r.IsHead() {
-			size = sizeHead
-		} else if !r.IsActive() {
-			size = sizeIdle
-		}
-
+		size := sizes[r.id]
 		if r.readahead == size {
 			continue
 		}
 
-		log.Infof("Setting readahead for reader %d as %s", r.id, humanize.Bytes(uint64(size)))
+		log.Infof("Setting readahead for %s reader %d as %s", r.priority, r.id, humanize.Bytes(uint64(size)))
 		r.readahead = size
 	}
 }
@@ -1676,6 +1650,32 @@ func (t *Torrent) GetNextEpisodeFile(season, episode int) *File {
 	return nil
 }
 
+// GetMappedEpisodeFile searches for episode file, using all orderings of the episode
+func (t *Torrent) GetMappedEpisodeFile(e *mapping.Episode, useAbsolute bool) *File {
+	if e == nil {
+		return nil
+	}
+
+	if e.Group.Episode > 0 {
+		if f := t.GetNextEpisodeFile(e.Group.Season, e.Group.Episode); f != nil {
+			return f
+		}
+	}
+	if f := t.GetNextEpisodeFile(e.TMDB.Season, e.TMDB.Episode); f != nil {
+		return f
+	}
+	for _, n := range e.Alternatives() {
+		if f := t.GetNextEpisodeFile(n.Season, n.Episode); f != nil {
+			return f
+		}
+	}
+	if useAbsolute && e.Absolute > 0 {
+		return t.GetNextSingleEpisodeFile(e.Absolute)
+	}
+
+	return nil
+}
+
 // GetNextSingleEpisodeFile ...
 func (t *Torrent) GetNextSingleEpisodeFile(episode int) *File {
 	lastMatched, foundMatches := 0, 0
@@ -1882,12 +1882,7 @@ func (t *Torrent) SelectDownloadFiles(btp *Player) {
 			return
 		}
 
-		var tvdbShow *tvdb.Show
-		// If show is Anime, we will need Tvdb Show entry for getting absolute numbers for episodes
-		if show.IsAnime() {
-			tvdbID := util.StrInterfaceToInt(show.ExternalIDs.TVDBID)
-			tvdbShow, _ = tvdb.GetShow(tvdbID, config.Get().Language)
-		}
+		m := mapping.Get(show)
 
 		for _, season := range show.Seasons {
 			if season == nil || season.EpisodeCount == 0 || season.Season != btp.p.Season {
@@ -1905,7 +1900,7 @@ func (t *Torrent) SelectDownloadFiles(btp *Player) {
 					continue
 				}
 
-				index, found := MatchEpisodeFilename(season.Season, episode.EpisodeNumber, show.CountRealSeasons() == 1, btp.p.Season, show, episode, tvdbShow, choices)
+				index, found := MatchEpisodeFilename(season.Season, episode.EpisodeNumber, show.CountRealSeasons() == 1, btp.p.Season, show, m, choices)
 				if index >= 0 && found == 1 {
 					t.DownloadFile(files[choices[index].Index])
 				}
@@ -1976,36 +1971,26 @@ func (t *Torrent) ChooseFile(btp *Player, xbmcHost *xbmc.XBMCHost) (*File, int,
 			//   in the torrent history table
 			go btp.smartMatch(choices)
 
-			lastMatched, foundMatches := MatchEpisodeFilename(btp.p.Season, btp.p.Episode, false, btp.p.Season, nil, nil, nil, choices)
+			// Episodes mapping is used to match alternative orderings and absolute numbers.
+			// It needs all seasons of the show, so it is built only if aired numbers do not match,
+			// unless episode group is selected for the show and takes precedence over aired numbers.
+			show := tmdb.GetShow(btp.p.ShowID, config.Get().Language)
+			var m *mapping.Show
+			if mapping.GetEpisodeGroup(btp.p.ShowID) != "" {
+				m = mapping.Get(show)
+			}
 
-			if foundMatches == 1 {
-				return files[choices[lastMatched].Index], lastMatched, nil
+			lastMatched, foundMatches := MatchEpisodeFilename(btp.p.Season, btp.p.Episode, false, btp.p.Season, show, m, choices)
+			if foundMatches != 1 && m == nil && show != nil {
+				m = mapping.Get(show)
+				lastMatched, foundMatches = MatchEpisodeFilename(btp.p.Season, btp.p.Episode, false, btp.p.Season, show, m, choices)
+			}
+			if em := m.Find(btp.p.Season, btp.p.Episode); em != nil && show.IsAnime() && em.Absolute > 0 {
+				btp.p.AbsoluteNumber = em.Absolute
 			}
 
-			if s := tmdb.GetShow(btp.p.ShowID, config.Get().Language); s != nil && s.IsAnime() {
-				season := tmdb.GetSeason(btp.p.ShowID, btp.p.Season, config.Get().Language, len(s.Seasons), false)
-				if season != nil && season.HasEpisode(btp.p.Episode) {
-					an, _ := s.ShowInfo(season.GetEpisode(btp.p.Episode))
-					if an != 0 {
-						btp.p.AbsoluteNumber = an
-
-						re := regexp.MustCompile(fmt.Sprintf(singleEpisodeMatchRegex, btp.p.AbsoluteNumber))
-						for index, choice := range choices {
-							if re.MatchString(choice.Filename) {
-								lastMatched = index
-								foundMatches++
-							}
-						}
-
-						if foundMatches == 1 {
-							if btp == nil {
-								t.DownloadFile(files[choices[lastMatched].Index])
-								t.SaveDBFiles()
-							}
-							return files[choices[lastMatched].Index], lastMatched, nil
-						}
-					}
-				}
+			if foundMatches == 1 {
+				return files[choices[lastMatched].Index], lastMatched, nil
 			}
 		}
 
@@ -2029,11 +2014,6 @@ func (t *Torrent) ChooseFile(btp *Player, xbmcHost *xbmc.XBMCHost) (*File, int,
 			}
 		}
 
-		items := make([]string, 0, len(choices))
-		for _, choice := range choices {
-			items = append(items, choice.DisplayName)
-		}
-
 		if btp != nil && btp.xbmcHost != nil {
 			xbmcHost = btp.xbmcHost
 		} else if xbmcHost == nil {
@@ -2043,7 +2023,44 @@ func (t *Torrent) ChooseFile(btp *Player, xbmcHost *xbmc.XBMCHost) (*File, int,
 			return nil, -1, errNoXBMCHost
 		}
 
-		choice := xbmcHost.ListDialog("LOCALIZE[30560];;"+searchTitle, items...)
+		// Tracks are shown for files with downloaded headers, and the first of them
+		// with embedded subtitles in user's language is preselected.
+		// Heads of a few first candidates are fetched for a few seconds, so files, that did not
+		// get their headers in time, or are not probed because of the limits, keep the default order.
+		candidates := make([]*File, 0, len(choices))
+		for _, c := range choices {
+			candidates = append(candidates, files[c.Index])
+		}
+		t.fetchProbeHeads(candidates)
+
+		preselect := -1
+		var languages []string
+		for i, c := range choices {
+			info := t.ProbeFile(files[c.Index])
+			if info == nil {
+				continue
+			}
+
+			c.DisplayName += " [COLOR gray]" + info.String() + "[/COLOR]"
+			if languages == nil {
+				languages = subtitlesLanguages(xbmcHost)
+			}
+			if preselect < 0 && info.HasSubtitles(languages...) {
+				preselect = i
+			}
+		}
+
+		items := make([]string, 0, len(choices))
+		for _, choice := range choices {
+			items = append(items, choice.DisplayName)
+		}
+
+		choice := -1
+		if preselect >= 0 {
+			choice = xbmcHost.ListDialogWithOptions(0, preselect, "LOCALIZE[30560];;"+searchTitle, items...)
+		} else {
+			choice = xbmcHost.ListDialog("LOCALIZE[30560];;"+searchTitle, items...)
+		}
 		log.Debugf("Choice selected: %d", choice)
 		if choice >= 0 {
 			if btp == nil {
@@ -2189,6 +2206,32 @@ func (t *Torrent) TorrentInfo(xbmcHost *xbmc.XBMCHost, w io.Writer, showTrackers
 
 	fmt.Fprint(w, "\n")
 
+	if readers := t.ReadersStats(); len(readers) > 0 {
+		fmt.Fprint(w, "    Readers:\n")
+		for _, r := range readers {
+			state := "active"
+			if r.Head {
+				state = "head"
+			} else if r.Evicted {
+				state = "evicted"
+			} else if r.Idle {
+				state = "idle"
+			} else if !r.Active {
+				state = "inactive"
+			}
+
+			fmt.Fprintf(w, "        %d: %s (%s), %s, from %s\n", r.ID, r.Path, r.Priority, state, r.Remote)
+			fmt.Fprintf(w, "            Position: %s / %s, readahead: %s, read: %s, opened: %s ago, used: %s ago\n",
+				humanize.Bytes(uint64(r.Position)),
+				humanize.Bytes(uint64(r.Size)),
+				humanize.Bytes(uint64(r.Readahead)),
+				humanize.Bytes(uint64(r.Read)),
+				time.Since(r.Opened).Round(time.Second),
+				time.Since(r.LastUsed).Round(time.Second))
+		}
+		fmt.Fprint(w, "\n")
+	}
+
 	if showTrackers {
 		fmt.Fprint(w, "    Libtorrent Trackers:\n")
 
@@ -25,6 +25,7 @@ import (
 	"github.com/elgatito/elementum/config"
 	"github.com/elgatito/elementum/proxy"
 	"github.com/elgatito/elementum/util"
+	"github.com/elgatito/elementum/util/source"
 	"github.com/elgatito/elementum/xbmc"
 )
 
@@ -252,6 +253,11 @@ func (t *TorrentFile) IsMagnet() bool {
 	return strings.HasPrefix(t.URI, "magnet:")
 }
 
+// IsMediaSource returns whether URI is a direct link to video file or HLS manifest, played without torrent
+func (t *TorrentFile) IsMediaSource() bool {
+	return source.New(t.URI, "") != nil
+}
+
 // IsValidMagnet Taken from anacrolix/torrent
 func (t *TorrentFile) IsValidMagnet() (err error) {
 	u, err := url.Parse(t.URI)
@@ -540,6 +546,13 @@ func (t *TorrentFile) Resolve() error {
 		return nil
 	}
 
+	// Direct links are not downloaded, they are identified by source ID to keep them among search results
+	if src := source.New(t.URI, ""); src != nil {
+		t.InfoHash = src.ID()
+		t.hasResolved = true
+		return nil
+	}
+
 	b, err := t.Download()
 	if err != nil {
 		return err
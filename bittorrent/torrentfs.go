@@ -5,9 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"time"
 
 	lt "github.com/ElementumOrg/libtorrent-go"
@@ -16,6 +18,7 @@ import (
 	"github.com/elgatito/elementum/config"
 	"github.com/elgatito/elementum/util"
 	"github.com/elgatito/elementum/util/event"
+	"github.com/elgatito/elementum/xbmc"
 )
 
 const (
@@ -27,6 +30,9 @@ type TorrentFS struct {
 	http.Dir
 	s      *Service
 	isHead bool
+
+	priority ReaderPriority
+	remote   string
 }
 
 // TorrentFSEntry ...
@@ -47,9 +53,15 @@ type TorrentFSEntry struct {
 	readahead   int64
 	storageType int
 
+	priority ReaderPriority
+	remote   string
+	opened   time.Time
+	read     int64
+
 	lastUsed time.Time
 	isActive bool
 	isHead   bool
+	evicted  bool
 }
 
 // PieceRange ...
@@ -63,9 +75,27 @@ func NewTorrentFS(service *Service, method string) *TorrentFS {
 		s:      service,
 		Dir:    http.Dir(service.config.DownloadPath),
 		isHead: method == "HEAD",
+
+		priority: ReaderForeground,
 	}
 }
 
+// NewClientTorrentFS returns TorrentFS for the request, readers of Kodi hosts get foreground priority,
+// readers of other clients, like DLNA renderers or shared links, get background priority
+func NewClientTorrentFS(service *Service, r *http.Request) *TorrentFS {
+	tfs := NewTorrentFS(service, r.Method)
+	tfs.remote = r.RemoteAddr
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if ip := net.ParseIP(host); (ip == nil || !ip.IsLoopback()) && !xbmc.ContainsXBMCHost(host) {
+		tfs.priority = ReaderBackground
+	}
+	return tfs
+}
+
 // Open ...
 func (tfs *TorrentFS) Open(uname string) (http.File, error) {
 	// URL always comes with "/" as the separator, but while we compare it to file storage path,
@@ -121,6 +151,10 @@ func NewTorrentFSEntry(file http.File, tfs *TorrentFS, t *Torrent, f *File, name
 		storageType: t.DownloadStorage,
 		id:          time.Now().UTC().UnixNano(),
 
+		priority: tfs.priority,
+		remote:   tfs.remote,
+		opened:   time.Now(),
+
 		lastUsed: time.Now(),
 		isActive: true,
 		isHead:   tfs.isHead,
@@ -186,7 +220,14 @@ func (tf *TorrentFSEntry) Close() error {
 // Read ...
 func (tf *TorrentFSEntry) Read(data []byte) (n int, err error) {
 	defer perf.ScopeTimer()()
-	tf.SetActive(true)
+	defer func() {
+		atomic.AddInt64(&tf.read, int64(n))
+	}()
+
+	// Reader, that comes back after being idle, can be evicted and should get its readahead back
+	if idle := tf.IsIdle(); tf.SetActive(true) || idle {
+		tf.t.ResetReaders()
+	}
 
 	currentOffset, err := tf.File.Seek(0, io.SeekCurrent)
 	if err != nil {
@@ -258,7 +299,8 @@ func (tf *TorrentFSEntry) Seek(offset int64, whence int) (int64, error) {
 		toUpdate := false
 		tf.t.muReaders.Lock()
 		for _, r := range tf.t.readers {
-			if r.id == tf.id {
+			// Background clients should not take readahead from the player
+			if r.id == tf.id || r.priority > tf.priority {
 				continue
 			}
 
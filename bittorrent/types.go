@@ -170,4 +170,8 @@ type RemoveOptions struct {
 	ForceDelete          bool
 	ForceConfirmation    bool
 	IsWatched            bool
+	// IsReplaced is set for torrent, replaced by another release of the same item,
+	// it is removed from the queue, so it is not offered as active torrent for the item,
+	// and is dropped with its data without asking
+	IsReplaced bool
 }
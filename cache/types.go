@@ -13,9 +13,13 @@ const (
 	TMDBKey          = "com.tmdb."
 	TVDBKey          = "com.tvdb."
 	TraktKey         = "com.trakt."
+	SimklKey         = "com.simkl."
 	LibraryKey       = "library."
 	FanartKey        = "fanart."
 	OpensubtitlesKey = "osdb."
+	MappingKey       = "mapping."
+	RecommendKey     = "recommend."
+	TrackerKey       = "tracker."
 
 	TMDBEpisodeKey                 = TMDBKey + "episode.%d.%d.%d.%s"
 	TMDBEpisodeExpire              = CacheExpireLong
@@ -62,6 +66,8 @@ const (
 	TraktListActivitiesExpire              = 30 * 24 * time.Hour
 	TraktPausedLastUpdatesKey              = TraktKey + "PausedLastUpdates.%d"
 	TraktPausedLastUpdatesExpire           = 30 * 24 * time.Hour
+	TraktPlaybackSyncedKey                 = TraktKey + "PlaybackSynced.%d"
+	TraktPlaybackSyncedExpire              = 30 * 24 * time.Hour
 	TraktMovieKey                          = TraktKey + "movie.%s"
 	TraktMovieExpire                       = CacheExpireLong
 	TraktMovieByTMDBKey                    = TraktKey + "movie.tmdb.%s"
@@ -108,6 +114,8 @@ const (
 	TraktShowsCalendarTotalExpire          = CacheExpireLong
 	TraktShowsHiddenProgressKey            = TraktKey + "shows.hidden.progress"
 	TraktShowsHiddenProgressExpire         = CacheExpireLong
+	TraktHiddenRecommendationsKey          = TraktKey + "hidden.recommendations.%s"
+	TraktHiddenRecommendationsExpire       = CacheExpireLong
 	TraktSeasonsKey                        = TraktKey + "seasons.%d"
 	TraktSeasonsExpire                     = CacheExpireLong
 	TraktSeasonsExtendedKey                = TraktKey + "seasons.%d.extended"
@@ -138,6 +146,10 @@ const (
 	TraktLockedAccountExpire               = 24 * time.Hour
 	TraktPaginatedRequestKey               = TraktKey + "paginated.%s.%s"
 	TraktPaginatedRequestExpire            = 24 * time.Hour
+	TraktRatingsKey                        = TraktKey + "ratings.%s"
+	TraktRatingsExpire                     = 24 * time.Hour
+	TraktFavoritesKey                      = TraktKey + "favorites.%s"
+	TraktFavoritesExpire                   = 24 * time.Hour
 
 	TVDBShowByIDKey    = TVDBKey + "show.%d.%s"
 	TVDBShowByIDExpire = CacheExpireLong
@@ -147,6 +159,9 @@ const (
 	FanartShowByIDKey     = FanartKey + "show.%d"
 	FanartShowByIDExpire  = CacheExpireLong
 
+	MappingShowKey    = MappingKey + "show.%d.%s"
+	MappingShowExpire = CacheExpireLong
+
 	LibraryStateKey               = LibraryKey + "State.%s"
 	LibraryStateExpire            = 30 * 24 * time.Hour
 	LibraryWatchedPlaycountKey    = LibraryKey + "WatchedLastPlaycount.%s"
@@ -157,4 +172,12 @@ const (
 	LibraryResolveFileExpire      = 60 * 24 * time.Hour
 	LibrarySyncPlaycountKey       = LibraryKey + "SyncLastPlaycount.%s"
 	LibrarySyncPlaycountExpire    = 30 * 24 * time.Hour
+
+	RecommendMoviesKey    = RecommendKey + "movies.%s.%s"
+	RecommendMoviesExpire = CacheExpireMedium
+	RecommendShowsKey     = RecommendKey + "shows.%s.%s"
+	RecommendShowsExpire  = CacheExpireMedium
+
+	TrackerActivitiesKey    = TrackerKey + "activities.%s.%s"
+	TrackerActivitiesExpire = 30 * 24 * time.Hour
 )
@@ -52,6 +52,7 @@ type Configuration struct {
 	LibraryPath                 string
 	Info                        *xbmc.AddonInfo
 	Platform                    *xbmc.Platform
+	Profile                     string
 	Language                    string
 	SecondLanguage              string
 	Region                      string
@@ -103,9 +104,19 @@ type Configuration struct {
 	LibrarySyncPlaybackEnabled  bool
 	LibraryUpdate               int
 	StrmLanguage                string
+	LibraryEpisodeOrdering      int
 	LibraryNFOMovies            bool
 	LibraryNFOShows             bool
+	AutoDownloadDelay           int
+	AutoDownloadRetry           int
+	AutoDownloadMinResolution   int
+	AutoDownloadMaxResolution   int
+	AutoDownloadMinSeeds        int
+	AutoDownloadMaxSize         int64
+	AutoDownloadExclude         string
+	AutoUpgradeInterval         int
 	PlaybackPercent             int
+	SkipIntro                   int
 	DownloadStorage             int
 	SkipBurstSearch             bool
 	SkipRepositorySearch        bool
@@ -206,6 +217,11 @@ type Configuration struct {
 	TraktCalendarsColorEpisode     string
 	TraktCalendarsColorUnaired     string
 	TraktUseLowestReleaseDate      bool
+	TraktRatePrompt                bool
+	TraktCheckin                   bool
+
+	SimklClientID string
+	SimklToken    string
 
 	UpdateFrequency                int
 	UpdateDelay                    int
@@ -228,6 +244,11 @@ type Configuration struct {
 	OSDBAutoLoadSkipExists bool
 	OSDBIncludedEnabled    bool
 	OSDBIncludedSkipExists bool
+	SubtitlesLocalPath     string
+	SubtitlesHTTPURL       string
+	SubtitlesConvertFormat string
+	DLNAEnabled            bool
+	DLNAFriendlyName       string
 
 	SortingModeMovies           int
 	SortingModeShows            int
@@ -601,6 +622,7 @@ func Reload() (ret *Configuration, err error) {
 		TorrentsPath:                torrentsPath,
 		Info:                        info,
 		Platform:                    platform,
+		Profile:                     settings.ToString("profile"),
 		Language:                    configBundle.Language,
 		SecondLanguage:              configBundle.SecondLanguage,
 		Region:                      configBundle.Region,
@@ -663,6 +685,7 @@ func Reload() (ret *Configuration, err error) {
 		ShowSeasonsOrder:            settings.ToInt("seasons_order"),
 		ShowSeasonsSpecials:         settings.ToBool("seasons_specials"),
 		PlaybackPercent:             settings.ToInt("playback_percent"),
+		SkipIntro:                   settings.ToInt("skip_intro"),
 		SmartEpisodeStart:           settings.ToBool("smart_episode_start"),
 		SmartEpisodeMatch:           settings.ToBool("smart_episode_match"),
 		SmartEpisodeChoose:          settings.ToBool("smart_episode_choose"),
@@ -672,8 +695,17 @@ func Reload() (ret *Configuration, err error) {
 		LibrarySyncPlaybackEnabled:  settings.ToBool("library_sync_playback_enabled"),
 		LibraryUpdate:               settings.ToInt("library_update"),
 		StrmLanguage:                settings.ToString("strm_language"),
+		LibraryEpisodeOrdering:      settings.ToInt("library_episode_ordering"),
 		LibraryNFOMovies:            settings.ToBool("library_nfo_movies"),
 		LibraryNFOShows:             settings.ToBool("library_nfo_shows"),
+		AutoDownloadDelay:           settings.ToInt("auto_download_delay"),
+		AutoDownloadRetry:           settings.ToInt("auto_download_retry"),
+		AutoDownloadMinResolution:   settings.ToInt("auto_download_min_resolution"),
+		AutoDownloadMaxResolution:   settings.ToInt("auto_download_max_resolution"),
+		AutoDownloadMinSeeds:        settings.ToInt("auto_download_min_seeds"),
+		AutoDownloadMaxSize:         int64(settings.ToInt("auto_download_max_size")) * 1024 * 1024,
+		AutoDownloadExclude:         settings.ToString("auto_download_exclude"),
+		AutoUpgradeInterval:         settings.ToInt("auto_upgrade_interval"),
 		SeedForever:                 settings.ToBool("seed_forever"),
 		ShareRatioLimit:             settings.ToInt("share_ratio_limit"),
 		SeedTimeRatioLimit:          settings.ToInt("seed_time_ratio_limit"),
@@ -751,6 +783,11 @@ func Reload() (ret *Configuration, err error) {
 		TraktCalendarsColorEpisode:     settings.ToString("trakt_calendars_color_episode"),
 		TraktCalendarsColorUnaired:     settings.ToString("trakt_calendars_color_unaired"),
 		TraktUseLowestReleaseDate:      settings.ToBool("trakt_use_lowest_release_date"),
+		TraktRatePrompt:                settings.ToBool("trakt_rate_prompt"),
+		TraktCheckin:                   settings.ToBool("trakt_checkin"),
+
+		SimklClientID: settings.ToString("simkl_client_id"),
+		SimklToken:    settings.ToString("simkl_token"),
 
 		UpdateFrequency:                settings.ToInt("library_update_frequency"),
 		UpdateDelay:                    settings.ToInt("library_update_delay"),
@@ -772,6 +809,11 @@ func Reload() (ret *Configuration, err error) {
 		OSDBAutoLoadSkipExists: settings.ToBool("osdb_auto_load_skipexists"),
 		OSDBIncludedEnabled:    settings.ToBool("osdb_included_enabled"),
 		OSDBIncludedSkipExists: settings.ToBool("osdb_included_skipexists"),
+		SubtitlesLocalPath:     settings.ToString("subtitles_local_path"),
+		SubtitlesHTTPURL:       settings.ToString("subtitles_http_url"),
+		SubtitlesConvertFormat: settings.ToString("subtitles_convert_format"),
+		DLNAEnabled:            settings.ToBool("dlna_enabled"),
+		DLNAFriendlyName:       settings.ToString("dlna_name"),
 
 		SortingModeMovies:           settings.ToInt("sorting_mode_movies"),
 		SortingModeShows:            settings.ToInt("sorting_mode_shows"),
@@ -941,6 +983,10 @@ func Reload() (ret *Configuration, err error) {
 		newConfig.DiskCacheSize = defaultDiskCacheSize
 	}
 
+	if newConfig.DLNAFriendlyName == "" {
+		newConfig.DLNAFriendlyName = "Elementum"
+	}
+
 	if newConfig.AutoYesEnabled {
 		xbmc.DialogAutoclose = newConfig.AutoYesTimeout
 	} else {
@@ -143,7 +143,7 @@ func (d *Database) GetFilename() string {
 	return d.fileName
 }
 
-// AddSearchHistory adds query to search history, according to media type
+// AddSearchHistory adds query to search history, according to media type and active profile
 func (d *StormDatabase) AddSearchHistory(historyType, query string) {
 	if d == nil || d.db == nil {
 		return
@@ -153,28 +153,45 @@ func (d *StormDatabase) AddSearchHistory(historyType, query string) {
 
 	var qh QueryHistory
 
-	if err := d.db.One("ID", fmt.Sprintf("%s|%s", historyType, query), &qh); err == nil {
+	profile := config.Get().Profile
+	id := fmt.Sprintf("%s|%s", historyType, query)
+	if profile != "" {
+		id = fmt.Sprintf("%s|%s", profile, id)
+	}
+
+	if err := d.db.One("ID", id, &qh); err == nil {
 		qh.Dt = time.Now()
 		d.db.Update(&qh)
 		return
 	}
 
 	qh = QueryHistory{
-		ID:    fmt.Sprintf("%s|%s", historyType, query),
-		Dt:    time.Now(),
-		Type:  historyType,
-		Query: query,
+		ID:      id,
+		Dt:      time.Now(),
+		Type:    historyType,
+		Query:   query,
+		Profile: profile,
 	}
 
 	d.db.Save(&qh)
 
 	var qhs []QueryHistory
-	d.db.Select(q.Eq("Type", historyType)).Skip(historyMaxSize).Find(&qhs)
+	d.db.Select(q.Eq("Type", historyType), q.Eq("Profile", profile)).Skip(historyMaxSize).Find(&qhs)
 	for _, qh := range qhs {
 		d.db.DeleteStruct(&qh)
 	}
 }
 
+// GetSearchHistory returns search history for selected media type and active profile, last queries first
+func (d *StormDatabase) GetSearchHistory(historyType string) (qs []QueryHistory) {
+	if d == nil || d.db == nil {
+		return
+	}
+
+	d.db.Select(q.Eq("Type", historyType), q.Eq("Profile", config.Get().Profile)).OrderBy("Dt").Reverse().Find(&qs)
+	return
+}
+
 // CleanSearchHistory cleans search history for selected media type
 func (d *StormDatabase) CleanSearchHistory(historyType string) {
 	if d == nil || d.db == nil {
@@ -184,7 +201,7 @@ func (d *StormDatabase) CleanSearchHistory(historyType string) {
 	defer perf.ScopeTimer()()
 
 	var qs []QueryHistory
-	d.db.Select(q.Eq("Type", historyType)).Find(&qs)
+	d.db.Select(q.Eq("Type", historyType), q.Eq("Profile", config.Get().Profile)).Find(&qs)
 	for _, q := range qs {
 		d.db.DeleteStruct(&q)
 	}
@@ -200,7 +217,7 @@ func (d *StormDatabase) RemoveSearchHistory(historyType, query string) {
 	defer perf.ScopeTimer()()
 
 	var qs []QueryHistory
-	d.db.Select(q.Eq("Type", historyType), q.Eq("Query", query)).Find(&qs)
+	d.db.Select(q.Eq("Type", historyType), q.Eq("Query", query), q.Eq("Profile", config.Get().Profile)).Find(&qs)
 	for _, q := range qs {
 		d.db.DeleteStruct(&q)
 	}
@@ -73,14 +73,121 @@ type LibraryItem struct {
 	MediaType int `storm:"index"`
 	State     int `storm:"index"`
 	ShowID    int `storm:"index"`
+
+	// Follow marks a show, which new episodes are downloaded automatically
+	Follow bool
+	// QualityCutoff is a resolution, up to which downloaded movie is upgraded automatically
+	QualityCutoff int
+}
+
+// ShowSettings keeps per-show settings, that do not depend on the show being in the library.
+// Shows are stored separately from LibraryItem, since TMDB IDs of movies and shows overlap.
+type ShowSettings struct {
+	ID int `storm:"id"`
+
+	// EpisodeGroup is TMDB episode group, selected for a show, to use instead of aired order
+	EpisodeGroup string
+}
+
+// MonitorItem keeps state of automatic downloads for a single movie or episode
+type MonitorItem struct {
+	ID        string `storm:"id"`
+	MediaType int    `storm:"index"`
+	TMDBID    int    `storm:"index"`
+	ShowID    int    `storm:"index"`
+	Season    int
+	Episode   int
+	State     int `storm:"index"`
+
+	Attempts  int
+	CheckedAt time.Time
+	DoneAt    time.Time
+
+	InfoHash     string
+	PreviousHash string
+	Name         string
+	Resolution   int
+	RipType      int
+	VideoCodec   int
+}
+
+// TraktQueueItem is a Trakt write request, that failed and waits to be sent again
+type TraktQueueItem struct {
+	ID          string `storm:"id"`
+	Action      string
+	Method      string
+	URL         string
+	Payload     []byte
+	Description string
+	Profile     string `storm:"index"`
+
+	Attempts  int
+	LastError string
+	CreatedAt time.Time `storm:"index"`
+	NextAt    time.Time `storm:"index"`
+}
+
+// WatchHistory is a local record of item playback, kept regardless of tracker authorization
+type WatchHistory struct {
+	ID        string `storm:"id"`
+	Profile   string `storm:"index"`
+	MediaType string `storm:"index"`
+	TMDBID    int    `storm:"index"`
+	ShowID    int    `storm:"index"`
+	Season    int
+	Episode   int
+
+	Title    string
+	URI      string
+	InfoHash string
+	Path     string
+	Size     int64
+	Index    int
+
+	Position  float64
+	Duration  float64
+	Watched   bool `storm:"index"`
+	PlayCount int
+
+	UpdatedAt time.Time `storm:"index"`
+	WatchedAt time.Time
+}
+
+// ShareLink is a signed link, that allows to stream a single item without access to the rest of API
+type ShareLink struct {
+	ID        string `storm:"id"`
+	InfoHash  string `storm:"index"`
+	FileIndex int
+	MediaType string
+	TMDBID    int
+	ShowID    int
+	Season    int
+	Episode   int
+	Title     string
+
+	// IP binds the link to a single client address, if not empty
+	IP string
+	// Rate is a bandwidth cap in bytes per second, zero is unlimited
+	Rate int64
+
+	CreatedAt time.Time
+	ExpiresAt time.Time `storm:"index"`
+}
+
+// Profile is a named user profile, keeping its own copy of per-user settings
+type Profile struct {
+	Name      string `storm:"id"`
+	Settings  map[string]string
+	CreatedAt time.Time
 }
 
 // QueryHistory ...
 type QueryHistory struct {
-	ID    string    `storm:"id"`
-	Type  string    `storm:"index"`
-	Query string    `storm:"index"`
-	Dt    time.Time `storm:"index"`
+	ID      string    `storm:"id"`
+	Type    string    `storm:"index"`
+	Query   string    `storm:"index"`
+	Profile string    `storm:"index"`
+	Dt      time.Time `storm:"index"`
 }
 
 // TorrentAssignMetadata ...
@@ -0,0 +1,355 @@
+package dlna
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asdine/storm"
+	"github.com/asdine/storm/q"
+
+	"github.com/elgatito/elementum/bittorrent"
+	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/database"
+	"github.com/elgatito/elementum/library"
+	"github.com/elgatito/elementum/tmdb"
+	"github.com/elgatito/elementum/util/upnp"
+)
+
+// Object IDs of static containers.
+// Torrents are "t/<infohash>" containers with "t/<infohash>/<file index>" items,
+// library movies are "m/<tmdb id>" items and shows are "s/<tmdb id>" containers.
+const (
+	rootID     = "0"
+	torrentsID = "torrents"
+	libraryID  = "library"
+	moviesID   = "library/movies"
+	showsID    = "library/shows"
+	historyID  = "history"
+
+	// autoFileIndex is used for torrents, that are not active, largest video file is chosen on play
+	autoFileIndex = -1
+)
+
+// content implements upnp.ContentDirectory with active torrents, library and torrents history
+type content struct {
+	s *bittorrent.Service
+}
+
+// Object ...
+func (c *content) Object(base, id string) (*upnp.Object, error) {
+	switch id {
+	case rootID:
+		return c.container(id, "-1", config.Get().DLNAFriendlyName), nil
+	case torrentsID:
+		return c.container(id, rootID, "Active torrents"), nil
+	case libraryID:
+		return c.container(id, rootID, "Library"), nil
+	case moviesID:
+		return c.container(id, libraryID, "Movies"), nil
+	case showsID:
+		return c.container(id, libraryID, "Shows"), nil
+	case historyID:
+		return c.container(id, rootID, "Torrents history"), nil
+	}
+
+	kind, rest, _ := strings.Cut(id, "/")
+	switch kind {
+	case "t":
+		hash, index, isFile := strings.Cut(rest, "/")
+		if !isFile {
+			return c.torrentContainer(hash, torrentsID)
+		}
+		i, err := strconv.Atoi(index)
+		if err != nil {
+			return nil, upnp.ErrNoSuchObject
+		}
+		for _, o := range c.torrentItems(base, hash) {
+			if o.ID == fileID(hash, i) {
+				return o, nil
+			}
+		}
+	case "m":
+		tmdbID, _ := strconv.Atoi(rest)
+		if o := c.movieItem(base, tmdbID); o != nil {
+			return o, nil
+		}
+	case "s":
+		showID, _ := strconv.Atoi(rest)
+		if o := c.showContainer(showID); o != nil {
+			return o, nil
+		}
+	}
+
+	return nil, upnp.ErrNoSuchObject
+}
+
+// Children ...
+func (c *content) Children(base, id string) ([]*upnp.Object, error) {
+	switch id {
+	case rootID:
+		ret := []*upnp.Object{}
+		for _, child := range []string{torrentsID, libraryID, historyID} {
+			if child == historyID && !config.Get().UseTorrentHistory {
+				continue
+			}
+			o, _ := c.Object(base, child)
+			ret = append(ret, o)
+		}
+		return ret, nil
+	case libraryID:
+		movies, _ := c.Object(base, moviesID)
+		shows, _ := c.Object(base, showsID)
+		return []*upnp.Object{movies, shows}, nil
+	case torrentsID:
+		ret := []*upnp.Object{}
+		for _, t := range c.s.GetTorrents() {
+			if o, err := c.torrentContainer(t.InfoHash(), torrentsID); err == nil {
+				ret = append(ret, o)
+			}
+		}
+		return ret, nil
+	case historyID:
+		return c.history(), nil
+	case moviesID:
+		return c.movies(base), nil
+	case showsID:
+		return c.shows(), nil
+	}
+
+	kind, rest, _ := strings.Cut(id, "/")
+	switch kind {
+	case "t":
+		if !strings.Contains(rest, "/") {
+			return c.torrentItems(base, rest), nil
+		}
+	case "s":
+		showID, _ := strconv.Atoi(rest)
+		ret := []*upnp.Object{}
+		for _, t := range c.showTorrents(showID) {
+			if o, err := c.torrentContainer(t.InfoHash(), id); err == nil {
+				ret = append(ret, o)
+			}
+		}
+		return ret, nil
+	}
+
+	return nil, upnp.ErrNoSuchObject
+}
+
+func (c *content) container(id, parentID, title string) *upnp.Object {
+	return &upnp.Object{ID: id, ParentID: parentID, Title: title, Class: upnp.ClassContainer}
+}
+
+func (c *content) torrentContainer(hash, parentID string) (*upnp.Object, error) {
+	if t := c.s.GetTorrentByHash(hash); t != nil {
+		o := c.container(torrentID(hash), parentID, t.Name())
+		o.ChildCount = len(torrentFiles(t))
+		return o, nil
+	}
+	if th := historyItem(hash); th != nil {
+		o := c.container(torrentID(hash), historyID, th.Name)
+		o.ChildCount = 1
+		return o, nil
+	}
+	return nil, upnp.ErrNoSuchObject
+}
+
+// torrentItems returns candidate files of active torrent,
+// or a single item with automatically chosen file, if torrent should be added on play
+func (c *content) torrentItems(base, hash string) []*upnp.Object {
+	ret := []*upnp.Object{}
+	if t := c.s.GetTorrentByHash(hash); t != nil {
+		for _, f := range torrentFiles(t) {
+			ret = append(ret, &upnp.Object{
+				ID:        fileID(hash, f.Index),
+				ParentID:  torrentID(hash),
+				Title:     filepath.Base(f.Path),
+				Class:     upnp.ClassFor(f.Path),
+				Resources: []*upnp.Resource{c.resource(base, hash, f.Index, f.Path, f.Size)},
+			})
+		}
+	} else if th := historyItem(hash); th != nil {
+		ret = append(ret, &upnp.Object{
+			ID:        fileID(hash, autoFileIndex),
+			ParentID:  torrentID(hash),
+			Title:     th.Name,
+			Class:     upnp.ClassVideo,
+			Resources: []*upnp.Resource{c.resource(base, hash, autoFileIndex, autoFileName(th.Name), 0)},
+		})
+	}
+	return ret
+}
+
+func (c *content) history() []*upnp.Object {
+	var ths []database.TorrentHistory
+	if err := database.GetStormDB().AllByIndex("Dt", &ths, storm.Reverse()); err != nil {
+		log.Debugf("Could not get list of history items: %s", err)
+	}
+
+	ret := make([]*upnp.Object, 0, len(ths))
+	for _, th := range ths {
+		o, err := c.torrentContainer(th.InfoHash, historyID)
+		if err != nil {
+			continue
+		}
+		o.ParentID = historyID
+		ret = append(ret, o)
+	}
+	return ret
+}
+
+func (c *content) movies(base string) []*upnp.Object {
+	var lis []database.LibraryItem
+	if err := database.GetStormDB().Select(q.Eq("MediaType", library.MovieType), q.Eq("State", library.StateActive)).Find(&lis); err != nil && err != storm.ErrNotFound {
+		log.Debugf("Could not get list of library movies: %s", err)
+	}
+
+	ret := []*upnp.Object{}
+	for _, li := range lis {
+		if o := c.movieItem(base, li.ID); o != nil {
+			ret = append(ret, o)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Title < ret[j].Title })
+	return ret
+}
+
+// movieItem returns library movie, that has an active or assigned torrent, as only those can be played without Kodi
+func (c *content) movieItem(base string, tmdbID int) *upnp.Object {
+	hash := c.movieTorrent(tmdbID)
+	if hash == "" {
+		return nil
+	}
+	movie := tmdb.GetMovie(tmdbID, config.Get().Language)
+	if movie == nil {
+		return nil
+	}
+
+	title := movie.GetTitle()
+	o := &upnp.Object{
+		ID:        fmt.Sprintf("m/%d", tmdbID),
+		ParentID:  moviesID,
+		Title:     title,
+		Class:     upnp.ClassMovie,
+		AlbumArt:  tmdb.ImageURL(movie.PosterPath, tmdb.GetImageQualities().Poster),
+		Resources: []*upnp.Resource{c.resource(base, hash, autoFileIndex, autoFileName(title), 0)},
+	}
+	if date, err := time.Parse("2006-01-02", movie.ReleaseDate); err == nil {
+		o.Date = date
+	}
+	if movie.Runtime > 0 {
+		o.Resources[0].Duration = time.Duration(movie.Runtime) * time.Minute
+	}
+	return o
+}
+
+func (c *content) movieTorrent(tmdbID int) string {
+	if t := c.s.HasTorrentByID(tmdbID); t != nil {
+		return t.InfoHash()
+	}
+
+	var ti database.TorrentAssignItem
+	if err := database.GetStormDB().One("TmdbID", tmdbID, &ti); err == nil {
+		return ti.InfoHash
+	}
+	return ""
+}
+
+func (c *content) shows() []*upnp.Object {
+	var lis []database.LibraryItem
+	if err := database.GetStormDB().Select(q.Eq("MediaType", library.ShowType), q.Eq("State", library.StateActive)).Find(&lis); err != nil && err != storm.ErrNotFound {
+		log.Debugf("Could not get list of library shows: %s", err)
+	}
+
+	ret := []*upnp.Object{}
+	for _, li := range lis {
+		if o := c.showContainer(li.ID); o != nil {
+			ret = append(ret, o)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Title < ret[j].Title })
+	return ret
+}
+
+func (c *content) showContainer(showID int) *upnp.Object {
+	show := tmdb.GetShow(showID, config.Get().Language)
+	if show == nil {
+		return nil
+	}
+
+	o := c.container(fmt.Sprintf("s/%d", showID), showsID, show.GetName())
+	o.ChildCount = len(c.showTorrents(showID))
+	o.AlbumArt = tmdb.ImageURL(show.PosterPath, tmdb.GetImageQualities().Poster)
+	return o
+}
+
+// showTorrents returns active torrents, that belong to the show
+func (c *content) showTorrents(showID int) []*bittorrent.Torrent {
+	ret := []*bittorrent.Torrent{}
+	for _, t := range c.s.GetTorrents() {
+		if t.DBItem != nil && t.DBItem.ShowID == showID {
+			ret = append(ret, t)
+		}
+	}
+	return ret
+}
+
+func (c *content) resource(base, hash string, index int, name string, size int64) *upnp.Resource {
+	return &upnp.Resource{
+		URL:          fmt.Sprintf("%s%s/stream/%s/%d/%s", base, prefix, hash, index, url.PathEscape(filepath.Base(name))),
+		ProtocolInfo: upnp.ProtocolInfo(upnp.MimeType(name)),
+		Size:         size,
+	}
+}
+
+// torrentFiles returns files of the torrent, that can be played
+func torrentFiles(t *bittorrent.Torrent) []*bittorrent.File {
+	if !t.HasMetadata() {
+		return nil
+	}
+
+	files := t.GetFiles()
+	choices, biggest, err := t.GetCandidateFiles(nil)
+	if err != nil || len(files) == 0 {
+		return nil
+	} else if len(choices) == 0 {
+		return []*bittorrent.File{files[biggest]}
+	}
+
+	ret := make([]*bittorrent.File, 0, len(choices))
+	for _, c := range choices {
+		ret = append(ret, files[c.Index])
+	}
+	return ret
+}
+
+func historyItem(hash string) *database.TorrentHistory {
+	if !config.Get().UseTorrentHistory {
+		return nil
+	}
+
+	var th database.TorrentHistory
+	if err := database.GetStormDB().One("InfoHash", hash, &th); err != nil {
+		return nil
+	}
+	return &th
+}
+
+// autoFileName returns file name for the item, which file is not known until torrent is added,
+// Matroska is assumed, as most of releases use it
+func autoFileName(title string) string {
+	return title + ".mkv"
+}
+
+func torrentID(hash string) string {
+	return "t/" + hash
+}
+
+func fileID(hash string, index int) string {
+	return fmt.Sprintf("t/%s/%d", hash, index)
+}
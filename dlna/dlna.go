@@ -0,0 +1,132 @@
+package dlna
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/op/go-logging"
+
+	"github.com/elgatito/elementum/bittorrent"
+	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/util/ident"
+	"github.com/elgatito/elementum/util/upnp"
+)
+
+// prefix is a path, DLNA server is mounted to
+const prefix = "/dlna"
+
+var log = logging.MustGetLogger("dlna")
+
+// Server is a DLNA media server, that exposes torrents and library to LAN devices
+type Server struct {
+	s      *bittorrent.Service
+	device *upnp.Device
+	upnp   *upnp.Handler
+	ssdp   *upnp.SSDPServer
+}
+
+// NewServer ...
+func NewServer(s *bittorrent.Service) *Server {
+	hostname, _ := os.Hostname()
+	device := &upnp.Device{
+		UUID:         upnp.NewUUID(fmt.Sprintf("elementum-%s-%d", hostname, config.Args.LocalPort)),
+		FriendlyName: config.Get().DLNAFriendlyName,
+		Manufacturer: "Elementum",
+		ModelName:    "Elementum",
+		ModelNumber:  ident.GetVersion(),
+		Prefix:       prefix,
+	}
+
+	return &Server{
+		s:      s,
+		device: device,
+		upnp:   upnp.NewHandler(device, &content{s: s}),
+	}
+}
+
+// Start starts SSDP announcements, if DLNA server is enabled in settings
+func (srv *Server) Start() {
+	if !config.Get().DLNAEnabled {
+		return
+	}
+
+	srv.ssdp = &upnp.SSDPServer{
+		Device:     srv.device,
+		HTTPPort:   config.Args.LocalPort,
+		ServerName: "Elementum/" + ident.GetVersion(),
+	}
+	if err := srv.ssdp.Start(); err != nil {
+		log.Errorf("Could not start SSDP server: %s", err)
+		srv.ssdp = nil
+	}
+}
+
+// Close stops SSDP announcements
+func (srv *Server) Close() {
+	if srv.ssdp != nil {
+		srv.ssdp.Close()
+		srv.ssdp = nil
+	}
+}
+
+// ServeHTTP serves UPnP requests and streams, requests are accepted only while the server is enabled
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !config.Get().DLNAEnabled {
+		http.NotFound(w, r)
+		return
+	}
+
+	if strings.HasPrefix(r.URL.Path, prefix+"/stream/") {
+		srv.stream(w, r)
+		return
+	}
+	srv.upnp.ServeHTTP(w, r)
+}
+
+// stream serves "/dlna/stream/<infohash>/<file index>/<name>" through TorrentFS,
+// adding the torrent from history or assigned torrents, if it is not active
+func (srv *Server) stream(w http.ResponseWriter, r *http.Request) {
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, prefix+"/stream/"), "/", 3)
+	if len(parts) < 2 {
+		http.NotFound(w, r)
+		return
+	}
+	index, err := strconv.Atoi(parts[1])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	t, err := srv.s.ActivateTorrent(parts[0])
+	if err != nil {
+		log.Warningf("Cannot stream %s: %s", r.URL.Path, err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	f := t.PlayableFile(index)
+	if f == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !f.Selected {
+		t.DownloadFile(f)
+		t.SaveDBFiles()
+	}
+
+	log.Infof("Streaming %s to %s", f.Path, r.RemoteAddr)
+
+	w.Header().Set("Connection", "close")
+	w.Header().Set("Content-Type", upnp.MimeType(f.Path))
+	w.Header().Set("transferMode.dlna.org", "Streaming")
+	w.Header().Set("contentFeatures.dlna.org", upnp.ContentFeatures())
+
+	req := r.Clone(r.Context())
+	req.URL.Path = "/" + filepath.ToSlash(f.Path)
+	req.URL.RawPath = ""
+	http.FileServer(bittorrent.NewClientTorrentFS(srv.s, r)).ServeHTTP(w, req)
+}
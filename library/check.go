@@ -0,0 +1,359 @@
+package library
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/anacrolix/missinggo/perf"
+	"github.com/asdine/storm"
+	"github.com/asdine/storm/q"
+
+	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/database"
+	"github.com/elgatito/elementum/library/uid"
+	"github.com/elgatito/elementum/util"
+	"github.com/elgatito/elementum/xbmc"
+)
+
+const (
+	// CheckOrphans are strm folders, that have no active item in the database
+	CheckOrphans CheckCategory = "orphans"
+	// CheckDeleted are items, marked as deleted in the database, that still have strm files
+	CheckDeleted CheckCategory = "deleted"
+	// CheckMissing are active items in the database, that have no strm files
+	CheckMissing CheckCategory = "missing"
+	// CheckKodi are Kodi library entries, that point to missing strm files
+	CheckKodi CheckCategory = "kodi"
+)
+
+const (
+	// CheckActionRecreate writes strm files again
+	CheckActionRecreate CheckAction = "recreate"
+	// CheckActionRemove removes files, database items or Kodi entries
+	CheckActionRemove CheckAction = "remove"
+	// CheckActionRelink marks existing strm files as active items in the database
+	CheckActionRelink CheckAction = "relink"
+)
+
+var (
+	// CheckCategories lists all categories in the order of reporting
+	CheckCategories = []CheckCategory{CheckOrphans, CheckDeleted, CheckMissing, CheckKodi}
+
+	// CheckActions lists fixes, available for each category, first one is the default
+	CheckActions = map[CheckCategory][]CheckAction{
+		CheckOrphans: {CheckActionRelink, CheckActionRemove},
+		CheckDeleted: {CheckActionRemove, CheckActionRelink},
+		CheckMissing: {CheckActionRecreate, CheckActionRemove},
+		CheckKodi:    {CheckActionRemove, CheckActionRecreate},
+	}
+
+	// ErrUnknownCheckAction is returned when action can't be applied to a category
+	ErrUnknownCheckAction = errors.New("Unknown action for this category")
+)
+
+// Count returns number of issues in all categories
+func (r *CheckReport) Count() (count int) {
+	for _, issues := range r.Issues {
+		count += len(issues)
+	}
+	return
+}
+
+func (r *CheckReport) add(i *CheckIssue) {
+	r.Issues[i.Category] = append(r.Issues[i.Category], i)
+}
+
+// Check compares strm files, Kodi library and Elementum database and reports inconsistencies
+func Check() (*CheckReport, error) {
+	defer perf.ScopeTimer()()
+
+	if err := checkLibraryPath(); err != nil {
+		return nil, err
+	}
+
+	report := &CheckReport{Issues: map[CheckCategory][]*CheckIssue{}}
+
+	var lis []database.LibraryItem
+	if err := database.GetStormDB().Select(q.Or(q.Eq("MediaType", MovieType), q.Eq("MediaType", ShowType))).Find(&lis); err != nil && err != storm.ErrNotFound {
+		return nil, err
+	}
+
+	items := map[int]map[int]database.LibraryItem{MovieType: {}, ShowType: {}}
+	for _, li := range lis {
+		items[li.MediaType][li.ID] = li
+	}
+
+	folders := map[int]map[int]string{
+		MovieType: scanStrmFolders(MoviesLibraryPath(), movieRegexp),
+		ShowType:  scanStrmFolders(ShowsLibraryPath(), showRegexp),
+	}
+
+	for _, mediaType := range []int{MovieType, ShowType} {
+		for id, path := range folders[mediaType] {
+			li, ok := items[mediaType][id]
+			if ok && li.State == StateActive {
+				continue
+			}
+
+			category := CheckOrphans
+			if ok && li.State == StateDeleted {
+				category = CheckDeleted
+			}
+			report.add(&CheckIssue{
+				Category:  category,
+				MediaType: mediaType,
+				TMDBID:    id,
+				Title:     filepath.Base(path),
+				Path:      path,
+			})
+		}
+
+		for id, li := range items[mediaType] {
+			if li.State != StateActive || id == 0 {
+				continue
+			}
+			if _, ok := folders[mediaType][id]; ok || hasExistingPath(mediaType, id) {
+				continue
+			}
+
+			report.add(&CheckIssue{
+				Category:  CheckMissing,
+				MediaType: mediaType,
+				TMDBID:    id,
+				Title:     fmt.Sprintf("%s %d", ItemTypes[mediaType], id),
+			})
+		}
+	}
+
+	checkKodiEntries(report)
+
+	log.Infof("Library check found %d issues", report.Count())
+	return report, nil
+}
+
+// scanStrmFolders returns folders, that contain Elementum strm files, by the TMDB ID from strm contents
+func scanStrmFolders(root string, re *regexp.Regexp) map[int]string {
+	ret := map[int]string{}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return ret
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		folder := filepath.Join(root, entry.Name())
+		filepath.WalkDir(folder, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !strings.HasSuffix(path, ".strm") {
+				return nil
+			}
+
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+			if matches := re.FindStringSubmatch(strings.TrimSpace(string(b))); len(matches) > 1 {
+				if id, _ := strconv.Atoi(matches[1]); id != 0 {
+					ret[id] = folder
+				}
+			}
+
+			// One strm file is enough to identify the folder
+			return filepath.SkipAll
+		})
+	}
+
+	return ret
+}
+
+// hasExistingPath checks whether item has strm files outside of Elementum library folders
+func hasExistingPath(mediaType, id int) bool {
+	var paths map[string]bool
+	if mediaType == MovieType {
+		paths = getMoviePathsByTMDB(id)
+	} else if mediaType == ShowType {
+		paths = getShowPathsByTMDB(id)
+	}
+
+	for path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// checkKodiEntries reports Kodi library entries, that point to missing strm files inside of Elementum library
+func checkKodiEntries(report *CheckReport) {
+	l := uid.Get()
+	libraryPath := filepath.Clean(config.Get().LibraryPath)
+
+	isMissing := func(file string) (string, bool) {
+		if file == "" || util.IsNetworkPath(file) || !strings.HasSuffix(file, ".strm") {
+			return "", false
+		}
+
+		path := util.GetRealPath(file, &config.LibrarySubstitutions)
+		if !strings.HasPrefix(filepath.Clean(path), libraryPath) {
+			return "", false
+		}
+
+		_, err := os.Stat(path)
+		return path, os.IsNotExist(err)
+	}
+
+	moviesMu := l.GetMutex(uid.MoviesMutex)
+	moviesMu.RLock()
+	for _, m := range l.Movies {
+		if m == nil || m.UIDs == nil || m.XbmcUIDs == nil {
+			continue
+		}
+		if path, missing := isMissing(m.File); missing {
+			report.add(&CheckIssue{
+				Category:  CheckKodi,
+				MediaType: MovieType,
+				TMDBID:    m.UIDs.TMDB,
+				KodiID:    m.XbmcUIDs.Kodi,
+				Title:     m.Title,
+				Path:      path,
+			})
+		}
+	}
+	moviesMu.RUnlock()
+
+	showsMu := l.GetMutex(uid.ShowsMutex)
+	showsMu.RLock()
+	for _, s := range l.Shows {
+		if s == nil || s.UIDs == nil {
+			continue
+		}
+		for _, e := range s.Episodes {
+			if e == nil || e.XbmcUIDs == nil {
+				continue
+			}
+			if path, missing := isMissing(e.File); missing {
+				report.add(&CheckIssue{
+					Category:  CheckKodi,
+					MediaType: EpisodeType,
+					TMDBID:    s.UIDs.TMDB,
+					KodiID:    e.XbmcUIDs.Kodi,
+					Title:     fmt.Sprintf("%s S%02dE%02d", s.Title, e.Season, e.Episode),
+					Path:      path,
+				})
+			}
+		}
+	}
+	showsMu.RUnlock()
+}
+
+// FixCheckIssues runs library check and applies an action to all issues of the category
+func FixCheckIssues(category CheckCategory, action CheckAction) (fixed, total int, err error) {
+	defer perf.ScopeTimer()()
+
+	if config.Get().LibraryReadOnly {
+		return 0, 0, ErrLibraryReadOnly
+	}
+
+	isAllowed := false
+	for _, a := range CheckActions[category] {
+		isAllowed = isAllowed || a == action
+	}
+	if !isAllowed {
+		return 0, 0, ErrUnknownCheckAction
+	}
+
+	report, err := Check()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	xbmcHost, _ := xbmc.GetLocalXBMCHost()
+	issues := report.Issues[category]
+	recreated := map[int]bool{}
+
+	for _, i := range issues {
+		if err := fixCheckIssue(xbmcHost, i, action, recreated); err != nil {
+			log.Warningf("Could not %s '%s': %s", action, i.Title, err)
+			continue
+		}
+		fixed++
+	}
+
+	if fixed > 0 && xbmcHost != nil {
+		if action == CheckActionRemove {
+			PlanOverallUpdate()
+		} else {
+			xbmcHost.VideoLibraryScan()
+		}
+	}
+
+	log.Infof("Library check: applied %s to %d of %d issues in %s", action, fixed, len(issues), category)
+	return fixed, len(issues), nil
+}
+
+func fixCheckIssue(xbmcHost *xbmc.XBMCHost, i *CheckIssue, action CheckAction, recreated map[int]bool) error {
+	showID := 0
+	if i.MediaType != MovieType {
+		showID = i.TMDBID
+	}
+
+	switch action {
+	case CheckActionRelink:
+		return updateDBItem(i.TMDBID, StateActive, i.MediaType, showID)
+
+	case CheckActionRecreate:
+		if i.TMDBID == 0 {
+			return errors.New("missing TMDB ID")
+		}
+		if recreated[i.TMDBID] {
+			return nil
+		}
+		recreated[i.TMDBID] = true
+
+		if i.MediaType == MovieType {
+			_, err := writeMovieStrm(strconv.Itoa(i.TMDBID), true)
+			return err
+		}
+		_, err := writeShowStrm(i.TMDBID, false, true)
+		return err
+
+	case CheckActionRemove:
+		switch i.Category {
+		case CheckMissing:
+			return deleteDBItem(i.TMDBID, i.MediaType, true, false)
+		case CheckKodi:
+			if xbmcHost == nil {
+				return errors.New("No Kodi instance found")
+			}
+			if i.MediaType == MovieType {
+				xbmcHost.VideoLibraryRemoveMovie(i.KodiID)
+			} else {
+				xbmcHost.VideoLibraryRemoveEpisode(i.KodiID)
+			}
+			return nil
+		default:
+			if err := os.RemoveAll(i.Path); err != nil {
+				return err
+			}
+			if xbmcHost != nil {
+				content := "movies"
+				if i.MediaType != MovieType {
+					content = "tvshows"
+				}
+				xbmcHost.VideoLibraryCleanDirectory(util.GetKodiPath(i.Path, &config.LibrarySubstitutions, xbmcHost.GetPlatform()), content, false)
+			}
+			return nil
+		}
+	}
+
+	return ErrUnknownCheckAction
+}
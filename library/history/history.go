@@ -0,0 +1,281 @@
+package history
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/asdine/storm"
+	"github.com/asdine/storm/q"
+	"github.com/cespare/xxhash"
+	"github.com/op/go-logging"
+
+	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/database"
+	"github.com/elgatito/elementum/library/playcount"
+	"github.com/elgatito/elementum/library/uid"
+)
+
+// Media types of history items
+const (
+	MovieType   = "movie"
+	ShowType    = "show"
+	SeasonType  = "season"
+	EpisodeType = "episode"
+	FileType    = "file"
+)
+
+// minResumePosition is a position, below which playback is not resumed
+const minResumePosition = 180
+
+var log = logging.MustGetLogger("history")
+
+// MovieID returns ID of history item for a movie
+func MovieID(tmdbID int) string {
+	return profileID(fmt.Sprintf("%s-%d", MovieType, tmdbID))
+}
+
+// ShowID returns ID of history item for a show
+func ShowID(showID int) string {
+	return profileID(fmt.Sprintf("%s-%d", ShowType, showID))
+}
+
+// SeasonID returns ID of history item for a season
+func SeasonID(showID, season int) string {
+	return profileID(fmt.Sprintf("%s-%d-%d", SeasonType, showID, season))
+}
+
+// EpisodeID returns ID of history item for an episode
+func EpisodeID(showID, season, episode int) string {
+	return profileID(fmt.Sprintf("%s-%d-%d-%d", EpisodeType, showID, season, episode))
+}
+
+// FileID returns ID of history item for a torrent file, that is not identified as a movie or episode
+func FileID(infoHash string, path string) string {
+	return profileID(fmt.Sprintf("%s-%s", FileType, strconv.FormatUint(xxhash.Sum64String(infoHash+path), 10)))
+}
+
+// profileID prefixes ID with active profile name, items of default profile keep plain IDs
+func profileID(id string) string {
+	if profile := config.Get().Profile; profile != "" {
+		return profile + "|" + id
+	}
+	return id
+}
+
+// NewItem returns history item for media, falling back to a torrent file, if media is not identified
+func NewItem(mediaType string, tmdbID, showID, season, episode int, infoHash, path string) *database.WatchHistory {
+	item := &database.WatchHistory{
+		Profile:   config.Get().Profile,
+		MediaType: mediaType,
+		TMDBID:    tmdbID,
+		ShowID:    showID,
+		Season:    season,
+		Episode:   episode,
+		InfoHash:  infoHash,
+		Path:      path,
+	}
+
+	switch {
+	case mediaType == MovieType && tmdbID != 0:
+		item.ID = MovieID(tmdbID)
+	case mediaType == EpisodeType && showID != 0:
+		item.ID = EpisodeID(showID, season, episode)
+	case mediaType == SeasonType && showID != 0:
+		item.ID = SeasonID(showID, season)
+	case mediaType == ShowType && showID != 0:
+		item.ID = ShowID(showID)
+	default:
+		item.MediaType = FileType
+		item.ID = FileID(infoHash, path)
+	}
+	return item
+}
+
+// Restore loads watched items of active profile into playcount, to show them as watched in lists
+func Restore() {
+	var items []database.WatchHistory
+	query := database.GetStormDB().Select(q.Eq("Watched", true), q.Eq("Profile", config.Get().Profile))
+	if err := query.Find(&items); err != nil && err != storm.ErrNotFound {
+		log.Warningf("Cannot read watch history: %s", err)
+		return
+	}
+
+	for i := range items {
+		setPlaycount(&items[i])
+	}
+	log.Debugf("Restored %d watched items from local history", len(items))
+}
+
+// Get returns history item by ID
+func Get(id string) *database.WatchHistory {
+	var item database.WatchHistory
+	if err := database.GetStormDB().One("ID", id, &item); err != nil {
+		return nil
+	}
+	return &item
+}
+
+// GetResume returns resume point of not finished item
+func GetResume(id string) *uid.Resume {
+	item := Get(id)
+	if item == nil || item.Watched || item.Position < minResumePosition || item.Duration <= 0 {
+		return nil
+	}
+	return &uid.Resume{Position: item.Position, Total: item.Duration}
+}
+
+// Update records playback of the item with current position
+func Update(item *database.WatchHistory, position, duration float64, watched bool) {
+	if item == nil || item.ID == "" {
+		return
+	}
+
+	if stored := Get(item.ID); stored != nil {
+		item.PlayCount = stored.PlayCount
+		item.WatchedAt = stored.WatchedAt
+		if item.Title == "" {
+			item.Title = stored.Title
+		}
+	}
+
+	item.Duration = duration
+	item.Position = position
+	item.Watched = watched
+	if watched {
+		item.Position = 0
+		item.PlayCount++
+		item.WatchedAt = time.Now()
+	} else if position < minResumePosition {
+		item.Position = 0
+	}
+
+	save(item)
+}
+
+// SetWatched marks item as watched or unwatched, keeping the resume point only for unwatched items
+func SetWatched(item *database.WatchHistory, watched bool) {
+	if item == nil || item.ID == "" {
+		return
+	}
+
+	if stored := Get(item.ID); stored != nil {
+		stored.Watched = watched
+		item = stored
+	} else {
+		item.Watched = watched
+	}
+
+	item.Position = 0
+	if watched {
+		if item.PlayCount == 0 {
+			item.PlayCount = 1
+		}
+		item.WatchedAt = time.Now()
+	} else {
+		item.PlayCount = 0
+	}
+
+	save(item)
+
+	if !watched {
+		clearChildren(item)
+	}
+}
+
+// clearChildren marks watched seasons and episodes of unwatched show or season as not watched
+func clearChildren(item *database.WatchHistory) {
+	if item.MediaType != ShowType && item.MediaType != SeasonType {
+		return
+	}
+
+	matchers := []q.Matcher{q.Eq("ShowID", item.ShowID), q.Eq("Watched", true), q.Eq("Profile", item.Profile)}
+	if item.MediaType == SeasonType {
+		matchers = append(matchers, q.Eq("Season", item.Season), q.Eq("MediaType", EpisodeType))
+	} else {
+		matchers = append(matchers, q.In("MediaType", []string{SeasonType, EpisodeType}))
+	}
+
+	var children []database.WatchHistory
+	if err := database.GetStormDB().Select(matchers...).Find(&children); err != nil {
+		if err != storm.ErrNotFound {
+			log.Warningf("Cannot read watch history of %s: %s", item.ID, err)
+		}
+		return
+	}
+
+	for i := range children {
+		children[i].Watched = false
+		children[i].PlayCount = 0
+		children[i].Position = 0
+		save(&children[i])
+	}
+}
+
+// RemoveProfile deletes all history items of the profile
+func RemoveProfile(profile string) error {
+	return database.GetStormDB().Select(q.Eq("Profile", profile)).Delete(&database.WatchHistory{})
+}
+
+// Remove deletes history item
+func Remove(id string) error {
+	item := Get(id)
+	if item == nil {
+		return storm.ErrNotFound
+	}
+
+	item.Watched = false
+	setPlaycount(item)
+	return database.GetStormDB().DeleteStruct(item)
+}
+
+// InProgress returns not finished items of active profile, last played first
+func InProgress() (items []database.WatchHistory) {
+	query := database.GetStormDB().Select(q.Eq("Watched", false), q.Gt("Position", 0), q.Eq("Profile", config.Get().Profile))
+	if err := query.Find(&items); err != nil && err != storm.ErrNotFound {
+		log.Warningf("Cannot read watch history: %s", err)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].UpdatedAt.After(items[j].UpdatedAt)
+	})
+	return
+}
+
+// ByMediaType returns items of active profile with the media type, last played first
+func ByMediaType(mediaType string) (items []database.WatchHistory) {
+	query := database.GetStormDB().Select(q.Eq("MediaType", mediaType), q.Eq("Profile", config.Get().Profile))
+	if err := query.Find(&items); err != nil && err != storm.ErrNotFound {
+		log.Warningf("Cannot read watch history: %s", err)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].UpdatedAt.After(items[j].UpdatedAt)
+	})
+	return
+}
+
+func save(item *database.WatchHistory) {
+	item.UpdatedAt = time.Now()
+	if err := database.GetStormDB().Save(item); err != nil {
+		log.Warningf("Cannot save watch history for %s: %s", item.ID, err)
+		return
+	}
+
+	setPlaycount(item)
+}
+
+// setPlaycount updates local watched state, used for list overlays
+func setPlaycount(item *database.WatchHistory) {
+	switch item.MediaType {
+	case MovieType:
+		playcount.SetLocal(playcount.MovieKey(item.TMDBID), item.Watched)
+	case ShowType:
+		playcount.SetLocal(playcount.ShowKey(item.ShowID), item.Watched)
+	case SeasonType:
+		playcount.SetLocal(playcount.SeasonKey(item.ShowID, item.Season), item.Watched)
+	case EpisodeType:
+		playcount.SetLocal(playcount.EpisodeKey(item.ShowID, item.Season, item.Episode), item.Watched)
+	}
+}
@@ -20,7 +20,9 @@ import (
 	"github.com/elgatito/elementum/cache"
 	"github.com/elgatito/elementum/config"
 	"github.com/elgatito/elementum/database"
+	"github.com/elgatito/elementum/library/history"
 	"github.com/elgatito/elementum/library/uid"
+	"github.com/elgatito/elementum/mapping"
 	"github.com/elgatito/elementum/tmdb"
 	"github.com/elgatito/elementum/trakt"
 	"github.com/elgatito/elementum/util"
@@ -134,6 +136,7 @@ func Init() {
 	removedEpisodes = make(chan *removedEpisode)
 
 	InitDB()
+	history.Restore()
 
 	xbmcHost, _ := xbmc.GetLocalXBMCHost()
 	if xbmcHost == nil {
@@ -591,6 +594,13 @@ func writeShowStrm(showID int, adding, force bool) (*tmdb.Show, error) {
 	}
 
 	addSpecials := config.Get().AddSpecials
+	m := mapping.Get(show)
+	if removed := removeStaleEpisodeStrm(showID, showPath, showStrm, m); removed > 0 {
+		log.Infof("Removed %d episode strm files of show %d, named in previous episode ordering", removed, showID)
+		if xbmcHost, err := xbmc.GetLocalXBMCHost(); xbmcHost != nil && err == nil {
+			defer xbmcHost.VideoLibraryCleanDirectory(showPath, "tvshows", false)
+		}
+	}
 
 	for _, season := range show.Seasons {
 		if season.EpisodeCount == 0 {
@@ -634,7 +644,7 @@ func writeShowStrm(showID int, adding, force bool) (*tmdb.Show, error) {
 				continue
 			}
 
-			episodeStrmPath := filepath.Join(showPath, fmt.Sprintf("%s S%02dE%02d.strm", showStrm, season.Season, episode.EpisodeNumber))
+			episodeStrmPath := filepath.Join(showPath, GetEpisodeStrmName(showStrm, m, season.Season, episode.EpisodeNumber))
 			playLink := URLForXBMC("/library/show/play/%d/%d/%d", showID, season.Season, episode.EpisodeNumber)
 			if _, err := os.Stat(episodeStrmPath); !force && err == nil {
 				continue
@@ -800,8 +810,8 @@ func RemoveEpisode(tmdbID int, showID int, seasonNumber int, episodeNumber int)
 		return errors.New("cannot find show path")
 	}
 
-	episodeStrm := fmt.Sprintf("%s S%02dE%02d.strm", showPath, seasonNumber, episodeNumber)
-	episodePath := filepath.Join(ShowsLibraryPath(), showPath, episodeStrm)
+	episodeStrm := GetEpisodeStrmName(showStrm, mapping.Get(show), seasonNumber, episodeNumber)
+	episodePath := filepath.Join(showPath, episodeStrm)
 
 	alreadyRemoved := false
 	if _, err := os.Stat(episodePath); err != nil {
@@ -847,6 +857,8 @@ func updateDBItem(tmdbID int, state int, mediaType int, showID int) error {
 		ShowID:    showID,
 		State:     state,
 	}
+	keepDBItemSettings(database.GetStormDB(), &li)
+
 	if err := database.GetStormDB().Save(&li); err != nil {
 		log.Debugf("updateDBItem failed: %s", err)
 		return err
@@ -870,6 +882,8 @@ func updateBatchDBItem(tmdbIds []int, state int, mediaType int, showID int) erro
 			ShowID:    showID,
 			State:     state,
 		}
+		keepDBItemSettings(tx, &li)
+
 		err = tx.Save(&li)
 		if err != nil {
 			return err
@@ -879,6 +893,22 @@ func updateBatchDBItem(tmdbIds []int, state int, mediaType int, showID int) erro
 	return tx.Commit()
 }
 
+// getDBItemSettings returns stored item to keep its settings, when item is re-saved
+func getDBItemSettings(node storm.Node, tmdbID int) (li database.LibraryItem) {
+	node.One("ID", tmdbID, &li)
+	return
+}
+
+// keepDBItemSettings copies per-item settings from the stored item of the same media type
+func keepDBItemSettings(node storm.Node, li *database.LibraryItem) {
+	stored := getDBItemSettings(node, li.ID)
+	if stored.MediaType != li.MediaType {
+		return
+	}
+	li.Follow = stored.Follow
+	li.QualityCutoff = stored.QualityCutoff
+}
+
 func deleteDBItem(tmdbID int, mediaType int, removal bool, purge bool) error {
 	defer perf.ScopeTimer()()
 
@@ -952,6 +982,158 @@ func IsInLibrary(id int, mediaType int) (res bool) {
 	return false
 }
 
+// SetShowEpisodeGroup stores TMDB episode group for a show, empty group resets show to aired order.
+// If show is in the library - strm files are re-written with new numbers.
+func SetShowEpisodeGroup(showID int, groupID string) error {
+	if showID <= 0 {
+		return fmt.Errorf("Cannot set episode group due to missing TMDB ID")
+	}
+
+	defer perf.ScopeTimer()()
+
+	s := database.ShowSettings{ID: showID}
+	database.GetStormDB().One("ID", showID, &s)
+	if s.EpisodeGroup == groupID {
+		return nil
+	}
+	s.EpisodeGroup = groupID
+
+	if groupID == "" {
+		if err := database.GetStormDB().DeleteStruct(&s); err != nil && err != storm.ErrNotFound {
+			return err
+		}
+	} else if err := database.GetStormDB().Save(&s); err != nil {
+		log.Debugf("Cannot save episode group: %s", err)
+		return err
+	}
+
+	log.Infof("Episode group for show %d is set to '%s'", showID, groupID)
+	mapping.Delete(showID, groupID)
+
+	if !IsInLibrary(showID, ShowType) {
+		return nil
+	}
+
+	return rewriteShowStrm(showID)
+}
+
+// SetShowFollow enables or disables automatic download of new episodes for a library show
+func SetShowFollow(showID int, follow bool) error {
+	if showID <= 0 {
+		return fmt.Errorf("Cannot follow show due to missing TMDB ID")
+	}
+
+	var li database.LibraryItem
+	if err := database.GetStormDB().One("ID", showID, &li); err != nil || li.State != StateActive || li.MediaType != ShowType {
+		return fmt.Errorf("Show (%d) is not in the library", showID)
+	}
+	if li.Follow == follow {
+		return nil
+	}
+
+	li.Follow = follow
+	if err := database.GetStormDB().Save(&li); err != nil {
+		log.Debugf("Cannot save follow flag: %s", err)
+		return err
+	}
+
+	log.Infof("Follow for show %d is set to %t", showID, follow)
+	return nil
+}
+
+// IsShowFollowed checks whether new episodes of a show are downloaded automatically
+func IsShowFollowed(showID int) bool {
+	li := getDBItemSettings(database.GetStormDB(), showID)
+	return li.State == StateActive && li.MediaType == ShowType && li.Follow
+}
+
+// GetFollowedShows returns TMDB IDs of active library shows, marked to follow
+func GetFollowedShows() (ids []int) {
+	var lis []database.LibraryItem
+	if err := database.GetStormDB().Select(q.Eq("MediaType", ShowType), q.Eq("State", StateActive), q.Eq("Follow", true)).Find(&lis); err != nil {
+		return
+	}
+
+	for _, li := range lis {
+		ids = append(ids, li.ID)
+	}
+	return
+}
+
+// SetMovieQualityCutoff sets resolution, up to which downloaded library movie is upgraded, zero disables upgrades
+func SetMovieQualityCutoff(tmdbID int, resolution int) error {
+	if tmdbID <= 0 {
+		return fmt.Errorf("Cannot set quality cutoff due to missing TMDB ID")
+	}
+
+	var li database.LibraryItem
+	if err := database.GetStormDB().One("ID", tmdbID, &li); err != nil || li.State != StateActive || li.MediaType != MovieType {
+		return fmt.Errorf("Movie (%d) is not in the library", tmdbID)
+	}
+	if li.QualityCutoff == resolution {
+		return nil
+	}
+
+	li.QualityCutoff = resolution
+	if err := database.GetStormDB().Save(&li); err != nil {
+		log.Debugf("Cannot save quality cutoff: %s", err)
+		return err
+	}
+
+	log.Infof("Quality cutoff for movie %d is set to %d", tmdbID, resolution)
+	return nil
+}
+
+// GetMovieQualityCutoff returns resolution, up to which library movie is upgraded
+func GetMovieQualityCutoff(tmdbID int) int {
+	li := getDBItemSettings(database.GetStormDB(), tmdbID)
+	if li.State != StateActive || li.MediaType != MovieType {
+		return 0
+	}
+	return li.QualityCutoff
+}
+
+// GetUpgradedMovies returns active library movies, that have quality cutoff set
+func GetUpgradedMovies() (lis []database.LibraryItem) {
+	if err := database.GetStormDB().Select(q.Eq("MediaType", MovieType), q.Eq("State", StateActive), q.Gt("QualityCutoff", 0)).Find(&lis); err != nil {
+		return nil
+	}
+	return
+}
+
+// rewriteShowStrm removes episode strm files of a show and writes them again,
+// used when episodes numbering has changed.
+func rewriteShowStrm(showID int) error {
+	show := tmdb.GetShow(showID, config.GetStrmLanguage())
+	if show == nil {
+		return fmt.Errorf("Unable to get show (%d)", showID)
+	}
+
+	showPath, showStrm := GetShowLibraryPath(show)
+	if showStrm == "" {
+		return errors.New("Can't find show path")
+	}
+
+	if entries, err := os.ReadDir(showPath); err == nil {
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.HasPrefix(entry.Name(), showStrm+" S") && strings.HasSuffix(entry.Name(), ".strm") {
+				os.Remove(filepath.Join(showPath, entry.Name()))
+			}
+		}
+	}
+
+	if _, err := writeShowStrm(showID, false, true); err != nil {
+		return err
+	}
+
+	if xbmcHost, err := xbmc.GetLocalXBMCHost(); xbmcHost != nil && err == nil {
+		xbmcHost.VideoLibraryCleanDirectory(showPath, "tvshows", false)
+		xbmcHost.VideoLibraryScan()
+	}
+
+	return nil
+}
+
 //
 // Maintenance
 //
@@ -1536,6 +1718,66 @@ func GetShowLibraryPath(show *tmdb.Show) (showPath, showStrm string) {
 	return
 }
 
+// GetEpisodeStrmName returns strm file name for an episode, numbered according to show's episode group
+// or library episode ordering
+func GetEpisodeStrmName(showStrm string, m *mapping.Show, season, episode int) string {
+	n := mapping.Number{Season: season, Episode: episode}
+	if em := m.Find(season, episode); em != nil {
+		n = em.Preferred(mapping.Ordering(config.Get().LibraryEpisodeOrdering))
+	}
+
+	return fmt.Sprintf("%s S%02dE%02d.strm", showStrm, n.Season, n.Episode)
+}
+
+// removeStaleEpisodeStrm removes episode strm files, which name differs from the name in current episode ordering,
+// left after library ordering setting or episodes mapping was changed. Episode is taken from play link of the file.
+func removeStaleEpisodeStrm(showID int, showPath, showStrm string, m *mapping.Show) (removed int) {
+	if m == nil {
+		return
+	}
+
+	entries, err := os.ReadDir(showPath)
+	if err != nil {
+		return
+	}
+
+	current := map[string]bool{}
+	for _, e := range m.Episodes {
+		current[GetEpisodeStrmName(showStrm, m, e.TMDB.Season, e.TMDB.Episode)] = true
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || current[name] || !strings.HasPrefix(name, showStrm+" S") || !strings.HasSuffix(name, ".strm") {
+			continue
+		}
+
+		path := filepath.Join(showPath, name)
+		link, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		matches := showRegexp.FindStringSubmatch(strings.TrimSpace(string(link)))
+		if len(matches) < 4 || matches[1] != strconv.Itoa(showID) {
+			continue
+		}
+
+		// Files of episodes, that are not known to mapping, are kept as they are
+		season, _ := strconv.Atoi(matches[2])
+		episode, _ := strconv.Atoi(matches[3])
+		if m.Find(season, episode) == nil {
+			continue
+		}
+
+		if err := os.Remove(path); err != nil {
+			log.Warningf("Cannot remove stale strm file %s: %s", path, err)
+			continue
+		}
+		removed++
+	}
+	return
+}
+
 func getMoviePathsByTMDB(id int) (ret map[string]bool) {
 	ret = map[string]bool{}
 
@@ -0,0 +1,265 @@
+package library
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/elgatito/elementum/cache"
+	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/library/uid"
+	"github.com/elgatito/elementum/trakt"
+	"github.com/elgatito/elementum/xbmc"
+)
+
+const (
+	// playbackTolerance is a time difference, below which local and Trakt progress are considered simultaneous
+	playbackTolerance = time.Minute
+	// playbackProgressTolerance is a progress difference in percents, below which progress is not pushed to Trakt
+	playbackProgressTolerance = 1.0
+)
+
+// playbackState is a progress of a single library item, that is compared with Trakt playback entry
+type playbackState struct {
+	key        string
+	kodiID     int
+	tmdbID     int
+	position   float64
+	total      float64
+	lastPlayed time.Time
+}
+
+// remotePlayback is a Trakt playback entry
+type remotePlayback struct {
+	id       int
+	progress float64
+	pausedAt time.Time
+}
+
+// ReconcileTraktPaused compares resume points of Kodi library with Trakt playback entries
+// and pushes or pulls whichever is newer.
+// Synced items are remembered, to reset local progress when Trakt entry was removed
+// and to remove Trakt entry when local progress was reset.
+func ReconcileTraktPaused(xbmcHost *xbmc.XBMCHost, itemType int, isRefreshNeeded bool) error {
+	if config.Get().TraktToken == "" || !config.Get().TraktSyncPlaybackProgress || config.Get().LibraryReadOnly {
+		return nil
+	}
+
+	started := time.Now()
+	defer func() {
+		log.Debugf("Trakt playback reconcile for '%s' finished in %s", ItemTypes[itemType], time.Since(started))
+	}()
+
+	cacheStore := cache.NewDBStore()
+	synced := map[string]time.Time{}
+
+	cacheKey := fmt.Sprintf(cache.TraktPlaybackSyncedKey, itemType)
+	cacheStore.Get(cacheKey, &synced)
+	defer func() {
+		cacheStore.Set(cacheKey, &synced, cache.TraktPlaybackSyncedExpire)
+	}()
+
+	var local []*playbackState
+	remote := map[string]*remotePlayback{}
+	// watchedAt keeps last time items were finished on Trakt, Trakt playback entry is removed when item is finished
+	watchedAt := map[string]time.Time{}
+	hasWatched := true
+
+	contentType := "movie"
+	if itemType == MovieType {
+		movies, err := trakt.PausedMovies(isRefreshNeeded)
+		if err != nil {
+			log.Warningf("TraktSync: Got error from PausedMovies: %s", err)
+			return err
+		}
+		for _, m := range movies {
+			if m != nil && m.Movie != nil && m.Movie.IDs != nil && m.Movie.IDs.TMDB != 0 {
+				remote[fmt.Sprintf("%d", m.Movie.IDs.TMDB)] = &remotePlayback{id: m.ID, progress: m.Progress, pausedAt: m.PausedAt}
+			}
+		}
+
+		if watchedMovies, err := trakt.WatchedMovies(false); err == nil {
+			for _, m := range watchedMovies {
+				if m != nil && m.Movie != nil && m.Movie.IDs != nil && m.Movie.IDs.TMDB != 0 {
+					watchedAt[fmt.Sprintf("%d", m.Movie.IDs.TMDB)] = m.LastWatchedAt
+				}
+			}
+		} else {
+			log.Warningf("TraktSync: Got error from WatchedMovies: %s", err)
+			hasWatched = false
+		}
+
+		local = localMoviesPlayback()
+	} else {
+		contentType = "episode"
+		shows, err := trakt.PausedShows(isRefreshNeeded)
+		if err != nil {
+			log.Warningf("TraktSync: Got error from PausedShows: %s", err)
+			return err
+		}
+		for _, s := range shows {
+			if s == nil || s.Show == nil || s.Show.IDs == nil || s.Show.IDs.TMDB == 0 || s.Episode == nil {
+				continue
+			}
+			remote[fmt.Sprintf("%d_%d_%d", s.Show.IDs.TMDB, s.Episode.Season, s.Episode.Number)] = &remotePlayback{id: s.ID, progress: s.Progress, pausedAt: s.PausedAt}
+		}
+
+		if watchedShows, err := trakt.WatchedShows(false); err == nil {
+			for _, s := range watchedShows {
+				if s == nil || s.Show == nil || s.Show.IDs == nil || s.Show.IDs.TMDB == 0 {
+					continue
+				}
+				for _, se := range s.Seasons {
+					if se == nil {
+						continue
+					}
+					for _, e := range se.Episodes {
+						if e != nil {
+							watchedAt[fmt.Sprintf("%d_%d_%d", s.Show.IDs.TMDB, se.Number, e.Number)] = e.LastWatchedAt
+						}
+					}
+				}
+			}
+		} else {
+			log.Warningf("TraktSync: Got error from WatchedShows: %s", err)
+			hasWatched = false
+		}
+
+		local = localEpisodesPlayback()
+	}
+
+	setProgress := func(p *playbackState, position, total int, dt time.Time) {
+		if itemType == MovieType {
+			xbmcHost.SetMovieProgressWithDate(p.kodiID, position, total, dt)
+		} else {
+			xbmcHost.SetEpisodeProgressWithDate(p.kodiID, position, total, dt)
+		}
+	}
+
+	pushed, pulled, removed := 0, 0, 0
+	for _, p := range local {
+		isLocal := p.position > 0 && p.total > 0
+		r := remote[p.key]
+		syncedAt, isSynced := synced[p.key]
+
+		switch {
+		case isLocal && r == nil:
+			if isSynced && !p.lastPlayed.After(syncedAt.Add(playbackTolerance)) {
+				// Trakt entry was removed after last sync, local progress is outdated
+				setProgress(p, 0, 0, p.lastPlayed)
+				delete(synced, p.key)
+				removed++
+			} else if w, ok := watchedAt[p.key]; ok && w.After(p.lastPlayed.Add(playbackTolerance)) {
+				// Item was finished on Trakt after it was played locally, local progress is outdated
+				setProgress(p, 0, 0, w)
+				delete(synced, p.key)
+				removed++
+			} else if !isSynced && !hasWatched {
+				// Without Trakt history it is unknown whether local progress is newer, so it is pushed on next run
+				continue
+			} else if p.tmdbID != 0 {
+				if err := trakt.SavePlayback(contentType, p.tmdbID, p.position, p.total); err == nil {
+					synced[p.key] = time.Now()
+					pushed++
+				}
+			}
+
+		case isLocal && r != nil:
+			progress := p.position / p.total * 100
+			if p.tmdbID != 0 && p.lastPlayed.After(r.pausedAt.Add(playbackTolerance)) && math.Abs(progress-r.progress) >= playbackProgressTolerance {
+				if err := trakt.SavePlayback(contentType, p.tmdbID, p.position, p.total); err == nil {
+					synced[p.key] = time.Now()
+					pushed++
+				}
+			} else if r.pausedAt.After(p.lastPlayed.Add(playbackTolerance)) {
+				setProgress(p, int(p.total/100*r.progress), int(p.total), r.pausedAt)
+				synced[p.key] = r.pausedAt
+				pulled++
+			} else {
+				synced[p.key] = r.pausedAt
+			}
+
+		case !isLocal && r != nil:
+			if !p.lastPlayed.IsZero() && p.lastPlayed.After(r.pausedAt.Add(playbackTolerance)) {
+				// Item was finished or its progress was reset locally after it was paused on Trakt
+				if err := trakt.RemovePlayback(r.id); err == nil {
+					delete(synced, p.key)
+					removed++
+				}
+			} else {
+				// Progress of items, not started locally, is pulled by RefreshTraktPaused
+				synced[p.key] = r.pausedAt
+			}
+
+		default:
+			delete(synced, p.key)
+		}
+	}
+
+	if pushed > 0 || pulled > 0 || removed > 0 {
+		log.Infof("Trakt playback reconcile for '%s': pushed %d, pulled %d, removed %d", ItemTypes[itemType], pushed, pulled, removed)
+	}
+	return nil
+}
+
+// localMoviesPlayback returns progress of library movies
+func localMoviesPlayback() (ret []*playbackState) {
+	l := uid.Get()
+
+	mu := l.GetMutex(uid.MoviesMutex)
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, m := range l.Movies {
+		if m == nil || m.UIDs == nil || m.UIDs.TMDB == 0 || m.UIDs.Kodi == 0 {
+			continue
+		}
+
+		p := &playbackState{
+			key:        fmt.Sprintf("%d", m.UIDs.TMDB),
+			kodiID:     m.UIDs.Kodi,
+			tmdbID:     m.UIDs.TMDB,
+			lastPlayed: m.LastPlayed,
+		}
+		if m.Resume != nil {
+			p.position = m.Resume.Position
+			p.total = m.Resume.Total
+		}
+		ret = append(ret, p)
+	}
+	return
+}
+
+// localEpisodesPlayback returns progress of library episodes
+func localEpisodesPlayback() (ret []*playbackState) {
+	l := uid.Get()
+
+	mu := l.GetMutex(uid.ShowsMutex)
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, s := range l.Shows {
+		if s == nil || s.UIDs == nil || s.UIDs.TMDB == 0 {
+			continue
+		}
+
+		for _, e := range s.Episodes {
+			if e == nil || e.UIDs == nil || e.UIDs.Kodi == 0 {
+				continue
+			}
+
+			p := &playbackState{
+				key:        fmt.Sprintf("%d_%d_%d", s.UIDs.TMDB, e.Season, e.Episode),
+				kodiID:     e.UIDs.Kodi,
+				tmdbID:     e.UIDs.TMDB,
+				lastPlayed: e.LastPlayed,
+			}
+			if e.Resume != nil {
+				p.position = e.Resume.Position
+				p.total = e.Resume.Total
+			}
+			ret = append(ret, p)
+		}
+	}
+	return
+}
@@ -36,6 +36,13 @@ var (
 
 	// Watched contains uint64 hashed bools
 	Watched = map[uint64]WatchedState{}
+
+	// Local contains items, watched according to local history.
+	// It is kept apart from Watched, which is rebuilt on library refresh.
+	Local = map[uint64]WatchedState{}
+
+	// Tracked contains items, watched according to trackers, that have no library sync of their own, by tracker name
+	Tracked = map[string]map[uint64]WatchedState{}
 )
 
 // WatchedState just a simple bool with Int() conversion
@@ -45,12 +52,79 @@ func searchForKey(k uint64) WatchedState {
 	Mu.RLock()
 	defer Mu.RUnlock()
 
-	return Watched[k]
+	if Watched[k] || Local[k] {
+		return true
+	}
+	for _, items := range Tracked {
+		if items[k] {
+			return true
+		}
+	}
+	return false
+}
+
+// SetLocal sets local watched state of the item
+func SetLocal(k uint64, watched bool) {
+	Mu.Lock()
+	defer Mu.Unlock()
+
+	if watched {
+		Local[k] = true
+	} else {
+		delete(Local, k)
+	}
+}
+
+// SetTracked replaces items, watched according to the tracker
+func SetTracked(name string, items map[uint64]WatchedState) {
+	Mu.Lock()
+	defer Mu.Unlock()
+
+	Tracked[name] = items
+}
+
+// HasTracked checks whether watched items of the tracker were loaded
+func HasTracked(name string) bool {
+	Mu.RLock()
+	defer Mu.RUnlock()
+
+	_, ok := Tracked[name]
+	return ok
+}
+
+// Reset clears watched states, which belong to previously active profile
+func Reset() {
+	Mu.Lock()
+	defer Mu.Unlock()
+
+	Watched = map[uint64]WatchedState{}
+	Local = map[uint64]WatchedState{}
+	Tracked = map[string]map[uint64]WatchedState{}
+}
+
+// MovieKey returns key of the movie by TMDB ID
+func MovieKey(id int) uint64 {
+	return xxhash.Sum64String(fmt.Sprintf("%d_%d_%d", MovieType, TMDBScraper, id))
+}
+
+// ShowKey returns key of the show by TMDB ID
+func ShowKey(id int) uint64 {
+	return xxhash.Sum64String(fmt.Sprintf("%d_%d_%d", ShowType, TMDBScraper, id))
+}
+
+// SeasonKey returns key of the season by show TMDB ID
+func SeasonKey(id int, season int) uint64 {
+	return xxhash.Sum64String(fmt.Sprintf("%d_%d_%d_%d", SeasonType, TMDBScraper, id, season))
+}
+
+// EpisodeKey returns key of the episode by show TMDB ID
+func EpisodeKey(id int, season, episode int) uint64 {
+	return xxhash.Sum64String(fmt.Sprintf("%d_%d_%d_%d_%d", EpisodeType, TMDBScraper, id, season, episode))
 }
 
 // GetWatchedMovieByTMDB checks whether item is watched
 func GetWatchedMovieByTMDB(id int) (ret WatchedState) {
-	return searchForKey(xxhash.Sum64String(fmt.Sprintf("%d_%d_%d", MovieType, TMDBScraper, id)))
+	return searchForKey(MovieKey(id))
 }
 
 // GetWatchedMovieByIMDB checks whether item is watched
@@ -65,7 +139,7 @@ func GetWatchedMovieByTrakt(id int) (ret WatchedState) {
 
 // GetWatchedShowByTMDB checks whether item is watched
 func GetWatchedShowByTMDB(id int) (ret WatchedState) {
-	return searchForKey(xxhash.Sum64String(fmt.Sprintf("%d_%d_%d", ShowType, TMDBScraper, id)))
+	return searchForKey(ShowKey(id))
 }
 
 // GetWatchedShowByTVDB checks whether item is watched
@@ -80,7 +154,7 @@ func GetWatchedShowByTrakt(id int) (ret WatchedState) {
 
 // GetWatchedSeasonByTMDB checks whether item is watched
 func GetWatchedSeasonByTMDB(id int, season int) (ret WatchedState) {
-	return searchForKey(xxhash.Sum64String(fmt.Sprintf("%d_%d_%d_%d", SeasonType, TMDBScraper, id, season)))
+	return searchForKey(SeasonKey(id, season))
 }
 
 // GetWatchedSeasonByTVDB checks whether item is watched
@@ -95,7 +169,7 @@ func GetWatchedSeasonByTrakt(id int, season int) (ret WatchedState) {
 
 // GetWatchedEpisodeByTMDB checks whether item is watched
 func GetWatchedEpisodeByTMDB(id int, season, episode int) (ret WatchedState) {
-	return searchForKey(xxhash.Sum64String(fmt.Sprintf("%d_%d_%d_%d_%d", EpisodeType, TMDBScraper, id, season, episode)))
+	return searchForKey(EpisodeKey(id, season, episode))
 }
 
 // GetWatchedEpisodeByTVDB checks whether item is watched
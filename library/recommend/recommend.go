@@ -0,0 +1,354 @@
+package recommend
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/anacrolix/missinggo/perf"
+	"github.com/op/go-logging"
+
+	"github.com/elgatito/elementum/cache"
+	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/database"
+	"github.com/elgatito/elementum/library/history"
+	"github.com/elgatito/elementum/library/playcount"
+	"github.com/elgatito/elementum/library/uid"
+	"github.com/elgatito/elementum/tmdb"
+)
+
+const (
+	// maxSeeds limits number of recently watched or added items, which are used to query TMDB
+	maxSeeds = 25
+	// maxItems limits number of stored recommendations
+	maxItems = 200
+
+	watchedWeight = 2.0
+	libraryWeight = 1.0
+
+	recommendationsWeight = 1.0
+	similarWeight         = 0.5
+
+	genreWeight  = 1.5
+	ratingWeight = 1.0
+	// minVotes is a number of votes, below which rating is trusted proportionally less
+	minVotes = 50
+)
+
+var log = logging.MustGetLogger("recommend")
+
+// Item is a recommended movie or show with its score
+type Item struct {
+	ID    int     `json:"id"`
+	Score float64 `json:"score"`
+}
+
+// seed is a watched or library item, which recommendations are built from
+type seed struct {
+	id     int
+	weight float64
+	dt     time.Time
+}
+
+// candidate is a movie or show, returned by TMDB for one or more seeds
+type candidate struct {
+	id          int
+	frequency   float64
+	genres      []int
+	voteAverage float64
+	voteCount   int
+}
+
+// source describes how to collect recommendations for a media type
+type source struct {
+	seeds   []*seed
+	genres  func(id int) []int
+	related map[float64]func(id int) []*tmdb.Entity
+}
+
+// Movies returns TMDB IDs of recommended movies, not watched and not hidden, best first
+func Movies(language string, hidden map[int]bool) []int {
+	cacheKey := fmt.Sprintf(cache.RecommendMoviesKey, config.Get().Profile, language)
+	items := cached(cacheKey, cache.RecommendMoviesExpire, func() []Item {
+		return build(&source{
+			seeds: movieSeeds(),
+			genres: func(id int) []int {
+				if m := tmdb.GetMovie(id, language); m != nil {
+					return genreIDs(m.Genres)
+				}
+				return nil
+			},
+			related: map[float64]func(id int) []*tmdb.Entity{
+				recommendationsWeight: func(id int) []*tmdb.Entity { return tmdb.RecommendedMovies(id, language) },
+				similarWeight:         func(id int) []*tmdb.Entity { return tmdb.SimilarMovies(id, language) },
+			},
+		})
+	})
+
+	return filter(items, func(id int) bool {
+		return hidden[id] || bool(playcount.GetWatchedMovieByTMDB(id))
+	})
+}
+
+// Shows returns TMDB IDs of recommended shows, not watched and not hidden, best first
+func Shows(language string, hidden map[int]bool) []int {
+	cacheKey := fmt.Sprintf(cache.RecommendShowsKey, config.Get().Profile, language)
+	items := cached(cacheKey, cache.RecommendShowsExpire, func() []Item {
+		return build(&source{
+			seeds: showSeeds(),
+			genres: func(id int) []int {
+				if s := tmdb.GetShow(id, language); s != nil {
+					return genreIDs(s.Genres)
+				}
+				return nil
+			},
+			related: map[float64]func(id int) []*tmdb.Entity{
+				recommendationsWeight: func(id int) []*tmdb.Entity { return tmdb.RecommendedShows(id, language) },
+				similarWeight:         func(id int) []*tmdb.Entity { return tmdb.SimilarShows(id, language) },
+			},
+		})
+	})
+
+	return filter(items, func(id int) bool {
+		return hidden[id] || bool(playcount.GetWatchedShowByTMDB(id))
+	})
+}
+
+// Clear removes stored recommendations, to rebuild them on next request
+func Clear() {
+	if cacheDB := database.GetCache(); cacheDB != nil {
+		cacheDB.DeleteWithPrefix(database.CommonBucket, []byte(cache.RecommendKey))
+	}
+}
+
+func cached(cacheKey string, expire time.Duration, builder func() []Item) (items []Item) {
+	cacheStore := cache.NewDBStore()
+	if err := cacheStore.Get(cacheKey, &items); err == nil {
+		return
+	}
+
+	items = builder()
+	if len(items) > 0 {
+		cacheStore.Set(cacheKey, &items, expire)
+	}
+	return
+}
+
+func filter(items []Item, isExcluded func(id int) bool) []int {
+	ret := make([]int, 0, len(items))
+	for _, i := range items {
+		if !isExcluded(i.ID) {
+			ret = append(ret, i.ID)
+		}
+	}
+	return ret
+}
+
+// build queries TMDB for most recent seeds and scores returned candidates
+// by how often they are returned, by overlap with genres of seeds and by rating
+func build(src *source) []Item {
+	defer perf.ScopeTimer()()
+
+	if len(src.seeds) == 0 {
+		return nil
+	}
+
+	started := time.Now()
+
+	known := map[int]bool{}
+	for _, s := range src.seeds {
+		known[s.id] = true
+	}
+
+	seeds := src.seeds
+	if len(seeds) > maxSeeds {
+		seeds = seeds[:maxSeeds]
+	}
+
+	var mu sync.Mutex
+	candidates := map[int]*candidate{}
+	taste := map[int]float64{}
+	totalWeight := 0.0
+
+	var wg sync.WaitGroup
+	for _, s := range seeds {
+		totalWeight += s.weight
+
+		wg.Add(1)
+		go func(s *seed) {
+			defer wg.Done()
+
+			genres := src.genres(s.id)
+			mu.Lock()
+			for _, g := range genres {
+				taste[g] += s.weight
+			}
+			mu.Unlock()
+		}(s)
+
+		for weight, get := range src.related {
+			wg.Add(1)
+			go func(s *seed, weight float64, get func(id int) []*tmdb.Entity) {
+				defer wg.Done()
+
+				entities := get(s.id)
+
+				mu.Lock()
+				defer mu.Unlock()
+				for _, e := range entities {
+					if e == nil || e.IsAdult || known[e.ID] {
+						continue
+					}
+
+					c, ok := candidates[e.ID]
+					if !ok {
+						c = &candidate{
+							id:          e.ID,
+							genres:      e.GenreIDs,
+							voteAverage: float64(e.VoteAverage),
+							voteCount:   e.VoteCount,
+						}
+						candidates[e.ID] = c
+					}
+					c.frequency += s.weight * weight
+				}
+			}(s, weight, get)
+		}
+	}
+	wg.Wait()
+
+	for g := range taste {
+		taste[g] /= totalWeight
+	}
+
+	items := make([]Item, 0, len(candidates))
+	for _, c := range candidates {
+		items = append(items, Item{ID: c.id, Score: c.score(taste)})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Score == items[j].Score {
+			return items[i].ID < items[j].ID
+		}
+		return items[i].Score > items[j].Score
+	})
+	if len(items) > maxItems {
+		items = items[:maxItems]
+	}
+
+	log.Debugf("Built %d recommendations from %d seeds in %s", len(items), len(seeds), time.Since(started))
+	return items
+}
+
+// score combines frequency of the candidate, its genre overlap with seeds (0..1)
+// and its rating (0..1), which is lowered for candidates with few votes
+func (c *candidate) score(taste map[int]float64) float64 {
+	overlap := 0.0
+	if len(c.genres) > 0 {
+		for _, g := range c.genres {
+			overlap += taste[g]
+		}
+		overlap /= float64(len(c.genres))
+	}
+
+	rating := c.voteAverage / 10 * math.Min(float64(c.voteCount)/minVotes, 1)
+
+	return c.frequency + genreWeight*overlap + ratingWeight*rating
+}
+
+// movieSeeds returns watched movies from local history and movies from Kodi library, watched and most recent first
+func movieSeeds() []*seed {
+	seeds := map[int]*seed{}
+	for _, h := range history.ByMediaType(history.MovieType) {
+		addSeed(seeds, h.TMDBID, watchedWeight, h.UpdatedAt)
+	}
+
+	l := uid.Get()
+	mu := l.GetMutex(uid.MoviesMutex)
+	mu.RLock()
+	for _, m := range l.Movies {
+		if m == nil || m.UIDs == nil {
+			continue
+		}
+		addSeed(seeds, m.UIDs.TMDB, libraryItemWeight(m.UIDs.Playcount, m.LastPlayed), latest(m.LastPlayed, m.DateAdded))
+	}
+	mu.RUnlock()
+
+	return sortSeeds(seeds)
+}
+
+// showSeeds returns shows with watched episodes from local history and shows from Kodi library, watched and most recent first
+func showSeeds() []*seed {
+	seeds := map[int]*seed{}
+	for _, mediaType := range []string{history.EpisodeType, history.SeasonType, history.ShowType} {
+		for _, h := range history.ByMediaType(mediaType) {
+			addSeed(seeds, h.ShowID, watchedWeight, h.UpdatedAt)
+		}
+	}
+
+	l := uid.Get()
+	mu := l.GetMutex(uid.ShowsMutex)
+	mu.RLock()
+	for _, s := range l.Shows {
+		if s == nil || s.UIDs == nil {
+			continue
+		}
+		addSeed(seeds, s.UIDs.TMDB, libraryItemWeight(s.UIDs.Playcount, s.LastPlayed), latest(s.LastPlayed, s.DateAdded))
+	}
+	mu.RUnlock()
+
+	return sortSeeds(seeds)
+}
+
+func addSeed(seeds map[int]*seed, id int, weight float64, dt time.Time) {
+	if id == 0 {
+		return
+	}
+
+	s, ok := seeds[id]
+	if !ok {
+		seeds[id] = &seed{id: id, weight: weight, dt: dt}
+		return
+	}
+	s.weight = math.Max(s.weight, weight)
+	s.dt = latest(s.dt, dt)
+}
+
+func sortSeeds(seeds map[int]*seed) []*seed {
+	ret := make([]*seed, 0, len(seeds))
+	for _, s := range seeds {
+		ret = append(ret, s)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].weight != ret[j].weight {
+			return ret[i].weight > ret[j].weight
+		}
+		return ret[i].dt.After(ret[j].dt)
+	})
+	return ret
+}
+
+func libraryItemWeight(count int, lastPlayed time.Time) float64 {
+	if count > 0 || !lastPlayed.IsZero() {
+		return watchedWeight
+	}
+	return libraryWeight
+}
+
+func latest(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func genreIDs(genres []*tmdb.IDName) []int {
+	ret := make([]int, 0, len(genres))
+	for _, g := range genres {
+		if g != nil {
+			ret = append(ret, g.ID)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,187 @@
+package library
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/elgatito/elementum/cache"
+	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/library/playcount"
+	"github.com/elgatito/elementum/library/uid"
+	"github.com/elgatito/elementum/tmdb"
+	"github.com/elgatito/elementum/tracker"
+	"github.com/elgatito/elementum/trakt"
+	"github.com/elgatito/elementum/xbmc"
+)
+
+var isTrackersRunning atomic.Bool
+
+// RefreshTrackers gets watched items and paused progress from enabled trackers.
+// Trakt is skipped, since it is synced by RefreshTrakt together with its lists.
+func RefreshTrackers() error {
+	if !isTrackersRunning.CompareAndSwap(false, true) {
+		return nil
+	}
+	defer isTrackersRunning.Store(false)
+
+	xbmcHost, _ := xbmc.GetLocalXBMCHost()
+	for _, t := range tracker.Get() {
+		if t.Name() == trakt.TrackerName {
+			continue
+		}
+
+		if err := refreshTracker(xbmcHost, t); err != nil {
+			log.Warningf("Sync of %s failed: %s", t.Name(), err)
+		}
+	}
+	return nil
+}
+
+// refreshTracker fetches lists of the tracker, that were changed after last sync
+func refreshTracker(xbmcHost *xbmc.XBMCHost, t tracker.Tracker) error {
+	started := time.Now()
+	defer func() {
+		log.Debugf("Sync of %s finished in %s", t.Name(), time.Since(started))
+	}()
+
+	activities, err := t.GetLastActivities()
+	if err != nil {
+		return err
+	} else if activities == nil {
+		return nil
+	}
+
+	cacheStore := cache.NewDBStore()
+	cacheKey := fmt.Sprintf(cache.TrackerActivitiesKey, t.Name(), config.Get().Profile)
+	previous := tracker.Activities{}
+	cacheStore.Get(cacheKey, &previous)
+
+	// Watched items are kept in memory, so they are fetched again after restart or profile switch
+	isFirstRun := !playcount.HasTracked(t.Name())
+
+	if isFirstRun || activities.MoviesWatched.After(previous.MoviesWatched) || activities.ShowsWatched.After(previous.ShowsWatched) {
+		if err := refreshTrackerWatched(xbmcHost, t); err != nil {
+			return err
+		}
+	}
+
+	if isFirstRun || activities.MoviesPaused.After(previous.MoviesPaused) {
+		if err := refreshTrackerPaused(xbmcHost, t, tracker.MovieType); err != nil {
+			return err
+		}
+	}
+	if isFirstRun || activities.ShowsPaused.After(previous.ShowsPaused) {
+		if err := refreshTrackerPaused(xbmcHost, t, tracker.EpisodeType); err != nil {
+			return err
+		}
+	}
+
+	return cacheStore.Set(cacheKey, activities, cache.TrackerActivitiesExpire)
+}
+
+// refreshTrackerWatched marks items, watched in the tracker, as watched in lists and in Kodi library
+func refreshTrackerWatched(xbmcHost *xbmc.XBMCHost, t tracker.Tracker) error {
+	watched := map[uint64]playcount.WatchedState{}
+	isLibraryWritable := xbmcHost != nil && !config.Get().LibraryReadOnly
+
+	for _, mediaType := range []string{tracker.MovieType, tracker.EpisodeType} {
+		items, err := t.GetWatched(mediaType)
+		if err != nil {
+			return err
+		}
+
+		for _, i := range items {
+			switch i.MediaType {
+			case tracker.MovieType:
+				watched[playcount.MovieKey(i.TMDBID)] = true
+				if !isLibraryWritable {
+					continue
+				}
+
+				if lm, err := uid.GetMovieByTMDB(i.TMDBID); err == nil && lm.UIDs != nil && isTrackerWatchedNewer(lm.IsWatched(), lm.LastPlayed, i.WatchedAt) {
+					lm.UIDs.Playcount = 1
+					xbmcHost.SetMovieWatchedWithDate(lm.UIDs.Kodi, 1, 0, 0, trackerWatchedAt(i))
+				}
+			case tracker.EpisodeType:
+				watched[playcount.EpisodeKey(i.ShowID, i.Season, i.Episode)] = true
+				if !isLibraryWritable {
+					continue
+				}
+
+				if ls, err := uid.GetShowByTMDB(i.ShowID); err == nil {
+					if e := ls.GetEpisode(i.Season, i.Episode); e != nil && e.UIDs != nil && isTrackerWatchedNewer(e.IsWatched(), e.LastPlayed, i.WatchedAt) {
+						e.UIDs.Playcount = 1
+						xbmcHost.SetEpisodeWatchedWithDate(e.UIDs.Kodi, 1, 0, 0, trackerWatchedAt(i))
+					}
+				}
+			}
+		}
+	}
+
+	playcount.SetTracked(t.Name(), watched)
+	log.Debugf("Got %d watched items from %s", len(watched), t.Name())
+	return nil
+}
+
+// refreshTrackerPaused sets progress, paused in the tracker, for library items, that were not played locally after that
+func refreshTrackerPaused(xbmcHost *xbmc.XBMCHost, t tracker.Tracker, mediaType string) error {
+	if xbmcHost == nil || config.Get().LibraryReadOnly {
+		return nil
+	}
+
+	paused, err := t.GetPaused(mediaType)
+	if err != nil {
+		return err
+	}
+
+	language := config.Get().Language
+	for _, p := range paused {
+		if p == nil || p.Item == nil || p.Progress <= 0 {
+			continue
+		}
+
+		switch p.Item.MediaType {
+		case tracker.MovieType:
+			lm, err := uid.GetMovieByTMDB(p.Item.TMDBID)
+			if err != nil || lm.UIDs == nil || lm.IsWatched() || !p.PausedAt.After(lm.LastPlayed) {
+				continue
+			}
+			if m := tmdb.GetMovie(p.Item.TMDBID, language); m != nil && m.Runtime > 0 {
+				runtime := m.Runtime * 60
+				xbmcHost.SetMovieProgressWithDate(lm.UIDs.Kodi, int(float64(runtime)*p.Progress/100), runtime, p.PausedAt)
+			}
+		case tracker.EpisodeType:
+			ls, err := uid.GetShowByTMDB(p.Item.ShowID)
+			if err != nil {
+				continue
+			}
+			e := ls.GetEpisode(p.Item.Season, p.Item.Episode)
+			if e == nil || e.UIDs == nil || e.IsWatched() || !p.PausedAt.After(e.LastPlayed) {
+				continue
+			}
+			if te := tmdb.GetEpisode(p.Item.ShowID, p.Item.Season, p.Item.Episode, language); te != nil && te.Runtime > 0 {
+				runtime := te.Runtime * 60
+				xbmcHost.SetEpisodeProgressWithDate(e.UIDs.Kodi, int(float64(runtime)*p.Progress/100), runtime, p.PausedAt)
+			}
+		}
+	}
+	return nil
+}
+
+// isTrackerWatchedNewer checks whether library item should be marked as watched,
+// items, played locally after they were watched in the tracker, are kept as is
+func isTrackerWatchedNewer(isWatched bool, lastPlayed, watchedAt time.Time) bool {
+	if isWatched {
+		return false
+	}
+	return lastPlayed.IsZero() || watchedAt.After(lastPlayed)
+}
+
+// trackerWatchedAt returns time, when item was watched, trackers may omit it for old history
+func trackerWatchedAt(i *tracker.Item) time.Time {
+	if i.WatchedAt.IsZero() {
+		return time.Now()
+	}
+	return i.WatchedAt
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/elgatito/elementum/config"
 	"github.com/elgatito/elementum/library/uid"
 	"github.com/elgatito/elementum/tmdb"
+	"github.com/elgatito/elementum/tracker"
 	"github.com/elgatito/elementum/trakt"
 	"github.com/elgatito/elementum/xbmc"
 )
@@ -33,6 +34,9 @@ func RefreshTrakt() error {
 		return err
 	}
 
+	// Other trackers are synced on the same schedule, but do not depend on Trakt settings
+	defer RefreshTrackers()
+
 	l := uid.Get()
 	if config.Get().TraktToken == "" || !config.Get().TraktSyncEnabled || (!config.Get().TraktSyncPlaybackEnabled && xbmcHost.PlayerIsPlaying()) {
 		// Even if sync is disabled, check if current Trakt auth is fine to use.
@@ -71,6 +75,12 @@ func RefreshTrakt() error {
 		return err
 	}
 
+	// Local playback progress does not change Trakt activities, so it is reconciled on every run
+	defer func() {
+		ReconcileTraktPaused(xbmcHost, MovieType, activities.MoviesPaused())
+		ReconcileTraktPaused(xbmcHost, EpisodeType, activities.EpisodesPaused())
+	}()
+
 	// If nothing changed from last check - skip everything
 	isFirstRun := !IsTraktInitialized || isKodiUpdated
 	if !activities.All() && !isFirstRun {
@@ -317,7 +327,7 @@ func refreshTraktMoviesWatched(xbmcHost *xbmc.XBMCHost, isRefreshNeeded bool) er
 	mu.Unlock()
 
 	if len(syncUnwatchMovies) > 0 {
-		if _, err := trakt.SetMultipleWatched(syncUnwatchMovies); err == nil {
+		if err := tracker.SetWatched(trakt.TrackerItems(syncUnwatchMovies), false); err == nil {
 			// Set cached entry to avoid running same item again
 			for _, i := range syncUnwatchMovies {
 				delete(lastPlaycount, i.KodiKey)
@@ -326,7 +336,7 @@ func refreshTraktMoviesWatched(xbmcHost *xbmc.XBMCHost, isRefreshNeeded bool) er
 		}
 	}
 	if len(syncWatchMovies) > 0 {
-		if _, err := trakt.SetMultipleWatched(syncWatchMovies); err == nil {
+		if err := tracker.SetWatched(trakt.TrackerItems(syncWatchMovies), true); err == nil {
 			// Set cached entry to avoid running same item again
 			for _, i := range syncWatchMovies {
 				syncPlaycount[i.KodiKey] = i.Watched
@@ -503,7 +513,7 @@ func refreshTraktShowsWatched(xbmcHost *xbmc.XBMCHost, isRefreshNeeded bool) err
 	mu.Unlock()
 
 	if len(syncUnwatchShows) > 0 {
-		if _, err := trakt.SetMultipleWatched(syncUnwatchShows); err == nil {
+		if err := tracker.SetWatched(trakt.TrackerItems(syncUnwatchShows), false); err == nil {
 			// Set cached entry to avoid running same item again
 			for _, i := range syncUnwatchShows {
 				delete(lastPlaycount, i.KodiKey)
@@ -512,7 +522,7 @@ func refreshTraktShowsWatched(xbmcHost *xbmc.XBMCHost, isRefreshNeeded bool) err
 		}
 	}
 	if len(syncWatchShows) > 0 {
-		if _, err := trakt.SetMultipleWatched(syncWatchShows); err == nil {
+		if err := tracker.SetWatched(trakt.TrackerItems(syncWatchShows), true); err == nil {
 			// Set cached entry to avoid running same item again
 			for _, i := range syncWatchShows {
 				syncPlaycount[i.KodiKey] = i.Watched
@@ -766,8 +776,18 @@ func RefreshTraktHidden(xbmcHost *xbmc.XBMCHost, itemType int, isRefreshNeeded b
 			log.Warningf("TraktSync: Got error from SyncShowsList for Watched Progress: %s", err)
 			return err
 		}
+		// hidden recommendations are used to filter local recommendations
+		if _, err := trakt.HiddenRecommendations("show", isRefreshNeeded); err != nil {
+			log.Warningf("TraktSync: Got error from HiddenRecommendations for shows: %s", err)
+			return err
+		}
 	} else if itemType == MovieType {
-		// calendar and recommendations for movies are handled on trakt side, and they are the only options
+		// calendar and recommendations for movies are handled on trakt side,
+		// hidden recommendations are used to filter local recommendations
+		if _, err := trakt.HiddenRecommendations("movie", isRefreshNeeded); err != nil {
+			log.Warningf("TraktSync: Got error from HiddenRecommendations for movies: %s", err)
+			return err
+		}
 	} else if itemType == SeasonType {
 		// looks like website does not allow to hide seasons, so we also can ignore them
 	}
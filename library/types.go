@@ -15,3 +15,24 @@ type removedEpisode struct {
 	Season   int
 	Episode  int
 }
+
+// CheckCategory identifies a kind of inconsistency, found by library check
+type CheckCategory string
+
+// CheckAction identifies a fix, that can be applied to a category of issues
+type CheckAction string
+
+// CheckIssue is a single inconsistency between strm files, Kodi library and Elementum database
+type CheckIssue struct {
+	Category  CheckCategory `json:"category"`
+	MediaType int           `json:"media_type"`
+	TMDBID    int           `json:"tmdb_id"`
+	KodiID    int           `json:"kodi_id"`
+	Title     string        `json:"title"`
+	Path      string        `json:"path"`
+}
+
+// CheckReport contains found inconsistencies, grouped by category
+type CheckReport struct {
+	Issues map[CheckCategory][]*CheckIssue `json:"issues"`
+}
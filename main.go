@@ -26,10 +26,17 @@ import (
 	"github.com/elgatito/elementum/broadcast"
 	"github.com/elgatito/elementum/config"
 	"github.com/elgatito/elementum/database"
+	"github.com/elgatito/elementum/dlna"
 	"github.com/elgatito/elementum/exit"
 	"github.com/elgatito/elementum/library"
 	"github.com/elgatito/elementum/lockfile"
+	"github.com/elgatito/elementum/monitor"
+	"github.com/elgatito/elementum/opensubtitles"
 	"github.com/elgatito/elementum/repository"
+	"github.com/elgatito/elementum/share"
+	"github.com/elgatito/elementum/simkl"
+	"github.com/elgatito/elementum/subtitles"
+	"github.com/elgatito/elementum/tracker"
 	"github.com/elgatito/elementum/trakt"
 	"github.com/elgatito/elementum/util"
 	"github.com/elgatito/elementum/util/ident"
@@ -203,7 +210,10 @@ func main() {
 		return
 	}
 
+	subtitles.Register(opensubtitles.NewProvider())
+
 	s := bittorrent.NewService()
+	dlnaServer := dlna.NewServer(s)
 
 	var shutdown = func(code int) {
 		if s == nil || s.Closer.IsSet() {
@@ -216,6 +226,7 @@ func main() {
 		s.Closer.Set()
 
 		log.Infof("Shutting down with code %d ...", code)
+		dlnaServer.Close()
 		library.CloseLibrary()
 		s.Close(true)
 
@@ -274,9 +285,11 @@ func main() {
 
 	http.Handle("/files/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Connection", "close")
-		handler := http.StripPrefix("/files/", http.FileServer(bittorrent.NewTorrentFS(s, r.Method)))
+		handler := http.StripPrefix("/files/", http.FileServer(bittorrent.NewClientTorrentFS(s, r)))
 		handler.ServeHTTP(w, r)
 	}))
+	http.Handle("/dlna/", dlnaServer)
+	http.Handle("/share/", share.NewHandler(s))
 
 	if config.Get().GreetingEnabled {
 		if xbmcHost, _ := xbmc.GetLocalXBMCHost(); xbmcHost != nil {
@@ -334,8 +347,13 @@ func main() {
 		xbmcHost.ResetRPC()
 	}()
 
+	tracker.Register(trakt.NewTracker(), simkl.NewTracker())
+
 	go library.Init()
 	go trakt.TokenRefreshHandler()
+	go trakt.QueueHandler()
+	go monitor.Init(s)
+	go dlnaServer.Start()
 	go db.MaintenanceRefreshHandler()
 	go cacheDB.MaintenanceRefreshHandler()
 	go util.FreeMemoryGC()
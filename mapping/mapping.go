@@ -0,0 +1,366 @@
+package mapping
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/anacrolix/missinggo/perf"
+	"github.com/op/go-logging"
+
+	"github.com/elgatito/elementum/cache"
+	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/database"
+	"github.com/elgatito/elementum/tmdb"
+	"github.com/elgatito/elementum/tvdb"
+	"github.com/elgatito/elementum/util"
+)
+
+const (
+	// if >= 80% of episodes have absolute numbers, assume it's because we need it
+	mixAbsoluteNumberPercentage = 0.8
+)
+
+var log = logging.MustGetLogger("mapping")
+
+var (
+	pendingMu sync.Mutex
+	pending   = map[string]*pendingBuild{}
+)
+
+// pendingBuild is a mapping, that is being built, so concurrent requests for the same show wait for it
+type pendingBuild struct {
+	done chan struct{}
+	m    *Show
+}
+
+// GetByID returns episodes mapping for a show with specific TMDB ID
+func GetByID(showID int) *Show {
+	if showID == 0 {
+		return nil
+	}
+
+	return Get(tmdb.GetShow(showID, config.Get().Language))
+}
+
+// Get returns episodes mapping for a show, cached version is used if available
+func Get(show *tmdb.Show) *Show {
+	if show == nil {
+		return nil
+	}
+
+	defer perf.ScopeTimer()()
+
+	var m *Show
+	cacheStore := cache.NewDBStore()
+	groupID := GetEpisodeGroup(show.ID)
+	key := fmt.Sprintf(cache.MappingShowKey, show.ID, groupID)
+	if err := cacheStore.Get(key, &m); err == nil && m != nil {
+		return m
+	}
+
+	return buildOnce(show, groupID, key)
+}
+
+// buildOnce builds mapping and stores it in cache, concurrent calls for the same show and group share the result
+func buildOnce(show *tmdb.Show, groupID, key string) *Show {
+	pendingMu.Lock()
+	if p, ok := pending[key]; ok {
+		pendingMu.Unlock()
+		<-p.done
+		return p.m
+	}
+	p := &pendingBuild{done: make(chan struct{})}
+	pending[key] = p
+	pendingMu.Unlock()
+
+	defer func() {
+		pendingMu.Lock()
+		delete(pending, key)
+		pendingMu.Unlock()
+		close(p.done)
+	}()
+
+	p.m = build(show, groupID)
+	cache.NewDBStore().Set(key, p.m, cache.MappingShowExpire)
+	return p.m
+}
+
+// GetEpisodeGroup returns TMDB episode group, selected for a show
+func GetEpisodeGroup(showID int) string {
+	var s database.ShowSettings
+	if err := database.GetStormDB().One("ID", showID, &s); err != nil {
+		return ""
+	}
+
+	return s.EpisodeGroup
+}
+
+// Delete removes cached mapping of a show for an episode group, so it will be rebuilt on next request
+func Delete(showID int, groupID string) {
+	cache.NewDBStore().Delete(fmt.Sprintf(cache.MappingShowKey, showID, groupID))
+}
+
+func build(show *tmdb.Show, groupID string) *Show {
+	m := &Show{
+		TMDBID:   show.ID,
+		Episodes: []*Episode{},
+	}
+	if show.ExternalIDs != nil {
+		m.TVDBID = util.StrInterfaceToInt(show.ExternalIDs.TVDBID)
+	}
+
+	// TVDB is used for absolute numbers, that are mostly needed for Anime
+	var tvdbShow *tvdb.Show
+	if m.TVDBID > 0 && show.IsAnime() {
+		if s, err := tvdb.GetShow(m.TVDBID, config.Get().Language); err == nil && s != nil {
+			tvdbShow = s
+			m.TVDBName = s.SeriesName
+		}
+	}
+
+	seasons := make(tmdb.SeasonList, 0, len(show.Seasons))
+	for _, s := range show.Seasons {
+		if s != nil && s.EpisodeCount > 0 {
+			seasons = append(seasons, s)
+		}
+	}
+	sort.Slice(seasons, func(i, j int) bool {
+		return seasons[i].Season < seasons[j].Season
+	})
+
+	regularEpisodes := 0
+	tvdbAbsolute := map[*Episode]int{}
+
+	for _, s := range seasons {
+		season := tmdb.GetSeason(show.ID, s.Season, config.Get().Language, len(show.Seasons), false)
+		if season == nil {
+			continue
+		}
+
+		// If season starts with episode number > 1, then TMDB uses absolute numbers
+		// for this season and we need to adjust them to get season numbers.
+		tillSeason := show.EpisodesTillSeason(s.Season)
+		isAbsoluteSeason := s.Season > 0 && season.GetFirstEpisodeNumber() > 1
+
+		for _, episode := range season.Episodes {
+			if episode == nil {
+				continue
+			}
+
+			number := Number{Season: s.Season, Episode: episode.EpisodeNumber}
+			e := &Episode{
+				TMDB:  number,
+				TVDB:  number,
+				Scene: number,
+			}
+
+			if s.Season > 0 {
+				regularEpisodes++
+				if isAbsoluteSeason {
+					e.Absolute = episode.EpisodeNumber
+					e.Scene.Episode = episode.EpisodeNumber - tillSeason
+				} else {
+					e.Absolute = episode.EpisodeNumber + tillSeason
+				}
+			}
+
+			if te := findTVDBEpisode(tvdbShow, episode); te != nil {
+				e.TVDB = Number{Season: te.SeasonNumber, Episode: te.EpisodeNumber}
+				if te.AbsoluteNumber > 0 && s.Season > 0 {
+					tvdbAbsolute[e] = te.AbsoluteNumber
+				}
+			}
+
+			m.Episodes = append(m.Episodes, e)
+		}
+	}
+
+	if regularEpisodes > 0 {
+		m.AbsoluteCoverage = float64(len(tvdbAbsolute)) / float64(regularEpisodes)
+	}
+	if m.AbsoluteCoverage >= mixAbsoluteNumberPercentage {
+		for e, an := range tvdbAbsolute {
+			e.Absolute = an
+		}
+	}
+
+	m.applyGroup(groupID)
+	m.applyOffline()
+
+	log.Debugf("Built mapping for show %d with %d episodes, absolute coverage: %.2f", m.TMDBID, len(m.Episodes), m.AbsoluteCoverage)
+	return m
+}
+
+// applyGroup fills Group numbers from TMDB episode group
+func (m *Show) applyGroup(groupID string) {
+	if groupID == "" {
+		return
+	}
+
+	group := tmdb.GetEpisodeGroup(groupID, config.Get().Language)
+	if group == nil {
+		log.Warningf("Could not get episode group %s for show %d", groupID, m.TMDBID)
+		return
+	}
+
+	m.EpisodeGroup = groupID
+	for _, g := range group.Groups {
+		if g == nil {
+			continue
+		}
+
+		for i, ge := range g.Episodes {
+			if ge == nil {
+				continue
+			}
+			if e := m.Find(ge.SeasonNumber, ge.EpisodeNumber); e != nil {
+				e.Group = Number{Season: g.Order, Episode: i + 1}
+			}
+		}
+	}
+}
+
+// findTVDBEpisode matches TMDB episode with TVDB episode by the air date, falling back to same numbers
+func findTVDBEpisode(tvdbShow *tvdb.Show, episode *tmdb.Episode) *tvdb.Episode {
+	if tvdbShow == nil || episode == nil {
+		return nil
+	}
+
+	if episode.AirDate != "" {
+		var matched *tvdb.Episode
+		matches := 0
+		for _, s := range tvdbShow.Seasons {
+			if s == nil {
+				continue
+			}
+			for _, e := range s.Episodes {
+				if e != nil && e.FirstAired == episode.AirDate {
+					matched = e
+					matches++
+				}
+			}
+		}
+
+		if matches == 1 {
+			return matched
+		}
+	}
+
+	if s := tvdbShow.GetSeason(episode.SeasonNumber); s != nil {
+		return s.GetEpisode(episode.EpisodeNumber)
+	}
+
+	return nil
+}
+
+// GetEpisodeGroup returns TMDB episode group, used in the mapping
+func (m *Show) GetEpisodeGroup() string {
+	if m == nil {
+		return ""
+	}
+	return m.EpisodeGroup
+}
+
+// Find returns mapping for an episode with TMDB numbers
+func (m *Show) Find(season, episode int) *Episode {
+	return m.FindBy(OrderingTMDB, season, episode)
+}
+
+// FindBy returns mapping for an episode, numbered in specific ordering.
+// Season number is ignored for absolute ordering.
+func (m *Show) FindBy(o Ordering, season, episode int) *Episode {
+	if m == nil {
+		return nil
+	}
+
+	n := Number{Season: season, Episode: episode}
+	for _, e := range m.Episodes {
+		switch o {
+		case OrderingAbsolute:
+			if e.Absolute == episode {
+				return e
+			}
+		case OrderingGroup:
+			if e.Group == n {
+				return e
+			}
+		default:
+			if e.Number(o) == n {
+				return e
+			}
+		}
+	}
+
+	return nil
+}
+
+// Next returns episode that follows provided TMDB episode, specials are skipped
+func (m *Show) Next(season, episode int) *Episode {
+	if m == nil {
+		return nil
+	}
+
+	found := false
+	for _, e := range m.Episodes {
+		if found && e.TMDB.Season > 0 {
+			return e
+		}
+		if e.TMDB.Season == season && e.TMDB.Episode == episode {
+			found = true
+		}
+	}
+
+	return nil
+}
+
+// Number returns season/episode pair in specific ordering
+func (e *Episode) Number(o Ordering) Number {
+	switch o {
+	case OrderingTVDB:
+		return e.TVDB
+	case OrderingScene:
+		return e.Scene
+	case OrderingAbsolute:
+		return Number{Season: 1, Episode: e.Absolute}
+	case OrderingGroup:
+		if e.Group.Episode > 0 {
+			return e.Group
+		}
+		return e.TMDB
+	default:
+		return e.TMDB
+	}
+}
+
+// Preferred returns numbers from episode group, if show has one selected, or numbers in specific ordering
+func (e *Episode) Preferred(o Ordering) Number {
+	if e.Group.Episode > 0 {
+		return e.Group
+	}
+	return e.Number(o)
+}
+
+// Alternatives returns unique season/episode pairs, that differ from TMDB numbers.
+// Episode group numbers go first, as they are explicitly selected for a show.
+func (e *Episode) Alternatives() []Number {
+	ret := []Number{}
+	for _, n := range []Number{e.Group, e.Scene, e.TVDB} {
+		if n.Episode <= 0 || n == e.TMDB {
+			continue
+		}
+
+		exists := false
+		for _, r := range ret {
+			if r == n {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			ret = append(ret, n)
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,199 @@
+package mapping
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/elgatito/elementum/cache"
+	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/database"
+	"github.com/elgatito/elementum/tmdb"
+	"github.com/elgatito/elementum/xbmc"
+)
+
+const testShowID = 1001
+
+// offlineFixture moves TMDB season 2 into scene season 1 and overrides numbers of a single episode
+const offlineFixture = `[
+	{
+		"tmdb_id": 1001,
+		"tvdb_id": 2002,
+		"seasons": [{"tmdb_season": 2, "scene_season": 1, "episode_offset": 3}],
+		"episodes": [{"tmdb": {"season": 1, "episode": 2}, "tvdb": {"season": 3, "episode": 7}, "absolute": 20}]
+	}
+]`
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "mapping")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	conf := config.Get()
+	conf.Info = &xbmc.AddonInfo{Path: dir, Profile: dir}
+	conf.ProfilePath = dir
+
+	resources := filepath.Join(dir, "resources", "mapping")
+	if err := os.MkdirAll(resources, 0755); err == nil {
+		err = os.WriteFile(filepath.Join(resources, offlineFileName), []byte(offlineFixture), 0644)
+	}
+	if err == nil {
+		_, err = database.InitStormDB(conf)
+	}
+	if err == nil {
+		_, err = database.InitCacheDB(conf)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// testShow returns mapping of two seasons with three episodes each, as it is built from TMDB
+func testShow() *Show {
+	m := &Show{TMDBID: testShowID}
+	for season := 1; season <= 2; season++ {
+		for episode := 1; episode <= 3; episode++ {
+			n := Number{Season: season, Episode: episode}
+			m.Episodes = append(m.Episodes, &Episode{TMDB: n, TVDB: n, Scene: n, Absolute: (season-1)*3 + episode})
+		}
+	}
+	return m
+}
+
+func TestApplyOffline(t *testing.T) {
+	m := testShow()
+	m.applyOffline()
+
+	if m.TVDBID != 2002 {
+		t.Errorf("Expected TVDB ID from offline mapping, got %d", m.TVDBID)
+	}
+
+	tests := []struct {
+		tmdb     Number
+		tvdb     Number
+		scene    Number
+		absolute int
+	}{
+		{Number{1, 1}, Number{1, 1}, Number{1, 1}, 1},
+		{Number{1, 2}, Number{3, 7}, Number{1, 2}, 20},
+		{Number{2, 1}, Number{2, 1}, Number{1, 4}, 4},
+		{Number{2, 3}, Number{2, 3}, Number{1, 6}, 6},
+	}
+
+	for _, test := range tests {
+		e := m.Find(test.tmdb.Season, test.tmdb.Episode)
+		if e == nil {
+			t.Errorf("Episode %+v not found", test.tmdb)
+			continue
+		}
+		if e.TVDB != test.tvdb || e.Scene != test.scene || e.Absolute != test.absolute {
+			t.Errorf("Episode %+v: expected tvdb %+v, scene %+v, absolute %d, got %+v", test.tmdb, test.tvdb, test.scene, test.absolute, e)
+		}
+	}
+}
+
+func TestFindBy(t *testing.T) {
+	m := testShow()
+	m.applyOffline()
+	m.Find(2, 1).Group = Number{Season: 5, Episode: 1}
+
+	tests := []struct {
+		ordering Ordering
+		number   Number
+		expected *Number
+	}{
+		{OrderingTMDB, Number{2, 2}, &Number{2, 2}},
+		{OrderingTVDB, Number{3, 7}, &Number{1, 2}},
+		{OrderingScene, Number{1, 5}, &Number{2, 2}},
+		{OrderingScene, Number{2, 2}, nil},
+		{OrderingAbsolute, Number{0, 20}, &Number{1, 2}},
+		{OrderingAbsolute, Number{9, 6}, &Number{2, 3}},
+		{OrderingGroup, Number{5, 1}, &Number{2, 1}},
+		{OrderingGroup, Number{1, 1}, nil},
+	}
+
+	for _, test := range tests {
+		e := m.FindBy(test.ordering, test.number.Season, test.number.Episode)
+		switch {
+		case test.expected == nil && e != nil:
+			t.Errorf("Ordering %d, %+v: expected no episode, got %+v", test.ordering, test.number, e.TMDB)
+		case test.expected != nil && e == nil:
+			t.Errorf("Ordering %d, %+v: expected %+v, got no episode", test.ordering, test.number, *test.expected)
+		case test.expected != nil && e.TMDB != *test.expected:
+			t.Errorf("Ordering %d, %+v: expected %+v, got %+v", test.ordering, test.number, *test.expected, e.TMDB)
+		}
+	}
+
+	if n := m.Find(2, 1).Number(OrderingAbsolute); n != (Number{1, 4}) {
+		t.Errorf("Expected absolute number in the first season, got %+v", n)
+	}
+	if n := m.Find(1, 3).Number(OrderingGroup); n != (Number{1, 3}) {
+		t.Errorf("Episode without group should keep TMDB numbers, got %+v", n)
+	}
+	if n := m.Find(2, 1).Preferred(OrderingScene); n != (Number{5, 1}) {
+		t.Errorf("Episode group numbers should be preferred, got %+v", n)
+	}
+	if e := m.Next(1, 3); e == nil || e.TMDB != (Number{2, 1}) {
+		t.Errorf("Expected next episode to be S02E01, got %+v", e)
+	}
+
+	alternatives := m.Find(2, 1).Alternatives()
+	if len(alternatives) != 2 || alternatives[0] != (Number{5, 1}) || alternatives[1] != (Number{1, 4}) {
+		t.Errorf("Expected group and scene alternatives, got %+v", alternatives)
+	}
+
+	var empty *Show
+	if empty.Find(1, 1) != nil || empty.Next(1, 1) != nil || empty.GetEpisodeGroup() != "" {
+		t.Errorf("Nil mapping should not find episodes")
+	}
+}
+
+func TestGetByEpisodeGroup(t *testing.T) {
+	show := &tmdb.Show{Entity: tmdb.Entity{ID: testShowID}}
+	cacheStore := cache.NewDBStore()
+
+	// Mappings are cached per episode group, so cached entries are returned without building
+	for _, groupID := range []string{"", "group"} {
+		m := testShow()
+		m.EpisodeGroup = groupID
+		if err := cacheStore.Set(fmt.Sprintf(cache.MappingShowKey, testShowID, groupID), m, cache.MappingShowExpire); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if m := Get(show); m == nil || m.GetEpisodeGroup() != "" {
+		t.Fatalf("Expected mapping without episode group, got %+v", m)
+	}
+
+	if err := database.GetStormDB().Save(&database.ShowSettings{ID: testShowID, EpisodeGroup: "group"}); err != nil {
+		t.Fatal(err)
+	}
+	if GetEpisodeGroup(testShowID) != "group" {
+		t.Fatalf("Expected episode group to be stored for the show")
+	}
+	if m := Get(show); m == nil || m.GetEpisodeGroup() != "group" {
+		t.Fatalf("Expected mapping of the selected episode group, got %+v", m)
+	}
+
+	Delete(testShowID, "group")
+	var m *Show
+	if err := cacheStore.Get(fmt.Sprintf(cache.MappingShowKey, testShowID, "group"), &m); err == nil {
+		t.Errorf("Expected mapping of the episode group to be deleted")
+	}
+	if err := cacheStore.Get(fmt.Sprintf(cache.MappingShowKey, testShowID, ""), &m); err != nil {
+		t.Errorf("Mapping without episode group should be kept: %s", err)
+	}
+
+	if Get(nil) != nil || GetByID(0) != nil {
+		t.Errorf("Expected no mapping without a show")
+	}
+}
@@ -0,0 +1,90 @@
+package mapping
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/goccy/go-json"
+
+	"github.com/elgatito/elementum/config"
+)
+
+const offlineFileName = "episodes_mapping.json"
+
+var (
+	offlineOnce  sync.Once
+	offlineShows map[int]*offlineShow
+)
+
+// loadOffline reads mapping file, shipped with the addon, and then the one from user's profile,
+// so that user's entries override shipped ones.
+func loadOffline() {
+	offlineShows = map[int]*offlineShow{}
+
+	for _, path := range []string{config.AddonResource("mapping", offlineFileName), filepath.Join(config.Get().ProfilePath, offlineFileName)} {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		var shows []*offlineShow
+		if err := json.Unmarshal(b, &shows); err != nil {
+			log.Warningf("Could not parse mapping file %s: %s", path, err)
+			continue
+		}
+
+		for _, s := range shows {
+			if s != nil && s.TMDBID > 0 {
+				offlineShows[s.TMDBID] = s
+			}
+		}
+		log.Infof("Loaded %d show mappings from %s", len(shows), path)
+	}
+}
+
+func (m *Show) applyOffline() {
+	offlineOnce.Do(loadOffline)
+
+	o, ok := offlineShows[m.TMDBID]
+	if !ok {
+		return
+	}
+
+	if o.TVDBID > 0 {
+		m.TVDBID = o.TVDBID
+	}
+
+	for _, rule := range o.Seasons {
+		if rule == nil {
+			continue
+		}
+
+		for _, e := range m.Episodes {
+			if e.TMDB.Season == rule.TMDBSeason {
+				e.Scene = Number{Season: rule.SceneSeason, Episode: e.TMDB.Episode + rule.EpisodeOffset}
+			}
+		}
+	}
+
+	for _, oe := range o.Episodes {
+		if oe == nil {
+			continue
+		}
+
+		e := m.Find(oe.TMDB.Season, oe.TMDB.Episode)
+		if e == nil {
+			continue
+		}
+
+		if oe.TVDB.Episode > 0 {
+			e.TVDB = oe.TVDB
+		}
+		if oe.Scene.Episode > 0 {
+			e.Scene = oe.Scene
+		}
+		if oe.Absolute > 0 {
+			e.Absolute = oe.Absolute
+		}
+	}
+}
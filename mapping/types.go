@@ -0,0 +1,61 @@
+package mapping
+
+// Ordering identifies numbering scheme used for show episodes
+type Ordering int
+
+const (
+	// OrderingTMDB is a default TMDB aired order
+	OrderingTMDB Ordering = iota
+	// OrderingTVDB is an order, used by TVDB
+	OrderingTVDB
+	// OrderingScene is an order, used by release groups
+	OrderingScene
+	// OrderingAbsolute is a continuous numbering, used mostly for Anime
+	OrderingAbsolute
+	// OrderingGroup is an order of TMDB episode group, selected for a show
+	OrderingGroup
+)
+
+// Number is a season/episode pair in specific ordering
+type Number struct {
+	Season  int `json:"season"`
+	Episode int `json:"episode"`
+}
+
+// Episode contains numbers of the same episode in all known orderings
+type Episode struct {
+	TMDB     Number `json:"tmdb"`
+	TVDB     Number `json:"tvdb"`
+	Scene    Number `json:"scene"`
+	Group    Number `json:"group"`
+	Absolute int    `json:"absolute"`
+}
+
+// Show contains episodes mapping for a single show
+type Show struct {
+	TMDBID   int        `json:"tmdb_id"`
+	TVDBID   int        `json:"tvdb_id"`
+	TVDBName string     `json:"tvdb_name"`
+	Episodes []*Episode `json:"episodes"`
+
+	// EpisodeGroup is TMDB episode group, that was used to fill Group numbers
+	EpisodeGroup string `json:"episode_group"`
+
+	// AbsoluteCoverage is a share of episodes that got absolute number from TVDB
+	AbsoluteCoverage float64 `json:"absolute_coverage"`
+}
+
+// offlineShow is an entry of the offline mapping file
+type offlineShow struct {
+	TMDBID   int              `json:"tmdb_id"`
+	TVDBID   int              `json:"tvdb_id"`
+	Seasons  []*offlineSeason `json:"seasons"`
+	Episodes []*Episode       `json:"episodes"`
+}
+
+// offlineSeason describes a rule that moves whole TMDB season into another scene season
+type offlineSeason struct {
+	TMDBSeason    int `json:"tmdb_season"`
+	SceneSeason   int `json:"scene_season"`
+	EpisodeOffset int `json:"episode_offset"`
+}
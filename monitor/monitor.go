@@ -0,0 +1,147 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/asdine/storm"
+	"github.com/op/go-logging"
+
+	"github.com/elgatito/elementum/bittorrent"
+	"github.com/elgatito/elementum/broadcast"
+	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/database"
+	"github.com/elgatito/elementum/xbmc"
+)
+
+const (
+	// StateWanted is set for items, that are still searched for
+	StateWanted = iota
+	// StateDone is set for items, that were added for download
+	StateDone
+	// StateReplacing is set for items, which better release is downloaded to replace the current one
+	StateReplacing
+)
+
+const (
+	// checkInterval is a period of checking monitored items
+	checkInterval = 30 * time.Minute
+	// startDelay gives Kodi and search providers time to start before first check
+	startDelay = 5 * time.Minute
+)
+
+var (
+	log = logging.MustGetLogger("monitor")
+
+	// ErrNoFile is returned when torrent has no file, matching monitored item
+	ErrNoFile = errors.New("No matching file found in torrent")
+)
+
+// Init starts periodic checks of monitored items
+func Init(s *bittorrent.Service) {
+	closer := broadcast.Closer.C()
+
+	select {
+	case <-closer:
+		return
+	case <-time.After(startDelay):
+	}
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		check(s)
+
+		select {
+		case <-closer:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func check(s *bittorrent.Service) {
+	xbmcHost, err := xbmc.GetLocalXBMCHost()
+	if xbmcHost == nil || err != nil {
+		log.Debugf("Skipping monitor check, Kodi is not available: %v", err)
+		return
+	}
+
+	checkShows(s, xbmcHost)
+	checkMovies(s, xbmcHost)
+}
+
+// retryInterval returns time to wait between searches for the same item
+func retryInterval() time.Duration {
+	if hours := config.Get().AutoDownloadRetry; hours > 0 {
+		return time.Duration(hours) * time.Hour
+	}
+	return 6 * time.Hour
+}
+
+// getItem returns stored state of monitored item, or a new item
+func getItem(id string) *database.MonitorItem {
+	item := &database.MonitorItem{}
+	if err := database.GetStormDB().One("ID", id, item); err != nil {
+		if err != storm.ErrNotFound {
+			log.Debugf("Cannot read monitor item %s: %s", id, err)
+		}
+		return &database.MonitorItem{ID: id, State: StateWanted}
+	}
+	return item
+}
+
+// saveItem stores state of monitored item
+func saveItem(item *database.MonitorItem) {
+	if err := database.GetStormDB().Save(item); err != nil {
+		log.Warningf("Cannot save monitor item %s: %s", item.ID, err)
+	}
+}
+
+// isDue checks whether item should be searched now
+func isDue(item *database.MonitorItem, interval time.Duration) bool {
+	return item.State == StateWanted && (item.CheckedAt.IsZero() || time.Since(item.CheckedAt) >= interval)
+}
+
+// download adds torrent in download mode and selects the file, returned by pick.
+// Torrent is removed on failure only if it was added here, existing torrents are kept as they are.
+func download(s *bittorrent.Service, link *bittorrent.TorrentFile, item *database.MonitorItem, contentType string, pick func(t *bittorrent.Torrent) *bittorrent.File) (*bittorrent.Torrent, error) {
+	t := s.GetTorrentByHash(link.InfoHash)
+	isAdded := false
+	if t == nil {
+		var err error
+		t, err = s.AddTorrent(nil, bittorrent.AddOptions{URI: link.URI, Paused: false, DownloadStorage: config.StorageFile, FirstTime: true, AddedTime: time.Now()})
+		if err != nil {
+			return nil, err
+		}
+		isAdded = true
+	}
+
+	drop := func() {
+		if isAdded {
+			s.RemoveTorrent(nil, t, bittorrent.RemoveOptions{ForceDrop: true, ForceDelete: true})
+		}
+	}
+
+	if !t.HasMetadata() {
+		t.WaitForMetadata(nil, t.InfoHash())
+	}
+	if !t.HasMetadata() {
+		drop()
+		return nil, fmt.Errorf("Could not fetch metadata for %s", link.Name)
+	}
+
+	file := pick(t)
+	if file == nil {
+		drop()
+		return nil, ErrNoFile
+	}
+
+	database.GetStorm().UpdateBTItem(t.InfoHash(), item.TMDBID, contentType, []string{file.Path}, "", item.ShowID, item.Season, item.Episode)
+	t.DownloadFile(file)
+	t.SaveDBFiles()
+
+	return t, nil
+}
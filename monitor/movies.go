@@ -0,0 +1,250 @@
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/anacrolix/missinggo/perf"
+	"github.com/zeebo/bencode"
+
+	"github.com/elgatito/elementum/bittorrent"
+	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/database"
+	"github.com/elgatito/elementum/library"
+	"github.com/elgatito/elementum/providers"
+	"github.com/elgatito/elementum/tmdb"
+	"github.com/elgatito/elementum/util/ip"
+	"github.com/elgatito/elementum/xbmc"
+)
+
+// upgradeInterval returns time to wait between searches for a better movie release
+func upgradeInterval() time.Duration {
+	if hours := config.Get().AutoUpgradeInterval; hours > 0 {
+		return time.Duration(hours) * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// checkMovies searches for better releases of downloaded library movies
+func checkMovies(s *bittorrent.Service, xbmcHost *xbmc.XBMCHost) {
+	defer perf.ScopeTimer()()
+
+	for _, li := range library.GetUpgradedMovies() {
+		item := getItem(fmt.Sprintf("movie-%d", li.ID))
+		item.MediaType = library.MovieType
+		item.TMDBID = li.ID
+
+		if item.State == StateReplacing {
+			checkMovieReplace(s, xbmcHost, item)
+		} else {
+			checkMovieUpgrade(s, xbmcHost, item, li.QualityCutoff)
+		}
+	}
+}
+
+func checkMovieUpgrade(s *bittorrent.Service, xbmcHost *xbmc.XBMCHost, item *database.MonitorItem, cutoff int) {
+	current := getAssignedRelease(item.TMDBID)
+	if current == nil {
+		// Movie was never downloaded, nothing to upgrade
+		return
+	}
+
+	setItemRelease(item, current)
+	if isCutoffReached(current, cutoff) {
+		if item.State != StateDone {
+			item.State = StateDone
+			item.DoneAt = time.Now()
+			saveItem(item)
+		}
+		return
+	}
+
+	// Cutoff could be raised after it was reached
+	if item.State == StateDone {
+		item.State = StateWanted
+	}
+	if !isDue(item, upgradeInterval()) {
+		return
+	}
+
+	item.Attempts++
+	item.CheckedAt = time.Now()
+	defer saveItem(item)
+
+	movie := tmdb.GetMovie(item.TMDBID, config.Get().Language)
+	if movie == nil {
+		return
+	}
+
+	searchers := providers.GetMovieSearchers(xbmcHost, ip.GetHTTPHost(xbmcHost))
+	if len(searchers) == 0 {
+		log.Warningf("No movie searchers available to search for %s", movie.GetTitle())
+		return
+	}
+
+	profile := GetQualityProfile()
+	var link *bittorrent.TorrentFile
+	for _, t := range providers.SearchMovieSilent(xbmcHost, searchers, movie, false) {
+		if t.InfoHash != current.InfoHash && t.Resolution <= cutoff && isBetterRelease(t, current) && profile.Passes(t) {
+			link = t
+			break
+		}
+	}
+	if link == nil {
+		log.Infof("No better release found for %s, current is %s", movie.GetTitle(), current.Name)
+		return
+	}
+
+	t, err := download(s, link, item, "movie", func(t *bittorrent.Torrent) *bittorrent.File {
+		_, biggest, err := t.GetCandidateFiles(nil)
+		if err != nil || biggest < 0 || biggest >= len(t.GetFiles()) {
+			return nil
+		}
+		return t.GetFiles()[biggest]
+	})
+	if err != nil {
+		log.Warningf("Could not download %s from %s: %s", movie.GetTitle(), link.Name, err)
+		return
+	}
+
+	log.Infof("Upgrading %s from %s to %s", movie.GetTitle(), current.Name, link.Name)
+	item.State = StateReplacing
+	item.PreviousHash = current.InfoHash
+	item.InfoHash = t.InfoHash()
+	setItemRelease(item, link)
+
+	xbmcHost.Notify("Elementum", fmt.Sprintf("LOCALIZE[30723];;%s", movie.GetTitle()), config.AddonIcon())
+}
+
+// checkMovieReplace waits for better release to finish downloading and then removes the old one
+func checkMovieReplace(s *bittorrent.Service, xbmcHost *xbmc.XBMCHost, item *database.MonitorItem) {
+	t := s.GetTorrentByHash(item.InfoHash)
+	if t == nil {
+		log.Infof("Upgrade torrent %s was removed, searching again", item.InfoHash)
+		item.State = StateWanted
+		item.InfoHash = item.PreviousHash
+		item.PreviousHash = ""
+		saveItem(item)
+		return
+	}
+	if t.GetProgress() < 100 {
+		return
+	}
+
+	// Metadata of previous release is removed with its link, so name is taken before that
+	old := s.GetTorrentByHash(item.PreviousHash)
+	previousName := ""
+	if old == nil && item.PreviousHash != "" && item.PreviousHash != t.InfoHash() {
+		previousName = getReleaseName(item.PreviousHash)
+	}
+
+	meta := t.UpdateMetadataTitle(t.Title(), t.GetMetadata())
+	database.GetStorm().AddTorrentLink(strconv.Itoa(item.TMDBID), t.InfoHash(), meta, true)
+
+	if old != nil && old.InfoHash() != t.InfoHash() {
+		s.RemoveTorrent(nil, old, bittorrent.RemoveOptions{IsReplaced: true})
+	} else if path := releasePath(config.Get().DownloadPath, previousName); path != "" {
+		removeReleaseFiles(path)
+	}
+
+	log.Infof("Replaced release of movie %d with %s", item.TMDBID, t.Name())
+	item.State = StateWanted
+	item.DoneAt = time.Now()
+	item.PreviousHash = ""
+	saveItem(item)
+
+	xbmcHost.Notify("Elementum", fmt.Sprintf("LOCALIZE[30724];;%s", t.Name()), config.AddonIcon())
+}
+
+// getAssignedRelease returns release, that is assigned to a movie
+func getAssignedRelease(tmdbID int) *bittorrent.TorrentFile {
+	var ti database.TorrentAssignItem
+	var tm database.TorrentAssignMetadata
+	if err := database.GetStormDB().One("TmdbID", tmdbID, &ti); err != nil {
+		return nil
+	}
+	if err := database.GetStormDB().One("InfoHash", ti.InfoHash, &tm); err != nil || len(tm.Metadata) == 0 {
+		return nil
+	}
+
+	torrent := &bittorrent.TorrentFile{}
+	if tm.Metadata[0] == '{' {
+		torrent.UnmarshalJSON(tm.Metadata)
+	} else {
+		torrent.LoadFromBytes(tm.Metadata)
+	}
+	torrent.InfoHash = ti.InfoHash
+
+	return torrent
+}
+
+// getReleaseName returns torrent name of the release from assigned metadata or torrent history,
+// it is the name of file or directory, created for the release in download path
+func getReleaseName(infoHash string) string {
+	var tm database.TorrentAssignMetadata
+	var th database.TorrentHistory
+
+	var metadata []byte
+	if err := database.GetStormDB().One("InfoHash", infoHash, &tm); err == nil {
+		metadata = tm.Metadata
+	} else if err := database.GetStormDB().One("InfoHash", infoHash, &th); err == nil {
+		metadata = th.Metadata
+	}
+	// JSON metadata has release name from a provider, that can differ from torrent name
+	if len(metadata) == 0 || metadata[0] == '{' {
+		return ""
+	}
+
+	var raw bittorrent.TorrentFileRaw
+	if err := bencode.DecodeBytes(metadata, &raw); err != nil {
+		return ""
+	}
+	name, _ := raw.Info["name"].(string)
+	return name
+}
+
+// releasePath returns path of the release in download path, or empty string if name can point outside of it
+func releasePath(downloadPath, name string) string {
+	if downloadPath == "" || downloadPath == "." || name == "" || name == "." || name == ".." || name != filepath.Base(name) {
+		return ""
+	}
+	return filepath.Join(downloadPath, name)
+}
+
+// removeReleaseFiles deletes files of the release, that is not in the session anymore
+func removeReleaseFiles(path string) {
+	if _, err := os.Stat(path); err != nil {
+		return
+	}
+
+	log.Infof("Deleting files of replaced release at %s", path)
+	if err := os.RemoveAll(path); err != nil {
+		log.Warningf("Could not delete files of replaced release at %s: %s", path, err)
+	}
+}
+
+func setItemRelease(item *database.MonitorItem, t *bittorrent.TorrentFile) {
+	item.Name = t.Name
+	item.Resolution = t.Resolution
+	item.RipType = t.RipType
+	item.VideoCodec = t.VideoCodec
+}
+
+// isCutoffReached checks whether release has wanted resolution and best rip type
+func isCutoffReached(t *bittorrent.TorrentFile, cutoff int) bool {
+	return t.Resolution >= cutoff && t.RipType >= bittorrent.RipBluRay
+}
+
+// isBetterRelease compares releases by resolution, then by rip type, then by video codec
+func isBetterRelease(t, current *bittorrent.TorrentFile) bool {
+	if t.Resolution != current.Resolution {
+		return t.Resolution > current.Resolution
+	}
+	if t.RipType != current.RipType {
+		return t.RipType > current.RipType
+	}
+	return t.VideoCodec > current.VideoCodec
+}
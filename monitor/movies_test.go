@@ -0,0 +1,78 @@
+package monitor
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/elgatito/elementum/bittorrent"
+)
+
+func release(resolution, ripType, videoCodec int) *bittorrent.TorrentFile {
+	return &bittorrent.TorrentFile{Resolution: resolution, RipType: ripType, VideoCodec: videoCodec}
+}
+
+func TestIsBetterRelease(t *testing.T) {
+	tests := []struct {
+		name     string
+		release  *bittorrent.TorrentFile
+		current  *bittorrent.TorrentFile
+		expected bool
+	}{
+		{"higher resolution", release(bittorrent.Resolution1080p, bittorrent.RipWeb, bittorrent.CodecH264), release(bittorrent.Resolution720p, bittorrent.RipBluRay, bittorrent.CodecH265), true},
+		{"lower resolution", release(bittorrent.Resolution720p, bittorrent.RipBluRay, bittorrent.CodecH265), release(bittorrent.Resolution1080p, bittorrent.RipWeb, bittorrent.CodecH264), false},
+		{"better rip type", release(bittorrent.Resolution1080p, bittorrent.RipBluRay, bittorrent.CodecH264), release(bittorrent.Resolution1080p, bittorrent.RipWeb, bittorrent.CodecH265), true},
+		{"worse rip type", release(bittorrent.Resolution1080p, bittorrent.RipHDTV, bittorrent.CodecH265), release(bittorrent.Resolution1080p, bittorrent.RipWeb, bittorrent.CodecH264), false},
+		{"better codec", release(bittorrent.Resolution1080p, bittorrent.RipWeb, bittorrent.CodecH265), release(bittorrent.Resolution1080p, bittorrent.RipWeb, bittorrent.CodecH264), true},
+		{"same release", release(bittorrent.Resolution1080p, bittorrent.RipWeb, bittorrent.CodecH264), release(bittorrent.Resolution1080p, bittorrent.RipWeb, bittorrent.CodecH264), false},
+	}
+
+	for _, test := range tests {
+		if got := isBetterRelease(test.release, test.current); got != test.expected {
+			t.Errorf("%s: expected %t, got %t", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestIsCutoffReached(t *testing.T) {
+	tests := []struct {
+		name     string
+		release  *bittorrent.TorrentFile
+		cutoff   int
+		expected bool
+	}{
+		{"cutoff resolution and bluray", release(bittorrent.Resolution1080p, bittorrent.RipBluRay, bittorrent.CodecH264), bittorrent.Resolution1080p, true},
+		{"higher resolution and bluray", release(bittorrent.Resolution4k, bittorrent.RipBluRay, bittorrent.CodecH265), bittorrent.Resolution1080p, true},
+		{"cutoff resolution and web", release(bittorrent.Resolution1080p, bittorrent.RipWeb, bittorrent.CodecH265), bittorrent.Resolution1080p, false},
+		{"lower resolution and bluray", release(bittorrent.Resolution720p, bittorrent.RipBluRay, bittorrent.CodecH264), bittorrent.Resolution1080p, false},
+	}
+
+	for _, test := range tests {
+		if got := isCutoffReached(test.release, test.cutoff); got != test.expected {
+			t.Errorf("%s: expected %t, got %t", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestReleasePath(t *testing.T) {
+	downloadPath := filepath.Join("downloads", "elementum")
+	tests := []struct {
+		downloadPath string
+		name         string
+		expected     string
+	}{
+		{downloadPath, "Movie.2020.1080p.BluRay", filepath.Join(downloadPath, "Movie.2020.1080p.BluRay")},
+		{downloadPath, "Movie.2020.mkv", filepath.Join(downloadPath, "Movie.2020.mkv")},
+		{downloadPath, "", ""},
+		{downloadPath, ".", ""},
+		{downloadPath, "..", ""},
+		{downloadPath, filepath.Join("..", "Movie"), ""},
+		{".", "Movie", ""},
+		{"", "Movie", ""},
+	}
+
+	for _, test := range tests {
+		if got := releasePath(test.downloadPath, test.name); got != test.expected {
+			t.Errorf("releasePath(%q, %q): expected %q, got %q", test.downloadPath, test.name, test.expected, got)
+		}
+	}
+}
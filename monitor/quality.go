@@ -0,0 +1,77 @@
+package monitor
+
+import (
+	"strings"
+
+	"github.com/elgatito/elementum/bittorrent"
+	"github.com/elgatito/elementum/config"
+)
+
+// QualityProfile defines limits for links, that can be downloaded automatically
+type QualityProfile struct {
+	MinResolution int
+	MaxResolution int
+	MinSeeds      int64
+	MaxSize       uint64
+	Exclude       []string
+}
+
+// GetQualityProfile returns profile from addon settings
+func GetQualityProfile() *QualityProfile {
+	conf := config.Get()
+
+	p := &QualityProfile{
+		MinResolution: conf.AutoDownloadMinResolution,
+		MaxResolution: conf.AutoDownloadMaxResolution,
+		MinSeeds:      int64(conf.AutoDownloadMinSeeds),
+	}
+	if conf.AutoDownloadMaxSize > 0 {
+		p.MaxSize = uint64(conf.AutoDownloadMaxSize)
+	}
+	for _, word := range strings.Split(conf.AutoDownloadExclude, ",") {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			p.Exclude = append(p.Exclude, word)
+		}
+	}
+
+	return p
+}
+
+// Passes checks whether link fits into the profile
+func (p *QualityProfile) Passes(t *bittorrent.TorrentFile) bool {
+	if t == nil || t.URI == "" {
+		return false
+	}
+	if p.MinResolution > 0 && t.Resolution < p.MinResolution {
+		return false
+	}
+	if p.MaxResolution > 0 && t.Resolution > p.MaxResolution {
+		return false
+	}
+	if t.Seeds < p.MinSeeds {
+		return false
+	}
+	if p.MaxSize > 0 && t.SizeParsed > p.MaxSize {
+		return false
+	}
+
+	name := strings.ToLower(t.Name)
+	for _, word := range p.Exclude {
+		if strings.Contains(name, word) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Pick returns first link, that passes the profile.
+// Links are expected to be sorted by search preferences already.
+func (p *QualityProfile) Pick(torrents []*bittorrent.TorrentFile) *bittorrent.TorrentFile {
+	for _, t := range torrents {
+		if p.Passes(t) {
+			return t
+		}
+	}
+	return nil
+}
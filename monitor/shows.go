@@ -0,0 +1,157 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/anacrolix/missinggo/perf"
+
+	"github.com/elgatito/elementum/bittorrent"
+	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/library"
+	"github.com/elgatito/elementum/mapping"
+	"github.com/elgatito/elementum/providers"
+	"github.com/elgatito/elementum/tmdb"
+	"github.com/elgatito/elementum/util"
+	"github.com/elgatito/elementum/util/ip"
+	"github.com/elgatito/elementum/xbmc"
+)
+
+// episodeLookback limits how old episodes are searched, so following a show
+// does not download the whole back catalogue
+const episodeLookback = 7 * 24 * time.Hour
+
+// checkShows searches for aired episodes of followed shows
+func checkShows(s *bittorrent.Service, xbmcHost *xbmc.XBMCHost) {
+	defer perf.ScopeTimer()()
+
+	for _, showID := range library.GetFollowedShows() {
+		checkShow(s, xbmcHost, showID)
+	}
+}
+
+func checkShow(s *bittorrent.Service, xbmcHost *xbmc.XBMCHost, showID int) {
+	show := tmdb.GetShow(showID, config.Get().Language)
+	if show == nil || show.LastEpisodeToAir == nil {
+		return
+	}
+
+	for _, seasonNumber := range dueSeasons(show) {
+		season := tmdb.GetSeason(showID, seasonNumber, config.Get().Language, len(show.Seasons), true)
+		if season == nil {
+			continue
+		}
+
+		for _, episode := range season.Episodes {
+			if episode == nil || !isEpisodeDue(episode) {
+				continue
+			}
+
+			checkEpisode(s, xbmcHost, show, season, episode)
+		}
+	}
+}
+
+// dueSeasons returns seasons up to the last aired one, that can have episodes aired within lookback period.
+// Season is skipped when the following season premiered before that period,
+// so a finale and a premiere of the next season, aired in the same week, are both found.
+func dueSeasons(show *tmdb.Show) (ret []int) {
+	last := show.LastEpisodeToAir.SeasonNumber
+	cutoff := time.Now().Add(-time.Duration(config.Get().AutoDownloadDelay)*time.Hour - episodeLookback)
+
+	seasons := make([]*tmdb.Season, 0, len(show.Seasons))
+	for _, season := range show.Seasons {
+		if season != nil && season.Season <= last && season.AirDate != "" {
+			seasons = append(seasons, season)
+		}
+	}
+	sort.Slice(seasons, func(i, j int) bool { return seasons[i].Season < seasons[j].Season })
+
+	for i, season := range seasons {
+		if i+1 < len(seasons) {
+			if next, err := time.Parse(time.DateOnly, seasons[i+1].AirDate); err == nil && next.Before(cutoff) {
+				continue
+			}
+		}
+		ret = append(ret, season.Season)
+	}
+
+	if len(ret) == 0 {
+		ret = append(ret, last)
+	}
+	return
+}
+
+// isEpisodeDue checks whether episode has aired at least configured delay ago
+func isEpisodeDue(episode *tmdb.Episode) bool {
+	airDate, isAired := util.AirDateWithAiredCheck(episode.AirDate, time.DateOnly, config.Get().ShowEpisodesOnReleaseDay)
+	if !isAired {
+		return false
+	}
+
+	delay := time.Duration(config.Get().AutoDownloadDelay) * time.Hour
+	since := time.Since(airDate)
+	return since >= delay && since <= delay+episodeLookback
+}
+
+func checkEpisode(s *bittorrent.Service, xbmcHost *xbmc.XBMCHost, show *tmdb.Show, season *tmdb.Season, episode *tmdb.Episode) {
+	item := getItem(fmt.Sprintf("episode-%d", episode.ID))
+	if !isDue(item, retryInterval()) {
+		return
+	}
+
+	item.MediaType = library.EpisodeType
+	item.TMDBID = episode.ID
+	item.ShowID = show.ID
+	item.Season = episode.SeasonNumber
+	item.Episode = episode.EpisodeNumber
+
+	title := fmt.Sprintf("%s S%02dE%02d", show.GetName(), episode.SeasonNumber, episode.EpisodeNumber)
+	if t := s.HasTorrentByEpisode(show.ID, item.Season, item.Episode); t != nil {
+		log.Infof("Episode %s is already in torrents list", title)
+		item.State = StateDone
+		item.DoneAt = time.Now()
+		item.InfoHash = t.InfoHash()
+		saveItem(item)
+		return
+	}
+
+	item.Attempts++
+	item.CheckedAt = time.Now()
+	defer saveItem(item)
+
+	log.Infof("Searching for %s, attempt %d", title, item.Attempts)
+	searchers := providers.GetEpisodeSearchers(xbmcHost, ip.GetHTTPHost(xbmcHost))
+	if len(searchers) == 0 {
+		log.Warningf("No episode searchers available to search for %s", title)
+		return
+	}
+
+	link := GetQualityProfile().Pick(providers.SearchEpisodeSilent(xbmcHost, searchers, show, season, episode))
+	if link == nil {
+		log.Infof("No links for %s pass quality profile, retrying in %s", title, retryInterval())
+		return
+	}
+
+	m := mapping.Get(show)
+	t, err := download(s, link, item, "episode", func(t *bittorrent.Torrent) *bittorrent.File {
+		if f := t.GetMappedEpisodeFile(m.Find(item.Season, item.Episode), show.IsAnime()); f != nil {
+			return f
+		}
+		return t.GetNextEpisodeFile(item.Season, item.Episode)
+	})
+	if err != nil {
+		log.Warningf("Could not download %s from %s: %s", title, link.Name, err)
+		return
+	}
+
+	log.Infof("Downloading %s from %s", title, link.Name)
+	item.State = StateDone
+	item.DoneAt = time.Now()
+	item.InfoHash = t.InfoHash()
+	item.Name = link.Name
+	item.Resolution = link.Resolution
+
+	xbmcHost.Notify("Elementum", fmt.Sprintf("LOCALIZE[30720];;%s", title), config.AddonIcon())
+}
@@ -6,18 +6,14 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
 	"os"
-	"path"
 	"path/filepath"
-	"sort"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/elgatito/elementum/config"
 	"github.com/elgatito/elementum/proxy"
-	"github.com/elgatito/elementum/tmdb"
+	"github.com/elgatito/elementum/subtitles"
 	"github.com/elgatito/elementum/util"
 	"github.com/elgatito/elementum/util/ident"
 	"github.com/elgatito/elementum/util/reqapi"
@@ -144,10 +140,12 @@ func ensureLogin() error {
 
 func payloadToParams(payload SearchPayload, page int) napping.Params {
 	params := napping.Params{
-		"query":   payload.Query,
-		"imdb_id": payload.IMDBId,
-		"tmdb_id": payload.TMDBId,
-		"year":    strconv.Itoa(payload.Year),
+		"query":          payload.Query,
+		"moviehash":      payload.Hash,
+		"imdb_id":        payload.IMDBId,
+		"tmdb_id":        payload.TMDBId,
+		"parent_tmdb_id": payload.ParentTMDBId,
+		"year":           strconv.Itoa(payload.Year),
 
 		"season_number":  strconv.Itoa(payload.Season),
 		"episode_number": strconv.Itoa(payload.Episode),
@@ -229,22 +227,6 @@ func DownloadSubtitles(id string) (resp DownloadResponse, err error) {
 	return
 }
 
-func DoSearch(payloads []SearchPayload, preferredLanguage string) ([]SearchResponseData, error) {
-	results, err := SearchSubtitles(payloads)
-	if err != nil {
-		return nil, err
-	}
-
-	if preferredLanguage != "" {
-		sort.Slice(results, func(i, j int) bool {
-			id := strings.ToLower(results[i].Attributes.Language) == preferredLanguage
-			return id
-		})
-	}
-
-	return results, nil
-}
-
 func checkError(req *reqapi.Request) {
 	if req.ResponseStatusCode == 406 {
 		if xbmcHost, _ := xbmc.GetLocalXBMCHost(); xbmcHost != nil {
@@ -272,14 +254,9 @@ func DoDownload(id string) (*os.File, string, string, error) {
 	}
 	defer resp.Body.Close()
 
-	subtitlesPath := filepath.Join(config.Get().DownloadPath, "Subtitles")
-	if config.Get().DownloadPath == "." {
-		subtitlesPath = filepath.Join(config.Get().TemporaryPath, "Subtitles")
-	}
-	if _, errStat := os.Stat(subtitlesPath); os.IsNotExist(errStat) {
-		if errMk := os.Mkdir(subtitlesPath, 0755); errMk != nil {
-			return nil, "", "", fmt.Errorf("Unable to create Subtitles folder")
-		}
+	subtitlesPath, err := subtitles.Dir()
+	if err != nil {
+		return nil, "", "", err
 	}
 
 	outPath := filepath.Join(subtitlesPath, downloadResp.FileName)
@@ -293,198 +270,3 @@ func DoDownload(id string) (*os.File, string, string, error) {
 
 	return outFile, downloadResp.FileName, outPath, nil
 }
-
-// GetPayloads ...
-func GetPayloads(xbmcHost *xbmc.XBMCHost, searchString string, languages []string, preferredLanguage string, showID int, playingFile string) ([]SearchPayload, string) {
-	log.Debugf("GetPayloads: %s; %#v; %s; %s", searchString, languages, preferredLanguage, playingFile)
-
-	// First of all, we get Subtitles language settings from Kodi
-	// (there is a separate setting for that) in Player settings.
-	if !config.Get().OSDBAutoLanguage && config.Get().OSDBLanguage != "" {
-		languages = []string{config.Get().OSDBLanguage}
-	}
-
-	// If there is preferred language - we should use it
-	if preferredLanguage != "" && preferredLanguage != "Unknown" && !contains(languages, preferredLanguage) {
-		languages = append([]string{preferredLanguage}, languages...)
-		preferredLanguage = strings.ToLower(preferredLanguage)
-	} else {
-		preferredLanguage = ""
-	}
-
-	labels := xbmcHost.InfoLabels(
-		"VideoPlayer.Title",
-		"VideoPlayer.OriginalTitle",
-		"VideoPlayer.Year",
-		"VideoPlayer.TVShowTitle",
-		"VideoPlayer.Season",
-		"VideoPlayer.Episode",
-		"VideoPlayer.IMDBNumber",
-	)
-	log.Debugf("Fetched VideoPlayer labels: %#v", labels)
-
-	for i, lang := range languages {
-		if lang == "Portuguese (Brazil)" {
-			languages[i] = "pt-br"
-		} else {
-			languages[i] = xbmcHost.ConvertLanguage(lang, xbmc.Iso639_1)
-		}
-	}
-
-	payloads := []SearchPayload{}
-	if searchString != "" {
-		payloads = append(payloads, SearchPayload{
-			Query:     searchString,
-			Languages: strings.Join(languages, ","),
-		})
-	} else if labels != nil {
-		// If player ListItem has IMDBNumber specified - we try to get TMDB item from it.
-		// If not - we can use localized show/movie name - which is not always found on OSDB.
-		if strings.HasPrefix(labels["VideoPlayer.IMDBNumber"], "tt") {
-			if labels["VideoPlayer.TVShowTitle"] != "" {
-				r := tmdb.Find(labels["VideoPlayer.IMDBNumber"], "imdb_id")
-				if r != nil && len(r.TVResults) > 0 {
-					labels["VideoPlayer.TVShowTitle"] = r.TVResults[0].OriginalName
-				}
-			} else {
-				r := tmdb.Find(labels["VideoPlayer.IMDBNumber"], "imdb_id")
-				if r != nil && len(r.MovieResults) > 0 {
-					labels["VideoPlayer.OriginalTitle"] = r.MovieResults[0].OriginalTitle
-				}
-			}
-		}
-
-		var err error
-		if showID != 0 {
-			err = appendEpisodePayloads(showID, labels, &payloads)
-		} else {
-			err = appendMoviePayloads(labels, &payloads)
-		}
-
-		if err != nil {
-			if !strings.HasPrefix(playingFile, "http://") && !strings.HasPrefix(playingFile, "https://") {
-				appendLocalFilePayloads(playingFile, &payloads)
-			} else {
-				appendRemoteFilePayloads(playingFile, &payloads)
-			}
-		}
-	}
-
-	for i, payload := range payloads {
-		payload.Languages = strings.Join(languages, ",")
-		payloads[i] = payload
-	}
-
-	return payloads, xbmcHost.ConvertLanguage(preferredLanguage, xbmc.Iso639_1)
-}
-
-func appendLocalFilePayloads(playingFile string, payloads *[]SearchPayload) error {
-	file, err := os.Open(playingFile)
-	if err != nil {
-		log.Debug(err)
-		return err
-	}
-	defer file.Close()
-
-	hashPayload := SearchPayload{}
-	if h, err := HashFile(file); err == nil {
-		hashPayload.Hash = h
-	}
-	hashPayload.Query = strings.Replace(filepath.Base(playingFile), filepath.Ext(playingFile), "", -1)
-	if hashPayload.Query != "" {
-		*payloads = append(*payloads, hashPayload)
-		return nil
-	}
-
-	return fmt.Errorf("Cannot collect local information")
-}
-
-func appendRemoteFilePayloads(playingFile string, payloads *[]SearchPayload) error {
-	u, err := url.Parse(playingFile)
-	if u == nil {
-		return err
-	}
-
-	f := path.Base(u.Path)
-	q := strings.Replace(filepath.Base(f), filepath.Ext(f), "", -1)
-
-	if q != "" {
-		*payloads = append(*payloads, SearchPayload{Query: q})
-		return nil
-	}
-
-	return fmt.Errorf("Cannot collect local information")
-}
-
-func appendMoviePayloads(labels map[string]string, payloads *[]SearchPayload) error {
-	title := labels["VideoPlayer.OriginalTitle"]
-	if title == "" {
-		title = labels["VideoPlayer.Title"]
-	}
-	imdb := labels["VideoPlayer.IMDBNumber"]
-	if imdb != "" {
-		imdb = imdb[2:]
-	}
-
-	if title != "" {
-		year, _ := strconv.Atoi(labels["VideoPlayer.Year"])
-
-		*payloads = append(*payloads, SearchPayload{
-			Type: "movie",
-
-			Query:  fmt.Sprintf("%s %d", title, year),
-			Year:   year,
-			IMDBId: imdb,
-		})
-		return nil
-	}
-
-	return fmt.Errorf("Cannot collect movie information")
-}
-
-func appendEpisodePayloads(showID int, labels map[string]string, payloads *[]SearchPayload) error {
-	season := -1
-	if labels["VideoPlayer.Season"] != "" {
-		if s, err := strconv.Atoi(labels["VideoPlayer.Season"]); err == nil {
-			season = s
-		}
-	}
-	episode := -1
-	if labels["VideoPlayer.Episode"] != "" {
-		if e, err := strconv.Atoi(labels["VideoPlayer.Episode"]); err == nil {
-			episode = e
-		}
-	}
-
-	if season >= 0 && episode > 0 {
-		title := labels["VideoPlayer.TVShowTitle"]
-		if showID != 0 {
-			// Trying to get Original name of the show, otherwise we will likely fail to find anything.
-			show := tmdb.GetShow(showID, config.Get().Language)
-			if show != nil {
-				title = show.OriginalName
-			}
-		}
-
-		searchString := fmt.Sprintf("%s S%02dE%02d", title, season, episode)
-		*payloads = append(*payloads, SearchPayload{
-			Type: "episode",
-
-			Query:   searchString,
-			Season:  season,
-			Episode: episode,
-		})
-		return nil
-	}
-
-	return fmt.Errorf("Cannot collect episode information")
-}
-
-func contains(s []string, e string) bool {
-	for _, a := range s {
-		if a == e {
-			return true
-		}
-	}
-	return false
-}
@@ -0,0 +1,103 @@
+package opensubtitles
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/elgatito/elementum/subtitles"
+)
+
+// Provider implements subtitles.SubtitleProvider with OpenSubtitles API
+type Provider struct{}
+
+// NewProvider ...
+func NewProvider() *Provider {
+	return &Provider{}
+}
+
+// Name ...
+func (p *Provider) Name() string {
+	return "opensubtitles"
+}
+
+// SearchByHash ...
+func (p *Provider) SearchByHash(q *subtitles.Query) ([]*subtitles.Subtitle, error) {
+	return p.search(q, SearchPayload{
+		Hash:  q.Hash,
+		Query: strings.TrimSuffix(q.FileName, filepath.Ext(q.FileName)),
+	})
+}
+
+// SearchByIDs ...
+func (p *Provider) SearchByIDs(q *subtitles.Query) ([]*subtitles.Subtitle, error) {
+	payload := SearchPayload{Type: q.Type}
+	if q.Type == "episode" {
+		// Show IDs are passed as parent IDs, IMDB number of the player can belong to the episode
+		if q.TMDBID == 0 {
+			return nil, nil
+		}
+		payload.ParentTMDBId = strconv.Itoa(q.TMDBID)
+		payload.Season = q.Season
+		payload.Episode = q.Episode
+	} else {
+		payload.IMDBId = strings.TrimPrefix(q.IMDBID, "tt")
+		if q.TMDBID != 0 {
+			payload.TMDBId = strconv.Itoa(q.TMDBID)
+		}
+	}
+
+	return p.search(q, payload)
+}
+
+// SearchByQuery ...
+func (p *Provider) SearchByQuery(q *subtitles.Query) ([]*subtitles.Subtitle, error) {
+	payload := SearchPayload{
+		Query: q.SearchText(),
+	}
+	if q.Text == "" {
+		payload.Type = q.Type
+		payload.Year = q.Year
+		payload.Season = q.Season
+		payload.Episode = q.Episode
+	}
+
+	return p.search(q, payload)
+}
+
+// Download ...
+func (p *Provider) Download(s *subtitles.Subtitle) (string, error) {
+	_, _, path, err := DoDownload(s.ID)
+	return path, err
+}
+
+func (p *Provider) search(q *subtitles.Query, payload SearchPayload) ([]*subtitles.Subtitle, error) {
+	payload.Languages = strings.Join(q.Languages, ",")
+
+	results, err := SearchSubtitles([]SearchPayload{payload})
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*subtitles.Subtitle, 0, len(results))
+	for _, r := range results {
+		if len(r.Attributes.Files) == 0 {
+			continue
+		}
+
+		file := r.Attributes.Files[0]
+		ret = append(ret, &subtitles.Subtitle{
+			Provider:        p.Name(),
+			ID:              strconv.Itoa(file.FileID),
+			FileName:        file.FileName,
+			Language:        strings.ToLower(r.Attributes.Language),
+			Format:          "srt",
+			Release:         r.Attributes.Release,
+			Rating:          r.Attributes.Ratings,
+			Downloads:       r.Attributes.DownloadCount,
+			HashMatch:       r.Attributes.MovieHashMatch,
+			HearingImpaired: r.Attributes.HearingImpaired,
+		})
+	}
+	return ret, nil
+}
@@ -94,15 +94,16 @@ type SearchResponse struct {
 }
 
 type SearchPayload struct {
-	Type      string `json:"type"`
-	Year      int    `json:"year"`
-	Season    int    `json:"season"`
-	Episode   int    `json:"episode"`
-	Query     string `json:"query"`
-	Hash      string `json:"moviehash"`
-	IMDBId    string `json:"imdb_id"`
-	TMDBId    string `json:"tmdb_id"`
-	Languages string `json:"languages"`
+	Type         string `json:"type"`
+	Year         int    `json:"year"`
+	Season       int    `json:"season"`
+	Episode      int    `json:"episode"`
+	Query        string `json:"query"`
+	Hash         string `json:"moviehash"`
+	IMDBId       string `json:"imdb_id"`
+	TMDBId       string `json:"tmdb_id"`
+	ParentTMDBId string `json:"parent_tmdb_id"`
+	Languages    string `json:"languages"`
 }
 
 type DownloadResponse struct {
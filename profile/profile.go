@@ -0,0 +1,228 @@
+package profile
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asdine/storm"
+	"github.com/asdine/storm/q"
+	"github.com/op/go-logging"
+
+	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/database"
+	"github.com/elgatito/elementum/library"
+	"github.com/elgatito/elementum/library/history"
+	"github.com/elgatito/elementum/library/playcount"
+	"github.com/elgatito/elementum/library/recommend"
+	"github.com/elgatito/elementum/trakt"
+	"github.com/elgatito/elementum/xbmc"
+)
+
+// defaultName is a storage name of the default profile, that is active when no profile is selected
+const defaultName = "default"
+
+var log = logging.MustGetLogger("profile")
+
+// settingKeys are addon settings, that are kept separately for each profile
+var settingKeys = []string{
+	"trakt_username",
+	"trakt_token",
+	"trakt_refresh_token",
+	"trakt_token_expiry",
+	"simkl_token",
+	"language",
+	"second_language",
+}
+
+var (
+	// ErrExists is returned when profile with the same name already exists
+	ErrExists = errors.New("Profile already exists")
+	// ErrNotFound is returned when profile does not exist
+	ErrNotFound = errors.New("Profile not found")
+	// ErrInvalidName is returned for empty or reserved profile names
+	ErrInvalidName = errors.New("Invalid profile name")
+	// ErrActive is returned when active or default profile is removed
+	ErrActive = errors.New("Cannot remove active or default profile")
+)
+
+func init() {
+	trakt.ProfileToken = storedTraktToken
+}
+
+// Current returns name of active profile, empty for default profile
+func Current() string {
+	return config.Get().Profile
+}
+
+// List returns names of all profiles, default profile goes first as an empty name
+func List() []string {
+	var profiles []database.Profile
+	if err := database.GetStormDB().AllByIndex("CreatedAt", &profiles); err != nil && err != storm.ErrNotFound {
+		log.Warningf("Cannot read profiles: %s", err)
+	}
+
+	names := []string{""}
+	for _, p := range profiles {
+		if p.Name != defaultName {
+			names = append(names, p.Name)
+		}
+	}
+	return names
+}
+
+// Create adds new profile with empty settings
+func Create(name string) error {
+	name = strings.TrimSpace(name)
+	if !isValidName(name) {
+		return ErrInvalidName
+	}
+
+	var stored database.Profile
+	if err := database.GetStormDB().One("Name", name, &stored); err == nil {
+		return ErrExists
+	}
+
+	return database.GetStormDB().Save(&database.Profile{
+		Name:      name,
+		Settings:  map[string]string{},
+		CreatedAt: time.Now(),
+	})
+}
+
+// CacheKey returns key of profile data in common cache bucket, data of default profile keeps plain key
+func CacheKey(name, key string) string {
+	if name == "" {
+		return key
+	}
+	return cacheKeyPrefix(name) + key
+}
+
+// cacheKeyPrefix returns prefix of all cached keys of the profile.
+// Name is closed with a separator, that is not allowed in names, so prefix of "a" does not match keys of "a.b".
+func cacheKeyPrefix(name string) string {
+	return "profile|" + name + "|"
+}
+
+// Remove deletes profile with its watch history, search history, queued Trakt requests and cached data, like menus
+func Remove(name string) error {
+	if name == "" || name == Current() {
+		return ErrActive
+	}
+
+	db := database.GetStormDB()
+
+	var stored database.Profile
+	if err := db.One("Name", name, &stored); err != nil {
+		return ErrNotFound
+	}
+
+	if err := history.RemoveProfile(name); err != nil && err != storm.ErrNotFound {
+		log.Warningf("Cannot remove watch history of profile %s: %s", name, err)
+	}
+	if err := db.Select(q.Eq("Profile", name)).Delete(&database.QueryHistory{}); err != nil && err != storm.ErrNotFound {
+		log.Warningf("Cannot remove search history of profile %s: %s", name, err)
+	}
+	if err := db.Select(q.Eq("Profile", name)).Delete(&database.TraktQueueItem{}); err != nil && err != storm.ErrNotFound {
+		log.Warningf("Cannot remove Trakt queue of profile %s: %s", name, err)
+	}
+	database.GetCache().DeleteWithPrefix(database.CommonBucket, []byte(cacheKeyPrefix(name)))
+	recommend.Clear()
+
+	return db.DeleteStruct(&stored)
+}
+
+// Switch stores settings of active profile, applies settings of selected profile
+// and resets watched state and Trakt cache, to run synchronization for selected profile.
+// Kodi library keeps a single watched state, so library sync runs for active profile only,
+// while queued Trakt writes of all profiles are sent in background by trakt.FlushQueue.
+func Switch(xbmcHost *xbmc.XBMCHost, name string) error {
+	if xbmcHost == nil {
+		return errors.New("Kodi is not available")
+	}
+
+	current := Current()
+	if name == current {
+		return nil
+	}
+
+	target := database.Profile{Settings: map[string]string{}}
+	if err := database.GetStormDB().One("Name", storageName(name), &target); err != nil && name != "" {
+		return ErrNotFound
+	}
+
+	log.Infof("Switching profile from '%s' to '%s'", current, name)
+
+	saved := database.Profile{
+		Name:      storageName(current),
+		Settings:  map[string]string{},
+		CreatedAt: time.Now(),
+	}
+	var stored database.Profile
+	if err := database.GetStormDB().One("Name", saved.Name, &stored); err == nil {
+		saved.CreatedAt = stored.CreatedAt
+	}
+	for _, key := range settingKeys {
+		saved.Settings[key] = xbmcHost.GetSettingString(key)
+	}
+	if err := database.GetStormDB().Save(&saved); err != nil {
+		return err
+	}
+
+	for _, key := range settingKeys {
+		value := target.Settings[key]
+		if value == "" && key == "trakt_token_expiry" {
+			value = "0"
+		}
+		xbmcHost.SetSetting(key, value)
+	}
+	xbmcHost.SetSetting("profile", name)
+
+	if _, err := config.Reload(); err != nil {
+		return err
+	}
+
+	// Watched state, ratings and cached Trakt lists belong to previous profile
+	playcount.Reset()
+	trakt.ResetMarks()
+	history.Restore()
+	library.IsTraktInitialized = false
+	library.ClearTraktCache(xbmcHost)
+	library.ClearPageCache(xbmcHost)
+
+	go func() {
+		trakt.FlushQueue(true)
+		if err := library.RefreshTrakt(); err != nil {
+			log.Warningf("Cannot synchronize Trakt for profile '%s': %s", name, err)
+		}
+	}()
+
+	return nil
+}
+
+// storedTraktToken returns Trakt token of inactive profile, saved when it was switched from,
+// so its queued Trakt writes are sent in background. Expired tokens are refreshed only for active profile.
+func storedTraktToken(name string) string {
+	var stored database.Profile
+	if err := database.GetStormDB().One("Name", storageName(name), &stored); err != nil {
+		return ""
+	}
+
+	if expiry, _ := strconv.ParseInt(stored.Settings["trakt_token_expiry"], 10, 64); expiry > 0 && time.Unix(expiry, 0).Before(time.Now()) {
+		return ""
+	}
+	return stored.Settings["trakt_token"]
+}
+
+// storageName returns name of profile's database record
+func storageName(name string) string {
+	if name == "" {
+		return defaultName
+	}
+	return name
+}
+
+func isValidName(name string) bool {
+	return name != "" && !strings.EqualFold(name, defaultName) && !strings.ContainsAny(name, "|/")
+}
@@ -0,0 +1,34 @@
+package profile
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCacheKeyPrefix(t *testing.T) {
+	names := []string{"a", "a.b", "ab", "a b"}
+	for _, name := range names {
+		if !isValidName(name) {
+			t.Fatalf("Expected %q to be a valid name", name)
+		}
+
+		prefix := cacheKeyPrefix(name)
+		if key := CacheKey(name, "MovieMenu"); !strings.HasPrefix(key, prefix) {
+			t.Errorf("Key %q of profile %q does not start with its prefix %q", key, name, prefix)
+		}
+
+		// Removal of a profile must not touch keys of profiles, which names start with its name
+		for _, other := range names {
+			if other != name && strings.HasPrefix(CacheKey(other, "MovieMenu"), prefix) {
+				t.Errorf("Prefix %q of profile %q matches key of profile %q", prefix, name, other)
+			}
+		}
+	}
+
+	if CacheKey("", "MovieMenu") != "MovieMenu" {
+		t.Errorf("Default profile should keep plain keys")
+	}
+	if isValidName("a|b") {
+		t.Errorf("Separator of cache keys should not be allowed in names")
+	}
+}
@@ -67,11 +67,16 @@ type EpisodeSearchObject struct {
 	Season         int               `json:"season"`
 	SeasonName     string            `json:"season_name"`
 	Episode        int               `json:"episode"`
+	SceneSeason    int               `json:"scene_season"`
+	SceneEpisode   int               `json:"scene_episode"`
+	TVDBSeason     int               `json:"tvdb_season"`
+	TVDBEpisode    int               `json:"tvdb_episode"`
 	Year           int               `json:"year"`
 	SeasonYear     int               `json:"season_year"`
 	ShowYear       int               `json:"show_year"`
 	Titles         map[string]string `json:"titles"`
 	AbsoluteNumber int               `json:"absolute_number"`
+	EpisodeGroup   string            `json:"episode_group"`
 	Anime          bool              `json:"anime"`
 }
 
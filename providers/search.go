@@ -157,6 +157,27 @@ func SearchEpisode(xbmcHost *xbmc.XBMCHost, searchers []EpisodeSearcher, show *t
 	return processLinks(xbmcHost, torrentsChan, SortShows, false)
 }
 
+// SearchEpisodeSilent ...
+func SearchEpisodeSilent(xbmcHost *xbmc.XBMCHost, searchers []EpisodeSearcher, show *tmdb.Show, season *tmdb.Season, episode *tmdb.Episode) []*bittorrent.TorrentFile {
+	torrentsChan := make(chan *bittorrent.TorrentFile)
+	go func() {
+		wg := sync.WaitGroup{}
+		for _, searcher := range searchers {
+			wg.Add(1)
+			go func(searcher EpisodeSearcher) {
+				defer wg.Done()
+				for _, torrent := range searcher.SearchEpisodeLinksSilent(show, season, episode) {
+					torrentsChan <- torrent
+				}
+			}(searcher)
+		}
+		wg.Wait()
+		close(torrentsChan)
+	}()
+
+	return processLinks(xbmcHost, torrentsChan, SortShows, true)
+}
+
 func processLinks(xbmcHost *xbmc.XBMCHost, torrentsChan chan *bittorrent.TorrentFile, sortType int, isSilent bool) []*bittorrent.TorrentFile {
 	torrentsMap := map[string]*bittorrent.TorrentFile{}
 
@@ -13,6 +13,7 @@ import (
 
 	"github.com/elgatito/elementum/bittorrent"
 	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/mapping"
 	"github.com/elgatito/elementum/tmdb"
 	"github.com/elgatito/elementum/util"
 	"github.com/elgatito/elementum/util/ip"
@@ -21,11 +22,6 @@ import (
 	"github.com/op/go-logging"
 )
 
-const (
-	// if >= 80% of episodes have absolute numbers, assume it's because we need it
-	mixAbsoluteNumberPercentage = 0.8
-)
-
 // AddonSearcher ...
 type AddonSearcher struct {
 	MovieSearcher
@@ -320,6 +316,18 @@ func (as *AddonSearcher) GetSeasonSearchObject(show *tmdb.Show, season *tmdb.Sea
 	return sObject
 }
 
+// GetEpisodeSearchSilentObject ...
+func (as *AddonSearcher) GetEpisodeSearchSilentObject(show *tmdb.Show, season *tmdb.Season, episode *tmdb.Episode) *EpisodeSearchObject {
+	o := as.GetEpisodeSearchObject(show, season, episode)
+	if o == nil {
+		return nil
+	}
+	o.Silent = true
+	o.SkipAuth = true
+
+	return o
+}
+
 // GetEpisodeSearchObject ...
 func (as *AddonSearcher) GetEpisodeSearchObject(show *tmdb.Show, season *tmdb.Season, episode *tmdb.Episode) *EpisodeSearchObject {
 	if show == nil || season == nil || episode == nil {
@@ -352,26 +360,33 @@ func (as *AddonSearcher) GetEpisodeSearchObject(show *tmdb.Show, season *tmdb.Se
 
 	// Some Torrents use absolute episodes range in name.
 	// Provider can use Absolute number to filter such.
+	seasonNumber := episode.SeasonNumber
+	episodeNumber := episode.EpisodeNumber
 	absoluteNumber := 0
-	if season.GetFirstEpisodeNumber() > 1 {
-		// If season starts with episode number > 1, we need to adjust absolute number
-		// to be able to search for absolute episodes.
-		absoluteNumber = episode.EpisodeNumber
-		episode.EpisodeNumber = episode.EpisodeNumber - show.EpisodesTillSeason(episode.SeasonNumber)
-	} else {
-		absoluteNumber = episode.EpisodeNumber + show.EpisodesTillSeason(episode.SeasonNumber)
+	sceneNumber := mapping.Number{Season: episode.SeasonNumber, Episode: episode.EpisodeNumber}
+	tvdbNumber := sceneNumber
+
+	m := mapping.Get(show)
+	if em := m.Find(episode.SeasonNumber, episode.EpisodeNumber); em != nil {
+		absoluteNumber = em.Absolute
+		sceneNumber = em.Scene
+		tvdbNumber = em.TVDB
+
+		// If season starts with episode number > 1, we need to use number inside of the season.
+		if season.GetFirstEpisodeNumber() > 1 {
+			episodeNumber = em.Scene.Episode
+		}
+
+		// Selected episode group replaces aired order, as releases are following it
+		if em.Group.Episode > 0 {
+			seasonNumber = em.Group.Season
+			episodeNumber = em.Group.Episode
+		}
 	}
 
 	// Try with TVDB as the fallback
-	if absoluteNumber == 0 && tvdbID > 0 {
-		an, st := show.ShowInfo(episode)
-
-		if an != 0 {
-			absoluteNumber = an
-		}
-		if st != "" {
-			title = st
-		}
+	if m != nil && m.TVDBName != "" && absoluteNumber == 0 {
+		title = m.TVDBName
 	}
 
 	sObject := &EpisodeSearchObject{
@@ -381,13 +396,18 @@ func (as *AddonSearcher) GetEpisodeSearchObject(show *tmdb.Show, season *tmdb.Se
 		ShowTMDBId:     show.ID,
 		Title:          NormalizeTitle(title),
 		Titles:         map[string]string{"original": NormalizeTitle(show.OriginalName), "source": show.OriginalName},
-		Season:         episode.SeasonNumber,
+		Season:         seasonNumber,
 		SeasonName:     seasonName,
-		Episode:        episode.EpisodeNumber,
+		Episode:        episodeNumber,
+		SceneSeason:    sceneNumber.Season,
+		SceneEpisode:   sceneNumber.Episode,
+		TVDBSeason:     tvdbNumber.Season,
+		TVDBEpisode:    tvdbNumber.Episode,
 		Year:           year,
 		SeasonYear:     seasonYear,
 		ShowYear:       showYear,
 		AbsoluteNumber: absoluteNumber,
+		EpisodeGroup:   m.GetEpisodeGroup(),
 		Anime:          show.IsAnime(),
 	}
 
@@ -505,3 +525,12 @@ func (as *AddonSearcher) SearchEpisodeLinks(show *tmdb.Show, season *tmdb.Season
 
 	return as.call("search_episode", as.GetEpisodeSearchObject(show, season, episode))
 }
+
+// SearchEpisodeLinksSilent ...
+func (as *AddonSearcher) SearchEpisodeLinksSilent(show *tmdb.Show, season *tmdb.Season, episode *tmdb.Episode) []*bittorrent.TorrentFile {
+	if show == nil || season == nil || episode == nil {
+		return []*bittorrent.TorrentFile{}
+	}
+
+	return as.call("search_episode", as.GetEpisodeSearchSilentObject(show, season, episode))
+}
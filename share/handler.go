@@ -0,0 +1,115 @@
+package share
+
+import (
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/elgatito/elementum/bittorrent"
+	"github.com/elgatito/elementum/database"
+)
+
+// prefix is a path, shared files are served on
+const prefix = "/share"
+
+// Handler streams shared files on "/share/<token>/<name>", without authorization of API requests
+type Handler struct {
+	s *bittorrent.Service
+}
+
+// NewHandler ...
+func NewHandler(s *bittorrent.Service) *Handler {
+	return &Handler{s: s}
+}
+
+// ServeHTTP ...
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, prefix+"/"), "/")
+
+	l, err := Verify(token)
+	if err != nil {
+		log.Warningf("Rejected share request from %s: %s", r.RemoteAddr, err)
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	} else if l.IP != "" && clientIP(r) != l.IP {
+		log.Warningf("Rejected share request from %s: link is bound to %s", r.RemoteAddr, l.IP)
+		http.Error(w, "share link is bound to another address", http.StatusForbidden)
+		return
+	}
+
+	t, err := h.s.ActivateTorrent(l.InfoHash)
+	if err != nil {
+		log.Warningf("Cannot stream share link %s: %s", l.ID, err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	f := linkFile(t, l)
+	if f == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !f.Selected {
+		t.DownloadFile(f)
+		t.SaveDBFiles()
+	}
+
+	log.Infof("Streaming shared %s to %s", f.Path, r.RemoteAddr)
+
+	var rw http.ResponseWriter = w
+	if l.Rate > 0 {
+		rw = &throttledWriter{ResponseWriter: w, limiter: getLimiter(l.ID, l.Rate)}
+	}
+	rw.Header().Set("Connection", "close")
+
+	req := r.Clone(r.Context())
+	req.URL.Path = "/" + filepath.ToSlash(f.Path)
+	req.URL.RawPath = ""
+	http.FileServer(bittorrent.NewClientTorrentFS(h.s, r)).ServeHTTP(rw, req)
+}
+
+// linkFile returns shared file, episodes are matched by their numbers, if file is not known yet
+func linkFile(t *bittorrent.Torrent, l *database.ShareLink) *bittorrent.File {
+	if l.FileIndex < 0 && l.MediaType == EpisodeType {
+		if f := t.EpisodeFile(l.ShowID, l.Season, l.Episode); f != nil {
+			return f
+		}
+	}
+	return t.PlayableFile(l.FileIndex)
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// throttledWriter limits rate of the response body to the bandwidth cap of the link
+type throttledWriter struct {
+	http.ResponseWriter
+
+	limiter *limiter
+}
+
+func (w *throttledWriter) Write(b []byte) (n int, err error) {
+	chunk := w.limiter.chunk()
+	for len(b) > 0 {
+		size := min(chunk, len(b))
+		if wait := w.limiter.reserve(size); wait > 0 {
+			time.Sleep(wait)
+		}
+
+		var m int
+		m, err = w.ResponseWriter.Write(b[:size])
+		n += m
+		if err != nil {
+			return
+		}
+		b = b[size:]
+	}
+	return
+}
@@ -0,0 +1,84 @@
+package share
+
+import (
+	"time"
+
+	"github.com/anacrolix/sync"
+)
+
+// limiterIdleTTL is a time after which unused limiter of a link is dropped
+const limiterIdleTTL = 10 * time.Minute
+
+var (
+	limitersMu sync.Mutex
+	limiters   = map[string]*limiter{}
+)
+
+// limiter is a token bucket with bandwidth cap of a link, shared by all requests of the link,
+// so parallel range requests of a player do not multiply the rate
+type limiter struct {
+	mu sync.Mutex
+
+	rate   int64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// getLimiter returns limiter of the link, created on first request or when its rate was changed
+func getLimiter(id string, rate int64) *limiter {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+
+	now := time.Now()
+	for key, l := range limiters {
+		if key != id && now.Sub(l.lastUsed()) > limiterIdleTTL {
+			delete(limiters, key)
+		}
+	}
+
+	if l, ok := limiters[id]; ok && l.rate == rate {
+		return l
+	}
+
+	burst := float64(max(rate/10, 1))
+	l := &limiter{rate: rate, burst: burst, tokens: burst, last: now}
+	limiters[id] = l
+	return l
+}
+
+// dropLimiter removes limiter of the revoked link
+func dropLimiter(id string) {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+
+	delete(limiters, id)
+}
+
+// reserve takes n bytes from the bucket and returns time to wait before sending them
+func (l *limiter) reserve(n int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*float64(l.rate))
+	l.last = now
+	l.tokens -= float64(n)
+
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / float64(l.rate) * float64(time.Second))
+}
+
+func (l *limiter) lastUsed() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.last
+}
+
+// chunk returns size of writes, that keeps the rate even for large buffers of file server
+func (l *limiter) chunk() int {
+	return int(l.burst)
+}
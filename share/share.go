@@ -0,0 +1,259 @@
+package share
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anacrolix/sync"
+	"github.com/asdine/storm"
+	"github.com/asdine/storm/q"
+	"github.com/op/go-logging"
+
+	"github.com/elgatito/elementum/bittorrent"
+	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/database"
+	"github.com/elgatito/elementum/tmdb"
+)
+
+// Media types of links, created for library items
+const (
+	MovieType   = "movie"
+	EpisodeType = "episode"
+)
+
+const (
+	// DefaultTTL is a lifetime of links, created without explicit expiration
+	DefaultTTL = 24 * time.Hour
+
+	// autoFileIndex is used when file is chosen on play
+	autoFileIndex = -1
+
+	secretBucket = "share"
+	secretKey    = "secret"
+)
+
+var log = logging.MustGetLogger("share")
+
+var (
+	// ErrInvalidToken is returned for tokens with wrong signature and for revoked links
+	ErrInvalidToken = errors.New("invalid share token")
+	// ErrExpired is returned for expired links
+	ErrExpired = errors.New("share link is expired")
+	// ErrNoTorrent is returned, when there is no torrent to share for the item
+	ErrNoTorrent = errors.New("no torrent found for the item")
+)
+
+var (
+	secretMu sync.Mutex
+	secret   []byte
+)
+
+// Options of a new link
+type Options struct {
+	// TTL is a lifetime of the link, DefaultTTL is used if zero
+	TTL time.Duration
+	// IP binds the link to a single client address
+	IP string
+	// Rate is a bandwidth cap in bytes per second
+	Rate int64
+}
+
+// ForTorrent returns link to the file of active or stored torrent, negative index lets to choose the file on play
+func ForTorrent(s *bittorrent.Service, hash string, index int) (*database.ShareLink, error) {
+	l := &database.ShareLink{InfoHash: hash, FileIndex: index}
+
+	if t := s.GetTorrentByHash(hash); t != nil {
+		l.Title = t.Name()
+		// Without metadata the file is chosen on play
+		if t.HasMetadata() {
+			f := t.PlayableFile(index)
+			if f == nil {
+				return nil, errors.New("file is not found")
+			}
+			l.FileIndex = f.Index
+			l.Title = filepath.Base(f.Path)
+		}
+	} else if torrent := bittorrent.StoredTorrent(hash); torrent != nil {
+		l.Title = torrent.Title
+	} else {
+		return nil, ErrNoTorrent
+	}
+	return l, nil
+}
+
+// ForMovie returns link to the movie, using active torrent or torrent, assigned to the movie
+func ForMovie(s *bittorrent.Service, tmdbID int) (*database.ShareLink, error) {
+	l := &database.ShareLink{MediaType: MovieType, TMDBID: tmdbID, FileIndex: autoFileIndex}
+	if movie := tmdb.GetMovie(tmdbID, config.Get().Language); movie != nil {
+		l.Title = movie.GetTitle()
+	}
+
+	var ti database.TorrentAssignItem
+	if t := s.HasTorrentByID(tmdbID); t != nil {
+		l.InfoHash = t.InfoHash()
+	} else if err := database.GetStormDB().One("TmdbID", tmdbID, &ti); err == nil {
+		l.InfoHash = ti.InfoHash
+	} else {
+		return nil, ErrNoTorrent
+	}
+	return l, nil
+}
+
+// ForEpisode returns link to the episode, using active torrent or torrent, assigned to the episode.
+// File is matched by episode number on play, if torrent is not active yet.
+func ForEpisode(s *bittorrent.Service, showID, season, episode int) (*database.ShareLink, error) {
+	l := &database.ShareLink{MediaType: EpisodeType, ShowID: showID, Season: season, Episode: episode, FileIndex: autoFileIndex}
+
+	show := tmdb.GetShow(showID, config.Get().Language)
+	if show == nil {
+		return nil, errors.New("unable to find show")
+	}
+	l.Title = fmt.Sprintf("%s S%02dE%02d", show.GetName(), season, episode)
+
+	ep := tmdb.GetEpisode(showID, season, episode, config.Get().Language)
+	if ep != nil {
+		l.TMDBID = ep.ID
+	}
+
+	var ti database.TorrentAssignItem
+	if t := s.HasTorrentByEpisode(showID, season, episode); t != nil {
+		l.InfoHash = t.InfoHash()
+		if f := t.EpisodeFile(showID, season, episode); f != nil {
+			l.FileIndex = f.Index
+		}
+	} else if ep != nil && database.GetStormDB().One("TmdbID", ep.ID, &ti) == nil {
+		l.InfoHash = ti.InfoHash
+	} else {
+		return nil, ErrNoTorrent
+	}
+	return l, nil
+}
+
+// Create saves the link with options
+func Create(l *database.ShareLink, opts Options) error {
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
+
+	l.ID = hex.EncodeToString(id)
+	l.IP = opts.IP
+	l.Rate = opts.Rate
+	l.CreatedAt = time.Now()
+	l.ExpiresAt = l.CreatedAt.Add(opts.TTL)
+	return database.GetStormDB().Save(l)
+}
+
+// List returns active links, newest first, expired links are removed
+func List() []*database.ShareLink {
+	Cleanup()
+
+	var links []*database.ShareLink
+	if err := database.GetStormDB().All(&links); err != nil {
+		log.Debugf("Could not get list of share links: %s", err)
+	}
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].CreatedAt.After(links[j].CreatedAt)
+	})
+	return links
+}
+
+// Revoke deletes the link, so its token is no longer accepted
+func Revoke(id string) error {
+	dropLimiter(id)
+	return database.GetStormDB().DeleteStruct(&database.ShareLink{ID: id})
+}
+
+// Cleanup deletes expired links
+func Cleanup() {
+	if err := database.GetStormDB().Select(q.Lt("ExpiresAt", time.Now())).Delete(&database.ShareLink{}); err != nil && err != storm.ErrNotFound {
+		log.Debugf("Could not remove expired share links: %s", err)
+	}
+}
+
+// Token returns signed token of the link, that keeps link ID and expiration time
+func Token(l *database.ShareLink) string {
+	payload := l.ID + "." + strconv.FormatInt(l.ExpiresAt.Unix(), 36)
+	return payload + "." + sign(l, payload)
+}
+
+// Verify checks signature and expiration of the token and returns its link
+func Verify(token string) (*database.ShareLink, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	var l database.ShareLink
+	if err := database.GetStormDB().One("ID", parts[0], &l); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	payload := parts[0] + "." + parts[1]
+	expires, err := strconv.ParseInt(parts[1], 36, 64)
+	if err != nil || expires != l.ExpiresAt.Unix() || !hmac.Equal([]byte(parts[2]), []byte(sign(&l, payload))) {
+		return nil, ErrInvalidToken
+	} else if time.Now().After(l.ExpiresAt) {
+		return nil, ErrExpired
+	}
+	return &l, nil
+}
+
+// URL returns streaming URL of the link on the host
+func URL(host string, l *database.ShareLink) string {
+	name := l.Title
+	if name == "" {
+		name = l.ID
+	}
+	return fmt.Sprintf("%s%s/%s/%s", host, prefix, Token(l), url.PathEscape(name))
+}
+
+// sign returns HMAC of token payload with restrictions of the link
+func sign(l *database.ShareLink, payload string) string {
+	mac := hmac.New(sha256.New, getSecret())
+	fmt.Fprintf(mac, "%s|%s|%d", payload, l.IP, l.Rate)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// getSecret returns signing key, it is generated once and kept in the database
+func getSecret() []byte {
+	secretMu.Lock()
+	defer secretMu.Unlock()
+
+	if secret != nil {
+		return secret
+	}
+
+	var stored string
+	if err := database.GetStormDB().Get(secretBucket, secretKey, &stored); err == nil {
+		if b, err := hex.DecodeString(stored); err == nil && len(b) > 0 {
+			secret = b
+			return secret
+		}
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		log.Errorf("Could not generate share secret: %s", err)
+	}
+	if err := database.GetStormDB().Set(secretBucket, secretKey, hex.EncodeToString(b)); err != nil {
+		log.Warningf("Could not save share secret: %s", err)
+	}
+	secret = b
+	return secret
+}
@@ -0,0 +1,187 @@
+package simkl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/jmcvetta/napping"
+	"github.com/op/go-logging"
+
+	"github.com/elgatito/elementum/broadcast"
+	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/util/reqapi"
+	"github.com/elgatito/elementum/xbmc"
+)
+
+var log = logging.MustGetLogger("simkl")
+
+// Client makes requests to Simkl API
+type Client struct {
+	API *reqapi.API
+
+	// Credentials returns application client ID and user access token
+	Credentials func() (clientID, token string)
+}
+
+// NewClient returns Simkl client, using addon settings for credentials
+func NewClient() *Client {
+	return &Client{
+		API: reqapi.SimklAPI,
+		Credentials: func() (string, string) {
+			return config.Get().SimklClientID, config.Get().SimklToken
+		},
+	}
+}
+
+// Authorized checks whether both client ID and access token are set
+func (c *Client) Authorized() bool {
+	clientID, token := c.Credentials()
+	return clientID != "" && token != ""
+}
+
+// GetHeader ...
+func (c *Client) GetHeader() http.Header {
+	clientID, token := c.Credentials()
+	header := http.Header{
+		"Content-type":  []string{"application/json"},
+		"simkl-api-key": []string{clientID},
+	}
+	if token != "" {
+		header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	}
+	return header
+}
+
+// Get runs GET request and unmarshals response into result
+func (c *Client) Get(url string, params napping.Params, result interface{}, description string) error {
+	req := reqapi.Request{
+		API:         c.API,
+		Method:      "GET",
+		URL:         url,
+		Header:      c.GetHeader(),
+		Params:      params.AsUrlValues(),
+		Result:      result,
+		Description: description,
+	}
+	return req.Do()
+}
+
+// Post runs POST request with JSON payload and unmarshals response into result, if it is set
+func (c *Client) Post(url string, payload interface{}, result interface{}, description string) error {
+	body := []byte("{}")
+	if payload != nil {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return err
+		}
+	}
+
+	req := reqapi.Request{
+		API:         c.API,
+		Method:      "POST",
+		URL:         url,
+		Header:      c.GetHeader(),
+		Params:      napping.Params{}.AsUrlValues(),
+		Payload:     bytes.NewBuffer(body),
+		Result:      result,
+		Description: description,
+
+		ResponseIgnore: []int{201, 409},
+	}
+	return req.Do()
+}
+
+// GetCode requests PIN code for device authorization
+func (c *Client) GetCode() (code *Code, err error) {
+	clientID, _ := c.Credentials()
+	err = c.Get("oauth/pin", napping.Params{"client_id": clientID}, &code, "oauth pin code")
+	if err == nil && (code == nil || code.UserCode == "") {
+		err = errors.New("Empty authorization code")
+	}
+	return
+}
+
+// PollToken waits for user to enter PIN code and returns access token
+func (c *Client) PollToken(code *Code) (*Token, error) {
+	clientID, _ := c.Credentials()
+
+	interval := time.Duration(code.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	expired := time.NewTimer(time.Duration(code.ExpiresIn) * time.Second)
+	defer expired.Stop()
+	closer := broadcast.Closer.C()
+
+	for {
+		select {
+		case <-closer:
+			return nil, errors.New("Cancelling authorization due to closing application state")
+
+		case <-expired.C:
+			return nil, errors.New("Code expired, please try again")
+
+		case <-ticker.C:
+			var token *Token
+			if err := c.Get("oauth/pin/"+code.UserCode, napping.Params{"client_id": clientID}, &token, "oauth pin token"); err != nil {
+				log.Debugf("Could not poll Simkl token: %s", err)
+				continue
+			}
+			if token != nil && token.AccessToken != "" {
+				return token, nil
+			}
+		}
+	}
+}
+
+// Authorize shows PIN code to the user and saves access token, once the code is entered
+func Authorize() error {
+	c := NewClient()
+	if clientID, _ := c.Credentials(); clientID == "" {
+		return errors.New("Simkl client ID is not set")
+	}
+
+	code, err := c.GetCode()
+	if err != nil {
+		log.Errorf("Could not get authorization code from Simkl: %s", err)
+		return err
+	}
+	log.Noticef("Got code for %s: %s", code.VerificationURL, code.UserCode)
+
+	go func() {
+		token, err := c.PollToken(code)
+		if err != nil {
+			log.Warningf("Simkl authorization failed: %s", err)
+			return
+		}
+
+		if xbmcHost, _ := xbmc.GetLocalXBMCHost(); xbmcHost != nil {
+			xbmcHost.SetSetting("simkl_token", token.AccessToken)
+			xbmcHost.Notify("Elementum", "LOCALIZE[30650]", config.AddonIcon())
+		}
+		config.Get().SimklToken = token.AccessToken
+	}()
+
+	if xbmcHost, _ := xbmc.GetLocalXBMCHost(); xbmcHost != nil {
+		if !xbmcHost.Dialog(xbmcHost.GetLocalizedString(30646), fmt.Sprintf(xbmcHost.GetLocalizedString(30649), code.VerificationURL, code.UserCode)) {
+			return errors.New("Authentication canceled")
+		}
+	}
+	return nil
+}
+
+// Deauthorize removes saved access token
+func Deauthorize() error {
+	if xbmcHost, _ := xbmc.GetLocalXBMCHost(); xbmcHost != nil {
+		xbmcHost.SetSetting("simkl_token", "")
+		xbmcHost.Notify("Elementum", "LOCALIZE[30652]", config.AddonIcon())
+	}
+	config.Get().SimklToken = ""
+	return nil
+}
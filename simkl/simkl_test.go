@@ -0,0 +1,297 @@
+package simkl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/elgatito/elementum/tracker"
+	"github.com/elgatito/elementum/util"
+	"github.com/elgatito/elementum/util/reqapi"
+)
+
+type request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   map[string]interface{}
+}
+
+// newTestTracker returns tracker, connected to a stand-in Simkl server, and requests made to it
+func newTestTracker(t *testing.T, responses map[string]string) (*Tracker, func() []request) {
+	var mu sync.Mutex
+	var requests []request
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := request{Method: r.Method, Path: r.URL.Path, Header: r.Header}
+		if b, _ := io.ReadAll(r.Body); len(b) > 0 {
+			if err := json.Unmarshal(b, &req.Body); err != nil {
+				t.Errorf("Invalid JSON payload for %s: %s", r.URL.Path, err)
+			}
+		}
+
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if resp, ok := responses[r.URL.Path]; ok {
+			io.WriteString(w, resp)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, "{}")
+	}))
+	t.Cleanup(srv.Close)
+
+	return &Tracker{Client: &Client{
+		API: &reqapi.API{
+			Ident:       reqapi.SimklIdent,
+			Endpoint:    srv.URL,
+			RateLimiter: util.NewRateLimiter(100, time.Second, 10),
+		},
+		Credentials: func() (string, string) {
+			return "client", "token"
+		},
+	}}, func() []request {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]request{}, requests...)
+	}
+}
+
+func TestScrobble(t *testing.T) {
+	tr, requests := newTestTracker(t, nil)
+
+	if err := tr.Scrobble("pause", &tracker.Item{MediaType: tracker.EpisodeType, TMDBID: 62085, ShowID: 1399, Season: 2, Episode: 3}, 42.5); err != nil {
+		t.Fatalf("Scrobble failed: %s", err)
+	}
+
+	reqs := requests()
+	if len(reqs) != 1 {
+		t.Fatalf("Expected 1 request, got %d", len(reqs))
+	}
+	r := reqs[0]
+	if r.Method != "POST" || r.Path != "/scrobble/pause" {
+		t.Errorf("Unexpected request %s %s", r.Method, r.Path)
+	}
+	if r.Header.Get("simkl-api-key") != "client" || r.Header.Get("Authorization") != "Bearer token" {
+		t.Errorf("Unexpected headers: %v", r.Header)
+	}
+	if r.Body["progress"] != 42.5 {
+		t.Errorf("Unexpected progress: %v", r.Body["progress"])
+	}
+	show := r.Body["show"].(map[string]interface{})
+	if show["ids"].(map[string]interface{})["tmdb"] != float64(1399) {
+		t.Errorf("Unexpected show: %v", show)
+	}
+	episode := r.Body["episode"].(map[string]interface{})
+	if episode["season"] != float64(2) || episode["number"] != float64(3) {
+		t.Errorf("Unexpected episode: %v", episode)
+	}
+	if _, ok := r.Body["movie"]; ok {
+		t.Errorf("Unexpected movie in episode scrobble")
+	}
+}
+
+func TestSetWatched(t *testing.T) {
+	tr, requests := newTestTracker(t, nil)
+
+	watchedAt := time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC)
+	items := []*tracker.Item{
+		{MediaType: tracker.MovieType, TMDBID: 603, WatchedAt: watchedAt},
+		{MediaType: tracker.EpisodeType, ShowID: 1399, Season: 1, Episode: 1},
+		{MediaType: tracker.EpisodeType, ShowID: 1399, Season: 1, Episode: 2},
+		{MediaType: tracker.EpisodeType, ShowID: 1399, Season: 2, Episode: 1},
+	}
+	if err := tr.SetWatched(items, true); err != nil {
+		t.Fatalf("SetWatched failed: %s", err)
+	}
+	if err := tr.SetWatched(items[:1], false); err != nil {
+		t.Fatalf("SetWatched failed: %s", err)
+	}
+
+	reqs := requests()
+	if len(reqs) != 2 || reqs[0].Path != "/sync/history" || reqs[1].Path != "/sync/history/remove" {
+		t.Fatalf("Unexpected requests: %v", reqs)
+	}
+
+	movies := reqs[0].Body["movies"].([]interface{})
+	if len(movies) != 1 || movies[0].(map[string]interface{})["watched_at"] != "2024-05-01T20:00:00Z" {
+		t.Errorf("Unexpected movies: %v", movies)
+	}
+	shows := reqs[0].Body["shows"].([]interface{})
+	if len(shows) != 1 {
+		t.Fatalf("Expected episodes grouped into 1 show, got %v", shows)
+	}
+	seasons := shows[0].(map[string]interface{})["seasons"].([]interface{})
+	if len(seasons) != 2 || len(seasons[0].(map[string]interface{})["episodes"].([]interface{})) != 2 {
+		t.Errorf("Unexpected seasons: %v", seasons)
+	}
+
+	removed := reqs[1].Body["movies"].([]interface{})
+	if _, ok := removed[0].(map[string]interface{})["watched_at"]; ok {
+		t.Errorf("Unexpected watched_at in history removal")
+	}
+}
+
+func TestSetWatchlist(t *testing.T) {
+	tr, requests := newTestTracker(t, nil)
+
+	item := &tracker.Item{MediaType: tracker.EpisodeType, TMDBID: 63056, ShowID: 1399, Season: 1, Episode: 1}
+	if err := tr.SetWatchlist(item, true); err != nil {
+		t.Fatalf("SetWatchlist failed: %s", err)
+	}
+	if err := tr.SetWatchlist(item, false); err != tracker.ErrNotSupported {
+		t.Fatalf("Expected removal to be not supported, got %v", err)
+	}
+
+	reqs := requests()
+	if len(reqs) != 1 || reqs[0].Path != "/sync/add-to-list" {
+		t.Fatalf("Unexpected requests: %v", reqs)
+	}
+	show := reqs[0].Body["shows"].([]interface{})[0].(map[string]interface{})
+	if show["to"] != "plantowatch" || fmt.Sprint(show["ids"].(map[string]interface{})["tmdb"]) != "1399" {
+		t.Errorf("Unexpected show: %v", show)
+	}
+}
+
+func TestGetWatched(t *testing.T) {
+	tr, requests := newTestTracker(t, map[string]string{
+		"/sync/all-items/movies/completed": `{"movies": [
+			{"last_watched_at": "2024-05-02T10:00:00Z", "status": "completed", "movie": {"title": "Movie", "ids": {"simkl": 1, "tmdb": "603"}}},
+			{"last_watched_at": "2024-05-02T10:00:00Z", "status": "completed", "movie": {"title": "Unknown", "ids": {"simkl": 2}}}
+		]}`,
+		"/sync/all-items/shows": `{"shows": [
+			{"status": "watching", "show": {"title": "Show", "ids": {"simkl": 17465, "tmdb": 1399}},
+				"seasons": [{"number": 1, "episodes": [
+					{"number": 1, "watched_at": "2024-05-01T20:00:00Z"},
+					{"number": 2}
+				]}]}
+		]}`,
+	})
+
+	movies, err := tr.GetWatched(tracker.MovieType)
+	if err != nil {
+		t.Fatalf("GetWatched failed: %s", err)
+	}
+	if len(movies) != 1 || movies[0].TMDBID != 603 || movies[0].WatchedAt.Day() != 2 {
+		t.Fatalf("Unexpected watched movies: %+v", movies)
+	}
+
+	episodes, err := tr.GetWatched(tracker.EpisodeType)
+	if err != nil {
+		t.Fatalf("GetWatched failed: %s", err)
+	}
+	if len(episodes) != 2 {
+		t.Fatalf("Expected 2 watched episodes, got %d", len(episodes))
+	}
+	if e := episodes[0]; e.MediaType != tracker.EpisodeType || e.ShowID != 1399 || e.Season != 1 || e.Episode != 1 || e.WatchedAt.Day() != 1 {
+		t.Errorf("Unexpected watched episode: %+v", e)
+	}
+	if e := episodes[1]; e.Episode != 2 || !e.WatchedAt.IsZero() {
+		t.Errorf("Unexpected watched episode: %+v", e)
+	}
+
+	reqs := requests()
+	if len(reqs) != 2 || reqs[0].Method != "GET" || reqs[1].Path != "/sync/all-items/shows" {
+		t.Errorf("Unexpected requests: %v", reqs)
+	}
+}
+
+func TestGetPaused(t *testing.T) {
+	tr, _ := newTestTracker(t, map[string]string{
+		"/sync/playback/movies": `[
+			{"id": 3, "progress": 60, "paused_at": "2024-05-02T20:00:00Z", "type": "movie",
+				"movie": {"title": "Movie", "ids": {"simkl": 1, "tmdb": 603}}}
+		]`,
+		"/sync/playback/episodes": `[
+			{"id": 1, "progress": 35.5, "paused_at": "2024-05-01T20:00:00Z", "type": "episode",
+				"show": {"title": "Show", "ids": {"simkl": 17465, "tmdb": "1399"}},
+				"episode": {"season": 1, "number": 4}},
+			{"id": 2, "progress": 10, "paused_at": "2024-05-01T20:00:00Z", "type": "episode",
+				"show": {"title": "Unknown", "ids": {"simkl": 1}},
+				"episode": {"season": 1, "number": 1}}
+		]`,
+	})
+
+	paused, err := tr.GetPaused(tracker.EpisodeType)
+	if err != nil {
+		t.Fatalf("GetPaused failed: %s", err)
+	}
+	if len(paused) != 1 {
+		t.Fatalf("Expected 1 paused episode, got %d", len(paused))
+	}
+	p := paused[0]
+	if p.Item.ShowID != 1399 || p.Item.Season != 1 || p.Item.Episode != 4 || p.Progress != 35.5 {
+		t.Errorf("Unexpected paused episode: %+v %+v", p, p.Item)
+	}
+	if !p.PausedAt.Equal(time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected paused time: %s", p.PausedAt)
+	}
+
+	paused, err = tr.GetPaused(tracker.MovieType)
+	if err != nil {
+		t.Fatalf("GetPaused failed: %s", err)
+	}
+	if len(paused) != 1 || paused[0].Item.MediaType != tracker.MovieType || paused[0].Item.TMDBID != 603 || paused[0].Progress != 60 {
+		t.Errorf("Unexpected paused movies: %+v", paused)
+	}
+}
+
+func TestGetLastActivities(t *testing.T) {
+	tr, _ := newTestTracker(t, map[string]string{
+		"/sync/activities": `{
+			"all": "2024-05-03T00:00:00Z",
+			"movies": {"all": "2024-05-03T00:00:00Z", "completed": "2024-05-02T00:00:00Z", "playback": "2024-05-01T00:00:00Z"},
+			"tv_shows": {"all": "2024-05-03T00:00:00Z", "completed": "2024-05-01T00:00:00Z", "watching": "2024-05-03T00:00:00Z"}
+		}`,
+	})
+
+	a, err := tr.GetLastActivities()
+	if err != nil || a == nil {
+		t.Fatalf("GetLastActivities failed: %v", err)
+	}
+	if a.MoviesWatched.Day() != 2 || a.MoviesPaused.Day() != 1 {
+		t.Errorf("Unexpected movies activities: %+v", a)
+	}
+	if a.ShowsWatched.Day() != 3 {
+		t.Errorf("Expected shows watched from watching list, got %s", a.ShowsWatched)
+	}
+}
+
+func TestFanOut(t *testing.T) {
+	first, firstRequests := newTestTracker(t, nil)
+	second, secondRequests := newTestTracker(t, nil)
+	disabled, disabledRequests := newTestTracker(t, nil)
+	disabled.Credentials = func() (string, string) { return "client", "" }
+
+	tracker.Register(first, second, disabled)
+
+	item := &tracker.Item{MediaType: tracker.MovieType, TMDBID: 603}
+	if err := tracker.Scrobble("start", item, 1); err != nil {
+		t.Fatalf("Scrobble failed: %s", err)
+	}
+	// Collection is not supported by Simkl, so nothing is written
+	if err := tracker.SetCollection(item, true); err != tracker.ErrNotSupported {
+		t.Fatalf("Expected collection to be not supported, got %v", err)
+	}
+	// Excepted trackers are not updated
+	if err := tracker.Scrobble("stop", item, 90, first.Name()); err != nil {
+		t.Fatalf("Scrobble failed: %s", err)
+	}
+
+	for i, reqs := range [][]request{firstRequests(), secondRequests()} {
+		if len(reqs) != 1 || reqs[0].Path != "/scrobble/start" {
+			t.Errorf("Tracker %d: unexpected requests: %v", i, reqs)
+		}
+	}
+	if reqs := disabledRequests(); len(reqs) != 0 {
+		t.Errorf("Disabled tracker received requests: %v", reqs)
+	}
+}
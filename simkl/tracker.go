@@ -0,0 +1,220 @@
+package simkl
+
+import (
+	"time"
+
+	"github.com/jmcvetta/napping"
+
+	"github.com/elgatito/elementum/tracker"
+)
+
+// Tracker is Simkl implementation of tracker.Tracker
+type Tracker struct {
+	*Client
+}
+
+// NewTracker returns Simkl tracker
+func NewTracker() *Tracker {
+	return &Tracker{Client: NewClient()}
+}
+
+// Name ...
+func (t *Tracker) Name() string {
+	return "simkl"
+}
+
+// Enabled ...
+func (t *Tracker) Enabled() bool {
+	return t.Authorized()
+}
+
+// Scrobble ...
+func (t *Tracker) Scrobble(action string, item *tracker.Item, progress float64) error {
+	payload := &ScrobblePayload{Progress: progress}
+	if item.MediaType == tracker.MovieType {
+		payload.Movie = &Media{IDs: &IDs{TMDB: ID(item.TMDBID)}}
+	} else if item.MediaType == tracker.EpisodeType {
+		payload.Show = &Media{IDs: &IDs{TMDB: ID(item.ShowID)}}
+		payload.Episode = &Episode{Season: item.Season, Number: item.Episode}
+	} else {
+		return tracker.ErrNotSupported
+	}
+
+	return t.Post("scrobble/"+action, payload, nil, "scrobble "+action)
+}
+
+// SetWatched ...
+func (t *Tracker) SetWatched(items []*tracker.Item, watched bool) error {
+	payload := &SyncPayload{}
+	shows := map[int]*SyncItem{}
+	seasons := map[int]map[int]*Season{}
+
+	for _, i := range items {
+		var at *time.Time
+		if watched && !i.WatchedAt.IsZero() {
+			watchedAt := i.WatchedAt
+			at = &watchedAt
+		}
+
+		switch i.MediaType {
+		case tracker.MovieType:
+			payload.Movies = append(payload.Movies, &SyncItem{IDs: &IDs{TMDB: ID(i.TMDBID)}, WatchedAt: at})
+			continue
+		case tracker.ShowType:
+			payload.Shows = append(payload.Shows, &SyncItem{IDs: &IDs{TMDB: ID(i.TMDBID)}, WatchedAt: at})
+			continue
+		}
+
+		// Seasons and episodes are grouped under their shows
+		show, ok := shows[i.ShowID]
+		if !ok {
+			show = &SyncItem{IDs: &IDs{TMDB: ID(i.ShowID)}}
+			shows[i.ShowID] = show
+			seasons[i.ShowID] = map[int]*Season{}
+			payload.Shows = append(payload.Shows, show)
+		}
+		season, ok := seasons[i.ShowID][i.Season]
+		if !ok {
+			season = &Season{Number: i.Season}
+			seasons[i.ShowID][i.Season] = season
+			show.Seasons = append(show.Seasons, season)
+		}
+		if i.MediaType == tracker.EpisodeType {
+			season.Episodes = append(season.Episodes, &Episode{Number: i.Episode, WatchedAt: at})
+		}
+	}
+
+	url := "sync/history"
+	if !watched {
+		url = "sync/history/remove"
+	}
+	return t.Post(url, payload, nil, "set watched")
+}
+
+// SetWatchlist ...
+func (t *Tracker) SetWatchlist(item *tracker.Item, add bool) error {
+	payload := &SyncPayload{}
+	entry := &SyncItem{IDs: &IDs{TMDB: ID(item.TMDBID)}, To: "plantowatch"}
+
+	if item.MediaType == tracker.MovieType {
+		payload.Movies = append(payload.Movies, entry)
+	} else {
+		if item.ShowID != 0 {
+			entry.IDs.TMDB = ID(item.ShowID)
+		}
+		payload.Shows = append(payload.Shows, entry)
+	}
+
+	// Simkl has no removal from a single list, history removal drops the item
+	// from all lists together with watched episodes, so it is not used here
+	if !add {
+		return tracker.ErrNotSupported
+	}
+	return t.Post("sync/add-to-list", payload, nil, "add to watchlist")
+}
+
+// SetCollection is not supported, Simkl has no collection lists
+func (t *Tracker) SetCollection(item *tracker.Item, add bool) error {
+	return tracker.ErrNotSupported
+}
+
+// GetWatched ...
+func (t *Tracker) GetWatched(mediaType string) (ret []*tracker.Item, err error) {
+	var items *AllItems
+	if mediaType == tracker.MovieType {
+		if err = t.Get("sync/all-items/movies/completed", napping.Params{}, &items, "watched movies"); err != nil || items == nil {
+			return nil, err
+		}
+		for _, m := range items.Movies {
+			if m == nil || m.Movie == nil || m.Movie.IDs == nil || m.Movie.IDs.TMDB == 0 {
+				continue
+			}
+			ret = append(ret, &tracker.Item{MediaType: tracker.MovieType, TMDBID: int(m.Movie.IDs.TMDB), WatchedAt: m.LastWatchedAt})
+		}
+		return ret, nil
+	}
+
+	if err = t.Get("sync/all-items/shows", napping.Params{"episode_watched_at": "yes"}, &items, "watched shows"); err != nil || items == nil {
+		return nil, err
+	}
+	for _, s := range items.Shows {
+		if s == nil || s.Show == nil || s.Show.IDs == nil || s.Show.IDs.TMDB == 0 {
+			continue
+		}
+		for _, season := range s.Seasons {
+			for _, e := range season.Episodes {
+				item := &tracker.Item{
+					MediaType: tracker.EpisodeType,
+					ShowID:    int(s.Show.IDs.TMDB),
+					Season:    season.Number,
+					Episode:   e.Number,
+				}
+				if e.WatchedAt != nil {
+					item.WatchedAt = *e.WatchedAt
+				}
+				ret = append(ret, item)
+			}
+		}
+	}
+	return ret, nil
+}
+
+// GetPaused ...
+func (t *Tracker) GetPaused(mediaType string) (ret []*tracker.Progress, err error) {
+	url := "sync/playback/episodes"
+	if mediaType == tracker.MovieType {
+		url = "sync/playback/movies"
+	}
+
+	var items []*PlaybackItem
+	if err = t.Get(url, napping.Params{}, &items, "paused "+mediaType); err != nil {
+		return nil, err
+	}
+
+	for _, p := range items {
+		if p == nil {
+			continue
+		}
+
+		var item *tracker.Item
+		if p.Movie != nil && p.Movie.IDs != nil && p.Movie.IDs.TMDB != 0 {
+			item = &tracker.Item{MediaType: tracker.MovieType, TMDBID: int(p.Movie.IDs.TMDB)}
+		} else if p.Show != nil && p.Show.IDs != nil && p.Show.IDs.TMDB != 0 && p.Episode != nil {
+			item = &tracker.Item{
+				MediaType: tracker.EpisodeType,
+				ShowID:    int(p.Show.IDs.TMDB),
+				Season:    p.Episode.Season,
+				Episode:   p.Episode.Number,
+			}
+		} else {
+			continue
+		}
+
+		ret = append(ret, &tracker.Progress{Item: item, Progress: p.Progress, PausedAt: p.PausedAt})
+	}
+	return ret, nil
+}
+
+// GetLastActivities ...
+func (t *Tracker) GetLastActivities() (*tracker.Activities, error) {
+	var a *Activities
+	if err := t.Post("sync/activities", nil, &a, "last activities"); err != nil || a == nil {
+		return nil, err
+	}
+
+	ret := &tracker.Activities{All: a.All}
+	if a.Movies != nil {
+		ret.MoviesWatched = a.Movies.Completed
+		ret.MoviesPaused = a.Movies.Playback
+		ret.MoviesWatchlist = a.Movies.PlanToWatch
+	}
+	if a.TVShows != nil {
+		ret.ShowsWatched = a.TVShows.Completed
+		if a.TVShows.Watching.After(ret.ShowsWatched) {
+			ret.ShowsWatched = a.TVShows.Watching
+		}
+		ret.ShowsPaused = a.TVShows.Playback
+		ret.ShowsWatchlist = a.TVShows.PlanToWatch
+	}
+	return ret, nil
+}
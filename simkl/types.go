@@ -0,0 +1,139 @@
+package simkl
+
+import (
+	"bytes"
+	"strconv"
+	"time"
+)
+
+// ID is a numeric ID, that Simkl returns either as a number or as a string
+type ID int
+
+// UnmarshalJSON ...
+func (id *ID) UnmarshalJSON(b []byte) error {
+	b = bytes.Trim(b, `"`)
+	if len(b) == 0 || string(b) == "null" {
+		*id = 0
+		return nil
+	}
+
+	i, err := strconv.Atoi(string(b))
+	if err != nil {
+		return err
+	}
+	*id = ID(i)
+	return nil
+}
+
+// IDs ...
+type IDs struct {
+	Simkl ID     `json:"simkl,omitempty"`
+	TMDB  ID     `json:"tmdb,omitempty"`
+	IMDB  string `json:"imdb,omitempty"`
+	TVDB  ID     `json:"tvdb,omitempty"`
+}
+
+// Media is a movie or show object in requests and responses
+type Media struct {
+	Title string `json:"title,omitempty"`
+	Year  int    `json:"year,omitempty"`
+	IDs   *IDs   `json:"ids"`
+}
+
+// Episode ...
+type Episode struct {
+	Season    int        `json:"season,omitempty"`
+	Number    int        `json:"number"`
+	WatchedAt *time.Time `json:"watched_at,omitempty"`
+}
+
+// Season ...
+type Season struct {
+	Number   int        `json:"number"`
+	Episodes []*Episode `json:"episodes,omitempty"`
+}
+
+// ScrobblePayload ...
+type ScrobblePayload struct {
+	Progress float64  `json:"progress"`
+	Movie    *Media   `json:"movie,omitempty"`
+	Show     *Media   `json:"show,omitempty"`
+	Episode  *Episode `json:"episode,omitempty"`
+}
+
+// SyncItem is a movie or show in sync requests
+type SyncItem struct {
+	To        string     `json:"to,omitempty"`
+	WatchedAt *time.Time `json:"watched_at,omitempty"`
+	IDs       *IDs       `json:"ids"`
+	Seasons   []*Season  `json:"seasons,omitempty"`
+}
+
+// SyncPayload ...
+type SyncPayload struct {
+	Movies []*SyncItem `json:"movies,omitempty"`
+	Shows  []*SyncItem `json:"shows,omitempty"`
+}
+
+// PlaybackItem is a paused movie or episode
+type PlaybackItem struct {
+	ID       int       `json:"id"`
+	Progress float64   `json:"progress"`
+	PausedAt time.Time `json:"paused_at"`
+	Type     string    `json:"type"`
+	Movie    *Media    `json:"movie"`
+	Show     *Media    `json:"show"`
+	Episode  *Episode  `json:"episode"`
+}
+
+// AllItems is a response with user lists
+type AllItems struct {
+	Movies []*ListItem `json:"movies"`
+	Shows  []*ListItem `json:"shows"`
+}
+
+// ListItem is a movie or show in user lists
+type ListItem struct {
+	LastWatchedAt time.Time `json:"last_watched_at"`
+	Status        string    `json:"status"`
+	Movie         *Media    `json:"movie"`
+	Show          *Media    `json:"show"`
+	Seasons       []*Season `json:"seasons"`
+}
+
+// Activities are last update times of user lists
+type Activities struct {
+	All     time.Time       `json:"all"`
+	Movies  *TypeActivities `json:"movies"`
+	TVShows *TypeActivities `json:"tv_shows"`
+	Anime   *TypeActivities `json:"anime"`
+}
+
+// TypeActivities ...
+type TypeActivities struct {
+	All         time.Time `json:"all"`
+	RatedAt     time.Time `json:"rated_at"`
+	Playback    time.Time `json:"playback"`
+	PlanToWatch time.Time `json:"plantowatch"`
+	Watching    time.Time `json:"watching"`
+	Completed   time.Time `json:"completed"`
+	Hold        time.Time `json:"hold"`
+	Dropped     time.Time `json:"dropped"`
+	RemovedFrom time.Time `json:"removed_from_list"`
+}
+
+// Code is a PIN authorization code
+type Code struct {
+	Result          string `json:"result"`
+	UserCode        string `json:"user_code"`
+	VerificationURL string `json:"verification_url"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+}
+
+// Token is a result of PIN authorization
+type Token struct {
+	Result      string `json:"result"`
+	Message     string `json:"message"`
+	AccessToken string `json:"access_token"`
+}
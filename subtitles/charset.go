@@ -0,0 +1,108 @@
+package subtitles
+
+import (
+	"bytes"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+
+	"github.com/elgatito/elementum/util/probe"
+)
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// languageCharsets are legacy encodings, subtitles in the language are usually stored with
+var languageCharsets = map[string]encoding.Encoding{
+	"ru": charmap.Windows1251,
+	"uk": charmap.Windows1251,
+	"be": charmap.Windows1251,
+	"bg": charmap.Windows1251,
+	"mk": charmap.Windows1251,
+	"sr": charmap.Windows1251,
+	"kk": charmap.Windows1251,
+	"pl": charmap.Windows1250,
+	"cs": charmap.Windows1250,
+	"sk": charmap.Windows1250,
+	"hu": charmap.Windows1250,
+	"ro": charmap.Windows1250,
+	"hr": charmap.Windows1250,
+	"sl": charmap.Windows1250,
+	"bs": charmap.Windows1250,
+	"sq": charmap.Windows1250,
+	"el": charmap.Windows1253,
+	"tr": charmap.Windows1254,
+	"he": charmap.Windows1255,
+	"ar": charmap.Windows1256,
+	"fa": charmap.Windows1256,
+	"et": charmap.Windows1257,
+	"lv": charmap.Windows1257,
+	"lt": charmap.Windows1257,
+	"vi": charmap.Windows1258,
+	"th": charmap.Windows874,
+	"ja": japanese.ShiftJIS,
+	"ko": korean.EUCKR,
+	"zh": simplifiedchinese.GBK,
+}
+
+// ToUTF8 returns subtitles text, decoded from detected charset.
+// Unicode is detected by byte order marks and validity of UTF-8,
+// legacy encodings are chosen by the language of subtitles or guessed from the text.
+func ToUTF8(data []byte, language string) (string, error) {
+	switch {
+	case bytes.HasPrefix(data, utf8BOM):
+		return string(data[len(utf8BOM):]), nil
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}), bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return decode(unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM), data)
+	case isUTF16LE(data):
+		return decode(unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), data)
+	case utf8.Valid(data):
+		return string(data), nil
+	}
+
+	if enc, ok := languageCharsets[probe.ShortLanguage(language)]; ok {
+		return decode(enc, data)
+	}
+	if isCyrillic(data) {
+		return decode(charmap.Windows1251, data)
+	}
+	return decode(charmap.Windows1252, data)
+}
+
+func decode(enc encoding.Encoding, data []byte) (string, error) {
+	ret, err := enc.NewDecoder().Bytes(data)
+	return string(ret), err
+}
+
+// isUTF16LE checks for zero high bytes of ASCII characters in UTF-16 text without BOM
+func isUTF16LE(data []byte) bool {
+	if len(data) < 64 {
+		return false
+	}
+	zeros := 0
+	for i := 1; i < 64; i += 2 {
+		if data[i] == 0 && data[i-1] != 0 {
+			zeros++
+		}
+	}
+	return zeros > 24
+}
+
+// isCyrillic checks whether most of letters are in the upper half of code page,
+// which is true for Cyrillic in Windows-1251 and rare for accented Latin letters
+func isCyrillic(data []byte) bool {
+	latin, high := 0, 0
+	for _, c := range data {
+		switch {
+		case c >= 0xC0:
+			high++
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+			latin++
+		}
+	}
+	return high > 0 && high*2 > latin
+}
@@ -0,0 +1,127 @@
+package subtitles
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/elgatito/elementum/config"
+)
+
+// Options describe processing of downloaded subtitles
+type Options struct {
+	// Format is a target format, empty to keep the original one
+	Format string
+	// Offset shifts subtitles, positive values show them later
+	Offset time.Duration
+	// FPS is a frame rate of the video
+	FPS float64
+	// SourceFPS is a frame rate of the release, subtitles were made for.
+	// Subtitles are rescaled, when it differs from FPS.
+	SourceFPS float64
+	// Language is a hint for charset detection of non-Unicode subtitles
+	Language string
+}
+
+// DefaultOptions returns options from addon settings
+func DefaultOptions() *Options {
+	return &Options{
+		Format: config.Get().SubtitlesConvertFormat,
+	}
+}
+
+// Convert re-encodes subtitles into UTF-8, and applies format conversion, offset and rescale.
+// Returns resulting text with its format.
+func Convert(data []byte, opts *Options) (string, string, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	text, err := ToUTF8(data, opts.Language)
+	if err != nil {
+		return "", "", err
+	}
+
+	doc, err := Parse(text, frameRate(opts.SourceFPS, opts.FPS))
+	if err != nil {
+		return "", "", err
+	}
+
+	format := strings.ToLower(opts.Format)
+	if !isFormat(format) {
+		format = doc.Format
+
+		// Frames are already converted to time, so Kodi does not need to guess the frame rate
+		if format == FormatMicroDVD && frameRate(doc.FPS, opts.SourceFPS, opts.FPS) > 0 {
+			format = FormatSRT
+		}
+	}
+
+	if source := frameRate(doc.FPS, opts.SourceFPS); source > 0 && opts.FPS > 0 && math.Abs(source-opts.FPS) > 0.01 {
+		doc.Rescale(source / opts.FPS)
+	}
+	if opts.Offset != 0 {
+		doc.Shift(opts.Offset)
+	}
+
+	return doc.Render(format, frameRate(opts.FPS, doc.FPS)), format, nil
+}
+
+// ConvertFile processes subtitles file with options, and returns path of the resulting file,
+// which has extension of the target format. Files in unknown formats are left as is.
+func ConvertFile(path string, opts *Options) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return path, err
+	}
+	if opts.isEmpty(path) && utf8.Valid(data) && !bytes.HasPrefix(data, utf8BOM) {
+		return path, nil
+	}
+
+	text, format, err := Convert(data, opts)
+	if err == ErrUnknownFormat {
+		log.Debugf("Keeping subtitles %s in original format", path)
+		return path, nil
+	} else if err != nil {
+		return path, err
+	}
+
+	outPath := strings.TrimSuffix(path, filepath.Ext(path)) + "." + format
+	if err := os.WriteFile(outPath, []byte(text), 0644); err != nil {
+		return path, err
+	}
+	if outPath != path {
+		os.Remove(path)
+	}
+
+	log.Debugf("Converted subtitles %s to %s", path, outPath)
+	return outPath, nil
+}
+
+// isEmpty checks whether options would keep the file as is
+func (o *Options) isEmpty(path string) bool {
+	return o == nil || (o.Offset == 0 && o.FPS == 0 && o.SourceFPS == 0 &&
+		(o.Format == "" || strings.EqualFold(o.Format, strings.TrimPrefix(filepath.Ext(path), "."))))
+}
+
+func isFormat(format string) bool {
+	switch format {
+	case FormatSRT, FormatASS, FormatSSA, FormatMicroDVD, FormatVTT:
+		return true
+	}
+	return false
+}
+
+// frameRate returns the first known frame rate
+func frameRate(rates ...float64) float64 {
+	for _, r := range rates {
+		if r > 0 {
+			return r
+		}
+	}
+	return 0
+}
@@ -0,0 +1,475 @@
+package subtitles
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Text subtitle formats, named by their file extensions
+const (
+	FormatSRT      = "srt"
+	FormatASS      = "ass"
+	FormatSSA      = "ssa"
+	FormatMicroDVD = "sub"
+	FormatVTT      = "vtt"
+)
+
+const (
+	// defaultFPS is used for frame based subtitles, when frame rate is not known
+	defaultFPS = 23.976
+	// microDVDDuration is a duration of MicroDVD subtitles without end frame
+	microDVDDuration = 3 * time.Second
+)
+
+// ErrUnknownFormat is returned for subtitles, that are not in one of supported text formats
+var ErrUnknownFormat = errors.New("unknown subtitles format")
+
+var (
+	srtTiming      = regexp.MustCompile(`^\s*(\d+:\d{1,2}:\d{1,2}[,.]\d{1,3})\s*-->\s*(\d+:\d{1,2}:\d{1,2}[,.]\d{1,3})`)
+	vttTiming      = regexp.MustCompile(`^\s*((?:\d+:)?\d{1,2}:\d{1,2}\.\d{1,3})\s*-->\s*((?:\d+:)?\d{1,2}:\d{1,2}\.\d{1,3})`)
+	microDVDLine   = regexp.MustCompile(`^\{(\d+)\}\{(\d*)\}(.*)$`)
+	microDVDCode   = regexp.MustCompile(`\{[^}]*\}`)
+	microDVDItalic = regexp.MustCompile(`(?i)\{y:[^}]*i[^}]*\}`)
+	assOverride    = regexp.MustCompile(`\{[^}]*\}`)
+	assStyleTag    = regexp.MustCompile(`\\([ibu])([01])`)
+	htmlTag        = regexp.MustCompile(`<(/?)([a-zA-Z]+)[^>]*>`)
+)
+
+// Cue is a single subtitle event.
+// Text lines are separated with "\n", and <i>, <b>, <u> tags are used for styling.
+type Cue struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+
+	// assFields keeps original fields of ASS/SSA dialogue lines
+	assFields []string
+}
+
+// Document is a parsed subtitles file
+type Document struct {
+	Format string
+	// FPS is a frame rate, declared in MicroDVD file
+	FPS  float64
+	Cues []*Cue
+
+	// assHeader keeps ASS/SSA sections before events, assFormat is the order of event fields
+	assHeader string
+	assFormat []string
+}
+
+// DetectFormat returns format of the subtitles text, or an empty string
+func DetectFormat(text string) string {
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for i := 0; i < 100 && scanner.Scan(); i++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "WEBVTT"):
+			return FormatVTT
+		case strings.EqualFold(line, "[Script Info]"):
+			return detectASSVersion(text)
+		case microDVDLine.MatchString(line):
+			return FormatMicroDVD
+		case srtTiming.MatchString(line):
+			return FormatSRT
+		}
+	}
+	return ""
+}
+
+func detectASSVersion(text string) string {
+	if strings.Contains(text, "[V4+ Styles]") || strings.Contains(strings.ToLower(text), "scripttype: v4.00+") {
+		return FormatASS
+	}
+	return FormatSSA
+}
+
+// Parse parses subtitles text, frames of MicroDVD subtitles are converted to time with fps,
+// unless the file declares own frame rate
+func Parse(text string, fps float64) (*Document, error) {
+	doc := &Document{Format: DetectFormat(text)}
+
+	switch doc.Format {
+	case FormatSRT:
+		doc.parseSRT(text, srtTiming)
+	case FormatVTT:
+		doc.parseSRT(text, vttTiming)
+	case FormatASS, FormatSSA:
+		doc.parseASS(text)
+	case FormatMicroDVD:
+		doc.parseMicroDVD(text, fps)
+	default:
+		return nil, ErrUnknownFormat
+	}
+
+	if len(doc.Cues) == 0 {
+		return nil, ErrUnknownFormat
+	}
+	return doc, nil
+}
+
+// Shift moves all cues by offset, cues that would end before the start are dropped
+func (d *Document) Shift(offset time.Duration) {
+	cues := d.Cues[:0]
+	for _, c := range d.Cues {
+		c.Start += offset
+		c.End += offset
+		if c.End <= 0 {
+			continue
+		}
+		if c.Start < 0 {
+			c.Start = 0
+		}
+		cues = append(cues, c)
+	}
+	d.Cues = cues
+}
+
+// Rescale multiplies all timings by ratio, which is a source to target frame rate ratio,
+// like 25/23.976 for subtitles, made for PAL release
+func (d *Document) Rescale(ratio float64) {
+	for _, c := range d.Cues {
+		c.Start = time.Duration(float64(c.Start) * ratio)
+		c.End = time.Duration(float64(c.End) * ratio)
+	}
+}
+
+// Render returns subtitles text in the format, fps is used for frame based formats
+func (d *Document) Render(format string, fps float64) string {
+	switch format {
+	case FormatVTT:
+		return d.renderVTT()
+	case FormatASS, FormatSSA:
+		return d.renderASS(format)
+	case FormatMicroDVD:
+		return d.renderMicroDVD(fps)
+	}
+	return d.renderSRT()
+}
+
+func (d *Document) parseSRT(text string, timing *regexp.Regexp) {
+	var cue *Cue
+	lines := []string{}
+
+	flush := func() {
+		if cue != nil && len(lines) > 0 {
+			cue.Text = strings.Join(lines, "\n")
+			d.Cues = append(d.Cues, cue)
+		}
+		cue = nil
+		lines = lines[:0]
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if m := timing.FindStringSubmatch(line); m != nil {
+			flush()
+			cue = &Cue{Start: parseTimestamp(m[1]), End: parseTimestamp(m[2])}
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		if cue != nil {
+			lines = append(lines, line)
+		}
+	}
+	flush()
+}
+
+func (d *Document) parseASS(text string) {
+	var header strings.Builder
+	inEvents := false
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "[") {
+			inEvents = strings.EqualFold(trimmed, "[Events]")
+			if !inEvents {
+				header.WriteString(line + "\n")
+			}
+			continue
+		}
+		if !inEvents {
+			header.WriteString(line + "\n")
+			continue
+		}
+
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(key) {
+		case "format":
+			d.assFormat = splitTrimmed(value, ",")
+		case "dialogue":
+			if c := d.parseASSDialogue(value); c != nil {
+				d.Cues = append(d.Cues, c)
+			}
+		}
+	}
+
+	d.assHeader = strings.TrimRight(header.String(), "\n")
+}
+
+func (d *Document) parseASSDialogue(value string) *Cue {
+	if len(d.assFormat) == 0 {
+		d.assFormat = strings.Split("Layer,Start,End,Style,Name,MarginL,MarginR,MarginV,Effect,Text", ",")
+	}
+	fields := strings.SplitN(strings.TrimLeft(value, " "), ",", len(d.assFormat))
+	if len(fields) != len(d.assFormat) {
+		return nil
+	}
+
+	c := &Cue{assFields: fields}
+	for i, name := range d.assFormat {
+		switch strings.ToLower(name) {
+		case "start":
+			c.Start = parseTimestamp(fields[i])
+		case "end":
+			c.End = parseTimestamp(fields[i])
+		case "text":
+			c.Text = assToText(fields[i])
+		}
+	}
+	return c
+}
+
+func (d *Document) parseMicroDVD(text string, fps float64) {
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		m := microDVDLine.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if m == nil {
+			continue
+		}
+
+		start, _ := strconv.Atoi(m[1])
+		end, _ := strconv.Atoi(m[2])
+
+		// Frame rate is declared as the first line, like "{1}{1}25.000"
+		if len(d.Cues) == 0 && d.FPS == 0 && start <= 1 && end <= 1 {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(m[3]), 64); err == nil && f > 0 {
+				d.FPS = f
+				continue
+			}
+		}
+
+		d.Cues = append(d.Cues, &Cue{
+			Start: time.Duration(float64(start) * float64(time.Second)),
+			End:   time.Duration(float64(end) * float64(time.Second)),
+			Text:  microDVDToText(m[3]),
+		})
+	}
+
+	// Frames are stored in seconds fields until frame rate is known
+	if d.FPS > 0 {
+		fps = d.FPS
+	} else if fps <= 0 {
+		fps = defaultFPS
+	}
+	for _, c := range d.Cues {
+		c.Start = time.Duration(float64(c.Start) / fps)
+		c.End = time.Duration(float64(c.End) / fps)
+
+		// End frame can be omitted, subtitle is shown for a few seconds then
+		if c.End <= c.Start {
+			c.End = c.Start + microDVDDuration
+		}
+	}
+}
+
+func (d *Document) renderSRT() string {
+	var b strings.Builder
+	for i, c := range d.Cues {
+		fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n\n", i+1, formatTimestamp(c.Start, ",", 3), formatTimestamp(c.End, ",", 3), c.Text)
+	}
+	return b.String()
+}
+
+func (d *Document) renderVTT() string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+	for _, c := range d.Cues {
+		fmt.Fprintf(&b, "%s --> %s\n%s\n\n", formatTimestamp(c.Start, ".", 3), formatTimestamp(c.End, ".", 3), c.Text)
+	}
+	return b.String()
+}
+
+func (d *Document) renderMicroDVD(fps float64) string {
+	if fps <= 0 {
+		fps = defaultFPS
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "{1}{1}%.3f\n", fps)
+	for _, c := range d.Cues {
+		fmt.Fprintf(&b, "{%d}{%d}%s\n", toFrame(c.Start, fps), toFrame(c.End, fps), textToMicroDVD(c.Text))
+	}
+	return b.String()
+}
+
+func (d *Document) renderASS(format string) string {
+	var b strings.Builder
+
+	// Styles and dialogue fields are kept, when source is in the same format
+	if d.Format == format && d.assHeader != "" {
+		b.WriteString(d.assHeader + "\n\n[Events]\n")
+		b.WriteString("Format: " + strings.Join(d.assFormat, ", ") + "\n")
+		for _, c := range d.Cues {
+			fields := append([]string{}, c.assFields...)
+			for i, name := range d.assFormat {
+				switch strings.ToLower(name) {
+				case "start":
+					fields[i] = formatTimestamp(c.Start, ".", 2)
+				case "end":
+					fields[i] = formatTimestamp(c.End, ".", 2)
+				}
+			}
+			b.WriteString("Dialogue: " + strings.Join(fields, ",") + "\n")
+		}
+		return b.String()
+	}
+
+	if format == FormatSSA {
+		b.WriteString("[Script Info]\nScriptType: v4.00\n\n[V4 Styles]\n")
+		b.WriteString("Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, TertiaryColour, BackColour, Bold, Italic, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, AlphaLevel, Encoding\n")
+		b.WriteString("Style: Default,Arial,20,16777215,65535,65535,0,0,0,1,2,0,2,10,10,10,0,1\n\n")
+		b.WriteString("[Events]\nFormat: Marked, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n")
+	} else {
+		b.WriteString("[Script Info]\nScriptType: v4.00+\nWrapStyle: 0\nScaledBorderAndShadow: yes\n\n[V4+ Styles]\n")
+		b.WriteString("Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding\n")
+		b.WriteString("Style: Default,Arial,20,&H00FFFFFF,&H0000FFFF,&H00000000,&H80000000,0,0,0,0,100,100,0,0,1,2,0,2,10,10,10,1\n\n")
+		b.WriteString("[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n")
+	}
+
+	first := "0"
+	if format == FormatSSA {
+		first = "Marked=0"
+	}
+	for _, c := range d.Cues {
+		fmt.Fprintf(&b, "Dialogue: %s,%s,%s,Default,,0,0,0,,%s\n", first, formatTimestamp(c.Start, ".", 2), formatTimestamp(c.End, ".", 2), textToASS(c.Text))
+	}
+	return b.String()
+}
+
+// parseTimestamp parses "h:mm:ss,mmm", "h:mm:ss.cc" and "mm:ss.mmm" timestamps
+func parseTimestamp(s string) time.Duration {
+	s = strings.TrimSpace(strings.Replace(s, ",", ".", 1))
+
+	fraction := time.Duration(0)
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		digits := s[i+1:]
+		if n, err := strconv.Atoi(digits); err == nil {
+			fraction = time.Duration(float64(n) / math.Pow10(len(digits)) * float64(time.Second))
+		}
+		s = s[:i]
+	}
+
+	ret := time.Duration(0)
+	for _, part := range strings.Split(s, ":") {
+		n, _ := strconv.Atoi(part)
+		ret = ret*60 + time.Duration(n)*time.Second
+	}
+	return ret + fraction
+}
+
+// formatTimestamp formats duration as "hh:mm:ss<sep><digits of fraction>",
+// ASS timestamps use 2 digits and a single digit for hours
+func formatTimestamp(d time.Duration, sep string, digits int) string {
+	if d < 0 {
+		d = 0
+	}
+	unit := time.Duration(math.Pow10(9 - digits))
+	d = (d + unit/2) / unit
+
+	fraction := int64(d % time.Duration(math.Pow10(digits)))
+	seconds := int64(d / time.Duration(math.Pow10(digits)))
+
+	hours := fmt.Sprintf("%02d", seconds/3600)
+	if digits == 2 {
+		hours = strconv.FormatInt(seconds/3600, 10)
+	}
+	return fmt.Sprintf("%s:%02d:%02d%s%0*d", hours, seconds/60%60, seconds%60, sep, digits, fraction)
+}
+
+func toFrame(d time.Duration, fps float64) int {
+	return int(math.Round(d.Seconds() * fps))
+}
+
+func assToText(s string) string {
+	s = strings.NewReplacer(`\N`, "\n", `\n`, "\n", `\h`, " ").Replace(s)
+	return assOverride.ReplaceAllStringFunc(s, func(block string) string {
+		ret := ""
+		for _, m := range assStyleTag.FindAllStringSubmatch(block, -1) {
+			if m[2] == "1" {
+				ret += "<" + m[1] + ">"
+			} else {
+				ret += "</" + m[1] + ">"
+			}
+		}
+		return ret
+	})
+}
+
+func textToASS(s string) string {
+	s = htmlTag.ReplaceAllStringFunc(s, func(tag string) string {
+		m := htmlTag.FindStringSubmatch(tag)
+		name := strings.ToLower(m[2])
+		if name != "i" && name != "b" && name != "u" {
+			return ""
+		}
+		if m[1] == "/" {
+			return `{\` + name + `0}`
+		}
+		return `{\` + name + `1}`
+	})
+	return strings.ReplaceAll(s, "\n", `\N`)
+}
+
+func microDVDToText(s string) string {
+	italic := microDVDItalic.MatchString(s)
+	s = microDVDCode.ReplaceAllString(s, "")
+
+	lines := strings.Split(s, "|")
+	if italic {
+		for i := range lines {
+			lines[i] = "<i>" + lines[i] + "</i>"
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func textToMicroDVD(s string) string {
+	italic := strings.HasPrefix(s, "<i>")
+	s = htmlTag.ReplaceAllString(s, "")
+	s = strings.ReplaceAll(s, "\n", "|")
+	if italic {
+		return "{y:i}" + s
+	}
+	return s
+}
+
+func splitTrimmed(s, sep string) []string {
+	ret := strings.Split(s, sep)
+	for i := range ret {
+		ret[i] = strings.TrimSpace(ret[i])
+	}
+	return ret
+}
@@ -1,4 +1,4 @@
-package opensubtitles
+package subtitles
 
 import (
 	"bytes"
@@ -10,28 +10,29 @@ import (
 )
 
 const (
-	chunkSize = 65536 // 64k
+	// HashChunkSize is a size of file head and tail, used for hash calculation
+	HashChunkSize = 65536 // 64k
 )
 
-// Hash ...
+// Hash calculates OpenSubtitles hash of the file, which is a sum of file size and its first and last 64k
 func Hash(r io.ReaderAt, size int64) (string, error) {
 	var hash uint64
 
-	if size < chunkSize*2 {
+	if size < HashChunkSize*2 {
 		return "", errors.New("File is too small")
 	}
 
 	// Read head and tail blocks.
-	buf := make([]byte, chunkSize*2)
-	if _, err := r.ReadAt(buf[:chunkSize], 0); err != nil {
+	buf := make([]byte, HashChunkSize*2)
+	if _, err := r.ReadAt(buf[:HashChunkSize], 0); err != nil {
 		return "", err
 	}
-	if _, err := r.ReadAt(buf[chunkSize:], size-chunkSize); err != nil {
+	if _, err := r.ReadAt(buf[HashChunkSize:], size-HashChunkSize); err != nil {
 		return "", err
 	}
 
 	// Convert to uint64, and sum.
-	nums := make([]uint64, (chunkSize*2)/8)
+	nums := make([]uint64, (HashChunkSize*2)/8)
 	reader := bytes.NewReader(buf)
 	if err := binary.Read(reader, binary.LittleEndian, &nums); err != nil {
 		return "", err
@@ -43,7 +44,7 @@ func Hash(r io.ReaderAt, size int64) (string, error) {
 	return fmt.Sprintf("%016x", hash+uint64(size)), nil
 }
 
-// HashFile ...
+// HashFile calculates OpenSubtitles hash of the local file
 func HashFile(file *os.File) (string, error) {
 	stats, err := file.Stat()
 	if err != nil {
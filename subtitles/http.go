@@ -0,0 +1,206 @@
+package subtitles
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-json"
+
+	"github.com/elgatito/elementum/proxy"
+	"github.com/elgatito/elementum/util"
+)
+
+// HTTPProvider searches subtitles with a generic HTTP API.
+// Search is a GET request to the URL with parameters:
+// mode ("hash", "ids" or "query"), hash, imdb_id, tmdb_id, type, season, episode, query and languages.
+// Response is a JSON list of subtitles:
+//
+//	[{"url": "...", "file_name": "...", "language": "en", "format": "srt", "release": "...",
+//	  "rating": 8.5, "downloads": 100, "hash_match": true, "hearing_impaired": false}]
+//
+// Subtitle is downloaded from its "url", resolved against provider URL.
+// Links to other hosts are skipped, so provider can not make Elementum request arbitrary addresses.
+type HTTPProvider struct {
+	URL    string
+	Client *http.Client
+}
+
+// httpSubtitle is a single subtitle in HTTP provider response
+type httpSubtitle struct {
+	URL             string  `json:"url"`
+	FileName        string  `json:"file_name"`
+	Language        string  `json:"language"`
+	Format          string  `json:"format"`
+	Release         string  `json:"release"`
+	Rating          float64 `json:"rating"`
+	Downloads       int     `json:"downloads"`
+	HashMatch       bool    `json:"hash_match"`
+	HearingImpaired bool    `json:"hearing_impaired"`
+}
+
+// NewHTTPProvider returns provider for the HTTP API, using proxied client
+func NewHTTPProvider(u string) *HTTPProvider {
+	return &HTTPProvider{URL: u, Client: proxy.GetClient()}
+}
+
+// Name ...
+func (p *HTTPProvider) Name() string {
+	return "http"
+}
+
+// SearchByHash ...
+func (p *HTTPProvider) SearchByHash(q *Query) ([]*Subtitle, error) {
+	return p.search(q, url.Values{
+		"mode": {"hash"},
+		"hash": {q.Hash},
+	})
+}
+
+// SearchByIDs ...
+func (p *HTTPProvider) SearchByIDs(q *Query) ([]*Subtitle, error) {
+	params := url.Values{"mode": {"ids"}}
+	if q.IMDBID != "" {
+		params.Set("imdb_id", q.IMDBID)
+	}
+	if q.TMDBID != 0 {
+		params.Set("tmdb_id", strconv.Itoa(q.TMDBID))
+	}
+	return p.search(q, params)
+}
+
+// SearchByQuery ...
+func (p *HTTPProvider) SearchByQuery(q *Query) ([]*Subtitle, error) {
+	return p.search(q, url.Values{
+		"mode":  {"query"},
+		"query": {q.SearchText()},
+	})
+}
+
+// Download saves subtitle into subtitles folder.
+// Subtitle ID is a reference, relative to provider URL, absolute URLs are rejected.
+func (p *HTTPProvider) Download(s *Subtitle) (string, error) {
+	link, err := p.resolve(s.ID)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := p.Client.Get(link.String())
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("bad status downloading %s: %s", link, resp.Status)
+	}
+
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+
+	name := filepath.Base(s.FileName)
+	if name == "." || name == string(filepath.Separator) {
+		name = path.Base(link.Path)
+	}
+
+	outPath := filepath.Join(dir, util.ToFileName(name))
+	out, err := os.Create(outPath)
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		return "", err
+	}
+	return outPath, nil
+}
+
+func (p *HTTPProvider) search(q *Query, params url.Values) ([]*Subtitle, error) {
+	base, err := url.Parse(p.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	if q.Type != "" {
+		params.Set("type", q.Type)
+	}
+	if q.Type == "episode" {
+		params.Set("season", strconv.Itoa(q.Season))
+		params.Set("episode", strconv.Itoa(q.Episode))
+	}
+	if len(q.Languages) > 0 {
+		params.Set("languages", strings.Join(q.Languages, ","))
+	}
+
+	values := base.Query()
+	for k, v := range params {
+		values[k] = v
+	}
+	u := *base
+	u.RawQuery = values.Encode()
+
+	resp, err := p.Client.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("bad status searching %s: %s", p.URL, resp.Status)
+	}
+
+	var results []httpSubtitle
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, err
+	}
+
+	ret := make([]*Subtitle, 0, len(results))
+	for _, r := range results {
+		link, err := base.Parse(r.URL)
+		if r.URL == "" || err != nil {
+			continue
+		} else if link.Scheme != base.Scheme || link.Host != base.Host {
+			log.Debugf("Skipping subtitle %s outside of provider %s", r.URL, base.Host)
+			continue
+		}
+
+		ret = append(ret, &Subtitle{
+			Provider:        p.Name(),
+			ID:              link.RequestURI(),
+			FileName:        r.FileName,
+			Language:        r.Language,
+			Format:          r.Format,
+			Release:         r.Release,
+			Rating:          r.Rating,
+			Downloads:       r.Downloads,
+			HashMatch:       r.HashMatch,
+			HearingImpaired: r.HearingImpaired,
+		})
+	}
+	return ret, nil
+}
+
+// resolve returns URL of the subtitle ID, that is a reference relative to provider URL
+func (p *HTTPProvider) resolve(id string) (*url.URL, error) {
+	base, err := url.Parse(p.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	ref, err := url.Parse(id)
+	if err != nil {
+		return nil, err
+	} else if id == "" || ref.IsAbs() || ref.Host != "" {
+		return nil, fmt.Errorf("subtitle %s is outside of %s", id, base.Host)
+	}
+	return base.ResolveReference(ref), nil
+}
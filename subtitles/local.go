@@ -0,0 +1,196 @@
+package subtitles
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elgatito/elementum/util"
+)
+
+// localRescanInterval is a time, after which files of the local folder are listed again
+const localRescanInterval = 10 * time.Minute
+
+// LocalProvider searches subtitles in a local folder, like a personal subtitles archive.
+// Files are matched by playing file name, by IDs and by title, found in file paths,
+// language is taken from the name suffix, like "Movie.2020.en.srt".
+type LocalProvider struct {
+	Path string
+
+	mu        sync.Mutex
+	files     []string
+	scanErr   error
+	scannedAt time.Time
+}
+
+// NewLocalProvider returns provider for subtitles in the folder
+func NewLocalProvider(path string) *LocalProvider {
+	return &LocalProvider{Path: path}
+}
+
+// Name ...
+func (p *LocalProvider) Name() string {
+	return "local"
+}
+
+// SearchByHash returns subtitles, named after the playing file or its hash
+func (p *LocalProvider) SearchByHash(q *Query) ([]*Subtitle, error) {
+	name := strings.Join(tokens(q.FileName), ".")
+
+	return p.find(func(rel string, fileTokens []string) bool {
+		base := strings.Join(tokens(filepath.Base(rel)), ".")
+		return (name != "" && base == name) || (q.Hash != "" && base == strings.ToLower(q.Hash))
+	}, true)
+}
+
+// SearchByIDs returns subtitles with IMDB or TMDB ID and episode number in the path,
+// like "Show (2020) {tmdb-1234}/Season 1/Show.S01E02.en.srt"
+func (p *LocalProvider) SearchByIDs(q *Query) ([]*Subtitle, error) {
+	imdb := strings.ToLower(q.IMDBID)
+	tmdb := strconv.Itoa(q.TMDBID)
+
+	return p.find(func(rel string, fileTokens []string) bool {
+		found := false
+		for i, t := range fileTokens {
+			if (imdb != "" && t == imdb) || (q.TMDBID != 0 && t == "tmdb" && i+1 < len(fileTokens) && fileTokens[i+1] == tmdb) {
+				found = true
+				break
+			}
+		}
+		return found && (q.Type != "episode" || containsToken(fileTokens, episodeToken(q.Season, q.Episode)))
+	}, false)
+}
+
+// SearchByQuery returns subtitles, which path contains all words of the query
+func (p *LocalProvider) SearchByQuery(q *Query) ([]*Subtitle, error) {
+	words := tokens(q.SearchText())
+	if len(words) == 0 {
+		return nil, nil
+	}
+
+	return p.find(func(rel string, fileTokens []string) bool {
+		for _, w := range words {
+			if !containsToken(fileTokens, w) {
+				return false
+			}
+		}
+		return true
+	}, false)
+}
+
+// Download copies the subtitle into subtitles folder. Archive files are never processed in place,
+// so conversion and sync offsets are applied to a fresh copy on each play.
+func (p *LocalProvider) Download(s *Subtitle) (string, error) {
+	path := filepath.Join(p.Path, filepath.FromSlash(s.ID))
+	if rel, err := filepath.Rel(p.Path, path); err != nil || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("subtitle %s is outside of %s", s.ID, p.Path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+
+	outPath := filepath.Join(dir, util.ToFileName(filepath.Base(path)))
+	if err := os.WriteFile(outPath, data, 0644); err != nil {
+		return "", err
+	}
+	return outPath, nil
+}
+
+func (p *LocalProvider) find(match func(rel string, fileTokens []string) bool, hashMatch bool) ([]*Subtitle, error) {
+	files, err := p.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []*Subtitle{}
+	for _, rel := range files {
+		if !match(rel, tokens(rel)) {
+			continue
+		}
+
+		name := filepath.Base(rel)
+		ret = append(ret, &Subtitle{
+			Provider:  p.Name(),
+			ID:        filepath.ToSlash(rel),
+			FileName:  name,
+			Language:  fileLanguage(name),
+			Format:    strings.TrimPrefix(strings.ToLower(filepath.Ext(name)), "."),
+			Release:   strings.TrimSuffix(name, filepath.Ext(name)),
+			HashMatch: hashMatch,
+		})
+	}
+	return ret, nil
+}
+
+// scan collects subtitle files of the folder, the list is kept for localRescanInterval
+func (p *LocalProvider) scan() ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.scannedAt.IsZero() && time.Since(p.scannedAt) < localRescanInterval {
+		return p.files, p.scanErr
+	}
+
+	files := []string{}
+	err := filepath.WalkDir(p.Path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable folders are skipped, not to fail the whole archive
+			if d != nil && d.IsDir() && path != p.Path {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || !util.IsSubtitlesExt(strings.ToLower(filepath.Ext(path))) {
+			return nil
+		}
+
+		if rel, errRel := filepath.Rel(p.Path, path); errRel == nil {
+			files = append(files, rel)
+		}
+		return nil
+	})
+
+	p.files, p.scanErr, p.scannedAt = files, err, time.Now()
+	return p.files, p.scanErr
+}
+
+// fileLanguage returns language suffix of the subtitle file name, like "en" in "Movie.en.srt"
+func fileLanguage(name string) string {
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	lang := strings.TrimPrefix(filepath.Ext(name), ".")
+	if len(lang) < 2 || len(lang) > 3 {
+		return ""
+	}
+	lang = strings.ToLower(lang)
+	for _, r := range lang {
+		if r < 'a' || r > 'z' {
+			return ""
+		}
+	}
+	return lang
+}
+
+func episodeToken(season, episode int) string {
+	return fmt.Sprintf("s%02de%02d", season, episode)
+}
+
+func containsToken(list []string, token string) bool {
+	for _, t := range list {
+		if t == token {
+			return true
+		}
+	}
+	return false
+}
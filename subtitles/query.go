@@ -0,0 +1,148 @@
+package subtitles
+
+import (
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/tmdb"
+	"github.com/elgatito/elementum/xbmc"
+)
+
+// NewQuery builds query for the item, playing in Kodi, from player labels and the playing file.
+// Languages are Kodi language names, converted to ISO 639-1 codes, with preferred language first.
+func NewQuery(xbmcHost *xbmc.XBMCHost, searchString string, languages []string, preferredLanguage string, showID int, playingFile string) *Query {
+	log.Debugf("NewQuery: %s; %#v; %s; %s", searchString, languages, preferredLanguage, playingFile)
+
+	// First of all, we get Subtitles language settings from Kodi
+	// (there is a separate setting for that) in Player settings.
+	if !config.Get().OSDBAutoLanguage && config.Get().OSDBLanguage != "" {
+		languages = []string{config.Get().OSDBLanguage}
+	}
+	if preferredLanguage != "" && preferredLanguage != "Unknown" {
+		languages = append([]string{preferredLanguage}, languages...)
+	}
+
+	q := &Query{
+		Text:      searchString,
+		Languages: convertLanguages(xbmcHost, languages),
+		TMDBID:    showID,
+	}
+	q.FileName, q.Hash = playingFileInfo(playingFile)
+
+	labels := xbmcHost.InfoLabels(
+		"VideoPlayer.Title",
+		"VideoPlayer.OriginalTitle",
+		"VideoPlayer.Year",
+		"VideoPlayer.TVShowTitle",
+		"VideoPlayer.Season",
+		"VideoPlayer.Episode",
+		"VideoPlayer.IMDBNumber",
+	)
+	log.Debugf("Fetched VideoPlayer labels: %#v", labels)
+	if labels == nil {
+		return q
+	}
+
+	imdb := labels["VideoPlayer.IMDBNumber"]
+	if !strings.HasPrefix(imdb, "tt") {
+		imdb = ""
+	}
+
+	if showID != 0 || labels["VideoPlayer.TVShowTitle"] != "" {
+		season, errSeason := strconv.Atoi(labels["VideoPlayer.Season"])
+		episode, errEpisode := strconv.Atoi(labels["VideoPlayer.Episode"])
+		if errSeason != nil || errEpisode != nil || season < 0 || episode <= 0 {
+			return q
+		}
+
+		q.Type = "episode"
+		q.Season = season
+		q.Episode = episode
+		q.Title = labels["VideoPlayer.TVShowTitle"]
+
+		// IMDB number of the episode is only used to find the show,
+		// original name of the show is more likely to be found than localized one.
+		if q.TMDBID == 0 && imdb != "" {
+			if r := tmdb.Find(imdb, "imdb_id"); r != nil && len(r.TVResults) > 0 {
+				q.TMDBID = r.TVResults[0].ID
+			}
+		}
+		if q.TMDBID != 0 {
+			if show := tmdb.GetShow(q.TMDBID, config.Get().Language); show != nil && show.OriginalName != "" {
+				q.Title = show.OriginalName
+			}
+		}
+		return q
+	}
+
+	q.Type = "movie"
+	q.IMDBID = imdb
+	q.Title = labels["VideoPlayer.OriginalTitle"]
+	if q.Title == "" {
+		q.Title = labels["VideoPlayer.Title"]
+	}
+	q.Year, _ = strconv.Atoi(labels["VideoPlayer.Year"])
+
+	if imdb != "" {
+		if r := tmdb.Find(imdb, "imdb_id"); r != nil && len(r.MovieResults) > 0 {
+			q.TMDBID = r.MovieResults[0].ID
+			if r.MovieResults[0].OriginalTitle != "" {
+				q.Title = r.MovieResults[0].OriginalTitle
+			}
+		}
+	}
+
+	return q
+}
+
+// convertLanguages converts Kodi language names into unique ISO 639-1 codes
+func convertLanguages(xbmcHost *xbmc.XBMCHost, languages []string) []string {
+	ret := make([]string, 0, len(languages))
+	for _, lang := range languages {
+		if lang == "" {
+			continue
+		}
+
+		code := ""
+		if lang == "Portuguese (Brazil)" {
+			code = "pt-br"
+		} else {
+			code = xbmcHost.ConvertLanguage(lang, xbmc.Iso639_1)
+		}
+
+		if code != "" && languageIndex(ret, code) < 0 {
+			ret = append(ret, code)
+		}
+	}
+	return ret
+}
+
+// playingFileInfo returns base name of the playing file, and its hash for local files
+func playingFileInfo(playingFile string) (name, hash string) {
+	if playingFile == "" {
+		return
+	}
+	if strings.HasPrefix(playingFile, "http://") || strings.HasPrefix(playingFile, "https://") {
+		if u, err := url.Parse(playingFile); err == nil {
+			name = path.Base(u.Path)
+		}
+		return
+	}
+
+	name = filepath.Base(playingFile)
+
+	file, err := os.Open(playingFile)
+	if err != nil {
+		log.Debug(err)
+		return
+	}
+	defer file.Close()
+
+	hash, _ = HashFile(file)
+	return
+}
@@ -0,0 +1,144 @@
+package subtitles
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Weights of ranking factors, language preference matters the most,
+// then exact file match and then similarity of release names
+const (
+	languageWeight   = 100
+	hashMatchWeight  = 50
+	similarityWeight = 40
+	ratingWeight     = 5
+)
+
+var (
+	tokenSeparator = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+	mediaExtension = regexp.MustCompile(`(\.[a-z]{2,3})?\.(srt|ssa|ass|sub|vtt|smi)$|\.(mkv|mp4|m4v|avi|mov|ts|wmv)$`)
+)
+
+// Rank merges duplicate subtitles and sorts them by language preference,
+// hash match, release name similarity with the playing file and rating
+func Rank(q *Query, subs []*Subtitle) []*Subtitle {
+	fileTokens := tokens(q.FileName)
+
+	// Same subtitle can be found with several search modes of the same provider
+	byID := map[string]*Subtitle{}
+	merged := make([]*Subtitle, 0, len(subs))
+	for _, s := range subs {
+		if s == nil {
+			continue
+		}
+
+		key := s.Provider + "|" + s.ID
+		if existing, ok := byID[key]; ok {
+			existing.HashMatch = existing.HashMatch || s.HashMatch
+			continue
+		}
+		byID[key] = s
+		merged = append(merged, s)
+	}
+
+	for _, s := range merged {
+		s.score = score(q, fileTokens, s)
+	}
+
+	// Same file can be mirrored by several providers, the best scored copy is kept
+	byFile := map[string]*Subtitle{}
+	ret := make([]*Subtitle, 0, len(merged))
+	for _, s := range merged {
+		key := strings.ToLower(s.Language) + "|" + strings.Join(tokens(s.FileName), ".")
+		if s.FileName == "" {
+			key = s.Provider + "|" + s.ID
+		}
+
+		if existing, ok := byFile[key]; ok {
+			if s.score > existing.score {
+				*existing = *s
+			}
+			continue
+		}
+		byFile[key] = s
+		ret = append(ret, s)
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].score > ret[j].score
+	})
+	return ret
+}
+
+func score(q *Query, fileTokens []string, s *Subtitle) (ret float64) {
+	if idx := languageIndex(q.Languages, s.Language); idx >= 0 {
+		ret += languageWeight * float64(len(q.Languages)-idx) / float64(len(q.Languages))
+	}
+	if s.HashMatch {
+		ret += hashMatchWeight
+	}
+
+	release := s.Release
+	if release == "" {
+		release = s.FileName
+	}
+	ret += similarityWeight * similarity(fileTokens, tokens(release))
+
+	if s.Rating > 0 {
+		ret += ratingWeight * min(s.Rating, 10) / 10
+	}
+	return
+}
+
+func languageIndex(languages []string, language string) int {
+	for i, l := range languages {
+		if strings.EqualFold(l, language) {
+			return i
+		}
+	}
+	return -1
+}
+
+// tokens splits release name into lowercase words, ignoring media extension and language suffix
+func tokens(name string) []string {
+	name = mediaExtension.ReplaceAllString(strings.ToLower(name), "")
+
+	ret := []string{}
+	for _, t := range tokenSeparator.Split(name, -1) {
+		if t != "" {
+			ret = append(ret, t)
+		}
+	}
+	return ret
+}
+
+// similarity returns Jaccard index of two token sets
+func similarity(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	set := map[string]bool{}
+	for _, t := range a {
+		set[t] = true
+	}
+
+	common := 0
+	union := len(set)
+	seen := map[string]bool{}
+	for _, t := range b {
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+
+		if set[t] {
+			common++
+		} else {
+			union++
+		}
+	}
+
+	return float64(common) / float64(union)
+}
@@ -0,0 +1,230 @@
+package subtitles
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/op/go-logging"
+
+	"github.com/elgatito/elementum/config"
+)
+
+var (
+	log = logging.MustGetLogger("subtitles")
+
+	providersMu sync.RWMutex
+	providers   []SubtitleProvider
+
+	// localProvider is kept between searches, not to scan the archive on each search
+	localMu       sync.Mutex
+	localProvider *LocalProvider
+
+	// ErrUnknownProvider is returned when subtitle belongs to a provider, that is not available
+	ErrUnknownProvider = fmt.Errorf("unknown subtitles provider")
+)
+
+// SubtitleProvider searches and downloads subtitles from a single source
+type SubtitleProvider interface {
+	// Name is a unique name of the provider, stored in found subtitles
+	Name() string
+	// SearchByHash searches subtitles for the exact file by its hash
+	SearchByHash(q *Query) ([]*Subtitle, error)
+	// SearchByIDs searches subtitles by IMDB/TMDB IDs with season and episode numbers
+	SearchByIDs(q *Query) ([]*Subtitle, error)
+	// SearchByQuery searches subtitles by text query
+	SearchByQuery(q *Query) ([]*Subtitle, error)
+	// Download stores subtitle on disk and returns its path
+	Download(s *Subtitle) (string, error)
+}
+
+// Query describes playing item, subtitles are searched for
+type Query struct {
+	// Type is "movie" or "episode", empty if unknown
+	Type string
+	// Hash is an OpenSubtitles hash of the playing file
+	Hash string
+	// IMDBID is an IMDB ID with "tt" prefix, of the movie or the show
+	IMDBID string
+	// TMDBID is a TMDB ID of the movie or the show
+	TMDBID  int
+	Title   string
+	Year    int
+	Season  int
+	Episode int
+	// Text is a free text search, which overrides title search
+	Text string
+	// FileName is a base name of the playing file, used to rank releases
+	FileName string
+	// Languages is a list of ISO 639-1 codes, the most preferred first
+	Languages []string
+}
+
+// Subtitle is a single subtitle found by a provider
+type Subtitle struct {
+	Provider string `json:"provider"`
+	// ID identifies subtitle within its provider, and is passed back to Download
+	ID              string  `json:"id"`
+	FileName        string  `json:"file_name"`
+	Language        string  `json:"language"`
+	Format          string  `json:"format"`
+	Release         string  `json:"release"`
+	Rating          float64 `json:"rating"`
+	Downloads       int     `json:"downloads"`
+	HashMatch       bool    `json:"hash_match"`
+	HearingImpaired bool    `json:"hearing_impaired"`
+
+	score float64
+}
+
+// HasIDs returns whether query has any ID to search with
+func (q *Query) HasIDs() bool {
+	return q.IMDBID != "" || q.TMDBID != 0
+}
+
+// SearchText returns text for search by query, built from title if there is no free text
+func (q *Query) SearchText() string {
+	if q.Text != "" {
+		return q.Text
+	}
+
+	switch {
+	case q.Title != "" && q.Type == "episode":
+		return fmt.Sprintf("%s S%02dE%02d", q.Title, q.Season, q.Episode)
+	case q.Title != "" && q.Year > 0:
+		return fmt.Sprintf("%s %d", q.Title, q.Year)
+	case q.Title != "":
+		return q.Title
+	}
+
+	return strings.TrimSuffix(q.FileName, filepath.Ext(q.FileName))
+}
+
+// Register adds provider to the list of providers, used by Search
+func Register(p SubtitleProvider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	providers = append(providers, p)
+}
+
+// Providers returns registered providers, followed by providers enabled in settings
+func Providers() []SubtitleProvider {
+	providersMu.RLock()
+	ret := append([]SubtitleProvider{}, providers...)
+	providersMu.RUnlock()
+
+	if p := getLocalProvider(); p != nil {
+		ret = append(ret, p)
+	}
+	if u := config.Get().SubtitlesHTTPURL; u != "" {
+		ret = append(ret, NewHTTPProvider(u))
+	}
+
+	return ret
+}
+
+// getLocalProvider returns provider for the local folder from settings, replaced when the folder is changed
+func getLocalProvider() *LocalProvider {
+	path := config.Get().SubtitlesLocalPath
+	if path == "" {
+		return nil
+	}
+
+	localMu.Lock()
+	defer localMu.Unlock()
+
+	if localProvider == nil || localProvider.Path != path {
+		localProvider = NewLocalProvider(path)
+	}
+	return localProvider
+}
+
+// Search searches subtitles with all providers and returns merged results, the best first
+func Search(q *Query) []*Subtitle {
+	return search(Providers(), q)
+}
+
+func search(list []SubtitleProvider, q *Query) []*Subtitle {
+	type searchFunc func(*Query) ([]*Subtitle, error)
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results []*Subtitle
+	)
+
+	run := func(p SubtitleProvider, mode string, f searchFunc) {
+		defer wg.Done()
+
+		subs, err := f(q)
+		if err != nil {
+			log.Warningf("Error searching subtitles by %s with %s: %s", mode, p.Name(), err)
+			return
+		}
+
+		mu.Lock()
+		results = append(results, subs...)
+		mu.Unlock()
+	}
+
+	for _, p := range list {
+		if q.Hash != "" {
+			wg.Add(1)
+			go run(p, "hash", p.SearchByHash)
+		}
+		if q.HasIDs() {
+			wg.Add(1)
+			go run(p, "IDs", p.SearchByIDs)
+		}
+		if q.SearchText() != "" {
+			wg.Add(1)
+			go run(p, "query", p.SearchByQuery)
+		}
+	}
+	wg.Wait()
+
+	return Rank(q, results)
+}
+
+// Download downloads subtitle with its provider, processes it with options,
+// or with options from settings if opts is nil, and returns local path
+func Download(s *Subtitle, opts *Options) (string, error) {
+	for _, p := range Providers() {
+		if p.Name() != s.Provider {
+			continue
+		}
+
+		path, err := p.Download(s)
+		if err != nil {
+			return "", err
+		}
+
+		if opts == nil {
+			opts = DefaultOptions()
+		}
+		if opts.Language == "" {
+			opts.Language = s.Language
+		}
+		return ConvertFile(path, opts)
+	}
+
+	return "", ErrUnknownProvider
+}
+
+// Dir returns folder for downloaded subtitles, creating it if needed
+func Dir() (string, error) {
+	dir := filepath.Join(config.Get().DownloadPath, "Subtitles")
+	if config.Get().DownloadPath == "." {
+		dir = filepath.Join(config.Get().TemporaryPath, "Subtitles")
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			return "", fmt.Errorf("Unable to create Subtitles folder: %s", err)
+		}
+	}
+
+	return dir, nil
+}
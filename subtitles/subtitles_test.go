@@ -0,0 +1,214 @@
+package subtitles
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elgatito/elementum/config"
+)
+
+func createFiles(t *testing.T, root string, files ...string) {
+	for _, f := range files {
+		path := filepath.Join(root, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("1\n00:00:01,000 --> 00:00:02,000\nHello\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLocalProvider(t *testing.T) {
+	root := t.TempDir()
+	createFiles(t, root,
+		"Movies/Movie.2020.1080p.BluRay.x264-GRP.en.srt",
+		"Movies/Movie.2020.720p.WEB.de.srt",
+		"Shows/Show (2019) {tmdb-1399}/Show.S01E02.720p.en.srt",
+		"Shows/Show (2019) {tmdb-1399}/Show.S01E03.720p.en.srt",
+		"Shows/Other {tmdb-13990}/Other.S01E02.en.srt",
+		"readme.txt",
+	)
+	p := NewLocalProvider(root)
+
+	subs, err := p.SearchByHash(&Query{FileName: "Movie.2020.1080p.BluRay.x264-GRP.mkv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs) != 1 || !subs[0].HashMatch || subs[0].Language != "en" || subs[0].Format != "srt" {
+		t.Fatalf("Unexpected search by file name result: %+v", subs)
+	}
+
+	subs, err = p.SearchByIDs(&Query{Type: "episode", TMDBID: 1399, Season: 1, Episode: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs) != 1 || subs[0].ID != "Shows/Show (2019) {tmdb-1399}/Show.S01E02.720p.en.srt" {
+		t.Fatalf("Unexpected search by IDs result: %+v", subs)
+	}
+
+	subs, err = p.SearchByQuery(&Query{Type: "movie", Title: "Movie", Year: 2020})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs) != 2 {
+		t.Fatalf("Expected 2 subtitles for query, got: %+v", subs)
+	}
+
+	config.Get().DownloadPath = t.TempDir()
+	path, err := p.Download(subs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		t.Fatalf("Expected subtitle to be copied out of the archive, got %s", path)
+	}
+	converted, err := ConvertFile(path, &Options{Format: FormatVTT, Offset: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(converted); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(subs[0].ID))); err != nil {
+		t.Fatalf("Archive subtitle should be kept: %s", err)
+	}
+	if _, err := p.Download(&Subtitle{ID: "../outside.srt"}); err == nil {
+		t.Error("Expected error for subtitle outside of the folder")
+	}
+}
+
+func TestHTTPProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/files/") {
+			w.Write([]byte("1\n00:00:01,000 --> 00:00:02,000\nHello\n"))
+			return
+		}
+
+		q := r.URL.Query()
+		if q.Get("key") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		switch q.Get("mode") {
+		case "hash":
+			if q.Get("hash") != "8e245d9679d31e12" {
+				t.Errorf("Unexpected hash: %s", q.Get("hash"))
+			}
+			w.Write([]byte(`[{"url": "/files/1.srt", "file_name": "Movie.2020.srt", "language": "en", "hash_match": true}]`))
+		case "query":
+			if q.Get("query") != "Movie 2020" || q.Get("languages") != "en,de" {
+				t.Errorf("Unexpected query: %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`[{"url": "files/2.srt", "file_name": "Movie.2020.de.srt", "language": "de", "rating": 9},
+				{"url": "https://example.com/3.srt", "file_name": "Movie.2020.de.srt", "language": "de", "rating": 10}]`))
+		default:
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer srv.Close()
+
+	p := &HTTPProvider{URL: srv.URL + "/search?key=secret", Client: srv.Client()}
+	q := &Query{Type: "movie", Hash: "8e245d9679d31e12", Title: "Movie", Year: 2020, Languages: []string{"en", "de"}}
+
+	subs := search([]SubtitleProvider{p}, q)
+	if len(subs) != 2 {
+		t.Fatalf("Expected 2 subtitles, got: %+v", subs)
+	}
+	if subs[0].ID != "/files/1.srt" || !subs[0].HashMatch {
+		t.Errorf("Unexpected first subtitle: %+v", subs[0])
+	}
+	if subs[1].ID != "/files/2.srt" || subs[1].Provider != "http" {
+		t.Errorf("Unexpected second subtitle: %+v", subs[1])
+	}
+
+	config.Get().DownloadPath = t.TempDir()
+	if path, err := p.Download(subs[1]); err != nil {
+		t.Fatal(err)
+	} else if filepath.Base(path) != "Movie.2020.de.srt" {
+		t.Errorf("Unexpected downloaded file: %s", path)
+	}
+	for _, id := range []string{"https://example.com/3.srt", "//example.com/3.srt", ""} {
+		if _, err := p.Download(&Subtitle{ID: id, FileName: "3.srt"}); err == nil {
+			t.Errorf("Expected error for subtitle %q outside of provider", id)
+		}
+	}
+}
+
+func TestRank(t *testing.T) {
+	q := &Query{
+		FileName:  "Show.S01E02.1080p.WEB-DL.DDP5.1.H.264-NTb.mkv",
+		Languages: []string{"en", "fr"},
+	}
+
+	subs := Rank(q, []*Subtitle{
+		{Provider: "a", ID: "1", FileName: "Show.S01E02.HDTV.x264-LOL.fr.srt", Language: "fr", HashMatch: true},
+		{Provider: "a", ID: "2", FileName: "Show.S01E02.HDTV.x264-LOL.en.srt", Language: "en", Rating: 10},
+		{Provider: "a", ID: "3", FileName: "Show.S01E02.1080p.WEB-DL.DDP5.1.H.264-NTb.en.srt", Language: "en"},
+		{Provider: "a", ID: "3", FileName: "Show.S01E02.1080p.WEB-DL.DDP5.1.H.264-NTb.en.srt", Language: "en", HashMatch: true},
+		{Provider: "b", ID: "x", FileName: "Show.S01E02.1080p.WEB-DL.DDP5.1.H.264-NTb.en.srt", Language: "EN"},
+		{Provider: "b", ID: "y", FileName: "Show.S01E02.es.srt", Language: "es"},
+	})
+
+	ids := []string{}
+	for _, s := range subs {
+		ids = append(ids, s.Provider+s.ID)
+	}
+	expected := []string{"a3", "a2", "a1", "by"}
+	if len(ids) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, ids)
+	}
+	for i := range expected {
+		if ids[i] != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected, ids)
+		}
+	}
+	if !subs[0].HashMatch {
+		t.Error("Hash match of merged duplicate is lost")
+	}
+}
+
+func TestConvert(t *testing.T) {
+	// Windows-1251 text with MicroDVD frames, made for 25 fps release
+	microDVD := append([]byte("{1}{1}25\r\n{250}{300}{y:i}"), 0xcf, 0xf0, 0xe8, 0xe2, 0xe5, 0xf2)
+	microDVD = append(microDVD, []byte("|Line\r\n{500}{}Next\r\n")...)
+
+	text, format, err := Convert(microDVD, &Options{FPS: 25, Offset: -time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "1\n00:00:09,000 --> 00:00:11,000\n<i>Привет</i>\n<i>Line</i>\n\n2\n00:00:19,000 --> 00:00:22,000\nNext\n\n"
+	if format != FormatSRT || text != expected {
+		t.Errorf("Unexpected conversion to %s:\n%q", format, text)
+	}
+
+	ass := "[Script Info]\nScriptType: v4.00+\n\n[V4+ Styles]\nStyle: Default,Arial,20\n\n[Events]\n" +
+		"Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n" +
+		"Dialogue: 0,0:00:25.00,0:00:27.50,Top,,0,0,0,,{\\i1}Hello{\\i0}, world\\Nagain\n"
+
+	text, format, err = Convert([]byte(ass), &Options{FPS: 23.976, SourceFPS: 25})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != FormatASS || !strings.Contains(text, "Dialogue: 0,0:00:26.07,0:00:28.67,Top,,0,0,0,,{\\i1}Hello") || !strings.Contains(text, "Style: Default,Arial,20") {
+		t.Errorf("Unexpected rescaled ASS:\n%s", text)
+	}
+
+	text, _, err = Convert([]byte(ass), &Options{Format: FormatVTT})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "WEBVTT\n\n00:00:25.000 --> 00:00:27.500\n<i>Hello</i>, world\nagain\n\n" {
+		t.Errorf("Unexpected VTT:\n%q", text)
+	}
+
+	if _, _, err := Convert([]byte{0x00, 0x00, 0x01, 0xba, 0x44}, nil); err != ErrUnknownFormat {
+		t.Errorf("Expected unknown format error for VobSub, got %v", err)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/elgatito/elementum/cache"
 	"github.com/elgatito/elementum/config"
 	"github.com/elgatito/elementum/fanart"
+	"github.com/elgatito/elementum/library/history"
 	"github.com/elgatito/elementum/library/playcount"
 	"github.com/elgatito/elementum/library/uid"
 	"github.com/elgatito/elementum/util"
@@ -184,6 +185,10 @@ func (episode *Episode) ToListItem(show *Show, season *Season) *xbmc.ListItem {
 			}
 		}
 	}
+	if r := history.GetResume(history.EpisodeID(show.ID, episode.SeasonNumber, episode.EpisodeNumber)); r != nil && item.Properties.ResumeTime == "" {
+		item.Properties.ResumeTime = strconv.FormatFloat(r.Position, 'f', 6, 64)
+		item.Properties.TotalTime = strconv.FormatFloat(r.Total, 'f', 6, 64)
+	}
 
 	episode.SetArt(show, season, item)
 
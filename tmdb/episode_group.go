@@ -0,0 +1,188 @@
+package tmdb
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/anacrolix/missinggo/perf"
+	"github.com/jmcvetta/napping"
+
+	"github.com/elgatito/elementum/cache"
+	"github.com/elgatito/elementum/util/reqapi"
+	"github.com/elgatito/elementum/xbmc"
+)
+
+// Episode group types, as defined by TMDB
+const (
+	EpisodeGroupOriginalAirDate = iota + 1
+	EpisodeGroupAbsolute
+	EpisodeGroupDVD
+	EpisodeGroupDigital
+	EpisodeGroupStoryArc
+	EpisodeGroupProduction
+	EpisodeGroupTV
+)
+
+var episodeGroupTypes = map[int]string{
+	EpisodeGroupOriginalAirDate: "Original Air Date",
+	EpisodeGroupAbsolute:        "Absolute",
+	EpisodeGroupDVD:             "DVD",
+	EpisodeGroupDigital:         "Digital",
+	EpisodeGroupStoryArc:        "Story Arc",
+	EpisodeGroupProduction:      "Production",
+	EpisodeGroupTV:              "TV",
+}
+
+// GetEpisodeGroups returns list of episode groups, available for a show.
+// Groups in the list do not contain episodes.
+func GetEpisodeGroups(showID int) []*EpisodeGroup {
+	defer perf.ScopeTimer()()
+
+	var groups *struct {
+		Results []*EpisodeGroup `json:"results"`
+	}
+
+	req := reqapi.Request{
+		API: reqapi.TMDBAPI,
+		URL: fmt.Sprintf("/tv/%d/episode_groups", showID),
+		Params: napping.Params{
+			"api_key": apiKey,
+		}.AsUrlValues(),
+		Result:      &groups,
+		Description: "show episode groups",
+
+		Cache:       true,
+		CacheExpire: cache.CacheExpireMedium,
+	}
+
+	req.Do()
+
+	if groups == nil {
+		return nil
+	}
+	return groups.Results
+}
+
+// GetEpisodeGroup returns episode group with all its episodes, sorted by the group order
+func GetEpisodeGroup(groupID string, language string) *EpisodeGroup {
+	if groupID == "" {
+		return nil
+	}
+
+	defer perf.ScopeTimer()()
+
+	var group *EpisodeGroup
+
+	req := reqapi.Request{
+		API: reqapi.TMDBAPI,
+		URL: fmt.Sprintf("/tv/episode_group/%s", groupID),
+		Params: napping.Params{
+			"api_key":  apiKey,
+			"language": language,
+		}.AsUrlValues(),
+		Result:      &group,
+		Description: "episode group",
+
+		Cache:       true,
+		CacheExpire: cache.CacheExpireMedium,
+	}
+
+	req.Do()
+
+	if group == nil {
+		return nil
+	}
+
+	sort.Slice(group.Groups, func(i, j int) bool {
+		return group.Groups[i].Order < group.Groups[j].Order
+	})
+	for _, g := range group.Groups {
+		sort.Slice(g.Episodes, func(i, j int) bool {
+			return g.Episodes[i].Order < g.Episodes[j].Order
+		})
+	}
+
+	return group
+}
+
+// GetTypeName returns readable name of the group type
+func (group *EpisodeGroup) GetTypeName() string {
+	if name, ok := episodeGroupTypes[group.Type]; ok {
+		return name
+	}
+	return ""
+}
+
+// GetSeason returns group season with specific order number
+func (group *EpisodeGroup) GetSeason(order int) *EpisodeGroupSeason {
+	if group == nil {
+		return nil
+	}
+
+	for _, g := range group.Groups {
+		if g != nil && g.Order == order {
+			return g
+		}
+	}
+	return nil
+}
+
+// ToListItem returns group as a list item, used to select the group for a show
+func (group *EpisodeGroup) ToListItem(show *Show) *xbmc.ListItem {
+	label := group.Name
+	if typeName := group.GetTypeName(); typeName != "" {
+		label = fmt.Sprintf("%s [%s]", group.Name, typeName)
+	}
+
+	item := &xbmc.ListItem{
+		Label: label,
+		Info: &xbmc.ListItemInfo{
+			Title:       label,
+			TVShowTitle: show.GetName(),
+			Plot:        fmt.Sprintf("%s\n%d seasons, %d episodes", group.Description, group.GroupCount, group.EpisodeCount),
+		},
+	}
+	show.SetArt(item)
+
+	return item
+}
+
+// ToListItem returns group season as a season list item
+func (season *EpisodeGroupSeason) ToListItem(show *Show) *xbmc.ListItem {
+	defer perf.ScopeTimer()()
+
+	name := season.Name
+	if name == "" {
+		name = fmt.Sprintf("Season %d", season.Order)
+	}
+
+	airDate := ""
+	if len(season.Episodes) > 0 {
+		airDate = season.Episodes[0].AirDate
+	}
+
+	item := &xbmc.ListItem{
+		Label: name,
+		Info: &xbmc.ListItemInfo{
+			Aired:         airDate,
+			Title:         name,
+			OriginalTitle: name,
+			Season:        season.Order,
+			TVShowTitle:   show.GetName(),
+			Plot:          show.overview(),
+			PlotOutline:   show.overview(),
+			DBTYPE:        "season",
+			Mediatype:     "season",
+			Genre:         show.GetGenres(),
+			Studio:        show.GetStudios(),
+		},
+		Properties: &xbmc.ListItemProperties{
+			TotalEpisodes: strconv.Itoa(len(season.Episodes)),
+			ShowTMDBId:    strconv.Itoa(show.ID),
+		},
+	}
+	show.SetArt(item)
+
+	return item
+}
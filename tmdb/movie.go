@@ -11,6 +11,7 @@ import (
 	"github.com/elgatito/elementum/cache"
 	"github.com/elgatito/elementum/config"
 	"github.com/elgatito/elementum/fanart"
+	"github.com/elgatito/elementum/library/history"
 	"github.com/elgatito/elementum/library/playcount"
 	"github.com/elgatito/elementum/library/uid"
 	"github.com/elgatito/elementum/util/reqapi"
@@ -309,6 +310,16 @@ func listMovies(endpoint string, params napping.Params, page int) (Movies, int)
 	return movies, totalResults
 }
 
+// SimilarMovies returns movies, similar to the movie by keywords and genres
+func SimilarMovies(tmdbID int, language string) []*Entity {
+	return relatedEntities(fmt.Sprintf("movie/%d/similar", tmdbID), language)
+}
+
+// RecommendedMovies returns movies, recommended by TMDB for the movie
+func RecommendedMovies(tmdbID int, language string) []*Entity {
+	return relatedEntities(fmt.Sprintf("movie/%d/recommendations", tmdbID), language)
+}
+
 // PopularMovies ...
 func PopularMovies(params DiscoverFilters, language string, page int) (Movies, int) {
 	var p napping.Params
@@ -488,6 +499,10 @@ func (movie *Movie) ToListItem() *xbmc.ListItem {
 			item.Properties.TotalTime = strconv.FormatFloat(lm.Resume.Total, 'f', 6, 64)
 		}
 	}
+	if r := history.GetResume(history.MovieID(movie.ID)); r != nil && item.Properties.ResumeTime == "" {
+		item.Properties.ResumeTime = strconv.FormatFloat(r.Position, 'f', 6, 64)
+		item.Properties.TotalTime = strconv.FormatFloat(r.Total, 'f', 6, 64)
+	}
 
 	movie.SetArt(item)
 
@@ -274,6 +274,16 @@ func listShows(endpoint string, params napping.Params, page int) (Shows, int) {
 	return shows, totalResults
 }
 
+// SimilarShows returns shows, similar to the show by keywords and genres
+func SimilarShows(showID int, language string) []*Entity {
+	return relatedEntities(fmt.Sprintf("tv/%d/similar", showID), language)
+}
+
+// RecommendedShows returns shows, recommended by TMDB for the show
+func RecommendedShows(showID int, language string) []*Entity {
+	return relatedEntities(fmt.Sprintf("tv/%d/recommendations", showID), language)
+}
+
 // PopularShows ...
 func PopularShows(params DiscoverFilters, language string, page int) (Shows, int) {
 	var p napping.Params
@@ -214,6 +214,31 @@ func Find(externalID string, externalSource string) *FindResult {
 	return result
 }
 
+// relatedEntities returns first page of entities from similar or recommendations endpoint
+func relatedEntities(endpoint string, language string) []*Entity {
+	var results *EntityList
+
+	req := reqapi.Request{
+		API: reqapi.TMDBAPI,
+		URL: fmt.Sprintf("/%s", endpoint),
+		Params: napping.Params{
+			"api_key":  apiKey,
+			"language": language,
+			"page":     "1",
+		}.AsUrlValues(),
+		Result:      &results,
+		Description: "related",
+
+		Cache:       true,
+		CacheExpire: cache.CacheExpireLong,
+	}
+
+	if err := req.Do(); err != nil || results == nil {
+		return nil
+	}
+	return results.Results
+}
+
 // GetCountries ...
 func GetCountries(language string) []*Country {
 	countries := CountryList{}
@@ -94,6 +94,32 @@ type Episode struct {
 	Runtime       int    `json:"runtime"`
 	SeasonNumber  int    `json:"season_number"`
 	StillPath     string `json:"still_path"`
+
+	// Order is a position of the episode inside of episode group
+	Order int `json:"order,omitempty"`
+}
+
+// EpisodeGroup is an alternative ordering of show episodes, like DVD or production order
+type EpisodeGroup struct {
+	ID           string      `json:"id"`
+	Name         string      `json:"name"`
+	Description  string      `json:"description"`
+	Type         int         `json:"type"`
+	EpisodeCount int         `json:"episode_count"`
+	GroupCount   int         `json:"group_count"`
+	Network      *IDNameLogo `json:"network"`
+
+	Groups []*EpisodeGroupSeason `json:"groups"`
+}
+
+// EpisodeGroupSeason is a group of episodes, that acts as a season inside of episode group
+type EpisodeGroupSeason struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Order  int    `json:"order"`
+	Locked bool   `json:"locked"`
+
+	Episodes EpisodeList `json:"episodes"`
 }
 
 // Entity ...
@@ -101,6 +127,7 @@ type Entity struct {
 	BackdropPath     string    `json:"backdrop_path"`
 	FirstAirDate     string    `json:"first_air_date"`
 	Genres           []*IDName `json:"genres"`
+	GenreIDs         []int     `json:"genre_ids,omitempty"`
 	ID               int       `json:"id"`
 	IsAdult          bool      `json:"adult"`
 	Name             string    `json:"name,omitempty"`
@@ -0,0 +1,192 @@
+package tracker
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/op/go-logging"
+)
+
+// Media types of tracked items
+const (
+	MovieType   = "movie"
+	ShowType    = "show"
+	SeasonType  = "season"
+	EpisodeType = "episode"
+)
+
+var (
+	log = logging.MustGetLogger("tracker")
+
+	// ErrNotSupported is returned by trackers, that have no such feature
+	ErrNotSupported = errors.New("Not supported by tracker")
+	// ErrNoTrackers is returned for writes, when no tracker is enabled
+	ErrNoTrackers = errors.New("No tracker is enabled")
+
+	mu       sync.RWMutex
+	trackers []Tracker
+)
+
+// Item is a movie, show, season or episode, identified by TMDB IDs.
+// For episodes and seasons ShowID, Season and Episode are set, TMDBID is set for episodes if known.
+type Item struct {
+	MediaType string    `json:"media_type"`
+	TMDBID    int       `json:"tmdb_id"`
+	ShowID    int       `json:"show_id,omitempty"`
+	Season    int       `json:"season,omitempty"`
+	Episode   int       `json:"episode,omitempty"`
+	WatchedAt time.Time `json:"watched_at,omitempty"`
+}
+
+// Progress is a paused playback of an item
+type Progress struct {
+	Item     *Item     `json:"item"`
+	Progress float64   `json:"progress"`
+	PausedAt time.Time `json:"paused_at"`
+}
+
+// Activities keeps last update times of tracker lists, used to decide whether lists should be fetched again
+type Activities struct {
+	All              time.Time `json:"all"`
+	MoviesWatched    time.Time `json:"movies_watched"`
+	MoviesPaused     time.Time `json:"movies_paused"`
+	MoviesWatchlist  time.Time `json:"movies_watchlist"`
+	MoviesCollection time.Time `json:"movies_collection"`
+	ShowsWatched     time.Time `json:"shows_watched"`
+	ShowsPaused      time.Time `json:"shows_paused"`
+	ShowsWatchlist   time.Time `json:"shows_watchlist"`
+	ShowsCollection  time.Time `json:"shows_collection"`
+}
+
+// Tracker is a watch-tracking service
+type Tracker interface {
+	// Name returns short name of the tracker
+	Name() string
+	// Enabled checks whether tracker is configured and authorized
+	Enabled() bool
+
+	// Scrobble reports playback state, action is one of "start", "pause" or "stop", progress is in percents
+	Scrobble(action string, item *Item, progress float64) error
+	// SetWatched adds items to watched history, or removes them from it
+	SetWatched(items []*Item, watched bool) error
+	// SetWatchlist adds item to watchlist, or removes it from it
+	SetWatchlist(item *Item, add bool) error
+	// SetCollection adds item to collection, or removes it from it
+	SetCollection(item *Item, add bool) error
+
+	// GetWatched returns watched movies or episodes
+	GetWatched(mediaType string) ([]*Item, error)
+	// GetPaused returns paused movies or episodes
+	GetPaused(mediaType string) ([]*Progress, error)
+	// GetLastActivities returns last update times of tracker lists
+	GetLastActivities() (*Activities, error)
+}
+
+// Register adds trackers, that receive writes
+func Register(t ...Tracker) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	trackers = append(trackers, t...)
+}
+
+// Get returns registered and enabled trackers
+func Get() (ret []Tracker) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, t := range trackers {
+		if t.Enabled() {
+			ret = append(ret, t)
+		}
+	}
+	return
+}
+
+// GetByName returns enabled tracker with specific name
+func GetByName(name string) Tracker {
+	for _, t := range Get() {
+		if t.Name() == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// Scrobble reports playback state to all enabled trackers, except the named ones
+func Scrobble(action string, item *Item, progress float64, except ...string) error {
+	return fanOut("scrobble", except, func(t Tracker) error {
+		return t.Scrobble(action, item, progress)
+	})
+}
+
+// SetWatched updates watched history in all enabled trackers, except the named ones
+func SetWatched(items []*Item, watched bool, except ...string) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	return fanOut("set watched", except, func(t Tracker) error {
+		return t.SetWatched(items, watched)
+	})
+}
+
+// SetWatchlist updates watchlist in all enabled trackers
+func SetWatchlist(item *Item, add bool) error {
+	return fanOut("set watchlist", nil, func(t Tracker) error {
+		return t.SetWatchlist(item, add)
+	})
+}
+
+// SetCollection updates collection in all enabled trackers
+func SetCollection(item *Item, add bool) error {
+	return fanOut("set collection", nil, func(t Tracker) error {
+		return t.SetCollection(item, add)
+	})
+}
+
+// fanOut runs write in all enabled trackers at once and joins their errors.
+// Trackers, that do not support the write, and excepted trackers are skipped,
+// ErrNotSupported is returned only if no tracker has made the write.
+func fanOut(description string, except []string, write func(t Tracker) error) error {
+	list := Get()
+	if len(list) == 0 {
+		return ErrNoTrackers
+	}
+
+	errs := make([]error, len(list))
+	var written, unsupported atomic.Int32
+
+	wg := sync.WaitGroup{}
+	for i, t := range list {
+		if slices.Contains(except, t.Name()) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, t Tracker) {
+			defer wg.Done()
+
+			if err := write(t); err == ErrNotSupported {
+				unsupported.Add(1)
+			} else if err != nil {
+				log.Warningf("Tracker %s failed to %s: %s", t.Name(), description, err)
+				errs[i] = fmt.Errorf("%s: %w", t.Name(), err)
+			} else {
+				written.Add(1)
+			}
+		}(i, t)
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	} else if written.Load() == 0 && unsupported.Load() > 0 {
+		return ErrNotSupported
+	}
+	return nil
+}
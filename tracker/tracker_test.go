@@ -0,0 +1,92 @@
+package tracker
+
+import (
+	"errors"
+	"testing"
+)
+
+// fakeTracker supports only watchlist additions
+type fakeTracker struct {
+	name    string
+	enabled bool
+	writes  int
+	err     error
+}
+
+func (t *fakeTracker) Name() string  { return t.name }
+func (t *fakeTracker) Enabled() bool { return t.enabled }
+
+func (t *fakeTracker) Scrobble(action string, item *Item, progress float64) error {
+	t.writes++
+	return t.err
+}
+
+func (t *fakeTracker) SetWatched(items []*Item, watched bool) error {
+	t.writes++
+	return t.err
+}
+
+func (t *fakeTracker) SetWatchlist(item *Item, add bool) error {
+	if !add {
+		return ErrNotSupported
+	}
+	t.writes++
+	return t.err
+}
+
+func (t *fakeTracker) SetCollection(item *Item, add bool) error {
+	return ErrNotSupported
+}
+
+func (t *fakeTracker) GetWatched(mediaType string) ([]*Item, error)    { return nil, nil }
+func (t *fakeTracker) GetPaused(mediaType string) ([]*Progress, error) { return nil, nil }
+func (t *fakeTracker) GetLastActivities() (*Activities, error)         { return &Activities{}, nil }
+
+func withTrackers(t *testing.T, list ...Tracker) {
+	mu.Lock()
+	previous := trackers
+	trackers = list
+	mu.Unlock()
+
+	t.Cleanup(func() {
+		mu.Lock()
+		trackers = previous
+		mu.Unlock()
+	})
+}
+
+func TestFanOutErrors(t *testing.T) {
+	item := &Item{MediaType: MovieType, TMDBID: 603}
+
+	withTrackers(t, &fakeTracker{name: "disabled"})
+	if err := SetWatchlist(item, true); err != ErrNoTrackers {
+		t.Errorf("Expected no trackers error, got %v", err)
+	}
+
+	first := &fakeTracker{name: "first", enabled: true}
+	second := &fakeTracker{name: "second", enabled: true}
+	withTrackers(t, first, second)
+
+	if err := SetWatchlist(item, true); err != nil {
+		t.Errorf("SetWatchlist failed: %s", err)
+	}
+	if first.writes != 1 || second.writes != 1 {
+		t.Errorf("Unexpected writes: %d, %d", first.writes, second.writes)
+	}
+	if err := SetWatchlist(item, false); err != ErrNotSupported {
+		t.Errorf("Expected not supported error, got %v", err)
+	}
+	if err := SetCollection(item, true); err != ErrNotSupported {
+		t.Errorf("Expected not supported error, got %v", err)
+	}
+
+	// Excepted trackers are skipped on purpose, that is not an error
+	if err := Scrobble("start", item, 1, "first", "second"); err != nil {
+		t.Errorf("Scrobble failed: %s", err)
+	}
+
+	second.err = errors.New("failed")
+	if err := Scrobble("start", item, 1); err == nil || !errors.Is(err, second.err) {
+		t.Errorf("Expected error of second tracker, got %v", err)
+	}
+}
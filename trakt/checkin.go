@@ -0,0 +1,69 @@
+package trakt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/jmcvetta/napping"
+
+	"github.com/elgatito/elementum/util/ident"
+	"github.com/elgatito/elementum/util/reqapi"
+)
+
+// ErrCheckinInProgress is returned when there is another active check-in, so the item is not checked in
+var ErrCheckinInProgress = errors.New("Another check-in is in progress")
+
+// Checkin marks movie or episode as being watched right now.
+// Trakt keeps check-in active for item runtime and adds it to history afterwards.
+func Checkin(contentType string, tmdbID int) error {
+	if err := Authorized(); err != nil {
+		return err
+	}
+
+	if contentType != "movie" && contentType != "episode" {
+		return fmt.Errorf("Check-in is not supported for %s", contentType)
+	}
+
+	log.Noticef("Checking in %s %d", contentType, tmdbID)
+
+	req := &reqapi.Request{
+		API:         reqapi.TraktAPI,
+		Method:      "POST",
+		URL:         "checkin",
+		Header:      GetAuthenticatedHeader(),
+		Params:      napping.Params{}.AsUrlValues(),
+		Payload:     bytes.NewBufferString(fmt.Sprintf(`{"%s": {"ids": {"tmdb": %d}}, "app_version": "%s"}`, contentType, tmdbID, ident.GetVersion())),
+		Description: "checkin",
+
+		// 409 informs that there is a check-in in progress already
+		ResponseIgnore: []int{201, 409},
+	}
+
+	if err := req.Do(); err != nil {
+		return err
+	} else if req.ResponseStatusCode == 409 {
+		return ErrCheckinInProgress
+	}
+	return nil
+}
+
+// CancelCheckin removes active check-in, used when playback is stopped early
+func CancelCheckin() error {
+	if err := Authorized(); err != nil {
+		return err
+	}
+
+	req := &reqapi.Request{
+		API:         reqapi.TraktAPI,
+		Method:      "DELETE",
+		URL:         "checkin",
+		Header:      GetAuthenticatedHeader(),
+		Params:      napping.Params{}.AsUrlValues(),
+		Description: "cancel checkin",
+
+		ResponseIgnore: []int{204},
+	}
+
+	return req.Do()
+}
@@ -1,13 +1,20 @@
 package trakt
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/anacrolix/missinggo/perf"
+	"github.com/goccy/go-json"
 	"github.com/jmcvetta/napping"
 
 	"github.com/elgatito/elementum/cache"
 	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/database"
 	"github.com/elgatito/elementum/util/reqapi"
 )
 
@@ -108,3 +115,215 @@ func (a *ListActivities) HasCurrent() bool {
 func (a *ListActivities) IsUpdated() bool {
 	return !a.HasPrevious() || !a.HasCurrent() || a.Current.UpdatedAt.After(a.Previous.UpdatedAt)
 }
+
+// ListPrivacies are privacy values, that can be set for a personal list
+var ListPrivacies = []string{"private", "friends", "public"}
+
+// listPayload is a body of list create and update requests
+type listPayload struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Privacy     string `json:"privacy,omitempty"`
+}
+
+// CreateList creates personal list with selected privacy
+func CreateList(name, description, privacy string) (list *List, err error) {
+	if err := Authorized(); err != nil {
+		return nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("List name is empty")
+	}
+	if privacy == "" {
+		privacy = ListPrivacies[0]
+	}
+
+	payload, _ := json.Marshal(listPayload{Name: name, Description: description, Privacy: privacy})
+	req := &reqapi.Request{
+		API:         reqapi.TraktAPI,
+		Method:      "POST",
+		URL:         fmt.Sprintf("users/%s/lists", config.Get().TraktUsername),
+		Header:      GetAuthenticatedHeader(),
+		Params:      napping.Params{}.AsUrlValues(),
+		Payload:     bytes.NewBuffer(payload),
+		Result:      &list,
+		Description: "create list",
+	}
+
+	if err = req.Do(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// UpdateList renames list or changes its privacy, empty values are kept unchanged
+func UpdateList(listID int, name, privacy string) (list *List, err error) {
+	if err := Authorized(); err != nil {
+		return nil, err
+	}
+
+	payload, _ := json.Marshal(listPayload{Name: strings.TrimSpace(name), Privacy: privacy})
+	req := &reqapi.Request{
+		API:         reqapi.TraktAPI,
+		Method:      "PUT",
+		URL:         fmt.Sprintf("users/%s/lists/%d", config.Get().TraktUsername, listID),
+		Header:      GetAuthenticatedHeader(),
+		Params:      napping.Params{}.AsUrlValues(),
+		Payload:     bytes.NewBuffer(payload),
+		Result:      &list,
+		Description: "update list",
+	}
+
+	if err = req.Do(); err != nil {
+		return nil, err
+	}
+
+	ClearListCache(config.Get().TraktUsername, strconv.Itoa(listID))
+	return list, nil
+}
+
+// DeleteList deletes personal list with all its items
+func DeleteList(listID int) error {
+	if err := Authorized(); err != nil {
+		return err
+	}
+
+	req := &reqapi.Request{
+		API:         reqapi.TraktAPI,
+		Method:      "DELETE",
+		URL:         fmt.Sprintf("users/%s/lists/%d", config.Get().TraktUsername, listID),
+		Header:      GetAuthenticatedHeader(),
+		Params:      napping.Params{}.AsUrlValues(),
+		Description: "delete list",
+	}
+
+	if err := req.Do(); err != nil {
+		return err
+	}
+
+	ClearListCache(config.Get().TraktUsername, strconv.Itoa(listID))
+	return nil
+}
+
+// ListItems returns movies and shows of the list, ordered by rank
+func ListItems(user, listID string) (items []*ListItem, err error) {
+	defer perf.ScopeTimer()()
+
+	url := fmt.Sprintf("users/%s/lists/%s/items/movie,show", user, listID)
+	if user == "Trakt" { // if this is "Official" public list - we use special endpoint
+		url = fmt.Sprintf("/lists/%s/items/movie,show", listID)
+	}
+
+	items, err = PaginatedRequest[*ListItem](
+		url,
+		napping.Params{
+			"limit": strconv.Itoa(250),
+		},
+		true,
+		true,
+		false,
+		cache.TraktMoviesListExpire,
+	)
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Rank < items[j].Rank
+	})
+	return
+}
+
+// ReorderList sets order of personal list items, using IDs of list items
+func ReorderList(listID int, ids []int) error {
+	if err := Authorized(); err != nil {
+		return err
+	}
+
+	payload, _ := json.Marshal(map[string][]int{"rank": ids})
+	req := &reqapi.Request{
+		API:         reqapi.TraktAPI,
+		Method:      "POST",
+		URL:         fmt.Sprintf("users/%s/lists/%d/items/reorder", config.Get().TraktUsername, listID),
+		Header:      GetAuthenticatedHeader(),
+		Params:      napping.Params{}.AsUrlValues(),
+		Payload:     bytes.NewBuffer(payload),
+		Description: "reorder list",
+	}
+
+	if err := req.Do(); err != nil {
+		return err
+	}
+
+	ClearListCache(config.Get().TraktUsername, strconv.Itoa(listID))
+	return nil
+}
+
+// CopyList creates personal list with movies and shows of another list
+func CopyList(user, listID string, name string) (list *List, err error) {
+	source, err := GetList(user, listID)
+	if err != nil {
+		return nil, err
+	} else if source == nil {
+		return nil, errors.New("List not found")
+	}
+
+	items, err := ListItems(user, listID)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		name = source.Name
+	}
+	if list, err = CreateList(name, source.Description, ListPrivacies[0]); err != nil {
+		return nil, err
+	}
+
+	payload := ListItemsPayload{}
+	for _, i := range items {
+		if i.Movie != nil && i.Movie.IDs != nil {
+			m := &Movie{}
+			m.IDs = i.Movie.IDs
+			payload.Movies = append(payload.Movies, m)
+		} else if i.Show != nil && i.Show.IDs != nil {
+			s := &Show{}
+			s.IDs = i.Show.IDs
+			payload.Shows = append(payload.Shows, s)
+		}
+	}
+	if len(payload.Movies) == 0 && len(payload.Shows) == 0 {
+		return list, nil
+	}
+
+	payloadJSON, _ := json.Marshal(payload)
+	req := &reqapi.Request{
+		API:         reqapi.TraktAPI,
+		Method:      "POST",
+		URL:         fmt.Sprintf("users/%s/lists/%d/items", config.Get().TraktUsername, list.ID()),
+		Header:      GetAuthenticatedHeader(),
+		Params:      napping.Params{}.AsUrlValues(),
+		Payload:     bytes.NewBuffer(payloadJSON),
+		Description: "copy list items",
+	}
+
+	if err = req.Do(); err != nil {
+		return list, err
+	}
+
+	ClearListCache(config.Get().TraktUsername, strconv.Itoa(list.ID()))
+	return list, nil
+}
+
+// ClearListCache deletes cached list activities and items, to request them again on next access
+func ClearListCache(user, listID string) {
+	cacheStore := cache.NewDBStore()
+	cacheStore.Delete(fmt.Sprintf(cache.TraktListActivitiesKey, user, listID))
+	cacheStore.Delete(fmt.Sprintf(cache.TraktMoviesListKey, listID))
+	cacheStore.Delete(fmt.Sprintf(cache.TraktShowsListKey, listID))
+
+	if db := database.GetCache(); db != nil {
+		for _, itemType := range []string{"movies", "shows"} {
+			db.DeleteWithPrefix(database.CommonBucket, []byte(fmt.Sprintf(cache.TraktPaginatedRequestKey, fmt.Sprintf("users/%s/lists/%s/items/%s", user, listID, itemType), "")))
+		}
+	}
+}
@@ -471,6 +471,28 @@ func ListItemsMovies(user, listID string) (movies []*Movies, err error) {
 	return movies, err
 }
 
+// CalendarMoviesRange returns calendar movies, released during the days, starting from the date
+func CalendarMoviesRange(endPoint string, start time.Time, days int, cacheExpire time.Duration) (movies []*CalendarMovie, err error) {
+	defer perf.ScopeTimer()()
+
+	req := &reqapi.Request{
+		API:    reqapi.TraktAPI,
+		URL:    fmt.Sprintf("calendars/%s/%s/%d", endPoint, start.Format(time.DateOnly), days),
+		Header: GetAuthenticatedHeader(),
+		Params: napping.Params{
+			"extended": "full",
+		}.AsUrlValues(),
+		Result:      &movies,
+		Description: "calendar movies range",
+
+		Cache:       true,
+		CacheExpire: cacheExpire,
+	}
+
+	err = req.Do()
+	return
+}
+
 // CalendarMovies ...
 func CalendarMovies(endPoint string, page string, cacheExpire time.Duration, isUpdateNeeded bool) (movies []*CalendarMovie, total int, err error) {
 	defer perf.ScopeTimer()()
@@ -0,0 +1,295 @@
+package trakt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/asdine/storm"
+	"github.com/jmcvetta/napping"
+
+	"github.com/elgatito/elementum/broadcast"
+	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/database"
+	"github.com/elgatito/elementum/util/reqapi"
+)
+
+const (
+	queueInterval    = 1 * time.Minute
+	queueMinBackoff  = 1 * time.Minute
+	queueMaxBackoff  = 6 * time.Hour
+	queueMaxAttempts = 50
+)
+
+var (
+	// ErrQueued is returned when write request failed with a temporary error
+	// and was saved to be sent again later
+	ErrQueued = errors.New("Trakt is not available, request is queued")
+
+	queueMu sync.Mutex
+	flushMu sync.Mutex
+
+	// ProfileToken returns stored Trakt token of an inactive profile, it is set by profile package
+	ProfileToken = func(profile string) string { return "" }
+)
+
+// QueueHandler periodically sends queued write requests
+func QueueHandler() {
+	ticker := time.NewTicker(queueInterval)
+	closer := broadcast.Closer.C()
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closer:
+			return
+		case <-ticker.C:
+			FlushQueue(false)
+		}
+	}
+}
+
+// GetQueue returns queued write requests in the order of sending
+func GetQueue() (items []database.TraktQueueItem) {
+	if err := database.GetStormDB().AllByIndex("CreatedAt", &items); err != nil && err != storm.ErrNotFound {
+		log.Warningf("Cannot read Trakt queue: %s", err)
+	}
+	return
+}
+
+// FlushQueue sends queued write requests, which retry time has come, or all requests if forced.
+// Requests of inactive profiles are sent with their stored tokens, profiles without a token wait until they are active.
+// Sending of a profile's requests stops on the first temporary failure to keep requests order.
+func FlushQueue(force bool) (sent, failed int) {
+	flushMu.Lock()
+	defer flushMu.Unlock()
+
+	now := time.Now()
+	tokens := map[string]string{config.Get().Profile: config.Get().TraktToken}
+	blocked := map[string]bool{}
+	for _, item := range GetQueue() {
+		if blocked[item.Profile] || (!force && item.NextAt.After(now)) {
+			continue
+		}
+
+		token, ok := tokens[item.Profile]
+		if !ok {
+			token = ProfileToken(item.Profile)
+			tokens[item.Profile] = token
+		}
+		if token == "" {
+			continue
+		}
+
+		header := GetAuthenticatedHeader()
+		header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		req := &reqapi.Request{
+			API:         reqapi.TraktAPI,
+			Method:      item.Method,
+			URL:         item.URL,
+			Header:      header,
+			Params:      napping.Params{}.AsUrlValues(),
+			Payload:     bytes.NewBuffer(item.Payload),
+			Description: item.Description,
+
+			ResponseIgnore: []int{201, 409},
+		}
+		err := req.Do()
+
+		if !updateQueued(&item, req, err) {
+			failed++
+			if isTemporary(req, err) {
+				blocked[item.Profile] = true
+			}
+			continue
+		}
+		sent++
+	}
+
+	if sent > 0 || failed > 0 {
+		log.Infof("Trakt queue: sent %d, failed %d requests", sent, failed)
+	}
+	return
+}
+
+// updateQueued stores result of sending queued item and returns whether it was sent
+func updateQueued(item *database.TraktQueueItem, req *reqapi.Request, err error) bool {
+	queueMu.Lock()
+	defer queueMu.Unlock()
+
+	db := database.GetStormDB()
+
+	// Item could be replaced with a newer request while this one was sent
+	var stored database.TraktQueueItem
+	if e := db.One("ID", item.ID, &stored); e != nil || !stored.CreatedAt.Equal(item.CreatedAt) {
+		return err == nil
+	}
+
+	if err == nil {
+		db.DeleteStruct(item)
+		return true
+	}
+
+	item.LastError = err.Error()
+	if isUnauthorized(req) {
+		// Request is kept without counting attempts, until Trakt is authorized again
+		item.NextAt = time.Now().Add(queueMinBackoff)
+		if e := db.Save(item); e != nil {
+			log.Warningf("Cannot update queued Trakt request %s: %s", item.ID, e)
+		}
+		return false
+	}
+
+	item.Attempts++
+	if !isTemporary(req, err) || item.Attempts >= queueMaxAttempts {
+		log.Warningf("Dropping queued Trakt request %s after %d attempts: %s", item.ID, item.Attempts, err)
+		db.DeleteStruct(item)
+		return false
+	}
+
+	item.NextAt = time.Now().Add(queueBackoff(item.Attempts))
+	if e := db.Save(item); e != nil {
+		log.Warningf("Cannot update queued Trakt request %s: %s", item.ID, e)
+	}
+	return false
+}
+
+// doQueued runs write request and queues it, if it failed with a temporary error.
+// Successful request removes older queued request for the same item.
+func doQueued(key string, req *reqapi.Request) error {
+	url := req.URL
+	var payload []byte
+	if req.Payload != nil {
+		payload = append(payload, req.Payload.Bytes()...)
+	}
+
+	err := req.Do()
+	if err == nil {
+		dequeue(key, "")
+		return nil
+	} else if !isTemporary(req, err) {
+		return err
+	}
+
+	enqueue(&database.TraktQueueItem{
+		ID:          key,
+		Method:      req.Method,
+		URL:         url,
+		Payload:     payload,
+		Description: req.Description,
+		LastError:   err.Error(),
+	})
+	return ErrQueued
+}
+
+// doQueuedHistory runs history request for the items and queues a separate request for each item,
+// if it failed with a temporary error, so queued history is collapsed by item, keeping the latest state.
+// Successful request removes older queued requests for all of its items.
+func doQueuedHistory(req *reqapi.Request, items []*WatchedItem, pre string, post string) error {
+	err := req.Do()
+	if err == nil {
+		for _, item := range items {
+			dequeue(item.queueKey(), "")
+		}
+		return nil
+	} else if !isTemporary(req, err) {
+		return err
+	}
+
+	for _, item := range items {
+		enqueue(&database.TraktQueueItem{
+			ID:          item.queueKey(),
+			Method:      req.Method,
+			URL:         req.URL,
+			Payload:     []byte(pre + item.String() + post),
+			Description: req.Description,
+			LastError:   err.Error(),
+		})
+	}
+	return ErrQueued
+}
+
+// enqueue saves request, replacing older request for the same item
+func enqueue(item *database.TraktQueueItem) {
+	queueMu.Lock()
+	defer queueMu.Unlock()
+
+	db := database.GetStormDB()
+
+	var stored database.TraktQueueItem
+	if err := db.One("ID", item.ID, &stored); err == nil && !supersedes(item.Action, stored.Action) {
+		log.Debugf("Keeping queued Trakt request %s with action %s", stored.ID, stored.Action)
+		return
+	}
+
+	item.Profile = config.Get().Profile
+	item.CreatedAt = time.Now()
+	item.NextAt = item.CreatedAt.Add(queueMinBackoff)
+	if err := db.Save(item); err != nil {
+		log.Warningf("Cannot queue Trakt request %s: %s", item.ID, err)
+		return
+	}
+	log.Infof("Queued Trakt request %s to be sent later", item.ID)
+}
+
+// dequeue removes queued request for the item, if it is superseded by the action
+func dequeue(key string, action string) {
+	queueMu.Lock()
+	defer queueMu.Unlock()
+
+	db := database.GetStormDB()
+
+	var stored database.TraktQueueItem
+	if err := db.One("ID", key, &stored); err == nil && supersedes(action, stored.Action) {
+		db.DeleteStruct(&stored)
+	}
+}
+
+// supersedes checks whether new request for the item replaces the queued one.
+// Final scrobble "stop" marks item as watched, so it is never replaced by progress updates.
+func supersedes(action string, queuedAction string) bool {
+	return queuedAction != "stop" || action == "stop"
+}
+
+// isTemporary checks whether failed request can succeed later
+func isTemporary(req *reqapi.Request, err error) bool {
+	if err == nil {
+		return false
+	}
+	return req.ResponseStatusCode == 0 || req.ResponseStatusCode == 429 || req.ResponseStatusCode >= 500 || isUnauthorized(req)
+}
+
+// isUnauthorized checks whether request failed because of expired or revoked token
+func isUnauthorized(req *reqapi.Request) bool {
+	return req.ResponseStatusCode == 401
+}
+
+func queueBackoff(attempts int) time.Duration {
+	backoff := queueMinBackoff
+	for i := 1; i < attempts && backoff < queueMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > queueMaxBackoff {
+		return queueMaxBackoff
+	}
+	return backoff
+}
+
+// queueKey returns identifier of the item, that is used to deduplicate queued requests
+func queueKey(kind string, itemType string, id interface{}) string {
+	if profile := config.Get().Profile; profile != "" {
+		return fmt.Sprintf("%s|%s:%s:%v", profile, kind, itemType, id)
+	}
+	return fmt.Sprintf("%s:%s:%v", kind, itemType, id)
+}
+
+// queueKey returns identifier of watched item for queued history requests
+func (item *WatchedItem) queueKey() string {
+	if item.Movie != 0 {
+		return queueKey("history", "movie", item.Movie)
+	}
+	return queueKey("history", "show", fmt.Sprintf("%d:%d:%d", item.Show, item.Season, item.Episode))
+}
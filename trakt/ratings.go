@@ -0,0 +1,370 @@
+package trakt
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/anacrolix/missinggo/perf"
+	"github.com/jmcvetta/napping"
+
+	"github.com/elgatito/elementum/cache"
+	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/util/reqapi"
+	"github.com/elgatito/elementum/xbmc"
+)
+
+// ratingsRefresh is how often in-memory ratings and favorites are rebuilt from cached lists
+const ratingsRefresh = 15 * time.Minute
+
+// userMarks keeps user ratings and favorites, indexed by TMDB IDs
+type userMarks struct {
+	mu sync.RWMutex
+
+	ratings      map[string]int
+	favorites    map[string]bool
+	loadedAt     time.Time
+	updateNeeded bool
+}
+
+var marks = &userMarks{}
+
+// Ratings returns user ratings of movies, shows or episodes
+func Ratings(itemType string, isUpdateNeeded bool) (items []*RatedItem, err error) {
+	if err := Authorized(); err != nil {
+		return nil, err
+	}
+
+	defer perf.ScopeTimer()()
+
+	cacheStore := cache.NewDBStore()
+	key := fmt.Sprintf(cache.TraktRatingsKey, itemType)
+
+	if !isUpdateNeeded {
+		if err := cacheStore.Get(key, &items); err == nil {
+			return items, nil
+		}
+	}
+
+	items, err = PaginatedRequest[*RatedItem](
+		"sync/ratings/"+itemType,
+		napping.Params{
+			"limit": strconv.Itoa(250),
+		},
+		true,
+		isUpdateNeeded,
+		false,
+		cache.TraktRatingsExpire,
+	)
+	if err == nil {
+		cacheStore.Set(key, &items, cache.TraktRatingsExpire)
+	}
+	return
+}
+
+// Favorites returns user favorite movies or shows
+func Favorites(itemType string, isUpdateNeeded bool) (items []*FavoriteItem, err error) {
+	if err := Authorized(); err != nil {
+		return nil, err
+	}
+
+	defer perf.ScopeTimer()()
+
+	cacheStore := cache.NewDBStore()
+	key := fmt.Sprintf(cache.TraktFavoritesKey, itemType)
+
+	if !isUpdateNeeded {
+		if err := cacheStore.Get(key, &items); err == nil {
+			return items, nil
+		}
+	}
+
+	items, err = PaginatedRequest[*FavoriteItem](
+		"sync/favorites/"+itemType,
+		napping.Params{
+			"limit": strconv.Itoa(250),
+		},
+		true,
+		isUpdateNeeded,
+		false,
+		cache.TraktFavoritesExpire,
+	)
+	if err == nil {
+		cacheStore.Set(key, &items, cache.TraktFavoritesExpire)
+	}
+	return
+}
+
+// SetRating rates movie, show or episode from 1 to 10, zero rating removes it.
+// For episodes tmdbID is show's ID.
+func SetRating(itemType string, tmdbID int, season, episode int, rating int) (req *reqapi.Request, err error) {
+	if err := Authorized(); err != nil {
+		return nil, err
+	}
+
+	var payload string
+	if itemType == "episodes" {
+		payload = fmt.Sprintf(`{"shows": [{"ids": {"tmdb": %d}, "seasons": [{"number": %d, "episodes": [{"number": %d, "rating": %d}]}]}]}`,
+			tmdbID, season, episode, rating)
+	} else {
+		payload = fmt.Sprintf(`{"%s": [{"ids": {"tmdb": %d}, "rating": %d}]}`, itemType, tmdbID, rating)
+	}
+
+	endPoint := "sync/ratings"
+	if rating == 0 {
+		endPoint = "sync/ratings/remove"
+	}
+
+	req = &reqapi.Request{
+		API:         reqapi.TraktAPI,
+		Method:      "POST",
+		URL:         endPoint,
+		Header:      GetAuthenticatedHeader(),
+		Params:      napping.Params{}.AsUrlValues(),
+		Payload:     bytes.NewBufferString(payload),
+		Description: "set rating",
+	}
+
+	key := ratingKey(itemType, tmdbID, season, episode)
+	if err = doQueued(queueKey("rating", itemType, key), req); err == nil || err == ErrQueued {
+		marks.setRating(key, rating)
+	}
+	return req, err
+}
+
+// AddToFavorites ...
+func AddToFavorites(itemType string, tmdbID string) (req *reqapi.Request, err error) {
+	return setFavorite(itemType, tmdbID, true)
+}
+
+// RemoveFromFavorites ...
+func RemoveFromFavorites(itemType string, tmdbID string) (req *reqapi.Request, err error) {
+	return setFavorite(itemType, tmdbID, false)
+}
+
+func setFavorite(itemType string, tmdbID string, add bool) (req *reqapi.Request, err error) {
+	if err := Authorized(); err != nil {
+		return nil, err
+	}
+
+	endPoint := "sync/favorites"
+	description := "add to favorites"
+	if !add {
+		endPoint = "sync/favorites/remove"
+		description = "remove from favorites"
+	}
+
+	req = &reqapi.Request{
+		API:         reqapi.TraktAPI,
+		Method:      "POST",
+		URL:         endPoint,
+		Header:      GetAuthenticatedHeader(),
+		Params:      napping.Params{}.AsUrlValues(),
+		Payload:     bytes.NewBufferString(fmt.Sprintf(`{"%s": [{"ids": {"tmdb": %s}}]}`, itemType, tmdbID)),
+		Description: description,
+	}
+
+	if err = doQueued(queueKey("favorites", itemType, tmdbID), req); err == nil || err == ErrQueued {
+		id, _ := strconv.Atoi(tmdbID)
+		marks.setFavorite(ratingKey(itemType, id, 0, 0), add)
+	}
+	return req, err
+}
+
+// GetMovieRating returns user rating of the movie, or zero if it is not rated
+func GetMovieRating(tmdbID int) int {
+	return marks.getRating(ratingKey("movies", tmdbID, 0, 0))
+}
+
+// GetShowRating returns user rating of the show, or zero if it is not rated
+func GetShowRating(tmdbID int) int {
+	return marks.getRating(ratingKey("shows", tmdbID, 0, 0))
+}
+
+// GetEpisodeRating returns user rating of the episode, or zero if it is not rated
+func GetEpisodeRating(showID, season, episode int) int {
+	return marks.getRating(ratingKey("episodes", showID, season, episode))
+}
+
+// IsFavoriteMovie checks whether movie is in user favorites
+func IsFavoriteMovie(tmdbID int) bool {
+	return marks.isFavorite(ratingKey("movies", tmdbID, 0, 0))
+}
+
+// IsFavoriteShow checks whether show is in user favorites
+func IsFavoriteShow(tmdbID int) bool {
+	return marks.isFavorite(ratingKey("shows", tmdbID, 0, 0))
+}
+
+// ResetMarks drops in-memory ratings and favorites, to load them again for another user
+func ResetMarks() {
+	marks.mu.Lock()
+	defer marks.mu.Unlock()
+
+	marks.ratings = nil
+	marks.favorites = nil
+	marks.updateNeeded = true
+}
+
+// ratingKey returns key of the item in ratings and favorites indexes
+func ratingKey(itemType string, tmdbID int, season, episode int) string {
+	if itemType == "episodes" {
+		return fmt.Sprintf("%s:%d:%d:%d", itemType, tmdbID, season, episode)
+	}
+	return fmt.Sprintf("%s:%d", itemType, tmdbID)
+}
+
+func (m *userMarks) getRating(key string) int {
+	if !m.load() {
+		return 0
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.ratings[key]
+}
+
+func (m *userMarks) isFavorite(key string) bool {
+	if !m.load() {
+		return false
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.favorites[key]
+}
+
+func (m *userMarks) setRating(key string, rating int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.ratings != nil {
+		if rating == 0 {
+			delete(m.ratings, key)
+		} else {
+			m.ratings[key] = rating
+		}
+	}
+	m.updateNeeded = true
+}
+
+func (m *userMarks) setFavorite(key string, add bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.favorites != nil {
+		if add {
+			m.favorites[key] = true
+		} else {
+			delete(m.favorites, key)
+		}
+	}
+	m.updateNeeded = true
+}
+
+// load rebuilds indexes from cached lists, if they are outdated, and returns whether indexes are available
+func (m *userMarks) load() bool {
+	if !config.Get().TraktAuthorized {
+		return false
+	}
+
+	m.mu.RLock()
+	fresh := m.ratings != nil && time.Since(m.loadedAt) < ratingsRefresh
+	m.mu.RUnlock()
+	if fresh {
+		return true
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.ratings != nil && time.Since(m.loadedAt) < ratingsRefresh {
+		return true
+	}
+
+	ratings := map[string]int{}
+	for _, itemType := range []string{"movies", "shows", "episodes"} {
+		items, err := Ratings(itemType, m.updateNeeded)
+		if err != nil {
+			log.Debugf("Could not get %s ratings: %s", itemType, err)
+			continue
+		}
+		for _, i := range items {
+			if key := i.key(); key != "" {
+				ratings[key] = i.Rating
+			}
+		}
+	}
+
+	favorites := map[string]bool{}
+	for _, itemType := range []string{"movies", "shows"} {
+		items, err := Favorites(itemType, m.updateNeeded)
+		if err != nil {
+			log.Debugf("Could not get %s favorites: %s", itemType, err)
+			continue
+		}
+		for _, i := range items {
+			if i.Movie != nil && i.Movie.IDs != nil {
+				favorites[ratingKey("movies", i.Movie.IDs.TMDB, 0, 0)] = true
+			} else if i.Show != nil && i.Show.IDs != nil {
+				favorites[ratingKey("shows", i.Show.IDs.TMDB, 0, 0)] = true
+			}
+		}
+	}
+
+	m.ratings = ratings
+	m.favorites = favorites
+	m.loadedAt = time.Now()
+	m.updateNeeded = false
+	return true
+}
+
+// key returns key of rated item in ratings index
+func (i *RatedItem) key() string {
+	switch {
+	case i.Episode != nil && i.Show != nil && i.Show.IDs != nil:
+		return ratingKey("episodes", i.Show.IDs.TMDB, i.Episode.Season, i.Episode.Number)
+	case i.Movie != nil && i.Movie.IDs != nil:
+		return ratingKey("movies", i.Movie.IDs.TMDB, 0, 0)
+	case i.Show != nil && i.Show.IDs != nil && i.Season == nil:
+		return ratingKey("shows", i.Show.IDs.TMDB, 0, 0)
+	}
+	return ""
+}
+
+// RateDialog asks user for a rating and saves it, returns selected rating or -1 if dialog was canceled
+func RateDialog(xbmcHost *xbmc.XBMCHost, itemType string, tmdbID int, season, episode int) int {
+	if xbmcHost == nil {
+		return -1
+	}
+
+	current := marks.getRating(ratingKey(itemType, tmdbID, season, episode))
+
+	choices := []string{}
+	for r := 10; r >= 1; r-- {
+		label := strconv.Itoa(r)
+		if r == current {
+			label = fmt.Sprintf("[B]%d[/B]", r)
+		}
+		choices = append(choices, label)
+	}
+	if current > 0 {
+		choices = append(choices, "LOCALIZE[30730]")
+	}
+
+	choice := xbmcHost.ListDialog("LOCALIZE[30729]", choices...)
+	if choice < 0 || choice >= len(choices) {
+		return -1
+	}
+
+	rating := 10 - choice
+	if _, err := SetRating(itemType, tmdbID, season, episode, rating); err != nil && err != ErrQueued {
+		xbmcHost.Notify("Elementum", err.Error(), config.AddonIcon())
+		return -1
+	}
+
+	xbmcHost.Notify("Elementum", "LOCALIZE[30733]", config.AddonIcon())
+	return rating
+}
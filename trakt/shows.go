@@ -540,6 +540,28 @@ func CalendarShows(endPoint string, page string, cacheExpire time.Duration, isUp
 	return
 }
 
+// CalendarShowsRange returns calendar shows, airing during the days, starting from the date
+func CalendarShowsRange(endPoint string, start time.Time, days int, cacheExpire time.Duration) (shows []*CalendarShow, err error) {
+	defer perf.ScopeTimer()()
+
+	req := &reqapi.Request{
+		API:    reqapi.TraktAPI,
+		URL:    fmt.Sprintf("calendars/%s/%s/%d", endPoint, start.Format(time.DateOnly), days),
+		Header: GetAvailableHeader(),
+		Params: napping.Params{
+			"extended": "full",
+		}.AsUrlValues(),
+		Result:      &shows,
+		Description: "calendar shows range",
+
+		Cache:       true,
+		CacheExpire: cacheExpire,
+	}
+
+	err = req.Do()
+	return
+}
+
 // WatchedShows ...
 func WatchedShows(isUpdateNeeded bool) (WatchedShowsType, error) {
 	defer perf.ScopeTimer()()
@@ -0,0 +1,220 @@
+package trakt
+
+import (
+	"strconv"
+
+	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/tracker"
+)
+
+// TrackerName is a name of Trakt tracker
+const TrackerName = "trakt"
+
+// Tracker is Trakt implementation of tracker.Tracker
+type Tracker struct{}
+
+// NewTracker returns Trakt tracker
+func NewTracker() *Tracker {
+	return &Tracker{}
+}
+
+// Name ...
+func (t *Tracker) Name() string {
+	return TrackerName
+}
+
+// Enabled ...
+func (t *Tracker) Enabled() bool {
+	return config.Get().TraktToken != ""
+}
+
+// Scrobble ...
+func (t *Tracker) Scrobble(action string, item *tracker.Item, progress float64) error {
+	Scrobble(action, item.MediaType, item.TMDBID, progress, 100)
+	return nil
+}
+
+// SetWatched ...
+func (t *Tracker) SetWatched(items []*tracker.Item, watched bool) error {
+	// Trakt history requests are split by type
+	movies := []*WatchedItem{}
+	shows := []*WatchedItem{}
+	for _, i := range items {
+		w := &WatchedItem{
+			MediaType: i.MediaType,
+			Watched:   watched,
+			WatchedAt: i.WatchedAt,
+		}
+		if i.MediaType == tracker.MovieType {
+			w.Movie = i.TMDBID
+			movies = append(movies, w)
+		} else {
+			w.Show = i.ShowID
+			w.Season = i.Season
+			w.Episode = i.Episode
+			shows = append(shows, w)
+		}
+	}
+
+	for _, list := range [][]*WatchedItem{movies, shows} {
+		if len(list) == 0 {
+			continue
+		}
+		if _, err := SetMultipleWatched(list); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetWatchlist ...
+func (t *Tracker) SetWatchlist(item *tracker.Item, add bool) (err error) {
+	itemType, tmdbID := trackerItemType(item)
+	if add {
+		_, err = AddToWatchlist(itemType, tmdbID)
+	} else {
+		_, err = RemoveFromWatchlist(itemType, tmdbID)
+	}
+	return
+}
+
+// SetCollection ...
+func (t *Tracker) SetCollection(item *tracker.Item, add bool) (err error) {
+	itemType, tmdbID := trackerItemType(item)
+	if add {
+		_, err = AddToCollection(itemType, tmdbID)
+	} else {
+		_, err = RemoveFromCollection(itemType, tmdbID)
+	}
+	return
+}
+
+// GetWatched ...
+func (t *Tracker) GetWatched(mediaType string) (ret []*tracker.Item, err error) {
+	if mediaType == tracker.MovieType {
+		movies, err := WatchedMovies(false)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range movies {
+			if m == nil || m.Movie == nil || m.Movie.IDs == nil {
+				continue
+			}
+			ret = append(ret, &tracker.Item{MediaType: tracker.MovieType, TMDBID: m.Movie.IDs.TMDB, WatchedAt: m.LastWatchedAt})
+		}
+		return ret, nil
+	}
+
+	shows, err := WatchedShows(false)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range shows {
+		if s == nil || s.Show == nil || s.Show.IDs == nil {
+			continue
+		}
+		for _, season := range s.Seasons {
+			for _, e := range season.Episodes {
+				ret = append(ret, &tracker.Item{
+					MediaType: tracker.EpisodeType,
+					ShowID:    s.Show.IDs.TMDB,
+					Season:    season.Number,
+					Episode:   e.Number,
+					WatchedAt: e.LastWatchedAt,
+				})
+			}
+		}
+	}
+	return ret, nil
+}
+
+// GetPaused ...
+func (t *Tracker) GetPaused(mediaType string) (ret []*tracker.Progress, err error) {
+	if mediaType == tracker.MovieType {
+		movies, err := PausedMovies(false)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range movies {
+			if m == nil || m.Movie == nil || m.Movie.IDs == nil {
+				continue
+			}
+			ret = append(ret, &tracker.Progress{
+				Item:     &tracker.Item{MediaType: tracker.MovieType, TMDBID: m.Movie.IDs.TMDB},
+				Progress: m.Progress,
+				PausedAt: m.PausedAt,
+			})
+		}
+		return ret, nil
+	}
+
+	episodes, err := PausedShows(false)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range episodes {
+		if e == nil || e.Show == nil || e.Show.IDs == nil || e.Episode == nil {
+			continue
+		}
+		item := &tracker.Item{
+			MediaType: tracker.EpisodeType,
+			ShowID:    e.Show.IDs.TMDB,
+			Season:    e.Episode.Season,
+			Episode:   e.Episode.Number,
+		}
+		if e.Episode.IDs != nil {
+			item.TMDBID = e.Episode.IDs.TMDB
+		}
+		ret = append(ret, &tracker.Progress{Item: item, Progress: e.Progress, PausedAt: e.PausedAt})
+	}
+	return ret, nil
+}
+
+// GetLastActivities ...
+func (t *Tracker) GetLastActivities() (*tracker.Activities, error) {
+	a, err := GetLastActivities()
+	if err != nil || a == nil {
+		return nil, err
+	}
+
+	return &tracker.Activities{
+		All:              a.All,
+		MoviesWatched:    a.Movies.WatchedAt,
+		MoviesPaused:     a.Movies.PausedAt,
+		MoviesWatchlist:  a.Movies.WatchlistedAt,
+		MoviesCollection: a.Movies.CollectedAt,
+		ShowsWatched:     a.Episodes.WatchedAt,
+		ShowsPaused:      a.Episodes.PausedAt,
+		ShowsWatchlist:   a.Shows.WatchlistedAt,
+		ShowsCollection:  a.Episodes.CollectedAt,
+	}, nil
+}
+
+// TrackerItems converts Trakt history items into tracker items
+func TrackerItems(items []*WatchedItem) []*tracker.Item {
+	ret := make([]*tracker.Item, 0, len(items))
+	for _, i := range items {
+		if i == nil {
+			continue
+		}
+		ret = append(ret, &tracker.Item{
+			MediaType: i.MediaType,
+			TMDBID:    i.Movie,
+			ShowID:    i.Show,
+			Season:    i.Season,
+			Episode:   i.Episode,
+			WatchedAt: i.WatchedAt,
+		})
+	}
+	return ret
+}
+
+// trackerItemType returns Trakt list type and TMDB ID, watchlist and collection keep whole shows
+func trackerItemType(item *tracker.Item) (string, string) {
+	if item.MediaType == tracker.MovieType {
+		return "movies", strconv.Itoa(item.TMDBID)
+	} else if item.ShowID != 0 {
+		return "shows", strconv.Itoa(item.ShowID)
+	}
+	return "shows", strconv.Itoa(item.TMDBID)
+}
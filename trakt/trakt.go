@@ -14,6 +14,7 @@ import (
 	"github.com/elgatito/elementum/broadcast"
 	"github.com/elgatito/elementum/cache"
 	"github.com/elgatito/elementum/config"
+	"github.com/elgatito/elementum/database"
 	"github.com/elgatito/elementum/library/uid"
 	"github.com/elgatito/elementum/util/ident"
 	"github.com/elgatito/elementum/util/reqapi"
@@ -570,7 +571,7 @@ func AddToWatchlist(itemType string, tmdbID string) (req *reqapi.Request, err er
 		Description: "add to watchlist",
 	}
 
-	return req, req.Do()
+	return req, doQueued(queueKey("watchlist", itemType, tmdbID), req)
 }
 
 // AddToUserlist ...
@@ -603,7 +604,10 @@ func AddToUserlist(listID int, itemType string, tmdbID string) (req *reqapi.Requ
 		Description: "add to userlist",
 	}
 
-	return req, req.Do()
+	if err = req.Do(); err == nil {
+		ClearListCache(config.Get().TraktUsername, strconv.Itoa(listID))
+	}
+	return req, err
 }
 
 // RemoveFromUserlist ...
@@ -636,7 +640,10 @@ func RemoveFromUserlist(listID int, itemType string, tmdbID string) (req *reqapi
 		Description: "remove from userlist",
 	}
 
-	return req, req.Do()
+	if err = req.Do(); err == nil {
+		ClearListCache(config.Get().TraktUsername, strconv.Itoa(listID))
+	}
+	return req, err
 }
 
 // RemoveFromWatchlist ...
@@ -655,7 +662,7 @@ func RemoveFromWatchlist(itemType string, tmdbID string) (req *reqapi.Request, e
 		Description: "remove from watchlist",
 	}
 
-	return req, req.Do()
+	return req, doQueued(queueKey("watchlist", itemType, tmdbID), req)
 }
 
 // AddToCollection ...
@@ -674,7 +681,7 @@ func AddToCollection(itemType string, tmdbID string) (req *reqapi.Request, err e
 		Description: "add to collection",
 	}
 
-	return req, req.Do()
+	return req, doQueued(queueKey("collection", itemType, tmdbID), req)
 }
 
 // RemoveFromCollection ...
@@ -693,7 +700,7 @@ func RemoveFromCollection(itemType string, tmdbID string) (req *reqapi.Request,
 		Description: "remove from collection",
 	}
 
-	return req, req.Do()
+	return req, doQueued(queueKey("collection", itemType, tmdbID), req)
 }
 
 // SetWatched adds and removes from watched history
@@ -724,7 +731,7 @@ func SetWatched(item *WatchedItem) (req *reqapi.Request, err error) {
 		Description: "set watched",
 	}
 
-	return req, req.Do()
+	return req, doQueuedHistory(req, []*WatchedItem{item}, pre, post)
 }
 
 // SetMultipleWatched adds and removes from watched history
@@ -740,11 +747,13 @@ func SetMultipleWatched(items []*WatchedItem) (*HistoryResponse, error) {
 	}
 
 	queries := []string{}
+	valid := make([]*WatchedItem, 0, len(items))
 	for _, item := range items {
 		if item == nil {
 			continue
 		}
 		queries = append(queries, item.String())
+		valid = append(valid, item)
 	}
 	query := strings.Join(queries, ", ")
 
@@ -769,7 +778,7 @@ func SetMultipleWatched(items []*WatchedItem) (*HistoryResponse, error) {
 		Description: "set multiple watched",
 	}
 
-	err := req.Do()
+	err := doQueuedHistory(req, valid, pre, post)
 	if err != nil {
 		log.Warningf("Error getting watched items: %s", err)
 		return nil, err
@@ -851,17 +860,133 @@ func Scrobble(action string, contentType string, tmdbID int, watched float64, ru
 		ResponseIgnore: []int{201, 409},
 	}
 
+	key := queueKey("scrobble", contentType, tmdbID)
 	if err := req.Do(); err != nil {
 		log.Errorf("Scrobble failed: %s", err)
-		// TODO: Check what is the real error source (if there is an error)
-		// if xbmcHost, _ := xbmc.GetLocalXBMCHost(); xbmcHost != nil {
-		// 	xbmcHost.Notify("Elementum", "Scrobble failed, check your logs.", config.AddonIcon())
-		// }
+		if isTemporary(req, err) {
+			// Playback is over by the time queued scrobble is sent, so "start" is saved as "pause" to keep the progress
+			if action == "start" {
+				action = "pause"
+			}
+			enqueue(&database.TraktQueueItem{
+				ID:          key,
+				Action:      action,
+				Method:      "POST",
+				URL:         fmt.Sprintf("scrobble/%s", action),
+				Payload:     []byte(payload),
+				Description: endPoint,
+				LastError:   err.Error(),
+			})
+		}
 	} else if !slices.Contains([]int{200, 201, 409}, req.ResponseStatusCode) {
 		log.Errorf("Failed to scrobble %s #%d to %s at %f: %d", contentType, tmdbID, action, progress, req.ResponseStatusCode)
+	} else {
+		dequeue(key, action)
 	}
 }
 
+// SavePlayback stores playback progress of the item on Trakt with a paused scrobble
+func SavePlayback(contentType string, tmdbID int, watched float64, runtime float64) error {
+	if err := Authorized(); err != nil {
+		return err
+	}
+
+	if runtime < 1 {
+		return fmt.Errorf("Unknown runtime of %s #%d", contentType, tmdbID)
+	}
+
+	progress := watched / runtime * 100
+	payload := fmt.Sprintf(`{"%s": {"ids": {"tmdb": %d}}, "progress": %f, "app_version": "%s"}`,
+		contentType, tmdbID, progress, ident.GetVersion())
+
+	req := &reqapi.Request{
+		API:         reqapi.TraktAPI,
+		Method:      "POST",
+		URL:         "scrobble/pause",
+		Header:      GetAuthenticatedHeader(),
+		Params:      napping.Params{}.AsUrlValues(),
+		Payload:     bytes.NewBufferString(payload),
+		Description: "scrobble/pause",
+
+		ResponseIgnore: []int{201, 409},
+	}
+
+	return doQueued(queueKey("scrobble", contentType, tmdbID), req)
+}
+
+// RemovePlayback removes playback progress entry
+func RemovePlayback(id int) error {
+	if err := Authorized(); err != nil {
+		return err
+	}
+
+	req := &reqapi.Request{
+		API:         reqapi.TraktAPI,
+		Method:      "DELETE",
+		URL:         fmt.Sprintf("sync/playback/%d", id),
+		Header:      GetAuthenticatedHeader(),
+		Params:      napping.Params{}.AsUrlValues(),
+		Description: "remove playback",
+
+		ResponseIgnore: []int{204, 404},
+	}
+
+	return doQueued(queueKey("playback", "", id), req)
+}
+
+// HiddenRecommendations returns TMDB IDs of movies or shows, hidden from recommendations
+func HiddenRecommendations(contentType string, isUpdateNeeded bool) (ids map[int]bool, err error) {
+	ids = map[int]bool{}
+	if err = Authorized(); err != nil {
+		return
+	}
+
+	cacheStore := cache.NewDBStore()
+	cacheKey := fmt.Sprintf(cache.TraktHiddenRecommendationsKey, contentType)
+	if !isUpdateNeeded {
+		if err = cacheStore.Get(cacheKey, &ids); err == nil {
+			return
+		}
+	}
+
+	params := napping.Params{
+		"type":  contentType,
+		"limit": "100",
+	}.AsUrlValues()
+
+	totalPages := 1
+	for page := 1; page < totalPages+1; page++ {
+		params.Set("page", strconv.Itoa(page))
+
+		var hidden []*HiddenItem
+		req := &reqapi.Request{
+			API:         reqapi.TraktAPI,
+			URL:         "users/hidden/recommendations",
+			Header:      GetAvailableHeader(),
+			Params:      params,
+			Result:      &hidden,
+			Description: "hidden recommendations",
+		}
+
+		if err = req.Do(); err != nil {
+			return
+		}
+
+		for _, h := range hidden {
+			if h.Movie != nil && h.Movie.IDs != nil && h.Movie.IDs.TMDB != 0 {
+				ids[h.Movie.IDs.TMDB] = true
+			} else if h.Show != nil && h.Show.IDs != nil && h.Show.IDs.TMDB != 0 {
+				ids[h.Show.IDs.TMDB] = true
+			}
+		}
+
+		totalPages = getPagination(req.ResponseHeader).PageCount
+	}
+
+	cacheStore.Set(cacheKey, &ids, cache.TraktHiddenRecommendationsExpire)
+	return
+}
+
 // GetLastActivities ...
 func GetLastActivities() (ret *UserActivities, err error) {
 	if err := Authorized(); err != nil {
@@ -134,6 +134,25 @@ type Watchlist struct {
 	Episodes []*Episode `json:"episodes"`
 }
 
+// RatedItem is a movie, show, season or episode, rated by user
+type RatedItem struct {
+	RatedAt time.Time `json:"rated_at"`
+	Rating  int       `json:"rating"`
+	Type    string    `json:"type"`
+	Movie   *Movie    `json:"movie"`
+	Show    *Show     `json:"show"`
+	Season  *Season   `json:"season"`
+	Episode *Episode  `json:"episode"`
+}
+
+// FavoriteItem is a movie or show in user favorites
+type FavoriteItem struct {
+	ListedAt time.Time `json:"listed_at"`
+	Type     string    `json:"type"`
+	Movie    *Movie    `json:"movie"`
+	Show     *Show     `json:"show"`
+}
+
 // WatchlistMovie ...
 type WatchlistMovie struct {
 	ListedAt time.Time `json:"listed_at"`
@@ -196,6 +215,14 @@ type HiddenShow struct {
 	Show     *Show     `json:"show"`
 }
 
+// HiddenItem is a movie or show, hidden from recommendations
+type HiddenItem struct {
+	HiddenAt time.Time `json:"hidden_at"`
+	Type     string    `json:"type"`
+	Movie    *Movie    `json:"movie"`
+	Show     *Show     `json:"show"`
+}
+
 // Sizes ...
 type Sizes struct {
 	Full      string `json:"full"`
@@ -270,6 +297,7 @@ type List struct {
 
 // ListItem ...
 type ListItem struct {
+	ID       int       `json:"id"`
 	Rank     int       `json:"rank"`
 	ListedAt time.Time `json:"listed_at"`
 	Type     string    `json:"type"`
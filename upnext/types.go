@@ -5,6 +5,8 @@ type Payload struct {
 	CurrentEpisode Episode `json:"current_episode"`
 	NextEpisode    Episode `json:"next_episode"`
 	PlayURL        string  `json:"play_url"`
+	// NotificationTime is a playback position in seconds, when notification is shown, like the start of credits
+	NotificationTime int `json:"notification_time,omitempty"`
 }
 
 // Episode info https://github.com/im85288/service.upnext/wiki/Integration
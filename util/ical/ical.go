@@ -0,0 +1,138 @@
+package ical
+
+import (
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	// maxLineLength is a maximum length of content line in octets, longer lines are folded
+	maxLineLength = 75
+
+	dateFormat     = "20060102"
+	dateTimeFormat = "20060102T150405"
+)
+
+// Calendar is an iCalendar object with a list of events
+type Calendar struct {
+	ProductID string
+	Name      string
+	// Location is a time zone, suggested to clients for displaying events.
+	// Event times are always written in UTC, as the calendar has no VTIMEZONE definitions.
+	Location *time.Location
+
+	Events []*Event
+}
+
+// Event is a single calendar event
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	URL         string
+	Start       time.Time
+	Duration    time.Duration
+	// AllDay marks events, which only have a date, like releases without known time
+	AllDay bool
+}
+
+// Write writes calendar in iCalendar format
+func (c *Calendar) Write(w io.Writer) error {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:" + escape(c.ProductID),
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+	}
+	if c.Name != "" {
+		lines = append(lines, "X-WR-CALNAME:"+escape(c.Name))
+	}
+	if tz := c.timezone(); tz != "" {
+		lines = append(lines, "X-WR-TIMEZONE:"+tz)
+	}
+
+	stamp := formatTime(time.Now())
+	for _, e := range c.Events {
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+escape(e.UID),
+			"DTSTAMP:"+stamp,
+		)
+
+		if e.AllDay {
+			lines = append(lines,
+				"DTSTART;VALUE=DATE:"+e.Start.Format(dateFormat),
+				"DTEND;VALUE=DATE:"+e.Start.AddDate(0, 0, 1).Format(dateFormat),
+			)
+		} else {
+			lines = append(lines,
+				"DTSTART:"+formatTime(e.Start),
+				"DTEND:"+formatTime(e.Start.Add(e.Duration)),
+			)
+		}
+
+		lines = append(lines, "SUMMARY:"+escape(e.Summary))
+		if e.Description != "" {
+			lines = append(lines, "DESCRIPTION:"+escape(e.Description))
+		}
+		if e.URL != "" {
+			lines = append(lines, "URL:"+e.URL)
+		}
+		lines = append(lines, "END:VEVENT")
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, l := range lines {
+		if _, err := io.WriteString(w, fold(l)+"\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// timezone returns name of calendar's time zone, empty for UTC or unnamed zones
+func (c *Calendar) timezone() string {
+	if c.Location == nil || c.Location == time.UTC || c.Location.String() == "Local" {
+		return ""
+	}
+	return c.Location.String()
+}
+
+// formatTime returns time in UTC form, that does not need time zone definition
+func formatTime(t time.Time) string {
+	return t.UTC().Format(dateTimeFormat) + "Z"
+}
+
+// escape escapes text value according to RFC 5545
+func escape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(s)
+}
+
+// fold splits content line into lines of at most 75 octets, without breaking UTF-8 characters
+func fold(line string) string {
+	if len(line) <= maxLineLength {
+		return line
+	}
+
+	var b strings.Builder
+	length := 0
+	for _, r := range line {
+		size := len(string(r))
+		if length+size > maxLineLength {
+			b.WriteString("\r\n ")
+			// Continuation line starts with a space, which is counted in line length
+			length = 1
+		}
+		b.WriteRune(r)
+		length += size
+	}
+	return b.String()
+}
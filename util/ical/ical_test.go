@@ -0,0 +1,79 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrite(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("Time zone database is not available: %s", err)
+	}
+
+	c := &Calendar{
+		ProductID: "-//Elementum//Calendar//EN",
+		Name:      "Elementum",
+		Location:  loc,
+		Events: []*Event{
+			{
+				UID:      "episode-1-1-2@elementum",
+				Summary:  "Show; S01E02, Pilot",
+				URL:      "http://127.0.0.1:65220/show/1/season/1/episode/2/play?external=1",
+				Start:    time.Date(2026, 1, 15, 2, 0, 0, 0, time.UTC),
+				Duration: 45 * time.Minute,
+			},
+			{
+				UID:     "movie-2@elementum",
+				Summary: "Movie",
+				Start:   time.Date(2026, 1, 20, 0, 0, 0, 0, time.UTC),
+				AllDay:  true,
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := c.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	for _, expected := range []string{
+		"X-WR-TIMEZONE:Europe/Berlin\r\n",
+		"DTSTART:20260115T020000Z\r\n",
+		"DTEND:20260115T024500Z\r\n",
+		`SUMMARY:Show\; S01E02\, Pilot` + "\r\n",
+		"DTSTART;VALUE=DATE:20260120\r\n",
+		"DTEND;VALUE=DATE:20260121\r\n",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected %q in calendar:\n%s", expected, out)
+		}
+	}
+
+	if strings.Contains(out, "TZID=") {
+		t.Errorf("Unexpected time zone reference without VTIMEZONE:\n%s", out)
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		if len(line) > maxLineLength {
+			t.Errorf("Line is not folded: %q", line)
+		}
+	}
+}
+
+func TestFold(t *testing.T) {
+	line := "DESCRIPTION:" + strings.Repeat("ä", 80)
+
+	folded := fold(line)
+	if strings.ReplaceAll(folded, "\r\n ", "") != line {
+		t.Errorf("Unfolded line differs from original: %q", folded)
+	}
+	for _, l := range strings.Split(folded, "\r\n") {
+		if len(l) > maxLineLength {
+			t.Errorf("Line is longer than %d octets: %q", maxLineLength, l)
+		}
+	}
+}
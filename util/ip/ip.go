@@ -224,6 +224,20 @@ func GetContextHTTPHost(ctx *gin.Context) string {
 	return fmt.Sprintf("http://%s:%d", host, config.Args.LocalPort)
 }
 
+// GetExternalHTTPHost returns host, that is reachable from other machines, regardless of the request origin
+func GetExternalHTTPHost() string {
+	host := "127.0.0.1"
+	if config.Args.ServerExternalIP != "" {
+		host = config.Args.ServerExternalIP
+	} else if localIP, err := LocalIP(nil); err == nil {
+		host = localIP.String()
+	} else {
+		log.Debugf("Error getting local IP: %s", err)
+	}
+
+	return fmt.Sprintf("http://%s:%d", host, config.Args.LocalPort)
+}
+
 // ElementumURL returns elementum url for external calls
 func ElementumURL(xbmcHost *xbmc.XBMCHost) string {
 	return GetHTTPHost(xbmcHost)
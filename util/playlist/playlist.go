@@ -0,0 +1,130 @@
+package playlist
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Playlist formats
+const (
+	FormatM3U  = "m3u"
+	FormatXSPF = "xspf"
+)
+
+// ErrUnknownFormat is returned for formats, that are not supported
+var ErrUnknownFormat = errors.New("unknown playlist format")
+
+// Playlist is an ordered list of streams
+type Playlist struct {
+	Title   string
+	Entries []*Entry
+}
+
+// Entry is a single stream of the playlist
+type Entry struct {
+	Title string
+	URL   string
+	// Duration is unknown if zero
+	Duration time.Duration
+	// Artwork is an URL of the image, shown by players for the entry
+	Artwork string
+}
+
+// ContentType returns MIME type of playlist format
+func ContentType(format string) string {
+	switch format {
+	case FormatM3U:
+		return "audio/x-mpegurl; charset=utf-8"
+	case FormatXSPF:
+		return "application/xspf+xml; charset=utf-8"
+	}
+	return "application/octet-stream"
+}
+
+// Write writes playlist in the format
+func (p *Playlist) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatM3U:
+		return p.WriteM3U(w)
+	case FormatXSPF:
+		return p.WriteXSPF(w)
+	}
+	return ErrUnknownFormat
+}
+
+// WriteM3U writes playlist in extended M3U format
+func (p *Playlist) WriteM3U(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	if p.Title != "" {
+		b.WriteString("#PLAYLIST:" + singleLine(p.Title) + "\n")
+	}
+
+	for _, e := range p.Entries {
+		duration := -1
+		if e.Duration > 0 {
+			duration = int(e.Duration.Seconds())
+		}
+
+		fmt.Fprintf(&b, "#EXTINF:%d", duration)
+		if e.Artwork != "" {
+			fmt.Fprintf(&b, ` tvg-logo="%s"`, strings.ReplaceAll(singleLine(e.Artwork), `"`, "%22"))
+		}
+		b.WriteString("," + singleLine(e.Title) + "\n")
+		b.WriteString(singleLine(e.URL) + "\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type xspfTrack struct {
+	Location string `xml:"location"`
+	Title    string `xml:"title,omitempty"`
+	Duration int64  `xml:"duration,omitempty"`
+	Image    string `xml:"image,omitempty"`
+}
+
+type xspfPlaylist struct {
+	XMLName xml.Name    `xml:"http://xspf.org/ns/0/ playlist"`
+	Version int         `xml:"version,attr"`
+	Title   string      `xml:"title,omitempty"`
+	Tracks  []xspfTrack `xml:"trackList>track"`
+}
+
+// WriteXSPF writes playlist in XSPF format
+func (p *Playlist) WriteXSPF(w io.Writer) error {
+	doc := xspfPlaylist{
+		Version: 1,
+		Title:   p.Title,
+		Tracks:  make([]xspfTrack, 0, len(p.Entries)),
+	}
+	for _, e := range p.Entries {
+		doc.Tracks = append(doc.Tracks, xspfTrack{
+			Location: e.URL,
+			Title:    e.Title,
+			Duration: e.Duration.Milliseconds(),
+			Image:    e.Artwork,
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// singleLine replaces line breaks, as each M3U directive should take a single line
+func singleLine(s string) string {
+	return strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(s)
+}
@@ -0,0 +1,73 @@
+package playlist
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testPlaylist() *Playlist {
+	return &Playlist{
+		Title: "Show - Season 1",
+		Entries: []*Entry{
+			{
+				Title:    "Show S01E01 - Pilot",
+				URL:      "http://192.168.1.2:65220/files/Show/Show.S01E01.mkv",
+				Duration: 45 * time.Minute,
+				Artwork:  "https://image.tmdb.org/t/p/w500/still.jpg",
+			},
+			{
+				Title: "Show.S01E02\n.mkv",
+				URL:   "http://192.168.1.2:65220/files/Show/Show.S01E02.mkv",
+			},
+		},
+	}
+}
+
+func TestWriteM3U(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testPlaylist().Write(&buf, FormatM3U); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := strings.Join([]string{
+		"#EXTM3U",
+		"#PLAYLIST:Show - Season 1",
+		`#EXTINF:2700 tvg-logo="https://image.tmdb.org/t/p/w500/still.jpg",Show S01E01 - Pilot`,
+		"http://192.168.1.2:65220/files/Show/Show.S01E01.mkv",
+		"#EXTINF:-1,Show.S01E02 .mkv",
+		"http://192.168.1.2:65220/files/Show/Show.S01E02.mkv",
+		"",
+	}, "\n")
+	if buf.String() != expected {
+		t.Errorf("Unexpected M3U:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
+
+func TestWriteXSPF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testPlaylist().Write(&buf, FormatXSPF); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	for _, s := range []string{
+		`<playlist xmlns="http://xspf.org/ns/0/" version="1">`,
+		"<title>Show - Season 1</title>",
+		"<location>http://192.168.1.2:65220/files/Show/Show.S01E01.mkv</location>",
+		"<duration>2700000</duration>",
+		"<image>https://image.tmdb.org/t/p/w500/still.jpg</image>",
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("XSPF does not contain %q:\n%s", s, out)
+		}
+	}
+	if strings.Count(out, "<track>") != 2 {
+		t.Errorf("Expected 2 tracks:\n%s", out)
+	}
+
+	if err := testPlaylist().Write(&buf, "pls"); err != ErrUnknownFormat {
+		t.Errorf("Expected unknown format error, got %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package probe
+
+import "strings"
+
+// iso639Short maps ISO 639-2 codes (both B and T variants) of common languages to ISO 639-1
+var iso639Short = map[string]string{
+	"alb": "sq", "sqi": "sq",
+	"ara": "ar",
+	"arm": "hy", "hye": "hy",
+	"baq": "eu", "eus": "eu",
+	"bel": "be",
+	"ben": "bn",
+	"bos": "bs",
+	"bul": "bg",
+	"cat": "ca",
+	"chi": "zh", "zho": "zh",
+	"cze": "cs", "ces": "cs",
+	"dan": "da",
+	"dut": "nl", "nld": "nl",
+	"eng": "en",
+	"est": "et",
+	"fin": "fi",
+	"fre": "fr", "fra": "fr",
+	"geo": "ka", "kat": "ka",
+	"ger": "de", "deu": "de",
+	"gre": "el", "ell": "el",
+	"heb": "he",
+	"hin": "hi",
+	"hrv": "hr",
+	"hun": "hu",
+	"ice": "is", "isl": "is",
+	"ind": "id",
+	"ita": "it",
+	"jpn": "ja",
+	"kaz": "kk",
+	"kor": "ko",
+	"lav": "lv",
+	"lit": "lt",
+	"mac": "mk", "mkd": "mk",
+	"may": "ms", "msa": "ms",
+	"nor": "no", "nob": "nb", "nno": "nn",
+	"per": "fa", "fas": "fa",
+	"pol": "pl",
+	"por": "pt",
+	"rum": "ro", "ron": "ro",
+	"rus": "ru",
+	"slo": "sk", "slk": "sk",
+	"slv": "sl",
+	"spa": "es",
+	"srp": "sr",
+	"swe": "sv",
+	"tha": "th",
+	"tur": "tr",
+	"ukr": "uk",
+	"vie": "vi",
+}
+
+// ShortLanguage returns ISO 639-1 code for ISO 639-2 codes and BCP 47 tags,
+// like "en" for "eng" or "en-US". Unknown codes are returned in lower case.
+func ShortLanguage(code string) string {
+	code = strings.ToLower(code)
+	if i := strings.IndexAny(code, "-_"); i > 0 {
+		code = code[:i]
+	}
+	if short, ok := iso639Short[code]; ok {
+		return short
+	}
+	return code
+}
@@ -0,0 +1,122 @@
+package probe
+
+import (
+	"regexp"
+	"time"
+)
+
+const (
+	// introSearchEnd limits start of untitled intro chapters
+	introSearchEnd = 5 * time.Minute
+	// creditsSearchStart is a share of duration, after which untitled credits chapters start
+	creditsSearchStart = 0.85
+
+	minIntroLength   = 20 * time.Second
+	maxIntroLength   = 130 * time.Second
+	minCreditsLength = 20 * time.Second
+	maxCreditsLength = 5 * time.Minute
+
+	// minUntitledChapters is a number of chapters, needed to guess markers by chapter positions
+	minUntitledChapters = 3
+)
+
+var (
+	// Short anime names, like "OP" or "ED2", are matched only as whole titles
+	introRe   = regexp.MustCompile(`(?i)\b(intro(duction)?|opening|main titles?|title sequence|theme song)\b|^\s*op\s?\d*\s*$`)
+	creditsRe = regexp.MustCompile(`(?i)\b(credits|ending|outro|end titles?|closing)\b|^\s*ed\s?\d*\s*$`)
+	previewRe = regexp.MustCompile(`(?i)\b(preview|next episode|next time|post[- ]credits?|stinger)\b`)
+)
+
+// Markers are ranges of the media, that can be skipped
+type Markers struct {
+	Intro   *Chapter
+	Credits *Chapter
+}
+
+// DetectMarkers finds intro and credits among chapters, by chapter titles,
+// or by positions and lengths, when chapters have generic titles, like "Chapter 01".
+// Duration is used for chapters without end time and should be the runtime of the media, if not known from container.
+func DetectMarkers(chapters []*Chapter, duration time.Duration) *Markers {
+	if len(chapters) == 0 {
+		return nil
+	}
+	if last := chapters[len(chapters)-1]; duration <= 0 || duration < last.Start {
+		duration = last.End
+	}
+
+	m := &Markers{}
+	for _, c := range chapters {
+		end := c.End
+		if end <= c.Start {
+			end = duration
+		}
+
+		switch {
+		case previewRe.MatchString(c.Title):
+			// "Post-credits" scenes and previews are worth watching
+			continue
+		case m.Intro == nil && introRe.MatchString(c.Title) && c.Start < duration/2:
+			m.Intro = &Chapter{Title: c.Title, Start: c.Start, End: end}
+		case m.Credits == nil && creditsRe.MatchString(c.Title):
+			// "Opening Credits" are matched as intro, credits in the first half are not final credits
+			if c.Start >= duration/2 {
+				m.Credits = &Chapter{Title: c.Title, Start: c.Start, End: end}
+			} else if m.Intro == nil {
+				m.Intro = &Chapter{Title: c.Title, Start: c.Start, End: end}
+			}
+		}
+	}
+
+	if len(chapters) >= minUntitledChapters && duration > 0 {
+		if m.Intro == nil {
+			m.Intro = guessIntro(chapters)
+		}
+		if m.Credits == nil {
+			m.Credits = guessCredits(chapters, duration)
+		}
+	}
+
+	if m.Intro == nil && m.Credits == nil {
+		return nil
+	}
+	return m
+}
+
+// guessIntro returns the first chapter near the start, that is as long as an opening sequence
+func guessIntro(chapters []*Chapter) *Chapter {
+	for _, c := range chapters {
+		if c.Start > introSearchEnd {
+			break
+		}
+		if length := c.End - c.Start; length >= minIntroLength && length <= maxIntroLength {
+			return &Chapter{Title: c.Title, Start: c.Start, End: c.End}
+		}
+	}
+	return nil
+}
+
+// guessCredits returns the first chapter near the end, that is as long as credits.
+// Previews of the next episode and short trailing chapters are not taken as credits.
+func guessCredits(chapters []*Chapter, duration time.Duration) *Chapter {
+	threshold := time.Duration(float64(duration) * creditsSearchStart)
+	for _, c := range chapters {
+		if c.Start < threshold || previewRe.MatchString(c.Title) {
+			continue
+		}
+		if length := c.End - c.Start; length < minCreditsLength || length > maxCreditsLength {
+			continue
+		}
+		return &Chapter{Title: c.Title, Start: c.Start, End: c.End}
+	}
+	return nil
+}
+
+// InIntro returns whether position is inside of the intro
+func (m *Markers) InIntro(position time.Duration) bool {
+	return m != nil && m.Intro != nil && position >= m.Intro.Start && position < m.Intro.End
+}
+
+// InCredits returns whether position is at the credits or after them
+func (m *Markers) InCredits(position time.Duration) bool {
+	return m != nil && m.Credits != nil && position >= m.Credits.Start
+}
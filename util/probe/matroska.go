@@ -0,0 +1,527 @@
+package probe
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// Matroska element IDs, with length marker bits
+const (
+	mkvEBML      = 0x1A45DFA3
+	mkvSegment   = 0x18538067
+	mkvSeekHead  = 0x114D9B74
+	mkvSeek      = 0x4DBB
+	mkvSeekID    = 0x53AB
+	mkvSeekPos   = 0x53AC
+	mkvInfo      = 0x1549A966
+	mkvTimeScale = 0x2AD7B1
+	mkvDuration  = 0x4489
+	mkvTracks    = 0x1654AE6B
+	mkvChapters  = 0x1043A770
+	mkvCluster   = 0x1F43B675
+
+	mkvTrackEntry     = 0xAE
+	mkvTrackType      = 0x83
+	mkvCodecID        = 0x86
+	mkvLanguage       = 0x22B59C
+	mkvLanguageBCP47  = 0x22B59D
+	mkvName           = 0x536E
+	mkvFlagDefault    = 0x88
+	mkvFlagForced     = 0x55AA
+	mkvVideo          = 0xE0
+	mkvPixelWidth     = 0xB0
+	mkvPixelHeight    = 0xBA
+	mkvColour         = 0x55B0
+	mkvTransfer       = 0x55BA
+	mkvAudio          = 0xE1
+	mkvChannels       = 0x9F
+	mkvSamplingFreq   = 0xB5
+	mkvBlockAddMap    = 0x41E4
+	mkvBlockAddIDType = 0x41E7
+
+	mkvEditionEntry     = 0x45B9
+	mkvEditionFlagDef   = 0x45DB
+	mkvChapterAtom      = 0xB6
+	mkvChapterTimeStart = 0x91
+	mkvChapterTimeEnd   = 0x92
+	mkvChapterHidden    = 0x98
+	mkvChapterDisplay   = 0x80
+	mkvChapString       = 0x85
+)
+
+const (
+	// mkvMaxElementSize limits size of header elements, read into memory
+	mkvMaxElementSize = 16 * 1024 * 1024
+	// mkvMaxTopLevel limits number of top level elements, checked before the first cluster
+	mkvMaxTopLevel = 64
+
+	mkvTypeVideo    = 1
+	mkvTypeAudio    = 2
+	mkvTypeSubtitle = 0x11
+
+	// Block additions of Dolby Vision configuration
+	dvcC = 0x64766343
+	dvvC = 0x64767643
+
+	unknownSize = -1
+)
+
+var (
+	ebmlMagic = []byte{0x1A, 0x45, 0xDF, 0xA3}
+
+	errBadVint = errors.New("bad EBML variable size integer")
+
+	mkvCodecs = map[string]string{
+		"V_MPEG4/ISO/AVC":  "h264",
+		"V_MPEGH/ISO/HEVC": "hevc",
+		"V_AV1":            "av1",
+		"V_VP8":            "vp8",
+		"V_VP9":            "vp9",
+		"V_MPEG4/ISO/ASP":  "mpeg4",
+		"V_MPEG4/ISO/SP":   "mpeg4",
+		"V_MPEG2":          "mpeg2video",
+		"V_MS/VFW/FOURCC":  "vfw",
+		"A_AAC":            "aac",
+		"A_AC3":            "ac3",
+		"A_EAC3":           "eac3",
+		"A_DTS":            "dca",
+		"A_TRUEHD":         "truehd",
+		"A_FLAC":           "flac",
+		"A_OPUS":           "opus",
+		"A_VORBIS":         "vorbis",
+		"A_MPEG/L3":        "mp3",
+		"A_MPEG/L2":        "mp2",
+		"A_PCM":            "pcm",
+		"S_TEXT/UTF8":      "subrip",
+		"S_TEXT/ASS":       "ass",
+		"S_TEXT/SSA":       "ssa",
+		"S_ASS":            "ass",
+		"S_SSA":            "ssa",
+		"S_TEXT/WEBVTT":    "webvtt",
+		"S_HDMV/PGS":       "pgs",
+		"S_VOBSUB":         "vobsub",
+		"S_DVBSUB":         "dvbsub",
+	}
+)
+
+// ebmlElement is a header of EBML element, data follows the header
+type ebmlElement struct {
+	id       uint32
+	dataOff  int64
+	dataSize int64
+}
+
+// end returns end of element data, elements can not exceed their parent
+func (e ebmlElement) end(parentEnd int64) int64 {
+	if e.dataSize == unknownSize || e.dataOff+e.dataSize > parentEnd {
+		return parentEnd
+	}
+	return e.dataOff + e.dataSize
+}
+
+func probeMatroska(r io.ReaderAt, size int64) (*MediaInfo, error) {
+	header, err := readElementHeader(r, 0, size)
+	if err != nil {
+		return nil, err
+	} else if header.id != mkvEBML {
+		return nil, ErrUnknownFormat
+	}
+
+	segment, err := readElementHeader(r, header.end(size), size)
+	if err != nil {
+		return nil, err
+	} else if segment.id != mkvSegment {
+		return nil, ErrUnknownFormat
+	}
+	segmentEnd := segment.end(size)
+
+	info := &MediaInfo{Container: "matroska"}
+	timeScale := uint64(1000000)
+	duration := 0.0
+	tracksPos := int64(-1)
+	chaptersPos := int64(-1)
+	hasTracks := false
+	hasChapters := false
+
+	pos := segment.dataOff
+	for i := 0; i < mkvMaxTopLevel && pos < segmentEnd; i++ {
+		e, err := readElementHeader(r, pos, size)
+		if err != nil {
+			// Elements after tracks are not needed, and can be in not yet available part of the file
+			if hasTracks {
+				break
+			}
+			return nil, err
+		}
+
+		switch e.id {
+		case mkvSeekHead:
+			data, err := readElementData(r, e)
+			if err != nil {
+				return nil, err
+			}
+			if p := seekPosition(data, mkvTracks); p >= 0 {
+				tracksPos = segment.dataOff + p
+			}
+			if p := seekPosition(data, mkvChapters); p >= 0 {
+				chaptersPos = segment.dataOff + p
+			}
+		case mkvInfo:
+			data, err := readElementData(r, e)
+			if err != nil {
+				return nil, err
+			}
+			walkElements(data, func(id uint32, payload []byte) {
+				switch id {
+				case mkvTimeScale:
+					timeScale = readUint(payload)
+				case mkvDuration:
+					duration = readFloat(payload)
+				}
+			})
+		case mkvTracks:
+			data, err := readElementData(r, e)
+			if err != nil {
+				return nil, err
+			}
+			info.Tracks = parseMatroskaTracks(data)
+			hasTracks = true
+		case mkvChapters:
+			if data, err := readElementData(r, e); err == nil {
+				info.Chapters = parseMatroskaChapters(data)
+				hasChapters = true
+			}
+		}
+
+		// Tracks are usually placed before clusters, otherwise they are found with seek head
+		if e.id == mkvCluster || e.dataSize == unknownSize {
+			break
+		}
+		pos = e.end(segmentEnd)
+	}
+
+	if !hasTracks && tracksPos > 0 && tracksPos < size {
+		e, err := readElementHeader(r, tracksPos, size)
+		if err != nil {
+			return nil, err
+		} else if e.id == mkvTracks {
+			data, err := readElementData(r, e)
+			if err != nil {
+				return nil, err
+			}
+			info.Tracks = parseMatroskaTracks(data)
+		}
+	}
+
+	// Chapters are optional, so they are skipped if that part of the file is not available yet
+	if !hasChapters && chaptersPos > 0 && chaptersPos < size {
+		if e, err := readElementHeader(r, chaptersPos, size); err == nil && e.id == mkvChapters {
+			if data, err := readElementData(r, e); err == nil {
+				info.Chapters = parseMatroskaChapters(data)
+			}
+		}
+	}
+
+	info.Duration = time.Duration(duration * float64(timeScale))
+	finishChapters(info.Chapters, info.Duration)
+	return info, nil
+}
+
+// parseMatroskaChapters returns visible chapters of the default edition, or of the first edition
+func parseMatroskaChapters(data []byte) []*Chapter {
+	var ret []*Chapter
+	hasDefault := false
+	walkElements(data, func(id uint32, edition []byte) {
+		if id != mkvEditionEntry || hasDefault {
+			return
+		}
+
+		chapters := []*Chapter{}
+		isDefault := false
+		walkElements(edition, func(id uint32, payload []byte) {
+			switch id {
+			case mkvEditionFlagDef:
+				isDefault = readUint(payload) == 1
+			case mkvChapterAtom:
+				if c := parseMatroskaChapter(payload); c != nil {
+					chapters = append(chapters, c)
+				}
+			}
+		})
+
+		if ret == nil || isDefault {
+			ret = chapters
+			hasDefault = isDefault
+		}
+	})
+	return ret
+}
+
+func parseMatroskaChapter(data []byte) *Chapter {
+	c := &Chapter{}
+	hidden := false
+	walkElements(data, func(id uint32, payload []byte) {
+		switch id {
+		case mkvChapterTimeStart:
+			c.Start = time.Duration(readUint(payload))
+		case mkvChapterTimeEnd:
+			c.End = time.Duration(readUint(payload))
+		case mkvChapterHidden:
+			hidden = readUint(payload) == 1
+		case mkvChapterDisplay:
+			walkElements(payload, func(id uint32, payload []byte) {
+				if id == mkvChapString && c.Title == "" {
+					c.Title = readString(payload)
+				}
+			})
+		}
+	})
+
+	if hidden {
+		return nil
+	}
+	return c
+}
+
+func parseMatroskaTracks(data []byte) []*Track {
+	tracks := []*Track{}
+	walkElements(data, func(id uint32, entry []byte) {
+		if id != mkvTrackEntry {
+			return
+		}
+
+		t := &Track{Language: "eng", Default: true}
+		trackType := uint64(0)
+		codec := ""
+		bcp47 := ""
+		walkElements(entry, func(id uint32, payload []byte) {
+			switch id {
+			case mkvTrackType:
+				trackType = readUint(payload)
+			case mkvCodecID:
+				codec = readString(payload)
+			case mkvLanguage:
+				t.Language = readString(payload)
+			case mkvLanguageBCP47:
+				bcp47 = readString(payload)
+			case mkvName:
+				t.Name = readString(payload)
+			case mkvFlagDefault:
+				t.Default = readUint(payload) == 1
+			case mkvFlagForced:
+				t.Forced = readUint(payload) == 1
+			case mkvVideo:
+				walkElements(payload, func(id uint32, payload []byte) {
+					switch id {
+					case mkvPixelWidth:
+						t.Width = int(readUint(payload))
+					case mkvPixelHeight:
+						t.Height = int(readUint(payload))
+					case mkvColour:
+						walkElements(payload, func(id uint32, payload []byte) {
+							if id == mkvTransfer && t.HDR == "" {
+								t.HDR = hdrFromTransfer(int(readUint(payload)))
+							}
+						})
+					}
+				})
+			case mkvAudio:
+				walkElements(payload, func(id uint32, payload []byte) {
+					switch id {
+					case mkvChannels:
+						t.Channels = int(readUint(payload))
+					case mkvSamplingFreq:
+						t.SampleRate = int(readFloat(payload))
+					}
+				})
+			case mkvBlockAddMap:
+				walkElements(payload, func(id uint32, payload []byte) {
+					if id == mkvBlockAddIDType {
+						if v := readUint(payload); v == dvcC || v == dvvC {
+							t.HDR = DolbyVision
+						}
+					}
+				})
+			}
+		})
+
+		switch trackType {
+		case mkvTypeVideo:
+			t.Type = VideoTrack
+		case mkvTypeAudio:
+			t.Type = AudioTrack
+		case mkvTypeSubtitle:
+			t.Type = SubtitleTrack
+		default:
+			return
+		}
+
+		if bcp47 != "" {
+			t.Language = bcp47
+		}
+		t.Codec = matroskaCodec(codec)
+		tracks = append(tracks, t)
+	})
+	return tracks
+}
+
+func matroskaCodec(codec string) string {
+	if c, ok := mkvCodecs[codec]; ok {
+		return c
+	}
+	// Codec IDs can have profile suffixes, like "A_AAC/MPEG4/LC" or "A_DTS/EXPRESS"
+	for prefix, c := range mkvCodecs {
+		if strings.HasPrefix(codec, prefix+"/") {
+			return c
+		}
+	}
+	return strings.ToLower(strings.TrimLeft(codec[min(len(codec), 2):], "_"))
+}
+
+// seekPosition returns position of element with given ID from seek head, relative to segment data
+func seekPosition(data []byte, target uint32) (ret int64) {
+	ret = -1
+	walkElements(data, func(id uint32, seek []byte) {
+		if id != mkvSeek {
+			return
+		}
+
+		seekID := uint32(0)
+		position := int64(-1)
+		walkElements(seek, func(id uint32, payload []byte) {
+			switch id {
+			case mkvSeekID:
+				seekID = uint32(readUint(payload))
+			case mkvSeekPos:
+				position = int64(readUint(payload))
+			}
+		})
+		if seekID == target && position >= 0 {
+			ret = position
+		}
+	})
+	return
+}
+
+// readElementHeader reads element ID and size at given offset
+func readElementHeader(r io.ReaderAt, off, size int64) (e ebmlElement, err error) {
+	if off < 0 || off >= size {
+		return e, io.ErrUnexpectedEOF
+	}
+
+	buf := make([]byte, 12)
+	if off+int64(len(buf)) > size {
+		buf = buf[:size-off]
+	}
+	if len(buf) < 2 {
+		return e, io.ErrUnexpectedEOF
+	}
+	if err = readAt(r, buf, off); err != nil {
+		return
+	}
+
+	id, idLen, err := readVint(buf, true)
+	if err != nil {
+		return
+	}
+	dataSize, sizeLen, err := readVint(buf[idLen:], false)
+	if err != nil {
+		return
+	}
+
+	e.id = uint32(id)
+	e.dataOff = off + int64(idLen+sizeLen)
+	e.dataSize = dataSize
+	return
+}
+
+func readElementData(r io.ReaderAt, e ebmlElement) ([]byte, error) {
+	if e.dataSize == unknownSize || e.dataSize > mkvMaxElementSize {
+		return nil, errors.New("header element is too big")
+	}
+
+	data := make([]byte, e.dataSize)
+	if err := readAt(r, data, e.dataOff); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// walkElements calls fn for each child element, stored in data
+func walkElements(data []byte, fn func(id uint32, payload []byte)) {
+	for len(data) > 0 {
+		id, idLen, err := readVint(data, true)
+		if err != nil {
+			return
+		}
+		size, sizeLen, err := readVint(data[idLen:], false)
+		if err != nil {
+			return
+		}
+
+		start := idLen + sizeLen
+		end := int64(start) + size
+		if size == unknownSize || end > int64(len(data)) {
+			end = int64(len(data))
+		}
+
+		fn(uint32(id), data[start:end])
+		data = data[end:]
+	}
+}
+
+// readVint reads EBML variable size integer, IDs keep their length marker bits.
+// Size with all value bits set means unknown size.
+func readVint(b []byte, isID bool) (int64, int, error) {
+	if len(b) == 0 || b[0] == 0 {
+		return 0, 0, errBadVint
+	}
+
+	length := 1
+	for mask := byte(0x80); b[0]&mask == 0; mask >>= 1 {
+		length++
+	}
+	if length > 8 || (isID && length > 4) || len(b) < length {
+		return 0, 0, errBadVint
+	}
+
+	value := int64(b[0])
+	if !isID {
+		value &= int64(0xFF >> length)
+	}
+	allOnes := value == int64(0xFF>>length)
+	for i := 1; i < length; i++ {
+		value = value<<8 | int64(b[i])
+		allOnes = allOnes && b[i] == 0xFF
+	}
+
+	if !isID && allOnes {
+		return unknownSize, length, nil
+	}
+	return value, length, nil
+}
+
+func readUint(b []byte) (ret uint64) {
+	for _, c := range b {
+		ret = ret<<8 | uint64(c)
+	}
+	return
+}
+
+func readFloat(b []byte) float64 {
+	switch len(b) {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+func readString(b []byte) string {
+	return strings.TrimRight(string(b), "\x00")
+}
@@ -0,0 +1,351 @@
+package probe
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+const (
+	// mp4MaxMoovSize limits size of moov box, read into memory
+	mp4MaxMoovSize = 32 * 1024 * 1024
+	// mp4MaxTopLevel limits number of top level boxes, checked while looking for moov
+	mp4MaxTopLevel = 64
+
+	// Offsets of children boxes in sample entries
+	mp4VisualEntryChildren = 78
+	mp4AudioEntryChildren  = 28
+)
+
+var mp4Codecs = map[string]string{
+	"avc1": "h264",
+	"avc3": "h264",
+	"hvc1": "hevc",
+	"hev1": "hevc",
+	"dvh1": "hevc",
+	"dvhe": "hevc",
+	"dva1": "h264",
+	"dvav": "h264",
+	"av01": "av1",
+	"vp09": "vp9",
+	"mp4v": "mpeg4",
+	"mp4a": "aac",
+	"ac-3": "ac3",
+	"ec-3": "eac3",
+	"dtsc": "dca",
+	"dtsh": "dca",
+	"dtsl": "dca",
+	"dtse": "dca",
+	"mlpa": "truehd",
+	"fLaC": "flac",
+	"Opus": "opus",
+	".mp3": "mp3",
+	"tx3g": "mov_text",
+	"wvtt": "webvtt",
+	"stpp": "ttml",
+	"c608": "eia_608",
+}
+
+// mp4Box is a box header, payload follows the header
+type mp4Box struct {
+	typ        string
+	payloadOff int64
+	size       int64
+}
+
+func probeMP4(r io.ReaderAt, size int64) (*MediaInfo, error) {
+	// Top level boxes are walked by headers only, moov can be placed after mdat
+	pos := int64(0)
+	for i := 0; i < mp4MaxTopLevel && pos < size; i++ {
+		box, err := readBoxHeader(r, pos, size)
+		if err != nil {
+			return nil, err
+		}
+
+		if box.typ == "moov" {
+			payloadSize := box.size - (box.payloadOff - pos)
+			if payloadSize > mp4MaxMoovSize {
+				return nil, errors.New("moov box is too big")
+			}
+
+			data := make([]byte, payloadSize)
+			if err := readAt(r, data, box.payloadOff); err != nil {
+				return nil, err
+			}
+			return parseMoov(data), nil
+		}
+
+		pos += box.size
+	}
+
+	return nil, ErrNoTracks
+}
+
+func readBoxHeader(r io.ReaderAt, off, size int64) (box mp4Box, err error) {
+	if off < 0 || off >= size {
+		return box, io.ErrUnexpectedEOF
+	}
+
+	buf := make([]byte, 16)
+	if off+int64(len(buf)) > size {
+		buf = buf[:size-off]
+	}
+	if len(buf) < 8 {
+		return box, io.ErrUnexpectedEOF
+	}
+	if err = readAt(r, buf, off); err != nil {
+		return
+	}
+
+	box.typ = string(buf[4:8])
+	box.size = int64(binary.BigEndian.Uint32(buf))
+	box.payloadOff = off + 8
+
+	switch box.size {
+	case 0:
+		box.size = size - off
+	case 1:
+		if len(buf) < 16 {
+			return box, io.ErrUnexpectedEOF
+		}
+		box.size = int64(binary.BigEndian.Uint64(buf[8:]))
+		box.payloadOff += 8
+	}
+
+	if box.size < box.payloadOff-off || box.size > size-off {
+		return box, errors.New("bad box size")
+	}
+	return
+}
+
+// walkBoxes calls fn for each box, stored in data
+func walkBoxes(data []byte, fn func(typ string, payload []byte)) {
+	for len(data) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(data))
+		typ := string(data[4:8])
+		header := uint64(8)
+
+		switch size {
+		case 0:
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return
+			}
+			size = binary.BigEndian.Uint64(data[8:])
+			header = 16
+		}
+		if size < header || size > uint64(len(data)) {
+			return
+		}
+
+		fn(typ, data[header:size])
+		data = data[size:]
+	}
+}
+
+func parseMoov(data []byte) *MediaInfo {
+	info := &MediaInfo{Container: "mp4"}
+
+	walkBoxes(data, func(typ string, payload []byte) {
+		switch typ {
+		case "mvhd":
+			info.Duration = parseMvhd(payload)
+		case "trak":
+			if t := parseTrak(payload); t != nil {
+				info.Tracks = append(info.Tracks, t)
+			}
+		case "udta":
+			walkBoxes(payload, func(typ string, payload []byte) {
+				if typ == "chpl" {
+					info.Chapters = parseChpl(payload)
+				}
+			})
+		}
+	})
+
+	finishChapters(info.Chapters, info.Duration)
+	return info
+}
+
+// parseChpl reads Nero chapter list, with start times in 100 nanosecond units
+func parseChpl(b []byte) []*Chapter {
+	off := 4
+	if len(b) > 0 && b[0] != 0 {
+		off += 4
+	}
+	if len(b) < off+1 {
+		return nil
+	}
+
+	count := int(b[off])
+	b = b[off+1:]
+
+	chapters := []*Chapter{}
+	for i := 0; i < count && len(b) >= 9; i++ {
+		start := binary.BigEndian.Uint64(b)
+		length := int(b[8])
+		if len(b) < 9+length {
+			break
+		}
+
+		chapters = append(chapters, &Chapter{
+			Title: string(b[9 : 9+length]),
+			Start: time.Duration(start * 100),
+		})
+		b = b[9+length:]
+	}
+	return chapters
+}
+
+func parseMvhd(b []byte) time.Duration {
+	var timeScale, duration uint64
+	if len(b) >= 32 && b[0] == 1 {
+		timeScale = uint64(binary.BigEndian.Uint32(b[20:]))
+		duration = binary.BigEndian.Uint64(b[24:])
+	} else if len(b) >= 20 {
+		timeScale = uint64(binary.BigEndian.Uint32(b[12:]))
+		duration = uint64(binary.BigEndian.Uint32(b[16:]))
+	}
+
+	if timeScale == 0 {
+		return 0
+	}
+	return time.Duration(float64(duration) / float64(timeScale) * float64(time.Second))
+}
+
+func parseTrak(data []byte) *Track {
+	t := &Track{Language: "und"}
+	handler := ""
+	enabled := false
+
+	walkBoxes(data, func(typ string, payload []byte) {
+		switch typ {
+		case "tkhd":
+			enabled = len(payload) >= 4 && payload[3]&1 != 0
+		case "mdia":
+			walkBoxes(payload, func(typ string, payload []byte) {
+				switch typ {
+				case "mdhd":
+					if l := parseMdhdLanguage(payload); l != "" {
+						t.Language = l
+					}
+				case "hdlr":
+					if len(payload) >= 12 {
+						handler = string(payload[8:12])
+					}
+				case "minf":
+					walkBoxes(payload, func(typ string, payload []byte) {
+						if typ == "stbl" {
+							walkBoxes(payload, func(typ string, payload []byte) {
+								if typ == "stsd" {
+									parseStsd(payload, t)
+								}
+							})
+						}
+					})
+				}
+			})
+		}
+	})
+
+	switch handler {
+	case "vide":
+		t.Type = VideoTrack
+	case "soun":
+		t.Type = AudioTrack
+	case "subt", "text", "sbtl", "clcp":
+		t.Type = SubtitleTrack
+	default:
+		return nil
+	}
+
+	t.Default = enabled
+	return t
+}
+
+// parseMdhdLanguage returns ISO 639-2/T language code, packed into 3 five bit characters
+func parseMdhdLanguage(b []byte) string {
+	off := 20
+	if len(b) > 0 && b[0] == 1 {
+		off = 32
+	}
+	if len(b) < off+2 {
+		return ""
+	}
+
+	packed := binary.BigEndian.Uint16(b[off:])
+	if packed == 0 || packed == 0x7FFF {
+		return ""
+	}
+
+	lang := []byte{
+		byte(packed>>10&0x1F) + 0x60,
+		byte(packed>>5&0x1F) + 0x60,
+		byte(packed&0x1F) + 0x60,
+	}
+	for _, c := range lang {
+		if c < 'a' || c > 'z' {
+			return ""
+		}
+	}
+	return string(lang)
+}
+
+// parseStsd reads codec and parameters from the first sample entry
+func parseStsd(b []byte, t *Track) {
+	if len(b) < 8 {
+		return
+	}
+
+	walkBoxes(b[8:], func(typ string, entry []byte) {
+		if t.Codec != "" {
+			return
+		}
+
+		t.Codec = typ
+		if c, ok := mp4Codecs[typ]; ok {
+			t.Codec = c
+		}
+		if typ == "dvh1" || typ == "dvhe" || typ == "dva1" || typ == "dvav" {
+			t.HDR = DolbyVision
+		}
+
+		switch {
+		case len(entry) >= mp4VisualEntryChildren && isVisualEntry(typ):
+			t.Width = int(binary.BigEndian.Uint16(entry[24:]))
+			t.Height = int(binary.BigEndian.Uint16(entry[26:]))
+
+			walkBoxes(entry[mp4VisualEntryChildren:], func(typ string, payload []byte) {
+				switch typ {
+				case "colr":
+					if len(payload) >= 8 && string(payload[:4]) == "nclx" && t.HDR == "" {
+						t.HDR = hdrFromTransfer(int(binary.BigEndian.Uint16(payload[6:])))
+					}
+				case "dvcC", "dvvC":
+					t.HDR = DolbyVision
+				}
+			})
+		case len(entry) >= mp4AudioEntryChildren && isAudioEntry(typ):
+			t.Channels = int(binary.BigEndian.Uint16(entry[16:]))
+			t.SampleRate = int(binary.BigEndian.Uint32(entry[24:]) >> 16)
+		}
+	})
+}
+
+func isVisualEntry(typ string) bool {
+	switch typ {
+	case "avc1", "avc3", "hvc1", "hev1", "dvh1", "dvhe", "dva1", "dvav", "av01", "vp09", "mp4v":
+		return true
+	}
+	return false
+}
+
+func isAudioEntry(typ string) bool {
+	switch typ {
+	case "mp4a", "ac-3", "ec-3", "dtsc", "dtsh", "dtsl", "dtse", "mlpa", "fLaC", "Opus", ".mp3":
+		return true
+	}
+	return false
+}
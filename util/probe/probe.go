@@ -0,0 +1,243 @@
+package probe
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TrackType is a type of media track
+type TrackType int
+
+// Track types
+const (
+	VideoTrack TrackType = iota + 1
+	AudioTrack
+	SubtitleTrack
+)
+
+// HDR formats of video tracks
+const (
+	HDR10       = "hdr10"
+	HLG         = "hlg"
+	DolbyVision = "dolbyvision"
+)
+
+// Colour transfer characteristics from ITU-T H.273, used by both containers
+const (
+	transferPQ  = 16
+	transferHLG = 18
+)
+
+var (
+	// ErrUnknownFormat is returned for files, that are neither Matroska nor MP4
+	ErrUnknownFormat = errors.New("unknown container format")
+	// ErrNoTracks is returned when container has no track descriptions in the readable part
+	ErrNoTracks = errors.New("no tracks found")
+)
+
+// Track describes a single stream of the container.
+// Codec names follow Kodi stream details, like "hevc", "eac3", "dca" or "subrip".
+type Track struct {
+	Type     TrackType
+	Codec    string
+	Language string
+	Name     string
+	Default  bool
+	Forced   bool
+
+	// Audio tracks
+	Channels   int
+	SampleRate int
+
+	// Video tracks
+	Width  int
+	Height int
+	HDR    string
+}
+
+// Chapter is a named part of the media, like "Opening" or "End Credits"
+type Chapter struct {
+	Title string
+	Start time.Duration
+	End   time.Duration
+}
+
+// MediaInfo describes container, its tracks and chapters
+type MediaInfo struct {
+	Container string
+	Duration  time.Duration
+	Tracks    []*Track
+	Chapters  []*Chapter
+}
+
+// Probe reads container headers and returns description of tracks.
+// Only headers are read, so it works on partially downloaded files,
+// as long as reader can provide the head of the file, and the tail for MP4 files with moov atom at the end.
+func Probe(r io.ReaderAt, size int64) (*MediaInfo, error) {
+	head := make([]byte, 12)
+	if size < int64(len(head)) {
+		return nil, ErrUnknownFormat
+	}
+	if err := readAt(r, head, 0); err != nil {
+		return nil, err
+	}
+
+	var (
+		info *MediaInfo
+		err  error
+	)
+	switch {
+	case bytes.Equal(head[:4], ebmlMagic):
+		info, err = probeMatroska(r, size)
+	case string(head[4:8]) == "ftyp":
+		info, err = probeMP4(r, size)
+	default:
+		return nil, ErrUnknownFormat
+	}
+
+	if err != nil {
+		return nil, err
+	} else if len(info.Tracks) == 0 {
+		return nil, ErrNoTracks
+	}
+	return info, nil
+}
+
+// Video returns the first video track
+func (m *MediaInfo) Video() *Track {
+	for _, t := range m.Tracks {
+		if t.Type == VideoTrack {
+			return t
+		}
+	}
+	return nil
+}
+
+// Audio returns audio tracks, default track first
+func (m *MediaInfo) Audio() []*Track {
+	return m.byType(AudioTrack)
+}
+
+// Subtitles returns subtitle tracks, default track first
+func (m *MediaInfo) Subtitles() []*Track {
+	return m.byType(SubtitleTrack)
+}
+
+// HasSubtitles returns whether there is a full (not forced) subtitle track in any of languages,
+// which are ISO 639-1 or ISO 639-2 codes
+func (m *MediaInfo) HasSubtitles(languages ...string) bool {
+	for _, t := range m.Subtitles() {
+		if t.Forced {
+			continue
+		}
+		for _, l := range languages {
+			if l != "" && ShortLanguage(t.Language) == ShortLanguage(l) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// String returns short description, like "HEVC 3840x2160 HDR10 | EAC3 6ch eng, AC3 6ch rus | Subs: eng, fre"
+func (m *MediaInfo) String() string {
+	parts := []string{}
+	if v := m.Video(); v != nil {
+		s := strings.ToUpper(v.Codec)
+		if v.Width > 0 && v.Height > 0 {
+			s += fmt.Sprintf(" %dx%d", v.Width, v.Height)
+		}
+		if v.HDR != "" {
+			s += " " + strings.ToUpper(v.HDR)
+		}
+		parts = append(parts, s)
+	}
+
+	audio := []string{}
+	for _, a := range m.Audio() {
+		s := strings.ToUpper(a.Codec)
+		if a.Channels > 0 {
+			s += fmt.Sprintf(" %dch", a.Channels)
+		}
+		if a.Language != "" && a.Language != "und" {
+			s += " " + a.Language
+		}
+		audio = append(audio, s)
+	}
+	if len(audio) > 0 {
+		parts = append(parts, strings.Join(audio, ", "))
+	}
+
+	subs := []string{}
+	seen := map[string]bool{}
+	for _, s := range m.Subtitles() {
+		if s.Language != "" && !seen[s.Language] {
+			seen[s.Language] = true
+			subs = append(subs, s.Language)
+		}
+	}
+	if len(subs) > 0 {
+		parts = append(parts, "Subs: "+strings.Join(subs, ", "))
+	}
+
+	return strings.Join(parts, " | ")
+}
+
+func (m *MediaInfo) byType(tt TrackType) []*Track {
+	ret := []*Track{}
+	others := []*Track{}
+	for _, t := range m.Tracks {
+		if t.Type != tt {
+			continue
+		}
+		if t.Default {
+			ret = append(ret, t)
+		} else {
+			others = append(others, t)
+		}
+	}
+	return append(ret, others...)
+}
+
+// finishChapters sorts chapters and fills missing end times with start of the next chapter, or with duration
+func finishChapters(chapters []*Chapter, duration time.Duration) {
+	sort.SliceStable(chapters, func(i, j int) bool {
+		return chapters[i].Start < chapters[j].Start
+	})
+	for i, c := range chapters {
+		if c.End > c.Start {
+			continue
+		}
+		if i+1 < len(chapters) {
+			c.End = chapters[i+1].Start
+		} else {
+			c.End = duration
+		}
+	}
+}
+
+func hdrFromTransfer(transfer int) string {
+	switch transfer {
+	case transferPQ:
+		return HDR10
+	case transferHLG:
+		return HLG
+	}
+	return ""
+}
+
+// readAt reads exactly len(b) bytes, treating EOF at the end of data as success
+func readAt(r io.ReaderAt, b []byte, off int64) error {
+	n, err := r.ReadAt(b, off)
+	if n == len(b) {
+		return nil
+	} else if err == nil || err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
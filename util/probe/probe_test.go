@@ -0,0 +1,344 @@
+package probe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+// ebml encodes element with 8 bytes size, payload is a concatenation of children
+func ebml(id uint32, children ...[]byte) []byte {
+	var b bytes.Buffer
+	idBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(idBytes, id)
+	b.Write(bytes.TrimLeft(idBytes, "\x00"))
+
+	payload := bytes.Join(children, nil)
+	size := make([]byte, 8)
+	binary.BigEndian.PutUint64(size, uint64(len(payload)))
+	size[0] = 0x01
+	b.Write(size)
+	b.Write(payload)
+	return b.Bytes()
+}
+
+func ebmlUint(id uint32, v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return ebml(id, b)
+}
+
+func ebmlFloat(id uint32, v float64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, math.Float64bits(v))
+	return ebml(id, b)
+}
+
+func ebmlString(id uint32, s string) []byte {
+	return ebml(id, []byte(s))
+}
+
+func box(typ string, children ...[]byte) []byte {
+	payload := bytes.Join(children, nil)
+	b := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint32(b, uint32(8+len(payload)))
+	copy(b[4:], typ)
+	return append(b, payload...)
+}
+
+func TestProbeMatroska(t *testing.T) {
+	tracks := ebml(mkvTracks,
+		ebml(mkvTrackEntry,
+			ebmlUint(mkvTrackType, mkvTypeVideo),
+			ebmlString(mkvCodecID, "V_MPEGH/ISO/HEVC"),
+			ebml(mkvVideo,
+				ebmlUint(mkvPixelWidth, 3840),
+				ebmlUint(mkvPixelHeight, 2160),
+				ebml(mkvColour, ebmlUint(mkvTransfer, transferPQ)),
+			),
+		),
+		ebml(mkvTrackEntry,
+			ebmlUint(mkvTrackType, mkvTypeAudio),
+			ebmlString(mkvCodecID, "A_EAC3"),
+			ebmlString(mkvLanguage, "rus"),
+			ebmlUint(mkvFlagDefault, 0),
+			ebml(mkvAudio, ebmlUint(mkvChannels, 6), ebmlFloat(mkvSamplingFreq, 48000)),
+		),
+		ebml(mkvTrackEntry,
+			ebmlUint(mkvTrackType, mkvTypeAudio),
+			ebmlString(mkvCodecID, "A_DTS/EXPRESS"),
+			ebml(mkvAudio, ebmlUint(mkvChannels, 8)),
+		),
+		ebml(mkvTrackEntry,
+			ebmlUint(mkvTrackType, mkvTypeSubtitle),
+			ebmlString(mkvCodecID, "S_TEXT/UTF8"),
+			ebmlString(mkvLanguage, "fre"),
+			ebmlUint(mkvFlagForced, 1),
+		),
+		ebml(mkvTrackEntry,
+			ebmlUint(mkvTrackType, mkvTypeSubtitle),
+			ebmlString(mkvCodecID, "S_HDMV/PGS"),
+			ebmlString(mkvLanguage, "ger"),
+		),
+	)
+
+	file := bytes.Join([][]byte{
+		ebml(mkvEBML, ebmlString(0x4282, "matroska")),
+		ebml(mkvSegment,
+			ebml(mkvInfo, ebmlUint(mkvTimeScale, 1000000), ebmlFloat(mkvDuration, 5400000)),
+			tracks,
+			ebml(mkvCluster, make([]byte, 1024)),
+		),
+	}, nil)
+
+	info, err := Probe(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Container != "matroska" || info.Duration != 90*time.Minute {
+		t.Errorf("Unexpected container info: %s, %s", info.Container, info.Duration)
+	}
+	if v := info.Video(); v == nil || v.Codec != "hevc" || v.Width != 3840 || v.Height != 2160 || v.HDR != HDR10 {
+		t.Errorf("Unexpected video track: %+v", v)
+	}
+
+	audio := info.Audio()
+	if len(audio) != 2 || audio[0].Codec != "dca" || audio[0].Language != "eng" || audio[0].Channels != 8 {
+		t.Fatalf("Unexpected audio tracks: %+v", audio)
+	}
+	if audio[1].Codec != "eac3" || audio[1].Language != "rus" || audio[1].Channels != 6 || audio[1].SampleRate != 48000 {
+		t.Errorf("Unexpected second audio track: %+v", audio[1])
+	}
+
+	if !info.HasSubtitles("de") || !info.HasSubtitles("deu") {
+		t.Error("German subtitles are not found")
+	}
+	if info.HasSubtitles("fr") {
+		t.Error("Forced subtitles should not count as full subtitles")
+	}
+	if s := info.String(); s != "HEVC 3840x2160 HDR10 | DCA 8ch eng, EAC3 6ch rus | Subs: fre, ger" {
+		t.Errorf("Unexpected description: %s", s)
+	}
+}
+
+func TestProbeMatroskaSeekHead(t *testing.T) {
+	tracks := ebml(mkvTracks,
+		ebml(mkvTrackEntry,
+			ebmlUint(mkvTrackType, mkvTypeSubtitle),
+			ebmlString(mkvCodecID, "S_TEXT/ASS"),
+			ebmlString(mkvLanguageBCP47, "pt-BR"),
+		),
+	)
+	cluster := ebml(mkvCluster, make([]byte, 1024))
+
+	// Seek head has fixed size, so position of tracks after the cluster is known in advance
+	seekHead := func(pos uint64) []byte {
+		return ebml(mkvSeekHead, ebml(mkvSeek, ebmlUint(mkvSeekID, mkvTracks), ebmlUint(mkvSeekPos, pos)))
+	}
+	segmentData := bytes.Join([][]byte{seekHead(0), cluster, tracks}, nil)
+	segmentData = bytes.Join([][]byte{seekHead(uint64(len(segmentData) - len(tracks))), cluster, tracks}, nil)
+
+	file := bytes.Join([][]byte{ebml(mkvEBML), ebml(mkvSegment, segmentData)}, nil)
+
+	info, err := Probe(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subs := info.Subtitles(); len(subs) != 1 || subs[0].Codec != "ass" || !info.HasSubtitles("pt") {
+		t.Errorf("Unexpected subtitles: %+v", subs)
+	}
+}
+
+func TestProbeMP4(t *testing.T) {
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[12:], 1000)
+	binary.BigEndian.PutUint32(mvhd[16:], 60000)
+
+	tkhd := func(enabled bool) []byte {
+		b := make([]byte, 84)
+		if enabled {
+			b[3] = 1
+		}
+		return box("tkhd", b)
+	}
+	mdhd := func(lang string) []byte {
+		b := make([]byte, 24)
+		packed := uint16(lang[0]-0x60)<<10 | uint16(lang[1]-0x60)<<5 | uint16(lang[2]-0x60)
+		binary.BigEndian.PutUint16(b[20:], packed)
+		return box("mdhd", b)
+	}
+	hdlr := func(handler string) []byte {
+		b := make([]byte, 25)
+		copy(b[8:], handler)
+		return box("hdlr", b)
+	}
+	stsd := func(entry []byte) []byte {
+		return box("minf", box("stbl", box("stsd", make([]byte, 8), entry)))
+	}
+
+	visual := make([]byte, mp4VisualEntryChildren)
+	binary.BigEndian.PutUint16(visual[24:], 1920)
+	binary.BigEndian.PutUint16(visual[26:], 1080)
+	colr := []byte("nclx\x00\x09\x00\x12\x00\x09\x00")
+	video := box("hvc1", visual, box("colr", colr))
+
+	sound := make([]byte, mp4AudioEntryChildren)
+	binary.BigEndian.PutUint16(sound[16:], 2)
+	binary.BigEndian.PutUint32(sound[24:], 44100<<16)
+	audio := box("mp4a", sound)
+
+	moov := box("moov",
+		box("mvhd", mvhd),
+		box("trak", tkhd(true), box("mdia", mdhd("und"), hdlr("vide"), stsd(video))),
+		box("trak", tkhd(true), box("mdia", mdhd("jpn"), hdlr("soun"), stsd(audio))),
+		box("trak", tkhd(false), box("mdia", mdhd("eng"), hdlr("sbtl"), stsd(box("tx3g", make([]byte, 38))))),
+	)
+
+	// moov atom placed after mdat, as it is written by most encoders
+	file := bytes.Join([][]byte{
+		box("ftyp", []byte("isom\x00\x00\x02\x00isomiso2")),
+		box("mdat", make([]byte, 4096)),
+		moov,
+	}, nil)
+
+	info, err := Probe(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Container != "mp4" || info.Duration != time.Minute {
+		t.Errorf("Unexpected container info: %s, %s", info.Container, info.Duration)
+	}
+	if v := info.Video(); v == nil || v.Codec != "hevc" || v.Width != 1920 || v.Height != 1080 || v.HDR != HLG {
+		t.Errorf("Unexpected video track: %+v", v)
+	}
+	if a := info.Audio(); len(a) != 1 || a[0].Codec != "aac" || a[0].Language != "jpn" || a[0].Channels != 2 || a[0].SampleRate != 44100 {
+		t.Errorf("Unexpected audio tracks: %+v", a)
+	}
+	if s := info.Subtitles(); len(s) != 1 || s[0].Codec != "mov_text" || !info.HasSubtitles("en") {
+		t.Errorf("Unexpected subtitle tracks: %+v", s)
+	}
+}
+
+func TestProbeTruncated(t *testing.T) {
+	// Elements and boxes with sizes past the end of file must not be read beyond it
+	for _, file := range []string{
+		"\x1aE\xdf\xa300000000",
+		"\x1aE\xdf\xa3\x80\x18\x53\x80\x67\x01\x00\x00\x00\x00\x00\x10\x00",
+		"\x00\x00\x00\x10ftypisom\x00\x00\x02\x00\xff\xff\xff\xffmdat",
+	} {
+		if _, err := Probe(bytes.NewReader([]byte(file)), int64(len(file))); err == nil {
+			t.Errorf("Expected error for truncated file %q", file)
+		}
+	}
+}
+
+func TestProbeUnknown(t *testing.T) {
+	file := []byte("RIFF\x00\x00\x00\x00AVI LIST")
+	if _, err := Probe(bytes.NewReader(file), int64(len(file))); err != ErrUnknownFormat {
+		t.Errorf("Expected unknown format error, got %v", err)
+	}
+}
+
+func TestProbeChapters(t *testing.T) {
+	chapter := func(start time.Duration, title string) []byte {
+		return ebml(mkvChapterAtom,
+			ebmlUint(mkvChapterTimeStart, uint64(start)),
+			ebml(mkvChapterDisplay, ebmlString(mkvChapString, title)),
+		)
+	}
+	chapters := ebml(mkvChapters,
+		ebml(mkvEditionEntry, chapter(0, "Director's Cut")),
+		ebml(mkvEditionEntry,
+			ebmlUint(mkvEditionFlagDef, 1),
+			chapter(90*time.Second, "Opening"),
+			chapter(0, "Prologue"),
+			chapter(40*time.Minute, "Ending"),
+		),
+	)
+
+	file := bytes.Join([][]byte{
+		ebml(mkvEBML),
+		ebml(mkvSegment,
+			ebml(mkvInfo, ebmlFloat(mkvDuration, float64(42*time.Minute/time.Millisecond))),
+			ebml(mkvTracks, ebml(mkvTrackEntry, ebmlUint(mkvTrackType, mkvTypeVideo), ebmlString(mkvCodecID, "V_AV1"))),
+			chapters,
+			ebml(mkvCluster, make([]byte, 1024)),
+		),
+	}, nil)
+
+	info, err := Probe(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := info.Chapters; len(c) != 3 || c[0].Title != "Prologue" || c[0].End != 90*time.Second || c[2].End != 42*time.Minute {
+		t.Errorf("Unexpected Matroska chapters: %+v", c)
+	}
+
+	chpl := []byte{1, 0, 0, 0, 0, 0, 0, 0, 2}
+	for _, c := range []struct {
+		start uint64
+		title string
+	}{{0, "Intro"}, {300000000, "Part 1"}} {
+		entry := make([]byte, 9)
+		binary.BigEndian.PutUint64(entry, c.start)
+		entry[8] = byte(len(c.title))
+		chpl = append(append(chpl, entry...), c.title...)
+	}
+
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[12:], 1000)
+	binary.BigEndian.PutUint32(mvhd[16:], 60000)
+
+	info = parseMoov(bytes.Join([][]byte{box("mvhd", mvhd), box("udta", box("chpl", chpl))}, nil))
+	if c := info.Chapters; len(c) != 2 || c[0].Title != "Intro" || c[0].End != 30*time.Second || c[1].End != time.Minute {
+		t.Errorf("Unexpected MP4 chapters: %+v", c)
+	}
+}
+
+func TestProbeChaptersPastEOF(t *testing.T) {
+	// Seek head may point to chapters beyond the end of a truncated or crafted file
+	seekHead := ebml(mkvSeekHead, ebml(mkvSeek, ebmlUint(mkvSeekID, mkvChapters), ebmlUint(mkvSeekPos, 1<<40)))
+	tracks := ebml(mkvTracks, ebml(mkvTrackEntry, ebmlUint(mkvTrackType, mkvTypeVideo), ebmlString(mkvCodecID, "V_VP9")))
+	file := bytes.Join([][]byte{ebml(mkvEBML), ebml(mkvSegment, seekHead, tracks, ebml(mkvCluster))}, nil)
+
+	info, err := Probe(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Chapters) != 0 {
+		t.Errorf("Unexpected chapters: %+v", info.Chapters)
+	}
+}
+
+func TestDetectMarkers(t *testing.T) {
+	chapters := func(titles ...string) []*Chapter {
+		starts := []time.Duration{0, 3 * time.Minute, 4*time.Minute + 30*time.Second, 20 * time.Minute, 41 * time.Minute, 43*time.Minute + 30*time.Second}
+		ret := []*Chapter{}
+		for i, title := range titles {
+			ret = append(ret, &Chapter{Title: title, Start: starts[i]})
+		}
+		finishChapters(ret, 44*time.Minute)
+		return ret
+	}
+
+	m := DetectMarkers(chapters("Cold Open", "Opening Credits", "Part A", "Part B", "End Credits", "Post-Credits Scene"), 0)
+	if m == nil || m.Intro.Start != 3*time.Minute || m.Intro.End != 4*time.Minute+30*time.Second || m.Credits.Start != 41*time.Minute {
+		t.Fatalf("Unexpected titled markers: %+v", m)
+	}
+	if !m.InIntro(200*time.Second) || m.InIntro(10*time.Minute) || !m.InCredits(42*time.Minute) || m.InCredits(30*time.Minute) {
+		t.Error("Unexpected position checks")
+	}
+
+	m = DetectMarkers(chapters("Chapter 01", "Chapter 02", "Chapter 03", "Chapter 04", "Chapter 05", "Chapter 06"), 44*time.Minute)
+	if m == nil || m.Intro == nil || m.Intro.Start != 3*time.Minute || m.Credits == nil || m.Credits.Start != 41*time.Minute {
+		t.Fatalf("Unexpected untitled markers: %+v", m)
+	}
+
+	if m := DetectMarkers(chapters("Chapter 01", "Chapter 02"), 44*time.Minute); m != nil {
+		t.Errorf("Markers should not be guessed from two chapters: %+v", m)
+	}
+}
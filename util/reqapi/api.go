@@ -44,6 +44,13 @@ var (
 		RetriesLeft: 3,
 		RateLimiter: util.NewRateLimiter(100, 10*time.Second, 25),
 	}
+
+	SimklAPI = &API{
+		Ident:       SimklIdent,
+		Endpoint:    "https://api.simkl.com",
+		RetriesLeft: 3,
+		RateLimiter: util.NewRateLimiter(100, 10*time.Second, 25),
+	}
 )
 
 var log = logging.MustGetLogger("reqapi")
@@ -56,6 +63,8 @@ func GetAPI(ident APIIdent) *API {
 		return TraktAPI
 	case FanArtIdent:
 		return FanartAPI
+	case SimklIdent:
+		return SimklAPI
 	default:
 		return nil
 	}
@@ -12,6 +12,7 @@ const (
 	TraktIdent         APIIdent = cache.TraktKey
 	FanArtIdent        APIIdent = cache.FanartKey
 	OpenSubtitlesIdent APIIdent = cache.OpensubtitlesKey
+	SimklIdent         APIIdent = cache.SimklKey
 )
 
 type API struct {
@@ -0,0 +1,298 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/anacrolix/sync"
+	"github.com/cespare/xxhash"
+	"github.com/op/go-logging"
+
+	"github.com/elgatito/elementum/proxy"
+)
+
+// Kinds of media sources
+const (
+	HTTP = "http"
+	HLS  = "hls"
+)
+
+const (
+	// sourceBlockSize is a size of ranges, read from HTTP sources for probing and subtitles hash
+	sourceBlockSize = 256 * 1024
+	// sourceMaxBlocks limits number of cached blocks of HTTP sources
+	sourceMaxBlocks = 32
+	// hlsMaxManifestSize limits size of manifest, read to check HLS sources
+	hlsMaxManifestSize = 1024 * 1024
+)
+
+var (
+	// sourceVideoExtensions are extensions of direct HTTP files, which are played without torrent
+	sourceVideoExtensions = map[string]bool{
+		".mkv":  true,
+		".mp4":  true,
+		".m4v":  true,
+		".mov":  true,
+		".webm": true,
+		".avi":  true,
+		".ts":   true,
+		".m2ts": true,
+		".mpg":  true,
+		".mpeg": true,
+		".wmv":  true,
+		".flv":  true,
+	}
+
+	errNoRanges = errors.New("server does not support range requests")
+	errClosed   = errors.New("source is closed")
+
+	log = logging.MustGetLogger("source")
+)
+
+// MediaSource is a non-torrent item of the player, like a direct HTTP file or HLS stream.
+// Player keeps resume, watched, scrobble, subtitles and UpNext handling for sources,
+// while Kodi reads the media directly from the source URL.
+type MediaSource interface {
+	// Kind is HTTP or HLS
+	Kind() string
+	// ID identifies the source among active players and stored resume points
+	ID() string
+	// Name is a file name of the media
+	Name() string
+	// Size is a length of the media, zero for live streams
+	Size() int64
+	// PlayURL is passed to Kodi to start playback
+	PlayURL() string
+	// Open checks availability of the source
+	Open() error
+	// ReaderAt returns reader of the media content, nil for sources without random access
+	ReaderAt() io.ReaderAt
+	// Close releases resources of the source
+	Close()
+}
+
+// New returns source for HTTP(S) links to video files or HLS manifests,
+// or nil for magnets and torrent links. Kind forces the source type, if not empty.
+func New(uri string, kind string) MediaSource {
+	u, err := url.Parse(uri)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil
+	}
+
+	if kind == "" {
+		ext := strings.ToLower(path.Ext(u.Path))
+		switch {
+		case ext == ".m3u8":
+			kind = HLS
+		case sourceVideoExtensions[ext]:
+			kind = HTTP
+		}
+	}
+
+	switch kind {
+	case HTTP:
+		return &httpSource{url: uri, name: sourceName(u)}
+	case HLS:
+		return &hlsSource{url: uri, name: sourceName(u)}
+	}
+	return nil
+}
+
+// sourceName returns unescaped last path element of the URL
+func sourceName(u *url.URL) string {
+	if name := path.Base(u.Path); name != "" && name != "/" && name != "." {
+		return name
+	}
+	return u.Host
+}
+
+func sourceID(kind, uri string) string {
+	return kind + ":" + strconv.FormatUint(xxhash.Sum64String(uri), 16)
+}
+
+// httpSource is a direct HTTP file, that supports range requests
+type httpSource struct {
+	url  string
+	name string
+	size int64
+
+	mu     sync.Mutex
+	blocks map[int64][]byte
+}
+
+func (s *httpSource) Kind() string    { return HTTP }
+func (s *httpSource) ID() string      { return sourceID(HTTP, s.url) }
+func (s *httpSource) Name() string    { return s.name }
+func (s *httpSource) Size() int64     { return s.size }
+func (s *httpSource) PlayURL() string { return s.url }
+
+// Open requests the first byte of the file, to get its size and check range support
+func (s *httpSource) Open() error {
+	resp, err := s.get(0, 0)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		s.name = path.Base(params["filename"])
+	}
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return errNoRanges
+	}
+
+	// Content-Range is "bytes 0-0/12345"
+	if _, total, ok := strings.Cut(resp.Header.Get("Content-Range"), "/"); ok {
+		s.size, _ = strconv.ParseInt(total, 10, 64)
+	}
+	if s.size <= 0 {
+		return errors.New("unknown size of the file")
+	}
+
+	s.blocks = map[int64][]byte{}
+	log.Infof("Opened HTTP source %s, size %d", s.name, s.size)
+	return nil
+}
+
+// ReaderAt ...
+func (s *httpSource) ReaderAt() io.ReaderAt {
+	return s
+}
+
+// ReadAt reads through cached blocks, so small reads of container headers do not issue a request each
+func (s *httpSource) ReadAt(b []byte, off int64) (n int, err error) {
+	for n < len(b) {
+		if off >= s.size {
+			return n, io.EOF
+		}
+
+		start := off - off%sourceBlockSize
+		block, err := s.block(start)
+		if err != nil {
+			return n, err
+		}
+
+		// Server may return less, than requested, so the block can end before the offset
+		if off-start >= int64(len(block)) {
+			return n, io.ErrUnexpectedEOF
+		}
+
+		copied := copy(b[n:], block[off-start:])
+		n += copied
+		off += int64(copied)
+	}
+	return n, nil
+}
+
+func (s *httpSource) block(start int64) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.blocks == nil {
+		return nil, errClosed
+	} else if b, ok := s.blocks[start]; ok {
+		return b, nil
+	}
+
+	end := start + sourceBlockSize - 1
+	if end >= s.size {
+		end = s.size - 1
+	}
+
+	resp, err := s.get(start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return nil, errNoRanges
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, end-start+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(s.blocks) >= sourceMaxBlocks {
+		s.blocks = map[int64][]byte{}
+	}
+	s.blocks[start] = b
+	return b, nil
+}
+
+func (s *httpSource) get(start, end int64) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+
+	resp, err := proxy.GetClient().Do(req)
+	if err != nil {
+		return nil, err
+	} else if resp.StatusCode >= 400 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("bad response status: %s", resp.Status)
+	}
+	return resp, nil
+}
+
+// Close ...
+func (s *httpSource) Close() {
+	s.mu.Lock()
+	s.blocks = nil
+	s.mu.Unlock()
+}
+
+// hlsSource is an HLS manifest, that is passed to Kodi as is
+type hlsSource struct {
+	url  string
+	name string
+}
+
+func (s *hlsSource) Kind() string          { return HLS }
+func (s *hlsSource) ID() string            { return sourceID(HLS, s.url) }
+func (s *hlsSource) Name() string          { return s.name }
+func (s *hlsSource) Size() int64           { return 0 }
+func (s *hlsSource) PlayURL() string       { return s.url }
+func (s *hlsSource) ReaderAt() io.ReaderAt { return nil }
+func (s *hlsSource) Close()                {}
+
+// Open checks that URL points to a playlist
+func (s *hlsSource) Open() error {
+	req, err := http.NewRequest(http.MethodGet, s.url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := proxy.GetClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("bad response status: %s", resp.Status)
+	}
+
+	r := bufio.NewReader(io.LimitReader(resp.Body, hlsMaxManifestSize))
+	line, err := r.ReadBytes('\n')
+	if err != nil && len(line) == 0 {
+		return err
+	} else if !bytes.HasPrefix(bytes.TrimPrefix(line, []byte("\xef\xbb\xbf")), []byte("#EXTM3U")) {
+		return errors.New("not an HLS playlist")
+	}
+
+	log.Infof("Opened HLS source %s", s.url)
+	return nil
+}
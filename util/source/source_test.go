@@ -0,0 +1,119 @@
+package source
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		uri  string
+		kind string
+		want string
+		name string
+	}{
+		{"https://cdn.example.com/live/master.m3u8?token=1", "", HLS, "master.m3u8"},
+		{"http://example.com/video/Movie%20Name.2020.mkv", "", HTTP, "Movie Name.2020.mkv"},
+		{"http://example.com/stream/segment.TS", "", HTTP, "segment.TS"},
+		{"http://example.com/play?id=42", HLS, HLS, "play"},
+		{"http://example.com/get/file", HTTP, HTTP, "file"},
+		{"http://example.com/download/file.torrent", "", "", ""},
+		{"magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567", "", "", ""},
+		{"magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567", HTTP, "", ""},
+		{"/home/user/movie.mkv", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		s := New(tt.uri, tt.kind)
+		if tt.want == "" {
+			if s != nil {
+				t.Errorf("New(%q, %q) = %s source, want nil", tt.uri, tt.kind, s.Kind())
+			}
+			continue
+		}
+
+		if s == nil {
+			t.Errorf("New(%q, %q) = nil, want %s source", tt.uri, tt.kind, tt.want)
+		} else if s.Kind() != tt.want || s.Name() != tt.name || s.PlayURL() != tt.uri {
+			t.Errorf("New(%q, %q) = %s source %q, want %s source %q", tt.uri, tt.kind, s.Kind(), s.Name(), tt.want, tt.name)
+		}
+	}
+
+	if New("http://a/1.mkv", "").ID() == New("http://a/2.mkv", "").ID() {
+		t.Error("Sources of different URLs should have different IDs")
+	}
+}
+
+// newRangeServer serves content with range requests, short responses are cut to a half of the requested range
+func newRangeServer(t *testing.T, content []byte, short bool) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var start, end int
+		if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end); err != nil {
+			w.Write(content)
+			return
+		}
+
+		body := content[start : end+1]
+		if short && len(body) > 1 {
+			body = body[:len(body)/2]
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(content)))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestHTTPSourceReadAt(t *testing.T) {
+	content := make([]byte, sourceBlockSize*2+100)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+
+	srv := newRangeServer(t, content, false)
+	s := New(srv.URL+"/movie.mkv", "")
+	if err := s.Open(); err != nil {
+		t.Fatalf("Open failed: %s", err)
+	}
+	if s.Size() != int64(len(content)) {
+		t.Fatalf("Unexpected size %d", s.Size())
+	}
+
+	// Read crosses the border of blocks
+	b := make([]byte, 200)
+	off := int64(sourceBlockSize - 100)
+	if n, err := s.ReaderAt().ReadAt(b, off); err != nil || n != len(b) || !bytes.Equal(b, content[off:off+200]) {
+		t.Fatalf("ReadAt(%d) = %d, %v", off, n, err)
+	}
+
+	// Read at the end returns available bytes with EOF
+	off = int64(len(content) - 50)
+	if n, err := s.ReaderAt().ReadAt(b, off); err != io.EOF || n != 50 || !bytes.Equal(b[:n], content[off:]) {
+		t.Fatalf("ReadAt(%d) = %d, %v", off, n, err)
+	}
+
+	s.Close()
+	if _, err := s.ReaderAt().ReadAt(b, 0); err == nil {
+		t.Error("ReadAt of closed source should fail")
+	}
+}
+
+func TestHTTPSourceShortResponse(t *testing.T) {
+	content := make([]byte, sourceBlockSize*2)
+
+	s := New(newRangeServer(t, content, true).URL+"/movie.mkv", "")
+	if err := s.Open(); err != nil {
+		t.Fatalf("Open failed: %s", err)
+	}
+
+	// Offset is beyond the half of the block, that server returns
+	b := make([]byte, 10)
+	if _, err := s.ReaderAt().ReadAt(b, sourceBlockSize-10); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected unexpected EOF for short response, got %v", err)
+	}
+}
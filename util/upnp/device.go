@@ -0,0 +1,221 @@
+package upnp
+
+import (
+	"crypto/md5"
+	"encoding/xml"
+	"fmt"
+)
+
+// Device and service types of the media server
+const (
+	MediaServerType       = "urn:schemas-upnp-org:device:MediaServer:1"
+	ContentDirectoryType  = "urn:schemas-upnp-org:service:ContentDirectory:1"
+	ConnectionManagerType = "urn:schemas-upnp-org:service:ConnectionManager:1"
+
+	contentDirectoryID  = "urn:upnp-org:serviceId:ContentDirectory"
+	connectionManagerID = "urn:upnp-org:serviceId:ConnectionManager"
+)
+
+// Device describes the media server, announced to the network
+type Device struct {
+	// UUID is a unique device identifier, without "uuid:" prefix
+	UUID         string
+	FriendlyName string
+	Manufacturer string
+	ModelName    string
+	ModelNumber  string
+	// Prefix is a path, handler is mounted to, like "/dlna"
+	Prefix string
+}
+
+// NewUUID returns stable UUID, generated from the seed
+func NewUUID(seed string) string {
+	h := md5.Sum([]byte(seed))
+	return fmt.Sprintf("%x-%x-%x-%x-%x", h[0:4], h[4:6], h[6:8], h[8:10], h[10:16])
+}
+
+// USN returns unique service name of the device for notification type
+func (d *Device) USN(nt string) string {
+	if nt == "uuid:"+d.UUID {
+		return nt
+	}
+	return "uuid:" + d.UUID + "::" + nt
+}
+
+// NotificationTypes returns all types, device is announced with
+func (d *Device) NotificationTypes() []string {
+	return []string{
+		"upnp:rootdevice",
+		"uuid:" + d.UUID,
+		MediaServerType,
+		ContentDirectoryType,
+		ConnectionManagerType,
+	}
+}
+
+// DescriptionPath returns path of device description, used as SSDP location
+func (d *Device) DescriptionPath() string {
+	return d.Prefix + "/device.xml"
+}
+
+type xmlService struct {
+	ServiceType string `xml:"serviceType"`
+	ServiceID   string `xml:"serviceId"`
+	SCPDURL     string `xml:"SCPDURL"`
+	ControlURL  string `xml:"controlURL"`
+	EventSubURL string `xml:"eventSubURL"`
+}
+
+type xmlDevice struct {
+	DeviceType   string       `xml:"deviceType"`
+	FriendlyName string       `xml:"friendlyName"`
+	Manufacturer string       `xml:"manufacturer"`
+	ModelName    string       `xml:"modelName"`
+	ModelNumber  string       `xml:"modelNumber"`
+	UDN          string       `xml:"UDN"`
+	DLNADoc      string       `xml:"urn:schemas-dlna-org:device-1-0 X_DLNADOC"`
+	Services     []xmlService `xml:"serviceList>service"`
+}
+
+type xmlRoot struct {
+	XMLName xml.Name  `xml:"urn:schemas-upnp-org:device-1-0 root"`
+	Major   int       `xml:"specVersion>major"`
+	Minor   int       `xml:"specVersion>minor"`
+	Device  xmlDevice `xml:"device"`
+}
+
+// Description returns device description document
+func (d *Device) Description() []byte {
+	root := xmlRoot{
+		Major: 1,
+		Minor: 0,
+		Device: xmlDevice{
+			DeviceType:   MediaServerType,
+			FriendlyName: d.FriendlyName,
+			Manufacturer: d.Manufacturer,
+			ModelName:    d.ModelName,
+			ModelNumber:  d.ModelNumber,
+			UDN:          "uuid:" + d.UUID,
+			DLNADoc:      "DMS-1.50",
+			Services: []xmlService{
+				{
+					ServiceType: ContentDirectoryType,
+					ServiceID:   contentDirectoryID,
+					SCPDURL:     d.Prefix + "/cds.xml",
+					ControlURL:  d.Prefix + "/control/cds",
+					EventSubURL: d.Prefix + "/event/cds",
+				},
+				{
+					ServiceType: ConnectionManagerType,
+					ServiceID:   connectionManagerID,
+					SCPDURL:     d.Prefix + "/cms.xml",
+					ControlURL:  d.Prefix + "/control/cms",
+					EventSubURL: d.Prefix + "/event/cms",
+				},
+			},
+		},
+	}
+
+	b, _ := xml.MarshalIndent(root, "", "  ")
+	return append([]byte(xml.Header), b...)
+}
+
+// contentDirectorySCPD describes actions of ContentDirectory service, that are implemented
+const contentDirectorySCPD = `<?xml version="1.0" encoding="utf-8"?>
+<scpd xmlns="urn:schemas-upnp-org:service-1-0">
+  <specVersion><major>1</major><minor>0</minor></specVersion>
+  <actionList>
+    <action>
+      <name>Browse</name>
+      <argumentList>
+        <argument><name>ObjectID</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_ObjectID</relatedStateVariable></argument>
+        <argument><name>BrowseFlag</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_BrowseFlag</relatedStateVariable></argument>
+        <argument><name>Filter</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_Filter</relatedStateVariable></argument>
+        <argument><name>StartingIndex</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_Index</relatedStateVariable></argument>
+        <argument><name>RequestedCount</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_Count</relatedStateVariable></argument>
+        <argument><name>SortCriteria</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_SortCriteria</relatedStateVariable></argument>
+        <argument><name>Result</name><direction>out</direction><relatedStateVariable>A_ARG_TYPE_Result</relatedStateVariable></argument>
+        <argument><name>NumberReturned</name><direction>out</direction><relatedStateVariable>A_ARG_TYPE_Count</relatedStateVariable></argument>
+        <argument><name>TotalMatches</name><direction>out</direction><relatedStateVariable>A_ARG_TYPE_Count</relatedStateVariable></argument>
+        <argument><name>UpdateID</name><direction>out</direction><relatedStateVariable>A_ARG_TYPE_UpdateID</relatedStateVariable></argument>
+      </argumentList>
+    </action>
+    <action>
+      <name>GetSearchCapabilities</name>
+      <argumentList>
+        <argument><name>SearchCaps</name><direction>out</direction><relatedStateVariable>SearchCapabilities</relatedStateVariable></argument>
+      </argumentList>
+    </action>
+    <action>
+      <name>GetSortCapabilities</name>
+      <argumentList>
+        <argument><name>SortCaps</name><direction>out</direction><relatedStateVariable>SortCapabilities</relatedStateVariable></argument>
+      </argumentList>
+    </action>
+    <action>
+      <name>GetSystemUpdateID</name>
+      <argumentList>
+        <argument><name>Id</name><direction>out</direction><relatedStateVariable>SystemUpdateID</relatedStateVariable></argument>
+      </argumentList>
+    </action>
+  </actionList>
+  <serviceStateTable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_ObjectID</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_Result</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_BrowseFlag</name><dataType>string</dataType><allowedValueList><allowedValue>BrowseMetadata</allowedValue><allowedValue>BrowseDirectChildren</allowedValue></allowedValueList></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_Filter</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_SortCriteria</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_Index</name><dataType>ui4</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_Count</name><dataType>ui4</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_UpdateID</name><dataType>ui4</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>SearchCapabilities</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>SortCapabilities</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="yes"><name>SystemUpdateID</name><dataType>ui4</dataType></stateVariable>
+  </serviceStateTable>
+</scpd>`
+
+// connectionManagerSCPD describes actions of ConnectionManager service
+const connectionManagerSCPD = `<?xml version="1.0" encoding="utf-8"?>
+<scpd xmlns="urn:schemas-upnp-org:service-1-0">
+  <specVersion><major>1</major><minor>0</minor></specVersion>
+  <actionList>
+    <action>
+      <name>GetProtocolInfo</name>
+      <argumentList>
+        <argument><name>Source</name><direction>out</direction><relatedStateVariable>SourceProtocolInfo</relatedStateVariable></argument>
+        <argument><name>Sink</name><direction>out</direction><relatedStateVariable>SinkProtocolInfo</relatedStateVariable></argument>
+      </argumentList>
+    </action>
+    <action>
+      <name>GetCurrentConnectionIDs</name>
+      <argumentList>
+        <argument><name>ConnectionIDs</name><direction>out</direction><relatedStateVariable>CurrentConnectionIDs</relatedStateVariable></argument>
+      </argumentList>
+    </action>
+    <action>
+      <name>GetCurrentConnectionInfo</name>
+      <argumentList>
+        <argument><name>ConnectionID</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_ConnectionID</relatedStateVariable></argument>
+        <argument><name>RcsID</name><direction>out</direction><relatedStateVariable>A_ARG_TYPE_RcsID</relatedStateVariable></argument>
+        <argument><name>AVTransportID</name><direction>out</direction><relatedStateVariable>A_ARG_TYPE_AVTransportID</relatedStateVariable></argument>
+        <argument><name>ProtocolInfo</name><direction>out</direction><relatedStateVariable>A_ARG_TYPE_ProtocolInfo</relatedStateVariable></argument>
+        <argument><name>PeerConnectionManager</name><direction>out</direction><relatedStateVariable>A_ARG_TYPE_ConnectionManager</relatedStateVariable></argument>
+        <argument><name>PeerConnectionID</name><direction>out</direction><relatedStateVariable>A_ARG_TYPE_ConnectionID</relatedStateVariable></argument>
+        <argument><name>Direction</name><direction>out</direction><relatedStateVariable>A_ARG_TYPE_Direction</relatedStateVariable></argument>
+        <argument><name>Status</name><direction>out</direction><relatedStateVariable>A_ARG_TYPE_ConnectionStatus</relatedStateVariable></argument>
+      </argumentList>
+    </action>
+  </actionList>
+  <serviceStateTable>
+    <stateVariable sendEvents="yes"><name>SourceProtocolInfo</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="yes"><name>SinkProtocolInfo</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="yes"><name>CurrentConnectionIDs</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_ConnectionStatus</name><dataType>string</dataType><allowedValueList><allowedValue>OK</allowedValue><allowedValue>ContentFormatMismatch</allowedValue><allowedValue>InsufficientBandwidth</allowedValue><allowedValue>UnreliableChannel</allowedValue><allowedValue>Unknown</allowedValue></allowedValueList></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_ConnectionManager</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_Direction</name><dataType>string</dataType><allowedValueList><allowedValue>Input</allowedValue><allowedValue>Output</allowedValue></allowedValueList></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_ProtocolInfo</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_ConnectionID</name><dataType>i4</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_AVTransportID</name><dataType>i4</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_RcsID</name><dataType>i4</dataType></stateVariable>
+  </serviceStateTable>
+</scpd>`
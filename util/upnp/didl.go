@@ -0,0 +1,177 @@
+package upnp
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// UPnP classes of content directory objects
+const (
+	ClassContainer = "object.container.storageFolder"
+	ClassVideo     = "object.item.videoItem"
+	ClassMovie     = "object.item.videoItem.movie"
+	ClassAudio     = "object.item.audioItem.musicTrack"
+	ClassItem      = "object.item"
+)
+
+// dlnaStreamingFlags allow range requests and streaming transfer mode
+const dlnaStreamingFlags = "DLNA.ORG_OP=01;DLNA.ORG_CI=0;DLNA.ORG_FLAGS=01700000000000000000000000000000"
+
+var mimeTypes = map[string]string{
+	".mkv":  "video/x-matroska",
+	".mk3d": "video/x-matroska",
+	".webm": "video/webm",
+	".mp4":  "video/mp4",
+	".m4v":  "video/mp4",
+	".mov":  "video/quicktime",
+	".avi":  "video/avi",
+	".divx": "video/avi",
+	".wmv":  "video/x-ms-wmv",
+	".asf":  "video/x-ms-asf",
+	".mpg":  "video/mpeg",
+	".mpeg": "video/mpeg",
+	".vob":  "video/mpeg",
+	".ts":   "video/vnd.dlna.mpeg-tts",
+	".m2ts": "video/vnd.dlna.mpeg-tts",
+	".mts":  "video/vnd.dlna.mpeg-tts",
+	".flv":  "video/x-flv",
+	".mp3":  "audio/mpeg",
+	".flac": "audio/flac",
+	".m4a":  "audio/mp4",
+	".ogg":  "audio/ogg",
+	".wav":  "audio/wav",
+	".srt":  "application/x-subrip",
+}
+
+// Object is a container or an item of content directory
+type Object struct {
+	ID         string
+	ParentID   string
+	Title      string
+	Class      string
+	ChildCount int
+	Date       time.Time
+	// AlbumArt is an URL of the poster
+	AlbumArt  string
+	Resources []*Resource
+}
+
+// Resource is a playable representation of the item
+type Resource struct {
+	URL          string
+	ProtocolInfo string
+	Size         int64
+	Duration     time.Duration
+	// Resolution is formatted as "1920x1080"
+	Resolution string
+}
+
+// IsContainer returns whether object is a container
+func (o *Object) IsContainer() bool {
+	return strings.HasPrefix(o.Class, "object.container")
+}
+
+// MimeType returns MIME type, DLNA clients expect for the file
+func MimeType(path string) string {
+	if t, ok := mimeTypes[strings.ToLower(filepath.Ext(path))]; ok {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// ClassFor returns UPnP class of the item for the file
+func ClassFor(path string) string {
+	switch t := MimeType(path); {
+	case strings.HasPrefix(t, "audio/"):
+		return ClassAudio
+	case strings.HasPrefix(t, "video/"):
+		return ClassVideo
+	}
+	return ClassItem
+}
+
+// ProtocolInfo returns protocol info of HTTP resource with MIME type
+func ProtocolInfo(mimeType string) string {
+	return "http-get:*:" + mimeType + ":" + dlnaStreamingFlags
+}
+
+// ContentFeatures returns value for contentFeatures.dlna.org header of streamed files
+func ContentFeatures() string {
+	return dlnaStreamingFlags
+}
+
+type didlRes struct {
+	ProtocolInfo string `xml:"protocolInfo,attr"`
+	Size         int64  `xml:"size,attr,omitempty"`
+	Duration     string `xml:"duration,attr,omitempty"`
+	Resolution   string `xml:"resolution,attr,omitempty"`
+	URL          string `xml:",chardata"`
+}
+
+type didlObject struct {
+	XMLName    xml.Name
+	ID         string    `xml:"id,attr"`
+	ParentID   string    `xml:"parentID,attr"`
+	Restricted int       `xml:"restricted,attr"`
+	ChildCount *int      `xml:"childCount,attr"`
+	Searchable *int      `xml:"searchable,attr"`
+	Title      string    `xml:"dc:title"`
+	Class      string    `xml:"upnp:class"`
+	Date       string    `xml:"dc:date,omitempty"`
+	AlbumArt   string    `xml:"upnp:albumArtURI,omitempty"`
+	Res        []didlRes `xml:"res"`
+}
+
+// MarshalDIDL returns DIDL-Lite document with objects
+func MarshalDIDL(objects []*Object) string {
+	var b strings.Builder
+	b.WriteString(`<DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:upnp="urn:schemas-upnp-org:metadata-1-0/upnp/">`)
+
+	enc := xml.NewEncoder(&b)
+	for _, o := range objects {
+		obj := didlObject{
+			XMLName:    xml.Name{Local: "item"},
+			ID:         o.ID,
+			ParentID:   o.ParentID,
+			Restricted: 1,
+			Title:      o.Title,
+			Class:      o.Class,
+			AlbumArt:   o.AlbumArt,
+		}
+		if o.IsContainer() {
+			count, searchable := o.ChildCount, 0
+			obj.XMLName.Local = "container"
+			obj.ChildCount = &count
+			obj.Searchable = &searchable
+		}
+		if !o.Date.IsZero() {
+			obj.Date = o.Date.Format("2006-01-02")
+		}
+		for _, r := range o.Resources {
+			res := didlRes{
+				ProtocolInfo: r.ProtocolInfo,
+				Size:         r.Size,
+				Resolution:   r.Resolution,
+				URL:          r.URL,
+			}
+			if r.Duration > 0 {
+				res.Duration = formatDuration(r.Duration)
+			}
+			obj.Res = append(obj.Res, res)
+		}
+		enc.Encode(obj)
+	}
+	enc.Flush()
+
+	b.WriteString(`</DIDL-Lite>`)
+	return b.String()
+}
+
+// formatDuration formats duration as "H:MM:SS.000"
+func formatDuration(d time.Duration) string {
+	s := int(d.Seconds())
+	return fmt.Sprintf("%d:%02d:%02d.000", s/3600, s/60%60, s%60)
+}
@@ -0,0 +1,267 @@
+package upnp
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UPnP error codes, returned in SOAP faults
+const (
+	ErrorInvalidAction = 401
+	ErrorInvalidArgs   = 402
+	ErrorActionFailed  = 501
+	ErrorNoSuchObject  = 701
+)
+
+// ErrNoSuchObject is returned by content directory for unknown object IDs
+var ErrNoSuchObject = errors.New("no such object")
+
+// ContentDirectory provides objects to browse.
+// Base is an URL of the server, as it was requested by the client, like "http://192.168.1.2:65220".
+type ContentDirectory interface {
+	// Object returns metadata of the object
+	Object(base, id string) (*Object, error)
+	// Children returns direct children of the container
+	Children(base, id string) ([]*Object, error)
+}
+
+// Handler serves device description and control requests of ContentDirectory and ConnectionManager
+type Handler struct {
+	Device  *Device
+	Content ContentDirectory
+
+	updateID uint32
+}
+
+// NewHandler ...
+func NewHandler(device *Device, content ContentDirectory) *Handler {
+	return &Handler{
+		Device:   device,
+		Content:  content,
+		updateID: uint32(time.Now().Unix()),
+	}
+}
+
+// ServeHTTP ...
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, h.Device.Prefix)
+
+	switch {
+	case path == "/device.xml":
+		writeXML(w, h.Device.Description())
+	case path == "/cds.xml":
+		writeXML(w, []byte(contentDirectorySCPD))
+	case path == "/cms.xml":
+		writeXML(w, []byte(connectionManagerSCPD))
+	case path == "/control/cds" && r.Method == http.MethodPost:
+		h.control(w, r, ContentDirectoryType, h.contentDirectoryAction)
+	case path == "/control/cms" && r.Method == http.MethodPost:
+		h.control(w, r, ConnectionManagerType, h.connectionManagerAction)
+	case strings.HasPrefix(path, "/event/"):
+		h.event(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Error is an UPnP error, returned as SOAP fault
+type Error struct {
+	Code        int
+	Description string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("UPnP error %d: %s", e.Code, e.Description)
+}
+
+type actionFunc func(r *http.Request, action string, args map[string]string) ([][2]string, error)
+
+type soapEnvelope struct {
+	Body struct {
+		Action struct {
+			XMLName xml.Name
+			Args    []struct {
+				XMLName xml.Name
+				Value   string `xml:",chardata"`
+			} `xml:",any"`
+		} `xml:",any"`
+	} `xml:"Body"`
+}
+
+func (h *Handler) control(w http.ResponseWriter, r *http.Request, serviceType string, fn actionFunc) {
+	var env soapEnvelope
+	if err := xml.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&env); err != nil {
+		writeFault(w, &Error{ErrorInvalidAction, "Invalid SOAP request"})
+		return
+	}
+
+	action := env.Body.Action.XMLName.Local
+	args := map[string]string{}
+	for _, a := range env.Body.Action.Args {
+		args[a.XMLName.Local] = a.Value
+	}
+
+	out, err := fn(r, action, args)
+	if err != nil {
+		log.Debugf("UPnP action %s failed: %s", action, err)
+		upnpErr := &Error{}
+		if !errors.As(err, &upnpErr) {
+			upnpErr = &Error{ErrorActionFailed, err.Error()}
+		}
+		writeFault(w, upnpErr)
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
+	b.WriteString(`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>`)
+	fmt.Fprintf(&b, `<u:%sResponse xmlns:u="%s">`, action, serviceType)
+	for _, kv := range out {
+		fmt.Fprintf(&b, "<%s>%s</%s>", kv[0], escapeXML(kv[1]), kv[0])
+	}
+	fmt.Fprintf(&b, `</u:%sResponse></s:Body></s:Envelope>`, action)
+
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	w.Header().Set("Ext", "")
+	io.WriteString(w, b.String())
+}
+
+func (h *Handler) contentDirectoryAction(r *http.Request, action string, args map[string]string) ([][2]string, error) {
+	switch action {
+	case "GetSearchCapabilities":
+		return [][2]string{{"SearchCaps", ""}}, nil
+	case "GetSortCapabilities":
+		return [][2]string{{"SortCaps", ""}}, nil
+	case "GetSystemUpdateID":
+		return [][2]string{{"Id", strconv.FormatUint(uint64(h.updateID), 10)}}, nil
+	case "Browse":
+		return h.browse(r, args)
+	}
+	return nil, &Error{ErrorInvalidAction, "Invalid action " + action}
+}
+
+func (h *Handler) browse(r *http.Request, args map[string]string) ([][2]string, error) {
+	base := "http://" + r.Host
+	id := args["ObjectID"]
+
+	var objects []*Object
+	total := 1
+
+	switch args["BrowseFlag"] {
+	case "BrowseMetadata":
+		obj, err := h.Content.Object(base, id)
+		if err != nil {
+			return nil, browseError(err)
+		}
+		objects = []*Object{obj}
+	case "BrowseDirectChildren":
+		children, err := h.Content.Children(base, id)
+		if err != nil {
+			return nil, browseError(err)
+		}
+		total = len(children)
+
+		start, _ := strconv.Atoi(args["StartingIndex"])
+		count, _ := strconv.Atoi(args["RequestedCount"])
+		if start < 0 || start > len(children) {
+			start = len(children)
+		}
+		end := len(children)
+		if count > 0 && start+count < end {
+			end = start + count
+		}
+		objects = children[start:end]
+	default:
+		return nil, &Error{ErrorInvalidArgs, "Invalid BrowseFlag"}
+	}
+
+	return [][2]string{
+		{"Result", MarshalDIDL(objects)},
+		{"NumberReturned", strconv.Itoa(len(objects))},
+		{"TotalMatches", strconv.Itoa(total)},
+		{"UpdateID", strconv.FormatUint(uint64(h.updateID), 10)},
+	}, nil
+}
+
+func (h *Handler) connectionManagerAction(r *http.Request, action string, args map[string]string) ([][2]string, error) {
+	switch action {
+	case "GetProtocolInfo":
+		seen := map[string]bool{}
+		source := []string{}
+		for _, t := range mimeTypes {
+			if !seen[t] {
+				seen[t] = true
+				source = append(source, "http-get:*:"+t+":*")
+			}
+		}
+		sort.Strings(source)
+		return [][2]string{{"Source", strings.Join(source, ",")}, {"Sink", ""}}, nil
+	case "GetCurrentConnectionIDs":
+		return [][2]string{{"ConnectionIDs", "0"}}, nil
+	case "GetCurrentConnectionInfo":
+		return [][2]string{
+			{"RcsID", "-1"},
+			{"AVTransportID", "-1"},
+			{"ProtocolInfo", ""},
+			{"PeerConnectionManager", ""},
+			{"PeerConnectionID", "-1"},
+			{"Direction", "Output"},
+			{"Status", "OK"},
+		}, nil
+	}
+	return nil, &Error{ErrorInvalidAction, "Invalid action " + action}
+}
+
+// event accepts subscriptions without sending events, as content changes are not tracked,
+// but some clients refuse to work with a server, that does not accept subscriptions
+func (h *Handler) event(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "SUBSCRIBE":
+		sid := r.Header.Get("Sid")
+		if sid == "" {
+			sid = "uuid:" + NewUUID(fmt.Sprintf("%s%d", r.RemoteAddr, time.Now().UnixNano()))
+		}
+		w.Header().Set("Sid", sid)
+		w.Header().Set("Timeout", "Second-1800")
+		w.WriteHeader(http.StatusOK)
+	case "UNSUBSCRIBE":
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func browseError(err error) error {
+	if errors.Is(err, ErrNoSuchObject) {
+		return &Error{ErrorNoSuchObject, "No such object"}
+	}
+	return err
+}
+
+func writeFault(w http.ResponseWriter, err *Error) {
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	w.WriteHeader(http.StatusInternalServerError)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?>`+
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>`+
+		`<s:Fault><faultcode>s:Client</faultcode><faultstring>UPnPError</faultstring><detail>`+
+		`<UPnPError xmlns="urn:schemas-upnp-org:control-1-0"><errorCode>%d</errorCode><errorDescription>%s</errorDescription></UPnPError>`+
+		`</detail></s:Fault></s:Body></s:Envelope>`, err.Code, escapeXML(err.Description))
+}
+
+func writeXML(w http.ResponseWriter, b []byte) {
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	w.Write(b)
+}
+
+func escapeXML(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,316 @@
+package upnp
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/op/go-logging"
+	"golang.org/x/net/ipv4"
+)
+
+var log = logging.MustGetLogger("upnp")
+
+// SSDPGroup is a standard SSDP multicast address
+var SSDPGroup = &net.UDPAddr{IP: net.IPv4(239, 255, 255, 250), Port: 1900}
+
+const (
+	defaultMaxAge = 30 * time.Minute
+	// maxResponseDelay limits random delay of search responses, requested by MX header
+	maxResponseDelay = time.Second
+)
+
+// SSDPServer announces the device and answers search requests
+type SSDPServer struct {
+	Device *Device
+	// Group is a multicast address to listen on, SSDPGroup if nil
+	Group *net.UDPAddr
+	// HTTPPort is a port of HTTP server, device description is served by
+	HTTPPort int
+	// MaxAge is a validity of announcements, they are repeated three times per period
+	MaxAge time.Duration
+	// ServerName is sent in SERVER header, like "Elementum/0.1"
+	ServerName string
+
+	conn    *net.UDPConn
+	pconn   *ipv4.PacketConn
+	closing chan struct{}
+	wg      sync.WaitGroup
+}
+
+// SearchResponse is a response to M-SEARCH request
+type SearchResponse struct {
+	ST       string
+	USN      string
+	Location string
+	Server   string
+	From     *net.UDPAddr
+}
+
+// Start starts listening for search requests and sending announcements
+func (s *SSDPServer) Start() (err error) {
+	if s.Group == nil {
+		s.Group = SSDPGroup
+	}
+	if s.MaxAge == 0 {
+		s.MaxAge = defaultMaxAge
+	}
+
+	if s.conn, err = net.ListenMulticastUDP("udp4", nil, s.Group); err != nil {
+		return err
+	}
+
+	// Joining the group on every interface, as default one can be a VPN
+	s.pconn = ipv4.NewPacketConn(s.conn)
+	for _, iface := range multicastInterfaces() {
+		iface := iface
+		if err := s.pconn.JoinGroup(&iface, &net.UDPAddr{IP: s.Group.IP}); err != nil {
+			log.Debugf("Cannot join SSDP group on %s: %s", iface.Name, err)
+		}
+	}
+	s.pconn.SetMulticastLoopback(true)
+
+	s.closing = make(chan struct{})
+	s.wg.Add(2)
+	go s.serve()
+	go s.announce()
+
+	log.Infof("Started SSDP server at %s for %s", s.Group, s.Device.FriendlyName)
+	return nil
+}
+
+// Close sends byebye notifications and stops the server
+func (s *SSDPServer) Close() {
+	if s.conn == nil {
+		return
+	}
+
+	close(s.closing)
+	s.notify("ssdp:byebye")
+	s.conn.Close()
+	s.wg.Wait()
+	s.conn = nil
+}
+
+func (s *SSDPServer) serve() {
+	defer s.wg.Done()
+
+	buf := make([]byte, 2048)
+	for {
+		n, from, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-s.closing:
+				return
+			default:
+			}
+			log.Debugf("SSDP read error: %s", err)
+			continue
+		}
+
+		req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(buf[:n])))
+		if err != nil || req.Method != "M-SEARCH" || strings.Trim(req.Header.Get("Man"), `"`) != "ssdp:discover" {
+			continue
+		}
+
+		go s.respond(req.Header.Get("St"), req.Header.Get("Mx"), from)
+	}
+}
+
+func (s *SSDPServer) respond(st, mx string, to *net.UDPAddr) {
+	types := []string{}
+	for _, nt := range s.Device.NotificationTypes() {
+		if st == "ssdp:all" || st == nt {
+			types = append(types, nt)
+		}
+	}
+	if len(types) == 0 {
+		return
+	}
+
+	// Responses are spread in time, as requested by MX header
+	if wait, _ := strconv.Atoi(mx); wait > 0 {
+		delay := time.Duration(wait) * time.Second
+		if delay > maxResponseDelay {
+			delay = maxResponseDelay
+		}
+		time.Sleep(time.Duration(rand.Int63n(int64(delay))))
+	}
+
+	location := s.location(localIPFor(to))
+	for _, nt := range types {
+		msg := fmt.Sprintf("HTTP/1.1 200 OK\r\n"+
+			"CACHE-CONTROL: max-age=%d\r\n"+
+			"DATE: %s\r\n"+
+			"EXT:\r\n"+
+			"LOCATION: %s\r\n"+
+			"SERVER: %s\r\n"+
+			"ST: %s\r\n"+
+			"USN: %s\r\n\r\n",
+			int(s.MaxAge.Seconds()), time.Now().UTC().Format(http.TimeFormat), location, s.serverHeader(), nt, s.Device.USN(nt))
+
+		if _, err := s.conn.WriteToUDP([]byte(msg), to); err != nil {
+			log.Debugf("Cannot send SSDP response to %s: %s", to, err)
+			return
+		}
+	}
+}
+
+func (s *SSDPServer) announce() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.MaxAge / 3)
+	defer ticker.Stop()
+
+	s.notify("ssdp:alive")
+	for {
+		select {
+		case <-s.closing:
+			return
+		case <-ticker.C:
+			s.notify("ssdp:alive")
+		}
+	}
+}
+
+// notify sends notifications to every interface with its own address in location
+func (s *SSDPServer) notify(nts string) {
+	for _, iface := range multicastInterfaces() {
+		iface := iface
+		ip := interfaceIPv4(&iface)
+		if ip == nil {
+			continue
+		}
+		if err := s.pconn.SetMulticastInterface(&iface); err != nil {
+			continue
+		}
+
+		for _, nt := range s.Device.NotificationTypes() {
+			msg := fmt.Sprintf("NOTIFY * HTTP/1.1\r\n"+
+				"HOST: %s\r\n"+
+				"CACHE-CONTROL: max-age=%d\r\n"+
+				"LOCATION: %s\r\n"+
+				"NT: %s\r\n"+
+				"NTS: %s\r\n"+
+				"SERVER: %s\r\n"+
+				"USN: %s\r\n\r\n",
+				s.Group, int(s.MaxAge.Seconds()), s.location(ip), nt, nts, s.serverHeader(), s.Device.USN(nt))
+
+			if _, err := s.pconn.WriteTo([]byte(msg), nil, s.Group); err != nil {
+				log.Debugf("Cannot send SSDP notification to %s: %s", iface.Name, err)
+				break
+			}
+		}
+	}
+}
+
+func (s *SSDPServer) location(ip net.IP) string {
+	return fmt.Sprintf("http://%s:%d%s", ip, s.HTTPPort, s.Device.DescriptionPath())
+}
+
+func (s *SSDPServer) serverHeader() string {
+	return "Linux/1.0 UPnP/1.0 DLNADOC/1.50 " + s.ServerName
+}
+
+// Search sends M-SEARCH request to the group and collects responses until timeout
+func Search(group *net.UDPAddr, st string, timeout time.Duration) ([]*SearchResponse, error) {
+	if group == nil {
+		group = SSDPGroup
+	}
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	mx := int(timeout.Seconds())
+	if mx < 1 {
+		mx = 1
+	}
+	msg := fmt.Sprintf("M-SEARCH * HTTP/1.1\r\nHOST: %s\r\nMAN: \"ssdp:discover\"\r\nMX: %d\r\nST: %s\r\n\r\n", group, mx, st)
+	if _, err := conn.WriteToUDP([]byte(msg), group); err != nil {
+		return nil, err
+	}
+
+	conn.SetReadDeadline(time.Now().Add(timeout))
+
+	ret := []*SearchResponse{}
+	buf := make([]byte, 2048)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				return ret, nil
+			}
+			return ret, err
+		}
+
+		resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(buf[:n])), nil)
+		if err != nil || resp.StatusCode != http.StatusOK {
+			continue
+		}
+		ret = append(ret, &SearchResponse{
+			ST:       resp.Header.Get("St"),
+			USN:      resp.Header.Get("Usn"),
+			Location: resp.Header.Get("Location"),
+			Server:   resp.Header.Get("Server"),
+			From:     from,
+		})
+	}
+}
+
+// multicastInterfaces returns active interfaces, that support multicast and have IPv4 address
+func multicastInterfaces() []net.Interface {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+
+	ret := []net.Interface{}
+	for _, iface := range ifaces {
+		iface := iface
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagMulticast == 0 || interfaceIPv4(&iface) == nil {
+			continue
+		}
+		ret = append(ret, iface)
+	}
+	return ret
+}
+
+func interfaceIPv4(iface *net.Interface) net.IP {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok {
+			if ip := ipnet.IP.To4(); ip != nil && !ip.IsLinkLocalUnicast() {
+				return ip
+			}
+		}
+	}
+	return nil
+}
+
+// localIPFor returns local address, that is used to reach the remote host
+func localIPFor(remote *net.UDPAddr) net.IP {
+	conn, err := net.DialUDP("udp4", nil, remote)
+	if err != nil {
+		return net.IPv4(127, 0, 0, 1)
+	}
+	defer conn.Close()
+
+	ip := conn.LocalAddr().(*net.UDPAddr).IP
+	if strings.HasPrefix(ip.String(), "0.") {
+		return net.IPv4(127, 0, 0, 1)
+	}
+	return ip
+}
@@ -0,0 +1,142 @@
+package upnp
+
+import (
+	"encoding/xml"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testContent struct{}
+
+func (c *testContent) Object(base, id string) (*Object, error) {
+	if id != "0" {
+		return nil, ErrNoSuchObject
+	}
+	return &Object{ID: "0", ParentID: "-1", Title: "Root", Class: ClassContainer, ChildCount: 3}, nil
+}
+
+func (c *testContent) Children(base, id string) ([]*Object, error) {
+	if id != "0" {
+		return nil, ErrNoSuchObject
+	}
+	ret := []*Object{}
+	for _, name := range []string{"One & Two.mkv", "Three.mp4", "Four.avi"} {
+		ret = append(ret, &Object{
+			ID:       "0/" + name,
+			ParentID: "0",
+			Title:    name,
+			Class:    ClassFor(name),
+			Resources: []*Resource{{
+				URL:          base + "/files/" + name,
+				ProtocolInfo: ProtocolInfo(MimeType(name)),
+				Size:         1024,
+				Duration:     90 * time.Minute,
+			}},
+		})
+	}
+	return ret, nil
+}
+
+func soapBrowse(t *testing.T, url, id, flag string, start, count int) (*http.Response, map[string]string) {
+	body := `<?xml version="1.0"?><s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>` +
+		`<u:Browse xmlns:u="urn:schemas-upnp-org:service:ContentDirectory:1">` +
+		`<ObjectID>` + id + `</ObjectID><BrowseFlag>` + flag + `</BrowseFlag><Filter>*</Filter>` +
+		`<StartingIndex>` + strconv.Itoa(start) + `</StartingIndex><RequestedCount>` + strconv.Itoa(count) + `</RequestedCount>` +
+		`<SortCriteria></SortCriteria></u:Browse></s:Body></s:Envelope>`
+
+	req, _ := http.NewRequest(http.MethodPost, url+"/dlna/control/cds", strings.NewReader(body))
+	req.Header.Set("SOAPAction", `"urn:schemas-upnp-org:service:ContentDirectory:1#Browse"`)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var env soapEnvelope
+	if err := xml.NewDecoder(resp.Body).Decode(&env); err != nil {
+		t.Fatal(err)
+	}
+	ret := map[string]string{}
+	for _, a := range env.Body.Action.Args {
+		ret[a.XMLName.Local] = a.Value
+	}
+	return resp, ret
+}
+
+func TestHandlerBrowse(t *testing.T) {
+	device := &Device{UUID: NewUUID("test"), FriendlyName: "Test", Prefix: "/dlna"}
+	srv := httptest.NewServer(NewHandler(device, &testContent{}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/dlna/device.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Unexpected description status %d", resp.StatusCode)
+	}
+
+	resp, out := soapBrowse(t, srv.URL, "0", "BrowseDirectChildren", 1, 5)
+	if resp.StatusCode != http.StatusOK || out["NumberReturned"] != "2" || out["TotalMatches"] != "3" {
+		t.Fatalf("Unexpected browse response %d: %v", resp.StatusCode, out)
+	}
+	didl := out["Result"]
+	if !strings.Contains(didl, `<item id="0/Three.mp4" parentID="0" restricted="1">`) ||
+		!strings.Contains(didl, `<upnp:class>object.item.videoItem</upnp:class>`) ||
+		!strings.Contains(didl, `size="1024" duration="1:30:00.000">`+srv.URL+`/files/Three.mp4</res>`) ||
+		strings.Contains(didl, "One") {
+		t.Errorf("Unexpected DIDL: %s", didl)
+	}
+
+	_, out = soapBrowse(t, srv.URL, "0", "BrowseMetadata", 0, 0)
+	if !strings.Contains(out["Result"], `<container id="0" parentID="-1" restricted="1" childCount="3" searchable="0">`) {
+		t.Errorf("Unexpected metadata: %s", out["Result"])
+	}
+
+	resp, _ = soapBrowse(t, srv.URL, "missing", "BrowseMetadata", 0, 0)
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected fault for missing object, got %d", resp.StatusCode)
+	}
+}
+
+func TestSSDPSearch(t *testing.T) {
+	if len(multicastInterfaces()) == 0 {
+		t.Skip("No multicast interfaces")
+	}
+
+	device := &Device{UUID: NewUUID("ssdp-test"), FriendlyName: "Test", Prefix: "/dlna"}
+	group := &net.UDPAddr{IP: SSDPGroup.IP, Port: 21900}
+	srv := &SSDPServer{Device: device, Group: group, HTTPPort: 65220, ServerName: "Test/1.0"}
+	if err := srv.Start(); err != nil {
+		t.Skipf("Cannot listen for multicast: %s", err)
+	}
+	defer srv.Close()
+
+	// Search request is delivered back to the host by multicast loopback
+	responses, err := Search(group, ContentDirectoryType, 1500*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(responses) != 1 {
+		t.Fatalf("Expected a single response, got %d", len(responses))
+	}
+	r := responses[0]
+	if r.ST != ContentDirectoryType || r.USN != "uuid:"+device.UUID+"::"+ContentDirectoryType ||
+		!strings.HasSuffix(r.Location, ":65220/dlna/device.xml") || !strings.HasSuffix(r.Server, "Test/1.0") {
+		t.Errorf("Unexpected response: %+v", r)
+	}
+
+	responses, err = Search(group, "ssdp:all", 1500*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(responses) != len(device.NotificationTypes()) {
+		t.Errorf("Expected responses for all types, got %d", len(responses))
+	}
+}
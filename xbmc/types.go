@@ -109,6 +109,7 @@ type ListItemInfo struct {
 	Top250        int            `json:"top250,omitempty"`
 	TrackNumber   int            `json:"tracknumber,omitempty"`
 	Rating        float32        `json:"rating,omitempty"`
+	UserRating    int            `json:"userrating,omitempty"`
 	PlayCount     int            `json:"playcount,omitempty"`
 	Overlay       GUIIconOverlay `json:"overlay,omitempty"`
 	Director      []string       `json:"director,omitempty"`